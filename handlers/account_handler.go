@@ -0,0 +1,233 @@
+package handlers
+
+import (
+	"archive/zip"
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"mentorly-backend/services"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+)
+
+// deletionConfirmation es el texto que el usuario tiene que escribir para eliminar su cuenta
+const deletionConfirmation = "ELIMINAR"
+
+type DeleteAccountRequest struct {
+	Confirmacion string `json:"confirmacion" binding:"required"`
+	Contrasena   string `json:"contrasena"`
+	// Codigo es obligatorio si la cuenta tiene la verificación en dos pasos activada
+	Codigo string `json:"codigo"`
+}
+
+// ExportAccountHandler - Descarga todos los datos guardados sobre el usuario.
+// formato=json (por defecto) o formato=zip (un archivo por sección).
+func (h *Handler) ExportAccountHandler(c *gin.Context) {
+	idPersona, ok := getIDPersona(c)
+	if !ok {
+		return
+	}
+
+	formato := c.DefaultQuery("formato", "json")
+	if formato != "json" && formato != "zip" {
+		c.JSON(http.StatusBadRequest, ResponseData{Success: false, Message: "Formato inválido (json o zip)"})
+		return
+	}
+
+	export, err := h.accountService.Export(context.Background(), idPersona)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, ResponseData{Success: false, Message: "Error al exportar los datos"})
+		return
+	}
+
+	sections := []struct {
+		name string
+		data any
+	}{
+		{"perfil", export.Perfil},
+		{"roles", export.Roles},
+		{"suscripciones", export.Suscripciones},
+		{"identidades", export.Identidades},
+		{"sesiones", export.Sesiones},
+		{"tokens_personales", export.TokensPersonales},
+		{"solicitudes_mentor", export.SolicitudesMentor},
+		{"perfil_mentor", export.PerfilMentor},
+		{"habilidades", export.Habilidades},
+		{"solicitudes_mentoria", export.SolicitudesMentoria},
+		{"eventos_login", export.EventosLogin},
+	}
+
+	var body bytes.Buffer
+	contentType := "application/json"
+	if formato == "zip" {
+		contentType = "application/zip"
+		zw := zip.NewWriter(&body)
+		for _, section := range sections {
+			w, err := zw.Create(section.name + ".json")
+			if err == nil {
+				err = writeJSON(w, section.data)
+			}
+			if err != nil {
+				c.JSON(http.StatusInternalServerError, ResponseData{Success: false, Message: "Error al exportar los datos"})
+				return
+			}
+		}
+		if err := zw.Close(); err != nil {
+			c.JSON(http.StatusInternalServerError, ResponseData{Success: false, Message: "Error al exportar los datos"})
+			return
+		}
+	} else if err := writeJSON(&body, export); err != nil {
+		c.JSON(http.StatusInternalServerError, ResponseData{Success: false, Message: "Error al exportar los datos"})
+		return
+	}
+
+	recordAudit(c, h.auditService, services.AuditEntry{
+		IDPersona: &idPersona,
+		Accion:    services.AuditAccountExported,
+		Detalle:   map[string]any{"formato": formato},
+	})
+
+	filename := fmt.Sprintf("mentorly-datos-%d-%s.%s", idPersona, export.FechaExportacion.Format("20060102"), formato)
+	c.Header("Content-Disposition", `attachment; filename="`+filename+`"`)
+	c.Header("Cache-Control", "no-store")
+	c.Data(http.StatusOK, contentType, body.Bytes())
+}
+
+// DeleteAccountHandler - Programa la eliminación de la cuenta. Exige escribir ELIMINAR,
+// la contraseña (si la cuenta tiene) y el código del segundo factor (si está activado).
+func (h *Handler) DeleteAccountHandler(c *gin.Context) {
+	idPersona, ok := getIDPersona(c)
+	if !ok {
+		return
+	}
+
+	var req DeleteAccountRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, ResponseData{Success: false, Message: "Datos inválidos: " + err.Error()})
+		return
+	}
+
+	if req.Confirmacion != deletionConfirmation {
+		c.JSON(http.StatusBadRequest, ResponseData{Success: false, Message: services.ErrDeletionNotConfirmed.Error()})
+		return
+	}
+
+	if !h.reauthenticateDeletion(c, idPersona, req) {
+		return
+	}
+
+	status, err := h.accountService.RequestDeletion(context.Background(), idPersona, auditRequest(c))
+	if err != nil {
+		respondAccountError(c, err, "Error al programar la eliminación de la cuenta")
+		return
+	}
+
+	clearAuthCookies(c)
+
+	c.JSON(http.StatusOK, ResponseData{
+		Success: true,
+		Message: "La cuenta se va a eliminar el " + status.ProgramadaPara.Format("02/01/2006") + ". Podés cancelarlo iniciando sesión antes de esa fecha",
+		Data:    status,
+	})
+}
+
+// GetAccountDeletionHandler - Indica si la cuenta tiene una eliminación programada
+func (h *Handler) GetAccountDeletionHandler(c *gin.Context) {
+	idPersona, ok := getIDPersona(c)
+	if !ok {
+		return
+	}
+
+	status, err := h.accountService.DeletionStatus(context.Background(), idPersona)
+	if err != nil {
+		respondAccountError(c, err, "Error al obtener el estado de la cuenta")
+		return
+	}
+
+	c.JSON(http.StatusOK, ResponseData{
+		Success: true,
+		Message: "Estado de eliminación obtenido",
+		Data:    status,
+	})
+}
+
+// CancelAccountDeletionHandler - Cancela la eliminación programada de la cuenta
+func (h *Handler) CancelAccountDeletionHandler(c *gin.Context) {
+	idPersona, ok := getIDPersona(c)
+	if !ok {
+		return
+	}
+
+	if err := h.accountService.CancelDeletion(context.Background(), idPersona, auditRequest(c)); err != nil {
+		respondAccountError(c, err, "Error al cancelar la eliminación de la cuenta")
+		return
+	}
+
+	c.JSON(http.StatusOK, ResponseData{
+		Success: true,
+		Message: "Eliminación de la cuenta cancelada",
+	})
+}
+
+// reauthenticateDeletion verifica la contraseña y, si está activado, el segundo factor.
+// Si falla responde el error y devuelve false.
+func (h *Handler) reauthenticateDeletion(c *gin.Context, idPersona int, req DeleteAccountRequest) bool {
+	hasPassword, err := h.authService.HasPassword(context.Background(), idPersona)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, ResponseData{Success: false, Message: "Error al verificar la identidad"})
+		return false
+	}
+
+	if hasPassword {
+		if err := h.authService.VerifyPassword(context.Background(), idPersona, req.Contrasena); err != nil {
+			if errors.Is(err, services.ErrWrongPassword) {
+				c.JSON(http.StatusForbidden, ResponseData{Success: false, Message: err.Error()})
+			} else {
+				c.JSON(http.StatusInternalServerError, ResponseData{Success: false, Message: "Error al verificar la identidad"})
+			}
+			return false
+		}
+	}
+
+	mfaEnabled, err := h.mfaService.IsEnabled(context.Background(), idPersona)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, ResponseData{Success: false, Message: "Error al verificar la identidad"})
+		return false
+	}
+
+	if mfaEnabled {
+		if err := h.mfaService.VerifyCode(context.Background(), idPersona, req.Codigo); err != nil {
+			if errors.Is(err, services.ErrInvalidMFACode) {
+				c.JSON(http.StatusForbidden, ResponseData{Success: false, Message: err.Error()})
+			} else {
+				c.JSON(http.StatusInternalServerError, ResponseData{Success: false, Message: "Error al verificar el código"})
+			}
+			return false
+		}
+	}
+
+	return true
+}
+
+// writeJSON escribe el valor como JSON indentado, para que el archivo descargado sea legible
+func writeJSON(w io.Writer, v any) error {
+	encoder := json.NewEncoder(w)
+	encoder.SetIndent("", "  ")
+	return encoder.Encode(v)
+}
+
+// respondAccountError traduce los errores de eliminación de cuenta a respuestas HTTP
+func respondAccountError(c *gin.Context, err error, message string) {
+	switch {
+	case errors.Is(err, services.ErrUserNotFound):
+		c.JSON(http.StatusNotFound, ResponseData{Success: false, Message: "Usuario no encontrado"})
+	case errors.Is(err, services.ErrAccountDeletionPending), errors.Is(err, services.ErrAccountDeletionNotPending):
+		c.JSON(http.StatusConflict, ResponseData{Success: false, Message: err.Error()})
+	default:
+		c.JSON(http.StatusInternalServerError, ResponseData{Success: false, Message: message})
+	}
+}
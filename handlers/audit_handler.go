@@ -0,0 +1,223 @@
+package handlers
+
+import (
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"log"
+	"mentorly-backend/models"
+	"mentorly-backend/services"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+const (
+	defaultAuditLimit = 50
+	maxAuditLimit     = 500
+)
+
+var auditCSVHeader = []string{
+	"id_auditoria", "fecha", "id_actor", "id_persona", "accion", "tipo_objetivo", "id_objetivo",
+	"antes", "despues", "detalle", "ip", "user_agent", "id_solicitud",
+}
+
+// GetAuditLogHandler - Consulta el registro de auditoría. Filtros opcionales: actor, usuario,
+// accion (exacta o prefijo terminado en punto), tipo_objetivo, id_objetivo, id_solicitud,
+// desde y hasta (AAAA-MM-DD, hasta inclusive).
+func (h *Handler) GetAuditLogHandler(c *gin.Context) {
+	filter, ok := getAuditFilter(c)
+	if !ok {
+		return
+	}
+
+	filter.Limit, filter.Offset, ok = getPagination(c, defaultAuditLimit, maxAuditLimit)
+	if !ok {
+		return
+	}
+
+	events, err := h.auditService.Search(context.Background(), filter)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, ResponseData{Success: false, Message: "Error al obtener la auditoría"})
+		return
+	}
+
+	c.JSON(http.StatusOK, ResponseData{
+		Success: true,
+		Message: "Auditoría obtenida correctamente",
+		Data:    events,
+	})
+}
+
+// ExportAuditLogHandler - Descarga en CSV los eventos que cumplen los mismos filtros que
+// GetAuditLogHandler, sin paginar
+func (h *Handler) ExportAuditLogHandler(c *gin.Context) {
+	filter, ok := getAuditFilter(c)
+	if !ok {
+		return
+	}
+
+	filename := "auditoria-" + time.Now().Format("20060102-150405") + ".csv"
+	c.Header("Content-Type", "text/csv; charset=utf-8")
+	c.Header("Content-Disposition", `attachment; filename="`+filename+`"`)
+	c.Header("Cache-Control", "no-store")
+	c.Status(http.StatusOK)
+
+	w := csv.NewWriter(c.Writer)
+	if err := w.Write(auditCSVHeader); err != nil {
+		return
+	}
+
+	// Una vez enviado el encabezado ya no se puede responder con un error: se registra y se corta
+	err := h.auditService.Export(context.Background(), filter, func(e models.AuditEvent) error {
+		return w.Write(auditCSVRecord(e))
+	})
+	w.Flush()
+	if err == nil {
+		err = w.Error()
+	}
+	if err != nil {
+		log.Printf("Error al exportar la auditoría: %v", err)
+	}
+
+	recordAudit(c, h.auditService, services.AuditEntry{
+		Accion:  services.AuditLogExported,
+		Detalle: map[string]any{"filtros": c.Request.URL.RawQuery},
+	})
+}
+
+// getAuditFilter lee los filtros de la query. Si alguno es inválido responde 400 y devuelve false.
+func getAuditFilter(c *gin.Context) (services.AuditFilter, bool) {
+	filter := services.AuditFilter{
+		Accion:       c.Query("accion"),
+		TipoObjetivo: c.Query("tipo_objetivo"),
+		IDObjetivo:   c.Query("id_objetivo"),
+		IDSolicitud:  c.Query("id_solicitud"),
+	}
+
+	for param, target := range map[string]**int{"actor": &filter.IDActor, "usuario": &filter.IDPersona} {
+		v := c.Query(param)
+		if v == "" {
+			continue
+		}
+		id, err := strconv.Atoi(v)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, ResponseData{Success: false, Message: "ID de " + param + " inválido"})
+			return filter, false
+		}
+		*target = &id
+	}
+
+	if v := c.Query("desde"); v != "" {
+		desde, err := time.Parse(adminDateLayout, v)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, ResponseData{Success: false, Message: "Fecha desde inválida (AAAA-MM-DD)"})
+			return filter, false
+		}
+		filter.Desde = &desde
+	}
+	if v := c.Query("hasta"); v != "" {
+		hasta, err := time.Parse(adminDateLayout, v)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, ResponseData{Success: false, Message: "Fecha hasta inválida (AAAA-MM-DD)"})
+			return filter, false
+		}
+		// Se incluye el día completo
+		hasta = hasta.AddDate(0, 0, 1)
+		filter.Hasta = &hasta
+	}
+
+	return filter, true
+}
+
+func auditCSVRecord(e models.AuditEvent) []string {
+	return []string{
+		strconv.FormatInt(e.IDAuditoria, 10),
+		e.Fecha.Format(time.RFC3339),
+		csvInt(e.IDActor),
+		csvInt(e.IDPersona),
+		csvCell(e.Accion),
+		csvCell(csvString(e.TipoObjetivo)),
+		csvCell(csvString(e.IDObjetivo)),
+		csvCell(csvJSON(e.Antes)),
+		csvCell(csvJSON(e.Despues)),
+		csvCell(csvJSON(e.Detalle)),
+		csvCell(csvString(e.IP)),
+		csvCell(csvString(e.UserAgent)),
+		csvCell(csvString(e.IDSolicitud)),
+	}
+}
+
+// csvCell evita que una planilla interprete como fórmula un valor controlado por el usuario
+func csvCell(value string) string {
+	if value != "" && strings.ContainsRune("=+-@\t\r", rune(value[0])) {
+		return "'" + value
+	}
+	return value
+}
+
+func csvInt(value *int) string {
+	if value == nil {
+		return ""
+	}
+	return strconv.Itoa(*value)
+}
+
+func csvString(value *string) string {
+	if value == nil {
+		return ""
+	}
+	return *value
+}
+
+func csvJSON(value any) string {
+	if value == nil {
+		return ""
+	}
+	encoded, err := json.Marshal(value)
+	if err != nil {
+		return ""
+	}
+	return string(encoded)
+}
+
+// auditRequest obtiene la IP, el user agent y el ID de la solicitud para la auditoría
+func auditRequest(c *gin.Context) services.AuditRequest {
+	return services.AuditRequest{
+		IP:          c.ClientIP(),
+		UserAgent:   c.Request.UserAgent(),
+		IDSolicitud: c.GetString("request_id"),
+	}
+}
+
+// recordAudit completa el evento con el actor y los datos de la solicitud y lo guarda.
+// Durante una suplantación el actor es el administrador.
+func recordAudit(c *gin.Context, audits *services.AuditService, entry services.AuditEntry) {
+	if entry.IDActor == nil {
+		if idActor := getIDActor(c); idActor != 0 {
+			entry.IDActor = &idActor
+		}
+	}
+	if idSuplantacion := c.GetInt("id_suplantacion"); idSuplantacion != 0 {
+		if entry.Detalle == nil {
+			entry.Detalle = map[string]any{}
+		}
+		entry.Detalle["id_suplantacion"] = idSuplantacion
+	}
+	entry.Request = auditRequest(c)
+
+	audits.Record(context.Background(), entry)
+}
+
+// recordLogin registra un inicio de sesión exitoso. metodo es "contrasena", "mfa" o el proveedor OAuth.
+func recordLogin(c *gin.Context, audits *services.AuditService, idPersona int, metodo string) {
+	recordAudit(c, audits, services.AuditEntry{
+		IDActor:   &idPersona,
+		IDPersona: &idPersona,
+		Accion:    services.AuditLogin,
+		Detalle:   map[string]any{"metodo": metodo},
+	})
+}
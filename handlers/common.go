@@ -2,9 +2,13 @@ package handlers
 
 import (
 	"fmt"
+	"mentorly-backend/services"
+	"net/http"
 	"os"
+	"strconv"
 	"time"
 
+	"github.com/gin-gonic/gin"
 	"github.com/golang-jwt/jwt/v5"
 )
 
@@ -16,56 +20,154 @@ type ResponseData struct {
 
 // TokenResponse es la estructura de datos devuelta en un login/registro exitoso.
 type TokenResponse struct {
-	Token     string `json:"token"`
-	IDPersona int    `json:"id_persona"`
-	Email     string `json:"email"`
-	Nombre    string `json:"nombre"`
+	Token        string `json:"token"`
+	RefreshToken string `json:"refresh_token"`
+	ExpiresIn    int    `json:"expires_in"`
+	IDPersona    int    `json:"id_persona"`
+	Email        string `json:"email"`
+	Nombre       string `json:"nombre"`
 }
 
+// AccessTokenDuration es la vida útil del JWT de acceso; la sesión se extiende con el refresh token
+const AccessTokenDuration = 15 * time.Minute
+
 type Claims struct {
 	IDPersona int    `json:"id_persona"`
 	Email     string `json:"email"`
+	IDSesion  int    `json:"sid"`
+	// Solo en tokens de suplantación: el administrador que actúa como el usuario y la suplantación
+	IDActor        int `json:"act,omitempty"`
+	IDSuplantacion int `json:"imp,omitempty"`
 	jwt.RegisteredClaims
 }
 
 // JWT Functions
 
-func GenerateToken(idPersona int, email string) (string, error) {
-	secretKey := os.Getenv("JWT_SECRET")
+// signingKeys firma y valida los JWT de acceso; se configura al iniciar con SetSigningKeys
+var signingKeys *services.SigningKeyService
+
+// SetSigningKeys configura las claves con las que se firman y validan los tokens
+func SetSigningKeys(keys *services.SigningKeyService) {
+	signingKeys = keys
+}
 
-	expirationTime := time.Now().Add(7 * 24 * time.Hour)
+func GenerateToken(idPersona int, email string, idSesion int) (string, error) {
+	expirationTime := time.Now().Add(AccessTokenDuration)
 
 	claims := &Claims{
 		IDPersona: idPersona,
 		Email:     email,
+		IDSesion:  idSesion,
 		RegisteredClaims: jwt.RegisteredClaims{
+			Issuer:    signingKeys.Issuer(),
+			Audience:  jwt.ClaimStrings{signingKeys.Audience()},
 			ExpiresAt: jwt.NewNumericDate(expirationTime),
 			IssuedAt:  jwt.NewNumericDate(time.Now()),
 		},
 	}
 
-	token := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
-	tokenString, err := token.SignedString([]byte(secretKey))
+	return signingKeys.Sign(claims)
+}
 
-	if err != nil {
-		return "", err
+// GenerateImpersonationToken firma un token de acceso para idPersona a nombre de idActor.
+// No tiene sesión ni refresh token: vence con la suplantación.
+func GenerateImpersonationToken(idPersona int, email string, idActor int, idSuplantacion int, duracion time.Duration) (string, error) {
+	claims := &Claims{
+		IDPersona:      idPersona,
+		Email:          email,
+		IDActor:        idActor,
+		IDSuplantacion: idSuplantacion,
+		RegisteredClaims: jwt.RegisteredClaims{
+			Issuer:    signingKeys.Issuer(),
+			Audience:  jwt.ClaimStrings{signingKeys.Audience()},
+			ExpiresAt: jwt.NewNumericDate(time.Now().Add(duracion)),
+			IssuedAt:  jwt.NewNumericDate(time.Now()),
+		},
 	}
 
-	return tokenString, nil
+	return signingKeys.Sign(claims)
 }
 
 func VerifyToken(tokenString string) (*Claims, error) {
-	secretKey := os.Getenv("JWT_SECRET")
-
 	claims := &Claims{}
 
-	token, err := jwt.ParseWithClaims(tokenString, claims, func(token *jwt.Token) (interface{}, error) {
-		return []byte(secretKey), nil
-	})
-
-	if err != nil || !token.Valid {
+	if err := signingKeys.Parse(tokenString, claims); err != nil {
 		return nil, fmt.Errorf("token inválido")
 	}
 
 	return claims, nil
 }
+
+// JWKSHandler - Publica las claves públicas para validar los tokens (/.well-known/jwks.json)
+func JWKSHandler(c *gin.Context) {
+	c.Header("Cache-Control", fmt.Sprintf("public, max-age=%d", int(services.JWKSCacheMaxAge.Seconds())))
+	c.JSON(http.StatusOK, signingKeys.JWKS())
+}
+
+// getIDPersona obtiene el ID del usuario autenticado que dejó AuthMiddleware.
+// Si no está presente responde 401 y devuelve false.
+func getIDPersona(c *gin.Context) (int, bool) {
+	idPersonaInterface, exists := c.Get("id_persona")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, ResponseData{Success: false, Message: "No autenticado"})
+		return 0, false
+	}
+
+	idPersona, ok := idPersonaInterface.(int)
+	if !ok {
+		c.JSON(http.StatusUnauthorized, ResponseData{Success: false, Message: "ID de usuario inválido"})
+		return 0, false
+	}
+
+	return idPersona, true
+}
+
+// getIDActor obtiene quién hace la solicitud: el administrador si es una suplantación,
+// si no el mismo usuario autenticado
+func getIDActor(c *gin.Context) int {
+	return c.GetInt("id_actor")
+}
+
+// isImpersonating indica si la solicitud se hace con un token de suplantación
+func isImpersonating(c *gin.Context) bool {
+	return c.GetInt("id_suplantacion") != 0
+}
+
+// getIDSesion obtiene la sesión del token de acceso (0 si se autenticó con un token personal)
+func getIDSesion(c *gin.Context) int {
+	return c.GetInt("id_sesion")
+}
+
+// getFrontendURL devuelve la URL base del frontend (FRONTEND_URL), con fallback para desarrollo
+func getFrontendURL() string {
+	frontendURL := os.Getenv("FRONTEND_URL") // ej: http://localhost:5173
+	if frontendURL == "" {
+		frontendURL = "http://localhost:5173" // fallback en dev
+	}
+	return frontendURL
+}
+
+// getPagination lee limit y offset de la query. Si son inválidos responde 400 y devuelve false.
+func getPagination(c *gin.Context, defaultLimit int, maxLimit int) (int, int, bool) {
+	limit := defaultLimit
+	if v := c.Query("limit"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n <= 0 {
+			c.JSON(http.StatusBadRequest, ResponseData{Success: false, Message: "Límite inválido"})
+			return 0, 0, false
+		}
+		limit = min(n, maxLimit)
+	}
+
+	offset := 0
+	if v := c.Query("offset"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n < 0 {
+			c.JSON(http.StatusBadRequest, ResponseData{Success: false, Message: "Offset inválido"})
+			return 0, 0, false
+		}
+		offset = n
+	}
+
+	return limit, offset, true
+}
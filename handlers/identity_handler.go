@@ -0,0 +1,103 @@
+package handlers
+
+import (
+	"errors"
+	"fmt"
+	"mentorly-backend/services"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+)
+
+// GetIdentitiesHandler - Lista los proveedores vinculados a la cuenta
+func (h *OAuthHandler) GetIdentitiesHandler(c *gin.Context) {
+	idPersona, ok := getIDPersona(c)
+	if !ok {
+		return
+	}
+
+	identities, err := h.identityService.ListIdentities(c.Request.Context(), idPersona)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, ResponseData{Success: false, Message: "Error al obtener las cuentas vinculadas"})
+		return
+	}
+
+	c.JSON(http.StatusOK, ResponseData{
+		Success: true,
+		Message: "Cuentas vinculadas obtenidas",
+		Data:    identities,
+	})
+}
+
+// LinkIdentityHandler - Devuelve la URL de autorización para vincular un proveedor a la cuenta
+func (h *OAuthHandler) LinkIdentityHandler(c *gin.Context) {
+	idPersona, ok := getIDPersona(c)
+	if !ok {
+		return
+	}
+
+	provider, ok := h.getProvider(c)
+	if !ok {
+		return
+	}
+
+	h.respondAuthURL(c, provider, idPersona)
+}
+
+// UnlinkIdentityHandler - Desvincula un proveedor de la cuenta
+func (h *OAuthHandler) UnlinkIdentityHandler(c *gin.Context) {
+	idPersona, ok := getIDPersona(c)
+	if !ok {
+		return
+	}
+
+	provider := services.OAuthProviderName(c.Param("provider"))
+	err := h.identityService.UnlinkIdentity(c.Request.Context(), idPersona, provider)
+	if err != nil {
+		switch {
+		case errors.Is(err, services.ErrIdentityNotFound):
+			c.JSON(http.StatusNotFound, ResponseData{Success: false, Message: "El proveedor no está vinculado"})
+		case errors.Is(err, services.ErrLastLoginMethod):
+			c.JSON(http.StatusConflict, ResponseData{Success: false, Message: err.Error()})
+		default:
+			c.JSON(http.StatusInternalServerError, ResponseData{Success: false, Message: "Error al desvincular el proveedor"})
+		}
+		return
+	}
+
+	recordAudit(c, h.auditService, services.AuditEntry{
+		IDPersona:    &idPersona,
+		Accion:       services.AuditIdentityUnlinked,
+		TipoObjetivo: services.AuditTargetIdentity,
+		IDObjetivo:   string(provider),
+	})
+
+	c.JSON(http.StatusOK, ResponseData{
+		Success: true,
+		Message: "Proveedor desvinculado correctamente",
+	})
+}
+
+// handleOAuthLink completa la vinculación iniciada desde LinkIdentityHandler
+func (h *OAuthHandler) handleOAuthLink(c *gin.Context, idPersona int, oauthUser *services.OAuthUserInfo) {
+	err := h.identityService.LinkIdentity(c.Request.Context(), idPersona, oauthUser)
+	if err != nil {
+		switch {
+		case errors.Is(err, services.ErrIdentityAlreadyLinked), errors.Is(err, services.ErrProviderAlreadyLinked):
+			c.JSON(http.StatusConflict, ResponseData{Success: false, Message: err.Error()})
+		default:
+			c.JSON(http.StatusInternalServerError, ResponseData{Success: false, Message: "Error al vincular el proveedor"})
+		}
+		return
+	}
+
+	recordAudit(c, h.auditService, services.AuditEntry{
+		IDActor:      &idPersona,
+		IDPersona:    &idPersona,
+		Accion:       services.AuditIdentityLinked,
+		TipoObjetivo: services.AuditTargetIdentity,
+		IDObjetivo:   string(oauthUser.Provider),
+	})
+
+	c.Redirect(http.StatusFound, fmt.Sprintf("%s/profile?linked=%s", getFrontendURL(), oauthUser.Provider))
+}
@@ -0,0 +1,188 @@
+package handlers
+
+import (
+	"context"
+	"errors"
+	"mentorly-backend/services"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+type StartImpersonationRequest struct {
+	Motivo string `json:"motivo" binding:"required,min=10,max=1000"`
+	// SoloLectura es true si no se envía: con el token solo se pueden hacer consultas
+	SoloLectura *bool `json:"solo_lectura"`
+	// DuracionMinutos es la vigencia del token (por defecto 15, máximo 60)
+	DuracionMinutos int `json:"duracion_minutos" binding:"omitempty,min=1,max=60"`
+}
+
+// StartImpersonationHandler - Emite un token de corta duración para actuar como el usuario
+func (h *Handler) StartImpersonationHandler(c *gin.Context) {
+	idActor, ok := getIDPersona(c)
+	if !ok {
+		return
+	}
+
+	idPersona, ok := getUserIDParam(c)
+	if !ok {
+		return
+	}
+
+	var req StartImpersonationRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, ResponseData{Success: false, Message: "Datos inválidos: " + err.Error()})
+		return
+	}
+
+	soloLectura := req.SoloLectura == nil || *req.SoloLectura
+	duracion := services.DefaultImpersonationDuration
+	if req.DuracionMinutos > 0 {
+		duracion = time.Duration(req.DuracionMinutos) * time.Minute
+	}
+
+	impersonation, err := h.impersonationService.Start(context.Background(), idActor, idPersona,
+		strings.TrimSpace(req.Motivo), soloLectura, duracion, auditRequest(c))
+	if err != nil {
+		respondImpersonationError(c, err, "Error al iniciar la suplantación")
+		return
+	}
+
+	token, err := GenerateImpersonationToken(idPersona, impersonation.Email, idActor, impersonation.IDSuplantacion, duracion)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, ResponseData{Success: false, Message: "Error al generar el token"})
+		return
+	}
+
+	c.JSON(http.StatusOK, ResponseData{
+		Success: true,
+		Message: "Suplantación iniciada. Todas las solicitudes con este token quedan registradas",
+		Data: gin.H{
+			"token":        token,
+			"expires_in":   int(duracion.Seconds()),
+			"suplantacion": impersonation,
+		},
+	})
+}
+
+// GetImpersonationsHandler - Lista las suplantaciones vigentes iniciadas por el administrador
+func (h *Handler) GetImpersonationsHandler(c *gin.Context) {
+	idActor, ok := getIDPersona(c)
+	if !ok {
+		return
+	}
+
+	impersonations, err := h.impersonationService.ListActive(context.Background(), idActor)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, ResponseData{Success: false, Message: "Error al obtener las suplantaciones"})
+		return
+	}
+
+	c.JSON(http.StatusOK, ResponseData{
+		Success: true,
+		Message: "Suplantaciones obtenidas correctamente",
+		Data:    impersonations,
+	})
+}
+
+// EndImpersonationHandler - Termina una suplantación; su token deja de servir
+func (h *Handler) EndImpersonationHandler(c *gin.Context) {
+	idActor, ok := getIDPersona(c)
+	if !ok {
+		return
+	}
+
+	idSuplantacion, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, ResponseData{Success: false, Message: "ID de suplantación inválido"})
+		return
+	}
+
+	if err := h.impersonationService.End(context.Background(), idSuplantacion, idActor, auditRequest(c)); err != nil {
+		respondImpersonationError(c, err, "Error al terminar la suplantación")
+		return
+	}
+
+	c.JSON(http.StatusOK, ResponseData{
+		Success: true,
+		Message: "Suplantación terminada",
+	})
+}
+
+// RejectImpersonation - Bloquea la ruta para tokens de suplantación (administración,
+// credenciales y datos de la cuenta). Va después de AuthMiddleware.
+func (h *Handler) RejectImpersonation() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if isImpersonating(c) {
+			c.JSON(http.StatusForbidden, ResponseData{Success: false, Message: "No disponible durante una suplantación"})
+			c.Abort()
+			return
+		}
+		c.Next()
+	}
+}
+
+// authenticateImpersonation valida un token de suplantación, aplica la restricción de solo
+// lectura y registra la solicitud en la auditoría.
+func (h *Handler) authenticateImpersonation(c *gin.Context, claims *Claims) {
+	soloLectura, err := h.impersonationService.Validate(context.Background(), claims.IDSuplantacion, claims.IDActor, claims.IDPersona)
+	if err != nil {
+		if errors.Is(err, services.ErrAccountSuspended) {
+			c.JSON(http.StatusForbidden, ResponseData{Success: false, Message: "La cuenta está suspendida"})
+		} else {
+			c.JSON(http.StatusUnauthorized, ResponseData{Success: false, Message: services.ErrImpersonationEnded.Error()})
+		}
+		c.Abort()
+		return
+	}
+
+	c.Set("id_persona", claims.IDPersona)
+	c.Set("id_actor", claims.IDActor)
+	c.Set("email", claims.Email)
+	c.Set("id_suplantacion", claims.IDSuplantacion)
+
+	// El registro se guarda al terminar, con el estado de la respuesta
+	defer func() {
+		recordAudit(c, h.auditService, services.AuditEntry{
+			IDPersona: &claims.IDPersona,
+			Accion:    services.AuditImpersonatedRequest,
+			Detalle: map[string]any{
+				"metodo": c.Request.Method,
+				"ruta":   c.Request.URL.Path,
+				"estado": c.Writer.Status(),
+			},
+		})
+	}()
+
+	if soloLectura && !isReadOnlyMethod(c.Request.Method) {
+		c.JSON(http.StatusForbidden, ResponseData{Success: false, Message: "La suplantación es de solo lectura"})
+		c.Abort()
+		return
+	}
+
+	c.Next()
+}
+
+func isReadOnlyMethod(method string) bool {
+	return method == http.MethodGet || method == http.MethodHead || method == http.MethodOptions
+}
+
+// respondImpersonationError traduce los errores de suplantación a respuestas HTTP
+func respondImpersonationError(c *gin.Context, err error, message string) {
+	switch {
+	case errors.Is(err, services.ErrUserNotFound):
+		c.JSON(http.StatusNotFound, ResponseData{Success: false, Message: "Usuario no encontrado"})
+	case errors.Is(err, services.ErrImpersonationNotFound):
+		c.JSON(http.StatusNotFound, ResponseData{Success: false, Message: err.Error()})
+	case errors.Is(err, services.ErrInvalidImpersonationTime):
+		c.JSON(http.StatusBadRequest, ResponseData{Success: false, Message: err.Error()})
+	case errors.Is(err, services.ErrCannotImpersonateSelf), errors.Is(err, services.ErrCannotImpersonateAdmin),
+		errors.Is(err, services.ErrAccountSuspended), errors.Is(err, services.ErrImpersonationEnded):
+		c.JSON(http.StatusConflict, ResponseData{Success: false, Message: err.Error()})
+	default:
+		c.JSON(http.StatusInternalServerError, ResponseData{Success: false, Message: message})
+	}
+}
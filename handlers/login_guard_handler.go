@@ -0,0 +1,198 @@
+package handlers
+
+import (
+	"context"
+	"errors"
+	"log"
+	"mentorly-backend/services"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+const (
+	defaultLoginEventsLimit = 100
+	maxLoginEventsLimit     = 500
+)
+
+type UnlockAccountRequest struct {
+	Token string `json:"token" binding:"required"`
+}
+
+type RequestUnlockRequest struct {
+	Email string `json:"email" binding:"required,email"`
+}
+
+type AdminUnlockRequest struct {
+	Email string `json:"email" binding:"omitempty,email"`
+	IP    string `json:"ip" binding:"omitempty,ip"`
+}
+
+// registerLoginFailure suma el intento fallido y, si la cuenta quedó bloqueada,
+// envía el email de desbloqueo en segundo plano para no alterar el tiempo de respuesta.
+func (h *Handler) registerLoginFailure(email string, ip string) {
+	locked, err := h.loginGuard.RegisterFailure(context.Background(), email, ip)
+	if err != nil {
+		log.Printf("Error al registrar intento de login fallido: %v", err)
+		return
+	}
+	if !locked {
+		return
+	}
+
+	go func() {
+		ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+		defer cancel()
+		if err := h.loginGuard.RequestUnlock(ctx, email); err != nil {
+			log.Printf("Error al enviar email de desbloqueo: %v", err)
+		}
+	}()
+}
+
+// checkReauthLimit frena la reautenticación (contraseña actual o código del segundo factor) si el
+// usuario agotó los intentos. Si está bloqueado responde 429 y devuelve false.
+func (h *Handler) checkReauthLimit(c *gin.Context, idPersona int) bool {
+	err := h.loginGuard.CheckReauth(context.Background(), idPersona)
+	if err == nil {
+		return true
+	}
+
+	var retryErr *services.RetryAfterError
+	if errors.As(err, &retryErr) {
+		c.Header("Retry-After", strconv.Itoa(int(retryErr.RetryAfter.Seconds())+1))
+		c.JSON(http.StatusTooManyRequests, ResponseData{Success: false, Message: "Demasiados intentos fallidos. Probá de nuevo más tarde"})
+	} else {
+		c.JSON(http.StatusInternalServerError, ResponseData{Success: false, Message: "Error al verificar la identidad"})
+	}
+	return false
+}
+
+// registerReauthFailure suma y audita un intento de reautenticación fallido.
+// metodo es "contrasena" o "mfa".
+func (h *Handler) registerReauthFailure(c *gin.Context, idPersona int, metodo string) {
+	locked, err := h.loginGuard.RegisterReauthFailure(context.Background(), idPersona)
+	if err != nil {
+		log.Printf("Error al registrar reautenticación fallida: %v", err)
+	}
+
+	recordAudit(c, h.auditService, services.AuditEntry{
+		IDPersona: &idPersona,
+		Accion:    services.AuditReauthFailed,
+		Detalle:   map[string]any{"metodo": metodo, "bloqueado": locked},
+	})
+}
+
+// registerReauthSuccess reinicia los intentos de reautenticación del usuario
+func (h *Handler) registerReauthSuccess(idPersona int) {
+	if err := h.loginGuard.RegisterReauthSuccess(context.Background(), idPersona); err != nil {
+		log.Printf("Error al reiniciar los intentos de reautenticación: %v", err)
+	}
+}
+
+// RequestUnlockHandler - Reenvía el enlace de desbloqueo de la cuenta.
+// Siempre responde lo mismo, exista o no el email y esté o no bloqueado.
+func (h *Handler) RequestUnlockHandler(c *gin.Context) {
+	var req RequestUnlockRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, ResponseData{Success: false, Message: "Datos inválidos: " + err.Error()})
+		return
+	}
+
+	go func(email string) {
+		ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+		defer cancel()
+		if err := h.loginGuard.RequestUnlock(ctx, email); err != nil {
+			log.Printf("Error al procesar desbloqueo de cuenta: %v", err)
+		}
+	}(req.Email)
+
+	c.JSON(http.StatusOK, ResponseData{
+		Success: true,
+		Message: "Si la cuenta está bloqueada, vas a recibir un enlace para desbloquearla",
+	})
+}
+
+// UnlockAccountHandler - Desbloquea la cuenta con el token recibido por email
+func (h *Handler) UnlockAccountHandler(c *gin.Context) {
+	var req UnlockAccountRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, ResponseData{Success: false, Message: "Datos inválidos: " + err.Error()})
+		return
+	}
+
+	err := h.loginGuard.UnlockWithToken(context.Background(), req.Token)
+	if err != nil {
+		if errors.Is(err, services.ErrInvalidUserToken) {
+			c.JSON(http.StatusBadRequest, ResponseData{Success: false, Message: "El enlace de desbloqueo es inválido o expiró"})
+		} else {
+			c.JSON(http.StatusInternalServerError, ResponseData{Success: false, Message: "Error al desbloquear la cuenta"})
+		}
+		return
+	}
+
+	c.JSON(http.StatusOK, ResponseData{
+		Success: true,
+		Message: "Cuenta desbloqueada. Ya podés iniciar sesión",
+	})
+}
+
+// AdminUnlockLoginHandler - Quita el bloqueo de inicio de sesión de un email y/o una IP
+func (h *Handler) AdminUnlockLoginHandler(c *gin.Context) {
+	var req AdminUnlockRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, ResponseData{Success: false, Message: "Datos inválidos: " + err.Error()})
+		return
+	}
+
+	if req.Email == "" && req.IP == "" {
+		c.JSON(http.StatusBadRequest, ResponseData{Success: false, Message: "Indicá un email o una IP"})
+		return
+	}
+
+	err := h.loginGuard.Unlock(context.Background(), req.Email, req.IP)
+	if err != nil {
+		if errors.Is(err, services.ErrLockoutNotFound) {
+			c.JSON(http.StatusNotFound, ResponseData{Success: false, Message: err.Error()})
+		} else {
+			c.JSON(http.StatusInternalServerError, ResponseData{Success: false, Message: "Error al quitar el bloqueo"})
+		}
+		return
+	}
+
+	recordAudit(c, h.auditService, services.AuditEntry{
+		Accion:  services.AuditLoginUnlocked,
+		Detalle: map[string]any{"email": req.Email, "ip": req.IP},
+	})
+
+	c.JSON(http.StatusOK, ResponseData{
+		Success: true,
+		Message: "Bloqueo eliminado correctamente",
+	})
+}
+
+// GetLoginEventsHandler - Lista los últimos intentos de inicio de sesión (?email=, ?ip=, ?limit=)
+func (h *Handler) GetLoginEventsHandler(c *gin.Context) {
+	limit := defaultLoginEventsLimit
+	if v := c.Query("limit"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n <= 0 {
+			c.JSON(http.StatusBadRequest, ResponseData{Success: false, Message: "Límite inválido"})
+			return
+		}
+		limit = min(n, maxLoginEventsLimit)
+	}
+
+	events, err := h.loginGuard.ListEvents(context.Background(), c.Query("email"), c.Query("ip"), limit)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, ResponseData{Success: false, Message: "Error al obtener los eventos de login"})
+		return
+	}
+
+	c.JSON(http.StatusOK, ResponseData{
+		Success: true,
+		Message: "Eventos obtenidos correctamente",
+		Data:    events,
+	})
+}
@@ -0,0 +1,221 @@
+package handlers
+
+import (
+	"context"
+	"errors"
+	"mentorly-backend/models"
+	"mentorly-backend/services"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+)
+
+const (
+	defaultMentorApplicationsLimit = 50
+	maxMentorApplicationsLimit     = 200
+)
+
+type MentorApplicationRequest struct {
+	AreasExperiencia string `json:"areas_experiencia" binding:"required,max=500"`
+	Experiencia      string `json:"experiencia" binding:"required,min=20,max=5000"`
+	LinkedInURL      string `json:"linkedin_url" binding:"required,url,max=255"`
+	Motivacion       string `json:"motivacion" binding:"required,min=20,max=5000"`
+}
+
+type ReviewMentorApplicationRequest struct {
+	Estado     string `json:"estado" binding:"required,oneof=aprobada rechazada cambios_solicitados"`
+	Comentario string `json:"comentario" binding:"max=2000"`
+}
+
+func (r MentorApplicationRequest) input() services.MentorApplicationInput {
+	return services.MentorApplicationInput{
+		AreasExperiencia: r.AreasExperiencia,
+		Experiencia:      r.Experiencia,
+		LinkedInURL:      r.LinkedInURL,
+		Motivacion:       r.Motivacion,
+	}
+}
+
+// SubmitMentorApplicationHandler - Envía la solicitud para ser mentor. Queda pendiente de revisión.
+func (h *Handler) SubmitMentorApplicationHandler(c *gin.Context) {
+	idPersona, ok := getIDPersona(c)
+	if !ok {
+		return
+	}
+
+	var req MentorApplicationRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, ResponseData{Success: false, Message: "Datos inválidos: " + err.Error()})
+		return
+	}
+
+	application, err := h.mentorApplicationService.Submit(context.Background(), idPersona, req.input())
+	if err != nil {
+		respondMentorApplicationError(c, err, "Error al enviar la solicitud")
+		return
+	}
+
+	c.JSON(http.StatusCreated, ResponseData{
+		Success: true,
+		Message: "Solicitud enviada. Te vamos a avisar cuando sea revisada",
+		Data:    application,
+	})
+}
+
+// GetMentorApplicationHandler - Obtiene la última solicitud para ser mentor del usuario
+func (h *Handler) GetMentorApplicationHandler(c *gin.Context) {
+	idPersona, ok := getIDPersona(c)
+	if !ok {
+		return
+	}
+
+	application, err := h.mentorApplicationService.GetLatest(context.Background(), idPersona)
+	if err != nil {
+		respondMentorApplicationError(c, err, "Error al obtener la solicitud")
+		return
+	}
+
+	c.JSON(http.StatusOK, ResponseData{
+		Success: true,
+		Message: "Solicitud obtenida correctamente",
+		Data:    application,
+	})
+}
+
+// UpdateMentorApplicationHandler - Corrige la solicitud cuando el equipo pidió cambios
+func (h *Handler) UpdateMentorApplicationHandler(c *gin.Context) {
+	idPersona, ok := getIDPersona(c)
+	if !ok {
+		return
+	}
+
+	var req MentorApplicationRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, ResponseData{Success: false, Message: "Datos inválidos: " + err.Error()})
+		return
+	}
+
+	application, err := h.mentorApplicationService.Resubmit(context.Background(), idPersona, req.input())
+	if err != nil {
+		respondMentorApplicationError(c, err, "Error al actualizar la solicitud")
+		return
+	}
+
+	c.JSON(http.StatusOK, ResponseData{
+		Success: true,
+		Message: "Solicitud actualizada y enviada nuevamente a revisión",
+		Data:    application,
+	})
+}
+
+// GetMentorApplicationsHandler - Cola de revisión: lista las solicitudes por estado (pendientes por defecto)
+func (h *Handler) GetMentorApplicationsHandler(c *gin.Context) {
+	estado := c.DefaultQuery("estado", models.MentorApplicationPending)
+	switch estado {
+	case models.MentorApplicationPending, models.MentorApplicationChangesRequested,
+		models.MentorApplicationApproved, models.MentorApplicationRejected:
+	default:
+		c.JSON(http.StatusBadRequest, ResponseData{Success: false, Message: "Estado inválido"})
+		return
+	}
+
+	limit, offset, ok := getPagination(c, defaultMentorApplicationsLimit, maxMentorApplicationsLimit)
+	if !ok {
+		return
+	}
+
+	applications, err := h.mentorApplicationService.List(context.Background(), estado, limit, offset)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, ResponseData{Success: false, Message: "Error al obtener las solicitudes"})
+		return
+	}
+
+	c.JSON(http.StatusOK, ResponseData{
+		Success: true,
+		Message: "Solicitudes obtenidas correctamente",
+		Data:    applications,
+	})
+}
+
+// GetMentorApplicationByIDHandler - Obtiene una solicitud para revisarla
+func (h *Handler) GetMentorApplicationByIDHandler(c *gin.Context) {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, ResponseData{Success: false, Message: "ID de solicitud inválido"})
+		return
+	}
+
+	application, err := h.mentorApplicationService.Get(context.Background(), id)
+	if err != nil {
+		respondMentorApplicationError(c, err, "Error al obtener la solicitud")
+		return
+	}
+
+	c.JSON(http.StatusOK, ResponseData{
+		Success: true,
+		Message: "Solicitud obtenida correctamente",
+		Data:    application,
+	})
+}
+
+// ReviewMentorApplicationHandler - Aprueba, rechaza o pide cambios en una solicitud pendiente.
+// Al aprobarla se otorga el rol mentor.
+func (h *Handler) ReviewMentorApplicationHandler(c *gin.Context) {
+	idRevisor, ok := getIDPersona(c)
+	if !ok {
+		return
+	}
+
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, ResponseData{Success: false, Message: "ID de solicitud inválido"})
+		return
+	}
+
+	var req ReviewMentorApplicationRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, ResponseData{Success: false, Message: "Datos inválidos: " + err.Error()})
+		return
+	}
+
+	application, err := h.mentorApplicationService.Review(context.Background(), id, idRevisor, req.Estado, req.Comentario)
+	if err != nil {
+		respondMentorApplicationError(c, err, "Error al revisar la solicitud")
+		return
+	}
+
+	recordAudit(c, h.auditService, services.AuditEntry{
+		IDPersona:    &application.IDPersona,
+		Accion:       services.AuditMentorApplicationReviewed,
+		TipoObjetivo: services.AuditTargetMentorApplication,
+		IDObjetivo:   strconv.Itoa(id),
+		Despues:      map[string]any{"estado": application.Estado},
+		Detalle:      map[string]any{"comentario": req.Comentario},
+	})
+
+	c.JSON(http.StatusOK, ResponseData{
+		Success: true,
+		Message: "Solicitud revisada correctamente",
+		Data:    application,
+	})
+}
+
+// respondMentorApplicationError traduce los errores de las solicitudes a respuestas HTTP
+func respondMentorApplicationError(c *gin.Context, err error, message string) {
+	switch {
+	case errors.Is(err, services.ErrInvalidLinkedInURL),
+		errors.Is(err, services.ErrInvalidReviewDecision),
+		errors.Is(err, services.ErrReviewCommentRequired):
+		c.JSON(http.StatusBadRequest, ResponseData{Success: false, Message: err.Error()})
+	case errors.Is(err, services.ErrMentorApplicationNotFound):
+		c.JSON(http.StatusNotFound, ResponseData{Success: false, Message: err.Error()})
+	case errors.Is(err, services.ErrAlreadyMentor),
+		errors.Is(err, services.ErrMentorApplicationOpen),
+		errors.Is(err, services.ErrMentorApplicationNotEditable),
+		errors.Is(err, services.ErrMentorApplicationNotPending):
+		c.JSON(http.StatusConflict, ResponseData{Success: false, Message: err.Error()})
+	default:
+		c.JSON(http.StatusInternalServerError, ResponseData{Success: false, Message: message})
+	}
+}
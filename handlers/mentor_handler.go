@@ -0,0 +1,115 @@
+package handlers
+
+import (
+	"context"
+	"errors"
+	"mentorly-backend/services"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+)
+
+const (
+	defaultMentorsLimit = 20
+	maxMentorsLimit     = 50
+
+	defaultTimezoneDifference = 3
+	maxSearchTextLength       = 200
+)
+
+// GetMentorsHandler - Búsqueda pública de mentores. Parámetros opcionales: q (texto), habilidad
+// (slug del catálogo) e idioma (se pueden repetir), categoria (slug), precio_min, precio_max, calificacion_min, disponible=true,
+// zona_horaria y diferencia_horaria (horas, 0 a 12, por defecto 3), orden (relevancia,
+// calificacion, precio_asc, precio_desc), cursor y limit.
+func (h *Handler) GetMentorsHandler(c *gin.Context) {
+	filter := services.MentorSearchFilter{
+		Texto:       strings.TrimSpace(c.Query("q")),
+		Habilidades: c.QueryArray("habilidad"),
+		Categoria:   c.Query("categoria"),
+		Idiomas:     c.QueryArray("idioma"),
+		ZonaHoraria: c.Query("zona_horaria"),
+		Orden:       c.Query("orden"),
+		Cursor:      c.Query("cursor"),
+		Limit:       defaultMentorsLimit,
+	}
+
+	if len(filter.Texto) > maxSearchTextLength {
+		c.JSON(http.StatusBadRequest, ResponseData{Success: false, Message: "La búsqueda es demasiado larga"})
+		return
+	}
+
+	var ok bool
+	if filter.PrecioMin, ok = queryFloat(c, "precio_min", 0, 100000); !ok {
+		return
+	}
+	if filter.PrecioMax, ok = queryFloat(c, "precio_max", 0, 100000); !ok {
+		return
+	}
+	if filter.CalificacionMin, ok = queryFloat(c, "calificacion_min", 1, 5); !ok {
+		return
+	}
+
+	if v := c.Query("disponible"); v != "" {
+		disponible, err := strconv.ParseBool(v)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, ResponseData{Success: false, Message: "Parámetro disponible inválido"})
+			return
+		}
+		filter.SoloDisponibles = disponible
+	}
+
+	filter.DiferenciaHoraria = defaultTimezoneDifference
+	if v := c.Query("diferencia_horaria"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n < 0 || n > 12 {
+			c.JSON(http.StatusBadRequest, ResponseData{Success: false, Message: "Diferencia horaria inválida (0 a 12 horas)"})
+			return
+		}
+		filter.DiferenciaHoraria = n
+	}
+
+	if v := c.Query("limit"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n <= 0 {
+			c.JSON(http.StatusBadRequest, ResponseData{Success: false, Message: "Límite inválido"})
+			return
+		}
+		filter.Limit = min(n, maxMentorsLimit)
+	}
+
+	result, err := h.mentorService.Search(context.Background(), filter)
+	if err != nil {
+		switch {
+		case errors.Is(err, services.ErrInvalidTimezone),
+			errors.Is(err, services.ErrInvalidMentorSort),
+			errors.Is(err, services.ErrInvalidCursor):
+			c.JSON(http.StatusBadRequest, ResponseData{Success: false, Message: err.Error()})
+		default:
+			c.JSON(http.StatusInternalServerError, ResponseData{Success: false, Message: "Error al buscar mentores"})
+		}
+		return
+	}
+
+	c.JSON(http.StatusOK, ResponseData{
+		Success: true,
+		Message: "Mentores obtenidos correctamente",
+		Data:    result,
+	})
+}
+
+// queryFloat lee un número opcional de la query dentro de [minValue, maxValue].
+// Si es inválido responde 400 y devuelve false.
+func queryFloat(c *gin.Context, name string, minValue float64, maxValue float64) (*float64, bool) {
+	v := c.Query(name)
+	if v == "" {
+		return nil, true
+	}
+	n, err := strconv.ParseFloat(v, 64)
+	if err != nil || n < minValue || n > maxValue {
+		c.JSON(http.StatusBadRequest, ResponseData{Success: false, Message: "Parámetro " + name + " inválido"})
+		return nil, false
+	}
+	return &n, true
+}
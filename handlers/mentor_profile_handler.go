@@ -0,0 +1,175 @@
+package handlers
+
+import (
+	"context"
+	"errors"
+	"mentorly-backend/services"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+)
+
+type MentorProfileRequest struct {
+	Titular          string   `json:"titular" binding:"required,min=10,max=120"`
+	Biografia        string   `json:"biografia" binding:"max=5000"`
+	AreasExperiencia []string `json:"areas_experiencia" binding:"required,min=1,max=10,dive,min=2,max=50"`
+	// Punteros para distinguir 0 (sin experiencia, sesiones gratis) de un campo sin enviar
+	AniosExperiencia *int     `json:"anios_experiencia" binding:"required,gte=0,lte=60"`
+	Idiomas          []string `json:"idiomas" binding:"required,min=1,max=10"`
+	ZonaHoraria      string   `json:"zona_horaria" binding:"required,max=64"`
+	PrecioSesion     *float64 `json:"precio_sesion" binding:"required,gte=0,lte=100000"`
+	LinkedInURL      string   `json:"linkedin_url" binding:"omitempty,url,max=255"`
+	GitHubURL        string   `json:"github_url" binding:"omitempty,url,max=255"`
+	AvatarURL        string   `json:"avatar_url" binding:"omitempty,url,max=500"`
+	// Disponible indica si acepta nuevos mentorados; si no se envía se deja como está
+	Disponible *bool `json:"disponible"`
+}
+
+func (r MentorProfileRequest) input() services.MentorProfileInput {
+	return services.MentorProfileInput{
+		Titular:          r.Titular,
+		Biografia:        r.Biografia,
+		AreasExperiencia: r.AreasExperiencia,
+		AniosExperiencia: *r.AniosExperiencia,
+		Idiomas:          r.Idiomas,
+		ZonaHoraria:      r.ZonaHoraria,
+		PrecioSesion:     *r.PrecioSesion,
+		LinkedInURL:      r.LinkedInURL,
+		GitHubURL:        r.GitHubURL,
+		AvatarURL:        r.AvatarURL,
+		Disponible:       r.Disponible,
+	}
+}
+
+// GetOwnMentorProfileHandler - Obtiene el perfil de mentor del usuario
+func (h *Handler) GetOwnMentorProfileHandler(c *gin.Context) {
+	idPersona, ok := getIDPersona(c)
+	if !ok {
+		return
+	}
+
+	profile, err := h.mentorProfileService.Get(context.Background(), idPersona)
+	if err != nil {
+		respondMentorProfileError(c, err, "Error al obtener el perfil de mentor")
+		return
+	}
+
+	c.JSON(http.StatusOK, ResponseData{
+		Success: true,
+		Message: "Perfil de mentor obtenido correctamente",
+		Data:    profile,
+	})
+}
+
+// CreateMentorProfileHandler - Crea el perfil de mentor. Requiere el rol mentor.
+func (h *Handler) CreateMentorProfileHandler(c *gin.Context) {
+	idPersona, ok := getIDPersona(c)
+	if !ok {
+		return
+	}
+
+	var req MentorProfileRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, ResponseData{Success: false, Message: "Datos inválidos: " + err.Error()})
+		return
+	}
+
+	profile, err := h.mentorProfileService.Create(context.Background(), idPersona, req.input())
+	if err != nil {
+		respondMentorProfileError(c, err, "Error al crear el perfil de mentor")
+		return
+	}
+
+	c.JSON(http.StatusCreated, ResponseData{
+		Success: true,
+		Message: "Perfil de mentor creado correctamente",
+		Data:    profile,
+	})
+}
+
+// UpdateMentorProfileHandler - Reemplaza los datos del perfil de mentor
+func (h *Handler) UpdateMentorProfileHandler(c *gin.Context) {
+	idPersona, ok := getIDPersona(c)
+	if !ok {
+		return
+	}
+
+	var req MentorProfileRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, ResponseData{Success: false, Message: "Datos inválidos: " + err.Error()})
+		return
+	}
+
+	profile, err := h.mentorProfileService.Update(context.Background(), idPersona, req.input())
+	if err != nil {
+		respondMentorProfileError(c, err, "Error al actualizar el perfil de mentor")
+		return
+	}
+
+	c.JSON(http.StatusOK, ResponseData{
+		Success: true,
+		Message: "Perfil de mentor actualizado correctamente",
+		Data:    profile,
+	})
+}
+
+// DeleteMentorProfileHandler - Borra el perfil de mentor; el rol mentor se conserva
+func (h *Handler) DeleteMentorProfileHandler(c *gin.Context) {
+	idPersona, ok := getIDPersona(c)
+	if !ok {
+		return
+	}
+
+	if err := h.mentorProfileService.Delete(context.Background(), idPersona); err != nil {
+		respondMentorProfileError(c, err, "Error al borrar el perfil de mentor")
+		return
+	}
+
+	c.JSON(http.StatusOK, ResponseData{
+		Success: true,
+		Message: "Perfil de mentor borrado correctamente",
+	})
+}
+
+// GetMentorProfileHandler - Perfil público de un mentor (no requiere sesión)
+func (h *Handler) GetMentorProfileHandler(c *gin.Context) {
+	idPersona, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, ResponseData{Success: false, Message: "ID de mentor inválido"})
+		return
+	}
+
+	profile, err := h.mentorProfileService.GetPublic(context.Background(), idPersona)
+	if err != nil {
+		respondMentorProfileError(c, err, "Error al obtener el perfil de mentor")
+		return
+	}
+
+	c.JSON(http.StatusOK, ResponseData{
+		Success: true,
+		Message: "Perfil de mentor obtenido correctamente",
+		Data:    profile,
+	})
+}
+
+// respondMentorProfileError traduce los errores de los perfiles de mentor a respuestas HTTP
+func respondMentorProfileError(c *gin.Context, err error, message string) {
+	switch {
+	case errors.Is(err, services.ErrInvalidTimezone),
+		errors.Is(err, services.ErrInvalidLanguage),
+		errors.Is(err, services.ErrInvalidLinkedInURL),
+		errors.Is(err, services.ErrInvalidGitHubURL),
+		errors.Is(err, services.ErrInvalidAvatarURL),
+		errors.Is(err, services.ErrDuplicateExpertiseArea):
+		c.JSON(http.StatusBadRequest, ResponseData{Success: false, Message: err.Error()})
+	case errors.Is(err, services.ErrNotMentor):
+		c.JSON(http.StatusForbidden, ResponseData{Success: false, Message: err.Error()})
+	case errors.Is(err, services.ErrMentorProfileNotFound):
+		c.JSON(http.StatusNotFound, ResponseData{Success: false, Message: err.Error()})
+	case errors.Is(err, services.ErrMentorProfileExists):
+		c.JSON(http.StatusConflict, ResponseData{Success: false, Message: err.Error()})
+	default:
+		c.JSON(http.StatusInternalServerError, ResponseData{Success: false, Message: message})
+	}
+}
@@ -0,0 +1,290 @@
+package handlers
+
+import (
+	"context"
+	"errors"
+	"log"
+	"mentorly-backend/services"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+)
+
+// MFAChallengeResponse se devuelve en lugar de los tokens cuando la cuenta tiene segundo factor
+type MFAChallengeResponse struct {
+	MFARequired bool   `json:"mfa_required"`
+	MFAToken    string `json:"mfa_token"`
+	ExpiresIn   int    `json:"expires_in"`
+}
+
+type MFAVerifyRequest struct {
+	MFAToken string `json:"mfa_token" binding:"required"`
+	Codigo   string `json:"codigo" binding:"required"`
+}
+
+type MFACodeRequest struct {
+	Codigo string `json:"codigo" binding:"required"`
+}
+
+// MFAReauthRequest pide la contraseña (si la cuenta tiene) y un código del segundo factor
+type MFAReauthRequest struct {
+	Contrasena string `json:"contrasena"`
+	Codigo     string `json:"codigo" binding:"required"`
+}
+
+// VerifyMFAHandler - Completa el inicio de sesión con el código TOTP o un código de recuperación
+func (h *Handler) VerifyMFAHandler(c *gin.Context) {
+	var req MFAVerifyRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, ResponseData{Success: false, Message: "Datos inválidos: " + err.Error()})
+		return
+	}
+
+	ip := c.ClientIP()
+	idPersona, useCookies, err := h.mfaService.CompleteChallenge(context.Background(), req.MFAToken, req.Codigo)
+	if err != nil {
+		switch {
+		case errors.Is(err, services.ErrInvalidMFACode):
+			// Los códigos incorrectos cuentan para la protección de fuerza bruta del login
+			if profile, profileErr := h.userService.GetUserProfile(context.Background(), idPersona); profileErr == nil {
+				h.registerLoginFailure(profile.Email, ip)
+			}
+			c.JSON(http.StatusUnauthorized, ResponseData{Success: false, Message: err.Error()})
+		case errors.Is(err, services.ErrInvalidMFAChallenge):
+			c.JSON(http.StatusUnauthorized, ResponseData{Success: false, Message: err.Error()})
+		default:
+			c.JSON(http.StatusInternalServerError, ResponseData{Success: false, Message: "Error al verificar el código"})
+		}
+		return
+	}
+
+	// La cuenta pudo ser suspendida mientras se completaba el desafío
+	if err := h.authService.CheckActive(context.Background(), idPersona); err != nil {
+		if errors.Is(err, services.ErrAccountSuspended) {
+			c.JSON(http.StatusForbidden, ResponseData{Success: false, Message: "La cuenta está suspendida"})
+		} else {
+			c.JSON(http.StatusNotFound, ResponseData{Success: false, Message: "Usuario no encontrado"})
+		}
+		return
+	}
+
+	profile, err := h.userService.GetUserProfile(context.Background(), idPersona)
+	if err != nil {
+		c.JSON(http.StatusNotFound, ResponseData{Success: false, Message: "Usuario no encontrado"})
+		return
+	}
+
+	if err := h.loginGuard.RegisterSuccess(context.Background(), profile.Email, ip); err != nil {
+		log.Printf("Error al reiniciar intentos de login: %v", err)
+	}
+
+	tokens, err := issueTokens(context.Background(), h.tokenService, sessionClient(c), idPersona, profile.Email, profile.Nombre)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, ResponseData{Success: false, Message: "Error al generar token"})
+		return
+	}
+
+	recordLogin(c, h.auditService, idPersona, "mfa")
+
+	if useCookies {
+		setAuthCookies(c, tokens)
+	}
+
+	c.JSON(http.StatusOK, ResponseData{
+		Success: true,
+		Message: "Sesión iniciada correctamente",
+		Data:    tokens,
+	})
+}
+
+// GetMFAStatusHandler - Indica si el usuario tiene activa la verificación en dos pasos
+func (h *Handler) GetMFAStatusHandler(c *gin.Context) {
+	idPersona, ok := getIDPersona(c)
+	if !ok {
+		return
+	}
+
+	status, err := h.mfaService.Status(context.Background(), idPersona)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, ResponseData{Success: false, Message: "Error al obtener la verificación en dos pasos"})
+		return
+	}
+
+	c.JSON(http.StatusOK, ResponseData{
+		Success: true,
+		Message: "Estado obtenido correctamente",
+		Data:    status,
+	})
+}
+
+// SetupTOTPHandler - Genera el secreto TOTP y la URI para el código QR.
+// El segundo factor no se activa hasta confirmar un código.
+func (h *Handler) SetupTOTPHandler(c *gin.Context) {
+	idPersona, ok := getIDPersona(c)
+	if !ok {
+		return
+	}
+
+	profile, err := h.userService.GetUserProfile(context.Background(), idPersona)
+	if err != nil {
+		c.JSON(http.StatusNotFound, ResponseData{Success: false, Message: "Usuario no encontrado"})
+		return
+	}
+
+	setup, err := h.mfaService.BeginTOTPSetup(context.Background(), idPersona, profile.Email)
+	if err != nil {
+		if errors.Is(err, services.ErrMFAAlreadyEnabled) {
+			c.JSON(http.StatusConflict, ResponseData{Success: false, Message: err.Error()})
+		} else {
+			c.JSON(http.StatusInternalServerError, ResponseData{Success: false, Message: "Error al configurar la verificación en dos pasos"})
+		}
+		return
+	}
+
+	c.JSON(http.StatusOK, ResponseData{
+		Success: true,
+		Message: "Escaneá el código QR con tu app de autenticación y confirmá con un código",
+		Data:    setup,
+	})
+}
+
+// ConfirmTOTPHandler - Activa el segundo factor y devuelve los códigos de recuperación
+func (h *Handler) ConfirmTOTPHandler(c *gin.Context) {
+	idPersona, ok := getIDPersona(c)
+	if !ok {
+		return
+	}
+
+	var req MFACodeRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, ResponseData{Success: false, Message: "Datos inválidos: " + err.Error()})
+		return
+	}
+
+	codes, err := h.mfaService.ConfirmTOTP(context.Background(), idPersona, req.Codigo)
+	if err != nil {
+		switch {
+		case errors.Is(err, services.ErrInvalidMFACode):
+			c.JSON(http.StatusBadRequest, ResponseData{Success: false, Message: err.Error()})
+		case errors.Is(err, services.ErrMFAAlreadyEnabled), errors.Is(err, services.ErrMFASetupNotStarted):
+			c.JSON(http.StatusConflict, ResponseData{Success: false, Message: err.Error()})
+		default:
+			c.JSON(http.StatusInternalServerError, ResponseData{Success: false, Message: "Error al activar la verificación en dos pasos"})
+		}
+		return
+	}
+
+	recordAudit(c, h.auditService, services.AuditEntry{IDPersona: &idPersona, Accion: services.AuditMFAEnabled})
+
+	c.JSON(http.StatusOK, ResponseData{
+		Success: true,
+		Message: "Verificación en dos pasos activada. Guardá los códigos de recuperación, no se vuelven a mostrar",
+		Data:    gin.H{"codigos_recuperacion": codes},
+	})
+}
+
+// RegenerateRecoveryCodesHandler - Reemplaza los códigos de recuperación (requiere reautenticación)
+func (h *Handler) RegenerateRecoveryCodesHandler(c *gin.Context) {
+	idPersona, ok := getIDPersona(c)
+	if !ok {
+		return
+	}
+
+	if !h.reauthenticateMFA(c, idPersona) {
+		return
+	}
+
+	codes, err := h.mfaService.RegenerateRecoveryCodes(context.Background(), idPersona)
+	if err != nil {
+		if errors.Is(err, services.ErrMFANotEnabled) {
+			c.JSON(http.StatusConflict, ResponseData{Success: false, Message: err.Error()})
+		} else {
+			c.JSON(http.StatusInternalServerError, ResponseData{Success: false, Message: "Error al generar los códigos de recuperación"})
+		}
+		return
+	}
+
+	c.JSON(http.StatusOK, ResponseData{
+		Success: true,
+		Message: "Códigos de recuperación generados. Los anteriores ya no sirven",
+		Data:    gin.H{"codigos_recuperacion": codes},
+	})
+}
+
+// DisableMFAHandler - Desactiva la verificación en dos pasos (requiere reautenticación)
+func (h *Handler) DisableMFAHandler(c *gin.Context) {
+	idPersona, ok := getIDPersona(c)
+	if !ok {
+		return
+	}
+
+	if !h.reauthenticateMFA(c, idPersona) {
+		return
+	}
+
+	if err := h.mfaService.Disable(context.Background(), idPersona); err != nil {
+		if errors.Is(err, services.ErrMFANotEnabled) {
+			c.JSON(http.StatusConflict, ResponseData{Success: false, Message: err.Error()})
+		} else {
+			c.JSON(http.StatusInternalServerError, ResponseData{Success: false, Message: "Error al desactivar la verificación en dos pasos"})
+		}
+		return
+	}
+
+	recordAudit(c, h.auditService, services.AuditEntry{IDPersona: &idPersona, Accion: services.AuditMFADisabled})
+
+	c.JSON(http.StatusOK, ResponseData{
+		Success: true,
+		Message: "Verificación en dos pasos desactivada",
+	})
+}
+
+// reauthenticateMFA exige la contraseña actual (si la cuenta tiene) y un código del segundo factor.
+// Los fallos se cuentan por usuario y bloquean la reautenticación al llegar al máximo.
+// Si falla responde el error y devuelve false.
+func (h *Handler) reauthenticateMFA(c *gin.Context, idPersona int) bool {
+	var req MFAReauthRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, ResponseData{Success: false, Message: "Datos inválidos: " + err.Error()})
+		return false
+	}
+
+	if !h.checkReauthLimit(c, idPersona) {
+		return false
+	}
+
+	hasPassword, err := h.authService.HasPassword(context.Background(), idPersona)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, ResponseData{Success: false, Message: "Error al verificar la identidad"})
+		return false
+	}
+
+	if hasPassword {
+		err := h.authService.VerifyPassword(context.Background(), idPersona, req.Contrasena)
+		if err != nil {
+			if errors.Is(err, services.ErrWrongPassword) {
+				h.registerReauthFailure(c, idPersona, "contrasena")
+				c.JSON(http.StatusForbidden, ResponseData{Success: false, Message: err.Error()})
+			} else {
+				c.JSON(http.StatusInternalServerError, ResponseData{Success: false, Message: "Error al verificar la identidad"})
+			}
+			return false
+		}
+	}
+
+	if err := h.mfaService.VerifyCode(context.Background(), idPersona, req.Codigo); err != nil {
+		switch {
+		case errors.Is(err, services.ErrInvalidMFACode):
+			h.registerReauthFailure(c, idPersona, "mfa")
+			c.JSON(http.StatusForbidden, ResponseData{Success: false, Message: err.Error()})
+		case errors.Is(err, services.ErrMFANotEnabled):
+			c.JSON(http.StatusConflict, ResponseData{Success: false, Message: err.Error()})
+		default:
+			c.JSON(http.StatusInternalServerError, ResponseData{Success: false, Message: "Error al verificar el código"})
+		}
+		return false
+	}
+
+	h.registerReauthSuccess(idPersona)
+	return true
+}
@@ -1,54 +1,79 @@
 package handlers
 
 import (
-	"context"
 	"errors"
 	"fmt"
 	"mentorly-backend/services"
 	"net/http"
-	"os"
+	"net/url"
 
 	"github.com/gin-gonic/gin"
 	"github.com/jackc/pgx/v5/pgxpool"
 )
 
+// La cookie oauthBrowserCookie guarda el nonce que ata el callback al navegador que pidió la URL
+// de autorización. Solo se envía a las rutas de callback (/auth/:provider/callback).
+const (
+	oauthBrowserCookie      = "oauth_browser"
+	oauthCallbackCookiePath = "/auth/"
+)
+
 type OAuthHandler struct {
-	db          *pgxpool.Pool
-	authService *services.AuthService
+	db              *pgxpool.Pool
+	providers       *services.OAuthRegistry
+	authService     *services.AuthService
+	tokenService    *services.TokenService
+	stateService    *services.OAuthStateService
+	identityService *services.IdentityService
+	mfaService      *services.MFAService
+	auditService    *services.AuditService
 }
 
-func NewOAuthHandler(db *pgxpool.Pool) *OAuthHandler {
+func NewOAuthHandler(db *pgxpool.Pool, providers *services.OAuthRegistry, encryptor *services.Encryptor) *OAuthHandler {
 	return &OAuthHandler{
-		db:          db,
-		authService: services.NewAuthService(db),
+		db:              db,
+		providers:       providers,
+		authService:     services.NewAuthService(db),
+		tokenService:    services.NewTokenService(db),
+		stateService:    services.NewOAuthStateService(db),
+		identityService: services.NewIdentityService(db),
+		mfaService:      services.NewMFAService(db, encryptor),
+		auditService:    services.NewAuditService(db),
 	}
 }
 
-// GoogleCallbackHandler maneja el callback de Google OAuth
-func (h *OAuthHandler) GoogleCallbackHandler(c *gin.Context) {
-	code := c.Query("code")
-	if code == "" {
-		c.JSON(http.StatusBadRequest, gin.H{
-			"success": false,
-			"message": "authorization code not provided",
-		})
+// GetAuthURLHandler redirige a la autenticación del proveedor indicado en la ruta.
+// El front tiene que abrirla con una navegación (no con fetch): así la cookie que ata el
+// callback al navegador se guarda aunque el front esté en otro sitio.
+func (h *OAuthHandler) GetAuthURLHandler(c *gin.Context) {
+	provider, ok := h.getProvider(c)
+	if !ok {
 		return
 	}
 
-	userInfo, err := services.ExchangeGoogleCode(code)
-	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{
-			"success": false,
-			"message": fmt.Sprintf("Failed to exchange code: %v", err),
-		})
+	authURL, ok := h.startAuth(c, provider, 0, http.SameSiteLaxMode)
+	if !ok {
 		return
 	}
 
-	h.handleOAuthLogin(c, userInfo)
+	c.Redirect(http.StatusFound, authURL)
 }
 
-// GitHubCallbackHandler maneja el callback de GitHub OAuth
-func (h *OAuthHandler) GitHubCallbackHandler(c *gin.Context) {
+// CallbackHandler maneja el callback OAuth del proveedor indicado en la ruta
+func (h *OAuthHandler) CallbackHandler(c *gin.Context) {
+	provider, ok := h.getProvider(c)
+	if !ok {
+		return
+	}
+
+	if errorParam := c.Query("error"); errorParam != "" {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"success": false,
+			"message": fmt.Sprintf("%s error: %s - %s", provider.Name(), errorParam, c.Query("error_description")),
+		})
+		return
+	}
+
 	code := c.Query("code")
 	if code == "" {
 		c.JSON(http.StatusBadRequest, gin.H{
@@ -58,7 +83,12 @@ func (h *OAuthHandler) GitHubCallbackHandler(c *gin.Context) {
 		return
 	}
 
-	userInfo, err := services.ExchangeGitHubCode(code)
+	state, ok := h.consumeState(c, provider.Name())
+	if !ok {
+		return
+	}
+
+	token, err := provider.Exchange(c.Request.Context(), code, state.CodeVerifier)
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{
 			"success": false,
@@ -67,134 +97,169 @@ func (h *OAuthHandler) GitHubCallbackHandler(c *gin.Context) {
 		return
 	}
 
-	h.handleOAuthLogin(c, userInfo)
-}
-
-// LinkedInCallbackHandler maneja el callback de LinkedIn OAuth
-func (h *OAuthHandler) LinkedInCallbackHandler(c *gin.Context) {
-	code := c.Query("code")
-	errorParam := c.Query("error")
-	errorDesc := c.Query("error_description")
-
-	if errorParam != "" {
-		c.JSON(http.StatusBadRequest, gin.H{
+	userInfo, err := provider.UserInfo(c.Request.Context(), token, state.Nonce)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
 			"success": false,
-			"message": fmt.Sprintf("LinkedIn error: %s - %s", errorParam, errorDesc),
+			"message": fmt.Sprintf("Failed to get user info: %v", err),
 		})
 		return
 	}
 
-	if code == "" {
-		c.JSON(http.StatusBadRequest, gin.H{
-			"success": false,
-			"message": "authorization code not provided",
-		})
-		return
-	}
+	h.completeOAuth(c, state, userInfo)
+}
 
-	userInfo, err := services.ExchangeLinkedInCode(code)
+// getProvider busca en el registro el proveedor del parámetro :provider.
+// Si no existe responde 404 y devuelve false.
+func (h *OAuthHandler) getProvider(c *gin.Context) (services.OAuthProvider, bool) {
+	provider, err := h.providers.Get(services.OAuthProviderName(c.Param("provider")))
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{
+		c.JSON(http.StatusNotFound, gin.H{
 			"success": false,
-			"message": fmt.Sprintf("Failed to exchange code: %v", err),
+			"message": "provider not supported",
 		})
+		return nil, false
+	}
+
+	return provider, true
+}
+
+// completeOAuth decide si el callback corresponde a un login o a una vinculación de cuenta
+func (h *OAuthHandler) completeOAuth(c *gin.Context, state *services.OAuthState, oauthUser *services.OAuthUserInfo) {
+	if state.IDPersona != 0 {
+		h.handleOAuthLink(c, state.IDPersona, oauthUser)
 		return
 	}
 
-	h.handleOAuthLogin(c, userInfo)
+	h.handleOAuthLogin(c, oauthUser)
 }
 
 // handleOAuthLogin gestiona el login/registro con OAuth
 func (h *OAuthHandler) handleOAuthLogin(c *gin.Context, oauthUser *services.OAuthUserInfo) {
-	// 1) Login/registro
-	idPersona, nombre, err := h.authService.LoginUser(c.Request.Context(), oauthUser.Email, "")
+	// 1) Resolver la cuenta por (proveedor, sujeto), registrándola si es nueva
+	idPersona, nombre, err := h.identityService.ResolveOAuthUser(c.Request.Context(), oauthUser)
 	if err != nil {
-		if errors.Is(err, services.ErrInvalidCredentials) {
-			idPersona, err = h.authService.RegisterUser(context.Background(), oauthUser.Name, "", oauthUser.Email, "")
-			if err != nil {
-				c.JSON(http.StatusInternalServerError, ResponseData{Success: false, Message: "Error al registrar usuario con OAuth"})
-				return
-			}
-			nombre = oauthUser.Name
+		switch {
+		case errors.Is(err, services.ErrIdentityEmailConflict):
+			c.JSON(http.StatusConflict, ResponseData{Success: false, Message: err.Error()})
+		case errors.Is(err, services.ErrOAuthEmailRequired):
+			c.JSON(http.StatusBadRequest, ResponseData{Success: false, Message: err.Error()})
+		default:
+			c.JSON(http.StatusInternalServerError, ResponseData{Success: false, Message: "Error al procesar usuario con OAuth"})
+		}
+		return
+	}
+
+	// 2) Las cuentas suspendidas no pueden iniciar sesión
+	if err := h.authService.CheckActive(c.Request.Context(), idPersona); err != nil {
+		if errors.Is(err, services.ErrAccountSuspended) {
+			c.JSON(http.StatusForbidden, ResponseData{Success: false, Message: "La cuenta está suspendida"})
 		} else {
 			c.JSON(http.StatusInternalServerError, ResponseData{Success: false, Message: "Error al procesar usuario con OAuth"})
+		}
+		return
+	}
+
+	// 3) Con segundo factor activo, el front pide el código y completa el login en /auth/mfa/verify
+	mfaEnabled, err := h.mfaService.IsEnabled(c.Request.Context(), idPersona)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, ResponseData{Success: false, Message: "Error al procesar usuario con OAuth"})
+		return
+	}
+	if mfaEnabled {
+		mfaToken, err := h.mfaService.CreateChallenge(c.Request.Context(), idPersona, true)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, ResponseData{Success: false, Message: "Error al procesar usuario con OAuth"})
 			return
 		}
+		c.Redirect(http.StatusFound, fmt.Sprintf("%s/mfa?mfa_token=%s", getFrontendURL(), url.QueryEscape(mfaToken)))
+		return
 	}
 
-	// Generar token JWT
-	// 2) Generar tu JWT local
-	token, err := GenerateToken(idPersona, oauthUser.Email)
+	// 4) Generar el JWT local y el refresh token
+	tokens, err := issueTokens(c.Request.Context(), h.tokenService, sessionClient(c), idPersona, oauthUser.Email, nombre)
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"success": false, "message": fmt.Sprintf("Error generating token: %v", err)})
 		return
 	}
 
-	// 3) Guardarlo en cookie HttpOnly y redirigir al front
-	frontendURL := os.Getenv("FRONTEND_URL") // ej: http://localhost:5173
-	if frontendURL == "" {
-		frontendURL = "http://localhost:5173" // fallback en dev
-	}
-	c.SetCookie(
-		"auth_token",
-		token,
-		60*60*24*7, // 7 días
-		"/",
-		"",    // domain (vacío = host del backend)
-		false, // secure (poné true en https)
-		true,  // httpOnly
-	)
+	// 5) Guardarlos en cookies HttpOnly y redirigir al front
+	setAuthCookies(c, tokens)
+	recordLogin(c, h.auditService, idPersona, string(oauthUser.Provider))
 
-	c.Redirect(http.StatusFound, fmt.Sprintf("%s/role", frontendURL))
+	c.Redirect(http.StatusFound, fmt.Sprintf("%s/role", getFrontendURL()))
 
 	c.JSON(http.StatusOK, gin.H{
 		"success": true,
 		"message": "Login successful",
-		"data": TokenResponse{
-			Token:     token,
-			IDPersona: idPersona,
-			Email:     oauthUser.Email,
-			Nombre:    nombre,
-		},
+		"data":    tokens,
 	})
 
 }
 
-// GetGoogleAuthURL retorna la URL de autenticación de Google
-func (h *OAuthHandler) GetGoogleAuthURL(c *gin.Context) {
-	clientID := os.Getenv("GOOGLE_CLIENT_ID")
-	redirectURL := os.Getenv("GOOGLE_REDIRECT_URL")
-
-	authURL := fmt.Sprintf(
-		"https://accounts.google.com/o/oauth2/v2/auth?client_id=%s&redirect_uri=%s&response_type=code&scope=openid+profile+email",
-		clientID, redirectURL,
-	)
+// consumeState valida el parámetro state del callback y devuelve el verificador PKCE. El state
+// tiene que venir con la cookie del navegador que lo pidió, tanto en el login como en la vinculación.
+// Si el state falta, expiró, ya fue usado o es de otro navegador, responde 400 y devuelve false.
+func (h *OAuthHandler) consumeState(c *gin.Context, provider services.OAuthProviderName) (*services.OAuthState, bool) {
+	browserNonce, _ := c.Cookie(oauthBrowserCookie)
+	state, err := h.stateService.ConsumeState(c.Request.Context(), provider, c.Query("state"), browserNonce)
+	if err != nil {
+		if errors.Is(err, services.ErrInvalidOAuthState) {
+			c.JSON(http.StatusBadRequest, gin.H{
+				"success": false,
+				"message": "invalid or expired state",
+			})
+		} else {
+			c.JSON(http.StatusInternalServerError, gin.H{
+				"success": false,
+				"message": fmt.Sprintf("Failed to validate state: %v", err),
+			})
+		}
+		return nil, false
+	}
+	setOAuthBrowserCookie(c, "", -1, http.SameSiteLaxMode)
 
-	c.JSON(http.StatusOK, gin.H{"auth_url": authURL})
+	return state, true
 }
 
-// GetGitHubAuthURL retorna la URL de autenticación de GitHub
-func (h *OAuthHandler) GetGitHubAuthURL(c *gin.Context) {
-	clientID := os.Getenv("GITHUB_CLIENT_ID")
-	redirectURL := os.Getenv("GITHUB_REDIRECT_URL")
-
-	authURL := fmt.Sprintf(
-		"https://github.com/login/oauth/authorize?client_id=%s&redirect_uri=%s&scope=user:email",
-		clientID, redirectURL,
-	)
+// respondAuthURL genera un state nuevo y responde con la URL de autorización del proveedor.
+// La respuesta llega a un fetch del front (con credentials: "include"), que puede estar en otro
+// sitio: la cookie va con SameSite=None para que el navegador la guarde.
+func (h *OAuthHandler) respondAuthURL(c *gin.Context, provider services.OAuthProvider, idPersona int) {
+	authURL, ok := h.startAuth(c, provider, idPersona, http.SameSiteNoneMode)
+	if !ok {
+		return
+	}
 
 	c.JSON(http.StatusOK, gin.H{"auth_url": authURL})
 }
 
-// GetLinkedInAuthURL retorna la URL de autenticación de LinkedIn
-func (h *OAuthHandler) GetLinkedInAuthURL(c *gin.Context) {
-	clientID := os.Getenv("LINKEDIN_CLIENT_ID")
-	redirectURL := os.Getenv("LINKEDIN_REDIRECT_URL")
+// startAuth genera un state nuevo, guarda el nonce del navegador en la cookie y devuelve la URL
+// de autorización del proveedor. Si falla responde el error y devuelve false.
+func (h *OAuthHandler) startAuth(c *gin.Context, provider services.OAuthProvider, idPersona int, sameSite http.SameSite) (string, bool) {
+	authRequest, err := h.stateService.CreateState(c.Request.Context(), provider.Name(), idPersona)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"success": false,
+			"message": fmt.Sprintf("Failed to create state: %v", err),
+		})
+		return "", false
+	}
 
-	authURL := fmt.Sprintf(
-		"https://www.linkedin.com/oauth/v2/authorization?response_type=code&client_id=%s&redirect_uri=%s&scope=openid%%20profile%%20email",
-		clientID, redirectURL,
-	)
+	setOAuthBrowserCookie(c, authRequest.BrowserNonce, int(services.OAuthStateDuration.Seconds()), sameSite)
+	return provider.AuthCodeURL(authRequest), true
+}
 
-	c.JSON(http.StatusOK, gin.H{"auth_url": authURL})
+// setOAuthBrowserCookie escribe la cookie del nonce del navegador. Siempre va con Secure;
+// los navegadores aceptan cookies Secure en http://localhost para desarrollo.
+func setOAuthBrowserCookie(c *gin.Context, value string, maxAge int, sameSite http.SameSite) {
+	http.SetCookie(c.Writer, &http.Cookie{
+		Name:     oauthBrowserCookie,
+		Value:    value,
+		MaxAge:   maxAge,
+		Path:     oauthCallbackCookiePath,
+		Secure:   true,
+		HttpOnly: true,
+		SameSite: sameSite,
+	})
 }
@@ -0,0 +1,272 @@
+package handlers
+
+import (
+	"context"
+	"errors"
+	"log"
+	"mentorly-backend/services"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+type ForgotPasswordRequest struct {
+	Email string `json:"email" binding:"required,email"`
+}
+
+type ResetPasswordRequest struct {
+	Token      string `json:"token" binding:"required"`
+	Contrasena string `json:"contrasena" binding:"required,min=6"`
+	Confirmar  string `json:"confirmar" binding:"required"`
+}
+
+// ForgotPasswordHandler - Envía el enlace de recuperación de contraseña.
+// Siempre responde lo mismo, exista o no el email.
+func (h *Handler) ForgotPasswordHandler(c *gin.Context) {
+	var req ForgotPasswordRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, ResponseData{Success: false, Message: "Datos inválidos: " + err.Error()})
+		return
+	}
+
+	// Se procesa en segundo plano para que el tiempo de respuesta no revele si el email existe
+	go func(email string) {
+		ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+		defer cancel()
+		if err := h.passwordService.RequestPasswordReset(ctx, email); err != nil {
+			log.Printf("Error al procesar recuperación de contraseña: %v", err)
+		}
+	}(req.Email)
+
+	c.JSON(http.StatusOK, ResponseData{
+		Success: true,
+		Message: "Si el email está registrado, vas a recibir un enlace para restablecer tu contraseña",
+	})
+}
+
+// ResetPasswordHandler - Restablece la contraseña usando el token recibido por email
+func (h *Handler) ResetPasswordHandler(c *gin.Context) {
+	var req ResetPasswordRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, ResponseData{Success: false, Message: "Datos inválidos: " + err.Error()})
+		return
+	}
+
+	if req.Contrasena != req.Confirmar {
+		c.JSON(http.StatusBadRequest, ResponseData{Success: false, Message: "Las contraseñas no coinciden"})
+		return
+	}
+
+	hashedPassword, err := h.authService.HashPassword(req.Contrasena)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, ResponseData{Success: false, Message: "Error al procesar contraseña"})
+		return
+	}
+
+	idPersona, err := h.passwordService.ResetPassword(context.Background(), req.Token, hashedPassword)
+	if err != nil {
+		if errors.Is(err, services.ErrInvalidUserToken) {
+			c.JSON(http.StatusBadRequest, ResponseData{Success: false, Message: "El enlace de recuperación es inválido o expiró"})
+		} else {
+			c.JSON(http.StatusInternalServerError, ResponseData{Success: false, Message: "Error al restablecer la contraseña"})
+		}
+		return
+	}
+
+	recordAudit(c, h.auditService, services.AuditEntry{
+		IDActor:   &idPersona,
+		IDPersona: &idPersona,
+		Accion:    services.AuditPasswordReset,
+	})
+
+	clearAuthCookies(c)
+	c.JSON(http.StatusOK, ResponseData{
+		Success: true,
+		Message: "Contraseña restablecida correctamente. Iniciá sesión nuevamente",
+	})
+}
+
+type ChangePasswordRequest struct {
+	Actual    string `json:"actual" binding:"required"`
+	Nueva     string `json:"nueva" binding:"required,min=6"`
+	Confirmar string `json:"confirmar" binding:"required"`
+}
+
+// SetPasswordRequest pide el código del segundo factor si la cuenta lo tiene activado
+type SetPasswordRequest struct {
+	Nueva     string `json:"nueva" binding:"required,min=6"`
+	Confirmar string `json:"confirmar" binding:"required"`
+	Codigo    string `json:"codigo"`
+}
+
+// setPasswordLoginWindow es cuánto puede haber pasado desde el inicio de sesión para configurar
+// la contraseña sin segundo factor
+const setPasswordLoginWindow = 10 * time.Minute
+
+// ChangePasswordHandler - Cambia la contraseña verificando la actual.
+// Los intentos fallidos cuentan para el límite de reautenticación del usuario.
+// Revoca las demás sesiones y devuelve tokens nuevos para la sesión actual.
+func (h *Handler) ChangePasswordHandler(c *gin.Context) {
+	idPersona, ok := getIDPersona(c)
+	if !ok {
+		return
+	}
+
+	var req ChangePasswordRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, ResponseData{Success: false, Message: "Datos inválidos: " + err.Error()})
+		return
+	}
+
+	if req.Nueva != req.Confirmar {
+		c.JSON(http.StatusBadRequest, ResponseData{Success: false, Message: "Las contraseñas no coinciden"})
+		return
+	}
+
+	if !h.checkReauthLimit(c, idPersona) {
+		return
+	}
+
+	err := h.authService.VerifyPassword(context.Background(), idPersona, req.Actual)
+	if err != nil {
+		switch {
+		case errors.Is(err, services.ErrWrongPassword):
+			h.registerReauthFailure(c, idPersona, "contrasena")
+			c.JSON(http.StatusForbidden, ResponseData{Success: false, Message: err.Error()})
+		case errors.Is(err, services.ErrPasswordNotSet):
+			c.JSON(http.StatusConflict, ResponseData{Success: false, Message: err.Error()})
+		default:
+			c.JSON(http.StatusInternalServerError, ResponseData{Success: false, Message: "Error al verificar la contraseña"})
+		}
+		return
+	}
+	h.registerReauthSuccess(idPersona)
+
+	hashedPassword, err := h.authService.HashPassword(req.Nueva)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, ResponseData{Success: false, Message: "Error al procesar contraseña"})
+		return
+	}
+
+	if err := h.passwordService.ChangePassword(context.Background(), idPersona, hashedPassword); err != nil {
+		c.JSON(http.StatusInternalServerError, ResponseData{Success: false, Message: "Error al cambiar la contraseña"})
+		return
+	}
+
+	recordAudit(c, h.auditService, services.AuditEntry{IDPersona: &idPersona, Accion: services.AuditPasswordChange})
+
+	profile, err := h.userService.GetUserProfile(context.Background(), idPersona)
+	if err != nil {
+		c.JSON(http.StatusNotFound, ResponseData{Success: false, Message: "Usuario no encontrado"})
+		return
+	}
+
+	tokens, err := issueTokens(context.Background(), h.tokenService, sessionClient(c), idPersona, profile.Email, profile.Nombre)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, ResponseData{Success: false, Message: "Error al generar token"})
+		return
+	}
+
+	// Si la sesión usa cookies, se reemplazan por las nuevas
+	if _, err := c.Cookie(accessTokenCookie); err == nil {
+		setAuthCookies(c, tokens)
+	}
+
+	c.JSON(http.StatusOK, ResponseData{
+		Success: true,
+		Message: "Contraseña actualizada correctamente",
+		Data:    tokens,
+	})
+}
+
+// SetPasswordHandler - Configura una contraseña en una cuenta creada solo con OAuth.
+// Como no hay contraseña actual para verificar, exige el código del segundo factor si está
+// activado o, si no, que la sesión se haya iniciado hace poco.
+func (h *Handler) SetPasswordHandler(c *gin.Context) {
+	idPersona, ok := getIDPersona(c)
+	if !ok {
+		return
+	}
+
+	var req SetPasswordRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, ResponseData{Success: false, Message: "Datos inválidos: " + err.Error()})
+		return
+	}
+
+	if req.Nueva != req.Confirmar {
+		c.JSON(http.StatusBadRequest, ResponseData{Success: false, Message: "Las contraseñas no coinciden"})
+		return
+	}
+
+	if !h.reauthenticateSetPassword(c, idPersona, req.Codigo) {
+		return
+	}
+
+	hashedPassword, err := h.authService.HashPassword(req.Nueva)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, ResponseData{Success: false, Message: "Error al procesar contraseña"})
+		return
+	}
+
+	err = h.passwordService.SetPassword(context.Background(), idPersona, hashedPassword)
+	if err != nil {
+		if errors.Is(err, services.ErrPasswordAlreadySet) {
+			c.JSON(http.StatusConflict, ResponseData{Success: false, Message: "La cuenta ya tiene contraseña; usá el cambio de contraseña"})
+		} else {
+			c.JSON(http.StatusInternalServerError, ResponseData{Success: false, Message: "Error al configurar la contraseña"})
+		}
+		return
+	}
+
+	recordAudit(c, h.auditService, services.AuditEntry{IDPersona: &idPersona, Accion: services.AuditPasswordSet})
+
+	c.JSON(http.StatusOK, ResponseData{
+		Success: true,
+		Message: "Contraseña configurada correctamente",
+	})
+}
+
+// reauthenticateSetPassword verifica la identidad antes de configurar la contraseña.
+// Si falla responde el error y devuelve false.
+func (h *Handler) reauthenticateSetPassword(c *gin.Context, idPersona int, codigo string) bool {
+	mfaEnabled, err := h.mfaService.IsEnabled(context.Background(), idPersona)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, ResponseData{Success: false, Message: "Error al verificar la identidad"})
+		return false
+	}
+
+	if mfaEnabled {
+		if codigo == "" {
+			c.JSON(http.StatusForbidden, ResponseData{Success: false, Message: "Ingresá un código de verificación en dos pasos"})
+			return false
+		}
+		if !h.checkReauthLimit(c, idPersona) {
+			return false
+		}
+		if err := h.mfaService.VerifyCode(context.Background(), idPersona, codigo); err != nil {
+			if errors.Is(err, services.ErrInvalidMFACode) {
+				h.registerReauthFailure(c, idPersona, "mfa")
+				c.JSON(http.StatusForbidden, ResponseData{Success: false, Message: err.Error()})
+			} else {
+				c.JSON(http.StatusInternalServerError, ResponseData{Success: false, Message: "Error al verificar el código"})
+			}
+			return false
+		}
+		h.registerReauthSuccess(idPersona)
+		return true
+	}
+
+	// Con un token personal no hay sesión: RequireRecentLogin lo rechaza
+	err = h.sessionService.RequireRecentLogin(context.Background(), getIDSesion(c), idPersona, setPasswordLoginWindow)
+	if err != nil {
+		if errors.Is(err, services.ErrSessionNotRecent) {
+			c.JSON(http.StatusForbidden, ResponseData{Success: false, Message: err.Error()})
+		} else {
+			c.JSON(http.StatusInternalServerError, ResponseData{Success: false, Message: "Error al verificar la identidad"})
+		}
+		return false
+	}
+	return true
+}
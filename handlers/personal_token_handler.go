@@ -0,0 +1,177 @@
+package handlers
+
+import (
+	"context"
+	"errors"
+	"mentorly-backend/models"
+	"mentorly-backend/services"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+type CreatePersonalTokenRequest struct {
+	Nombre       string   `json:"nombre" binding:"required,min=1,max=100"`
+	Scopes       []string `json:"scopes" binding:"required,min=1"`
+	ExpiraEnDias int      `json:"expira_en_dias" binding:"omitempty,min=1,max=365"`
+}
+
+// CreatePersonalTokenResponse incluye el token en claro, que no se vuelve a mostrar
+type CreatePersonalTokenResponse struct {
+	Token string `json:"token"`
+	models.PersonalToken
+}
+
+// authenticatePersonalToken valida un token personal y los scopes requeridos por la ruta.
+// Las rutas sin scopes no aceptan tokens personales.
+func (h *Handler) authenticatePersonalToken(c *gin.Context, rawToken string, scopes []string) {
+	if len(scopes) == 0 {
+		c.JSON(http.StatusForbidden, ResponseData{
+			Success: false,
+			Message: "Esta ruta no acepta tokens personales",
+		})
+		c.Abort()
+		return
+	}
+
+	auth, err := h.personalTokenService.Authenticate(context.Background(), rawToken)
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, ResponseData{
+			Success: false,
+			Message: "Token inválido o expirado",
+		})
+		c.Abort()
+		return
+	}
+
+	for _, scope := range scopes {
+		if !services.HasScope(auth.Scopes, scope) {
+			c.JSON(http.StatusForbidden, ResponseData{
+				Success: false,
+				Message: "El token no tiene el scope requerido: " + scope,
+			})
+			c.Abort()
+			return
+		}
+	}
+
+	c.Set("id_persona", auth.IDPersona)
+	c.Set("id_actor", auth.IDPersona)
+	c.Set("email", auth.Email)
+	c.Set("id_token_personal", auth.IDToken)
+
+	c.Next()
+}
+
+// GetScopesHandler - Lista los scopes que se pueden otorgar a un token personal
+func (h *Handler) GetScopesHandler(c *gin.Context) {
+	c.JSON(http.StatusOK, ResponseData{
+		Success: true,
+		Message: "Scopes obtenidos correctamente",
+		Data:    services.AvailableScopes,
+	})
+}
+
+// CreatePersonalTokenHandler - Crea un token personal. El valor solo se devuelve en esta respuesta.
+func (h *Handler) CreatePersonalTokenHandler(c *gin.Context) {
+	idPersona, ok := getIDPersona(c)
+	if !ok {
+		return
+	}
+
+	var req CreatePersonalTokenRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, ResponseData{Success: false, Message: "Datos inválidos: " + err.Error()})
+		return
+	}
+
+	ttl := services.DefaultPersonalTokenDuration
+	if req.ExpiraEnDias > 0 {
+		ttl = time.Duration(req.ExpiraEnDias) * 24 * time.Hour
+	}
+
+	rawToken, token, err := h.personalTokenService.Create(context.Background(), idPersona, req.Nombre, req.Scopes, ttl)
+	if err != nil {
+		switch {
+		case errors.Is(err, services.ErrInvalidScope), errors.Is(err, services.ErrInvalidTokenDuration):
+			c.JSON(http.StatusBadRequest, ResponseData{Success: false, Message: err.Error()})
+		case errors.Is(err, services.ErrTooManyPersonalTokens):
+			c.JSON(http.StatusConflict, ResponseData{Success: false, Message: err.Error()})
+		default:
+			c.JSON(http.StatusInternalServerError, ResponseData{Success: false, Message: "Error al crear el token"})
+		}
+		return
+	}
+
+	recordAudit(c, h.auditService, services.AuditEntry{
+		IDPersona:    &idPersona,
+		Accion:       services.AuditTokenCreated,
+		TipoObjetivo: services.AuditTargetPersonalToken,
+		IDObjetivo:   strconv.Itoa(token.IDToken),
+		Despues:      token,
+	})
+
+	c.JSON(http.StatusCreated, ResponseData{
+		Success: true,
+		Message: "Token creado. Copialo ahora, no se vuelve a mostrar",
+		Data:    CreatePersonalTokenResponse{Token: rawToken, PersonalToken: *token},
+	})
+}
+
+// GetPersonalTokensHandler - Lista los tokens personales del usuario (sin el valor secreto)
+func (h *Handler) GetPersonalTokensHandler(c *gin.Context) {
+	idPersona, ok := getIDPersona(c)
+	if !ok {
+		return
+	}
+
+	tokens, err := h.personalTokenService.List(context.Background(), idPersona)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, ResponseData{Success: false, Message: "Error al obtener los tokens"})
+		return
+	}
+
+	c.JSON(http.StatusOK, ResponseData{
+		Success: true,
+		Message: "Tokens obtenidos correctamente",
+		Data:    tokens,
+	})
+}
+
+// RevokePersonalTokenHandler - Revoca un token personal
+func (h *Handler) RevokePersonalTokenHandler(c *gin.Context) {
+	idPersona, ok := getIDPersona(c)
+	if !ok {
+		return
+	}
+
+	idToken, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, ResponseData{Success: false, Message: "ID de token inválido"})
+		return
+	}
+
+	err = h.personalTokenService.Revoke(context.Background(), idPersona, idToken)
+	if err != nil {
+		if errors.Is(err, services.ErrPersonalTokenNotFound) {
+			c.JSON(http.StatusNotFound, ResponseData{Success: false, Message: err.Error()})
+		} else {
+			c.JSON(http.StatusInternalServerError, ResponseData{Success: false, Message: "Error al revocar el token"})
+		}
+		return
+	}
+
+	recordAudit(c, h.auditService, services.AuditEntry{
+		IDPersona:    &idPersona,
+		Accion:       services.AuditTokenRevoked,
+		TipoObjetivo: services.AuditTargetPersonalToken,
+		IDObjetivo:   strconv.Itoa(idToken),
+	})
+
+	c.JSON(http.StatusOK, ResponseData{
+		Success: true,
+		Message: "Token revocado correctamente",
+	})
+}
@@ -0,0 +1,67 @@
+package handlers
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"math"
+	"mentorly-backend/services"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+)
+
+// RateLimitKey obtiene la clave por la que se cuentan los requests
+type RateLimitKey func(c *gin.Context) string
+
+// RateLimitByIP cuenta por IP del cliente. Sirve para las rutas públicas.
+func RateLimitByIP(c *gin.Context) string {
+	return "ip:" + c.ClientIP()
+}
+
+// RateLimitByClient cuenta por token personal, si no por usuario y si no por IP.
+// Debe usarse después de AuthMiddleware.
+func RateLimitByClient(c *gin.Context) string {
+	if idToken := c.GetInt("id_token_personal"); idToken != 0 {
+		return fmt.Sprintf("pat:%d", idToken)
+	}
+	if idPersona := c.GetInt("id_persona"); idPersona != 0 {
+		return fmt.Sprintf("user:%d", idPersona)
+	}
+	return RateLimitByIP(c)
+}
+
+// RateLimit - Middleware que limita los requests según la política. Informa el estado en los
+// headers RateLimit-* y responde 429 con Retry-After al superarla. Si el almacenamiento
+// falla deja pasar el request para no cortar el servicio.
+func RateLimit(store services.RateLimitStore, policy services.RateLimitPolicy, key RateLimitKey) gin.HandlerFunc {
+	policyHeader := fmt.Sprintf("%d;w=%d", policy.Limit, int(policy.Window.Seconds()))
+
+	return func(c *gin.Context) {
+		result, err := store.Allow(context.Background(), key(c), policy)
+		if err != nil {
+			log.Printf("Error en rate limit (%s): %v", policy.Name, err)
+			c.Next()
+			return
+		}
+
+		reset := strconv.Itoa(int(math.Ceil(result.Reset.Seconds())))
+		c.Header("RateLimit-Policy", policyHeader)
+		c.Header("RateLimit-Limit", strconv.Itoa(result.Limit))
+		c.Header("RateLimit-Remaining", strconv.Itoa(result.Remaining))
+		c.Header("RateLimit-Reset", reset)
+
+		if !result.Allowed {
+			c.Header("Retry-After", reset)
+			c.JSON(http.StatusTooManyRequests, ResponseData{
+				Success: false,
+				Message: "Demasiadas solicitudes. Probá de nuevo en unos segundos",
+			})
+			c.Abort()
+			return
+		}
+
+		c.Next()
+	}
+}
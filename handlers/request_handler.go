@@ -0,0 +1,242 @@
+package handlers
+
+import (
+	"context"
+	"errors"
+	"mentorly-backend/models"
+	"mentorly-backend/services"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+)
+
+const (
+	defaultMentorshipRequestsLimit = 20
+	maxMentorshipRequestsLimit     = 100
+)
+
+type MentorshipRequestRequest struct {
+	IDMentor  int      `json:"id_mentor" binding:"required"`
+	Mensaje   string   `json:"mensaje" binding:"required,min=20,max=2000"`
+	Objetivos []string `json:"objetivos" binding:"required,min=1,max=5,dive,required,max=200"`
+}
+
+type DeclineMentorshipRequestRequest struct {
+	Motivo string `json:"motivo" binding:"required,max=1000"`
+}
+
+// CreateMentorshipRequestHandler - El mentorado le pide una mentoría a un mentor
+func (h *Handler) CreateMentorshipRequestHandler(c *gin.Context) {
+	idPersona, ok := getIDPersona(c)
+	if !ok {
+		return
+	}
+
+	var req MentorshipRequestRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, ResponseData{Success: false, Message: "Datos inválidos: " + err.Error()})
+		return
+	}
+
+	request, err := h.mentorshipRequestService.Create(context.Background(), idPersona, services.MentorshipRequestInput{
+		IDMentor:  req.IDMentor,
+		Mensaje:   req.Mensaje,
+		Objetivos: req.Objetivos,
+	})
+	if err != nil {
+		respondMentorshipRequestError(c, err, "Error al enviar la solicitud")
+		return
+	}
+
+	c.JSON(http.StatusCreated, ResponseData{
+		Success: true,
+		Message: "Solicitud enviada. Te vamos a avisar cuando el mentor responda",
+		Data:    request,
+	})
+}
+
+// GetSentMentorshipRequestsHandler - Solicitudes enviadas por el usuario. Filtro opcional: estado.
+func (h *Handler) GetSentMentorshipRequestsHandler(c *gin.Context) {
+	h.listMentorshipRequests(c, services.MentorshipRoleMentee)
+}
+
+// GetReceivedMentorshipRequestsHandler - Solicitudes recibidas por el mentor. Filtro opcional: estado.
+func (h *Handler) GetReceivedMentorshipRequestsHandler(c *gin.Context) {
+	h.listMentorshipRequests(c, services.MentorshipRoleMentor)
+}
+
+func (h *Handler) listMentorshipRequests(c *gin.Context, rol string) {
+	idPersona, ok := getIDPersona(c)
+	if !ok {
+		return
+	}
+
+	estado := c.Query("estado")
+	switch estado {
+	case "", models.MentorshipRequestPending, models.MentorshipRequestAccepted, models.MentorshipRequestDeclined,
+		models.MentorshipRequestExpired, models.MentorshipRequestWithdrawn:
+	default:
+		c.JSON(http.StatusBadRequest, ResponseData{Success: false, Message: "Estado inválido"})
+		return
+	}
+
+	limit, offset, ok := getPagination(c, defaultMentorshipRequestsLimit, maxMentorshipRequestsLimit)
+	if !ok {
+		return
+	}
+
+	requests, err := h.mentorshipRequestService.List(context.Background(), idPersona, rol, estado, limit, offset)
+	if err != nil {
+		respondMentorshipRequestError(c, err, "Error al obtener las solicitudes")
+		return
+	}
+
+	c.JSON(http.StatusOK, ResponseData{
+		Success: true,
+		Message: "Solicitudes obtenidas correctamente",
+		Data:    requests,
+	})
+}
+
+// GetMentorshipRequestHandler - Detalle de una solicitud enviada o recibida por el usuario
+func (h *Handler) GetMentorshipRequestHandler(c *gin.Context) {
+	idPersona, ok := getIDPersona(c)
+	if !ok {
+		return
+	}
+	idSolicitud, ok := getMentorshipRequestIDParam(c)
+	if !ok {
+		return
+	}
+
+	request, err := h.mentorshipRequestService.Get(context.Background(), idSolicitud, idPersona)
+	if err != nil {
+		respondMentorshipRequestError(c, err, "Error al obtener la solicitud")
+		return
+	}
+
+	c.JSON(http.StatusOK, ResponseData{
+		Success: true,
+		Message: "Solicitud obtenida correctamente",
+		Data:    request,
+	})
+}
+
+// AcceptMentorshipRequestHandler - El mentor acepta una solicitud pendiente
+func (h *Handler) AcceptMentorshipRequestHandler(c *gin.Context) {
+	idPersona, ok := getIDPersona(c)
+	if !ok {
+		return
+	}
+	idSolicitud, ok := getMentorshipRequestIDParam(c)
+	if !ok {
+		return
+	}
+
+	request, err := h.mentorshipRequestService.Accept(context.Background(), idSolicitud, idPersona)
+	if err != nil {
+		respondMentorshipRequestError(c, err, "Error al aceptar la solicitud")
+		return
+	}
+
+	c.JSON(http.StatusOK, ResponseData{
+		Success: true,
+		Message: "Solicitud aceptada",
+		Data:    request,
+	})
+}
+
+// DeclineMentorshipRequestHandler - El mentor rechaza una solicitud pendiente indicando el motivo
+func (h *Handler) DeclineMentorshipRequestHandler(c *gin.Context) {
+	idPersona, ok := getIDPersona(c)
+	if !ok {
+		return
+	}
+	idSolicitud, ok := getMentorshipRequestIDParam(c)
+	if !ok {
+		return
+	}
+
+	var req DeclineMentorshipRequestRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, ResponseData{Success: false, Message: "Datos inválidos: " + err.Error()})
+		return
+	}
+
+	request, err := h.mentorshipRequestService.Decline(context.Background(), idSolicitud, idPersona, req.Motivo)
+	if err != nil {
+		respondMentorshipRequestError(c, err, "Error al rechazar la solicitud")
+		return
+	}
+
+	c.JSON(http.StatusOK, ResponseData{
+		Success: true,
+		Message: "Solicitud rechazada",
+		Data:    request,
+	})
+}
+
+// WithdrawMentorshipRequestHandler - El mentorado retira una solicitud que sigue pendiente
+func (h *Handler) WithdrawMentorshipRequestHandler(c *gin.Context) {
+	idPersona, ok := getIDPersona(c)
+	if !ok {
+		return
+	}
+	idSolicitud, ok := getMentorshipRequestIDParam(c)
+	if !ok {
+		return
+	}
+
+	request, err := h.mentorshipRequestService.Withdraw(context.Background(), idSolicitud, idPersona)
+	if err != nil {
+		respondMentorshipRequestError(c, err, "Error al retirar la solicitud")
+		return
+	}
+
+	c.JSON(http.StatusOK, ResponseData{
+		Success: true,
+		Message: "Solicitud retirada",
+		Data:    request,
+	})
+}
+
+// StartMentorshipRequestExpiry inicia el vencimiento periódico de las solicitudes sin respuesta
+func (h *Handler) StartMentorshipRequestExpiry(ctx context.Context) {
+	h.mentorshipRequestService.StartExpiry(ctx)
+}
+
+func getMentorshipRequestIDParam(c *gin.Context) (int, bool) {
+	idSolicitud, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, ResponseData{Success: false, Message: "ID de solicitud inválido"})
+		return 0, false
+	}
+	return idSolicitud, true
+}
+
+// respondMentorshipRequestError traduce los errores de las solicitudes de mentoría a respuestas HTTP
+func respondMentorshipRequestError(c *gin.Context, err error, message string) {
+	switch {
+	case errors.Is(err, services.ErrMentorshipRequestNotFound):
+		c.JSON(http.StatusNotFound, ResponseData{Success: false, Message: err.Error()})
+	case errors.Is(err, services.ErrMentorProfileNotFound):
+		c.JSON(http.StatusNotFound, ResponseData{Success: false, Message: "Mentor no encontrado"})
+	case errors.Is(err, services.ErrPermissionDenied):
+		c.JSON(http.StatusForbidden, ResponseData{Success: false, Message: err.Error()})
+	case errors.Is(err, services.ErrMentorshipRequestOpen),
+		errors.Is(err, services.ErrMentorshipRequestNotPending),
+		errors.Is(err, services.ErrMentorshipRequestExpired),
+		errors.Is(err, services.ErrMentorNotAvailable):
+		c.JSON(http.StatusConflict, ResponseData{Success: false, Message: err.Error()})
+	case errors.Is(err, services.ErrMentorRequestLimit), errors.Is(err, services.ErrTooManyOpenRequests):
+		c.JSON(http.StatusTooManyRequests, ResponseData{Success: false, Message: err.Error()})
+	case errors.Is(err, services.ErrCannotRequestSelf),
+		errors.Is(err, services.ErrInvalidMentorshipGoals),
+		errors.Is(err, services.ErrDeclineReasonRequired),
+		errors.Is(err, services.ErrInvalidMentorshipRole):
+		c.JSON(http.StatusBadRequest, ResponseData{Success: false, Message: err.Error()})
+	default:
+		c.JSON(http.StatusInternalServerError, ResponseData{Success: false, Message: message})
+	}
+}
@@ -0,0 +1,40 @@
+package handlers
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"regexp"
+
+	"github.com/gin-gonic/gin"
+)
+
+// RequestIDHeader es el header con el identificador de la solicitud
+const RequestIDHeader = "X-Request-ID"
+
+// validRequestID acepta los IDs que mandan los proxies (UUID, hex, etc.) sin dejar pasar
+// valores arbitrarios a los logs
+var validRequestID = regexp.MustCompile(`^[A-Za-z0-9._:-]{1,64}$`)
+
+// RequestID - Middleware que asigna un ID a cada solicitud. Reutiliza el que llega en
+// X-Request-ID si es válido, lo devuelve en la respuesta y lo deja en el contexto
+// (request_id) para relacionar la auditoría con los logs.
+func RequestID() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		id := c.GetHeader(RequestIDHeader)
+		if !validRequestID.MatchString(id) {
+			id = newRequestID()
+		}
+
+		c.Set("request_id", id)
+		c.Header(RequestIDHeader, id)
+		c.Next()
+	}
+}
+
+func newRequestID() string {
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		return ""
+	}
+	return hex.EncodeToString(b)
+}
@@ -0,0 +1,312 @@
+package handlers
+
+import (
+	"context"
+	"errors"
+	"mentorly-backend/services"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+)
+
+type AssignRoleRequest struct {
+	Rol string `json:"rol" binding:"required"`
+}
+
+// RequirePermission - Exige que el rol del usuario autenticado otorgue el permiso.
+// Va después de AuthMiddleware.
+func (h *Handler) RequirePermission(permiso string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		idPersona, ok := getIDPersona(c)
+		if !ok {
+			c.Abort()
+			return
+		}
+
+		allowed, err := h.roleService.HasPermission(context.Background(), idPersona, permiso)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, ResponseData{Success: false, Message: "Error al verificar permisos"})
+			c.Abort()
+			return
+		}
+		if !allowed {
+			c.JSON(http.StatusForbidden, ResponseData{
+				Success: false,
+				Message: services.ErrPermissionDenied.Error(),
+				Data:    gin.H{"permiso_requerido": permiso},
+			})
+			c.Abort()
+			return
+		}
+
+		c.Next()
+	}
+}
+
+// GetRolesHandler - Lista los roles con sus permisos
+func (h *Handler) GetRolesHandler(c *gin.Context) {
+	roles, err := h.roleService.GetAllRoles(context.Background())
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, ResponseData{Success: false, Message: "Error al obtener los roles"})
+		return
+	}
+
+	c.JSON(http.StatusOK, ResponseData{
+		Success: true,
+		Message: "Roles obtenidos correctamente",
+		Data:    roles,
+	})
+}
+
+// AssignRoleHandler - Agrega un rol a un usuario, incluidos los que no se pueden elegir (admin).
+// Solo se pueden asignar roles cuyos permisos tenga quien los asigna, y nunca a uno mismo.
+func (h *Handler) AssignRoleHandler(c *gin.Context) {
+	idAdmin, ok := getIDPersona(c)
+	if !ok {
+		return
+	}
+
+	idPersona, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, ResponseData{Success: false, Message: "ID de usuario inválido"})
+		return
+	}
+
+	if idPersona == idAdmin {
+		c.JSON(http.StatusConflict, ResponseData{Success: false, Message: services.ErrCannotChangeOwnRole.Error()})
+		return
+	}
+
+	var req AssignRoleRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, ResponseData{Success: false, Message: "Datos inválidos: " + err.Error()})
+		return
+	}
+
+	role, err := h.roleService.AssignRole(context.Background(), idAdmin, idPersona, req.Rol)
+	if err != nil {
+		respondRoleError(c, err, "Error al asignar el rol")
+		return
+	}
+
+	recordAudit(c, h.auditService, services.AuditEntry{
+		IDPersona:    &idPersona,
+		Accion:       services.AuditRoleAssigned,
+		TipoObjetivo: services.AuditTargetUser,
+		IDObjetivo:   strconv.Itoa(idPersona),
+		Detalle:      map[string]any{"rol": role.NombreRol},
+	})
+
+	c.JSON(http.StatusOK, ResponseData{
+		Success: true,
+		Message: "Rol asignado correctamente",
+		Data: gin.H{
+			"id_persona": idPersona,
+			"rol":        role.NombreRol,
+		},
+	})
+}
+
+// RevokeRoleHandler - Quita un rol a un usuario
+func (h *Handler) RevokeRoleHandler(c *gin.Context) {
+	idAdmin, ok := getIDPersona(c)
+	if !ok {
+		return
+	}
+
+	idPersona, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, ResponseData{Success: false, Message: "ID de usuario inválido"})
+		return
+	}
+
+	// Evita que el último administrador se quite los permisos por error
+	if idPersona == idAdmin {
+		c.JSON(http.StatusConflict, ResponseData{Success: false, Message: services.ErrCannotChangeOwnRole.Error()})
+		return
+	}
+
+	if err := h.roleService.RevokeRole(context.Background(), idPersona, c.Param("rol")); err != nil {
+		respondRoleError(c, err, "Error al quitar el rol")
+		return
+	}
+
+	recordAudit(c, h.auditService, services.AuditEntry{
+		IDPersona:    &idPersona,
+		Accion:       services.AuditRoleRevoked,
+		TipoObjetivo: services.AuditTargetUser,
+		IDObjetivo:   strconv.Itoa(idPersona),
+		Detalle:      map[string]any{"rol": c.Param("rol")},
+	})
+
+	c.JSON(http.StatusOK, ResponseData{
+		Success: true,
+		Message: "Rol quitado correctamente",
+	})
+}
+
+// GetUserRolesHandler - Lista los roles del usuario autenticado, marcando el activo
+func (h *Handler) GetUserRolesHandler(c *gin.Context) {
+	idPersona, ok := getIDPersona(c)
+	if !ok {
+		return
+	}
+
+	roles, err := h.roleService.GetUserRoles(context.Background(), idPersona)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, ResponseData{Success: false, Message: "Error al obtener los roles"})
+		return
+	}
+
+	nombres := make([]string, len(roles))
+	for i, role := range roles {
+		nombres[i] = role.NombreRol
+	}
+	activo := activeRole(c, nombres)
+	for i := range roles {
+		roles[i].Activo = roles[i].NombreRol == activo
+	}
+
+	c.JSON(http.StatusOK, ResponseData{
+		Success: true,
+		Message: "Roles obtenidos correctamente",
+		Data:    roles,
+	})
+}
+
+// RemoveUserRoleHandler - El usuario deja uno de los roles que había elegido
+func (h *Handler) RemoveUserRoleHandler(c *gin.Context) {
+	idPersona, ok := getIDPersona(c)
+	if !ok {
+		return
+	}
+
+	if err := h.roleService.RemoveRole(context.Background(), idPersona, c.Param("rol")); err != nil {
+		respondRoleError(c, err, "Error al quitar el rol")
+		return
+	}
+
+	recordAudit(c, h.auditService, services.AuditEntry{
+		IDPersona:    &idPersona,
+		Accion:       services.AuditRoleRemoved,
+		TipoObjetivo: services.AuditTargetUser,
+		IDObjetivo:   strconv.Itoa(idPersona),
+		Detalle:      map[string]any{"rol": c.Param("rol")},
+	})
+
+	c.JSON(http.StatusOK, ResponseData{
+		Success: true,
+		Message: "Rol quitado correctamente",
+	})
+}
+
+// SetActiveRoleHandler - Cambia el rol activo de la sesión actual
+func (h *Handler) SetActiveRoleHandler(c *gin.Context) {
+	idPersona, ok := getIDPersona(c)
+	if !ok {
+		return
+	}
+
+	var req SelectRoleRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, ResponseData{Success: false, Message: "Rol inválido"})
+		return
+	}
+
+	if err := h.sessionService.SetActiveRole(context.Background(), getIDSesion(c), idPersona, req.Rol); err != nil {
+		respondRoleError(c, err, "Error al cambiar el rol activo")
+		return
+	}
+
+	recordAudit(c, h.auditService, services.AuditEntry{
+		IDPersona:    &idPersona,
+		Accion:       services.AuditActiveRoleChanged,
+		TipoObjetivo: services.AuditTargetUser,
+		IDObjetivo:   strconv.Itoa(idPersona),
+		Antes:        map[string]any{"rol_activo": c.GetString("rol_activo")},
+		Despues:      map[string]any{"rol_activo": req.Rol},
+	})
+
+	c.JSON(http.StatusOK, ResponseData{
+		Success: true,
+		Message: "Rol activo actualizado",
+		Data:    gin.H{"rol_activo": req.Rol},
+	})
+}
+
+// GetRoleProfileHandler - Obtiene los datos de perfil del usuario para uno de sus roles
+func (h *Handler) GetRoleProfileHandler(c *gin.Context) {
+	idPersona, ok := getIDPersona(c)
+	if !ok {
+		return
+	}
+
+	datos, err := h.roleService.GetRoleProfile(context.Background(), idPersona, c.Param("rol"))
+	if err != nil {
+		respondRoleError(c, err, "Error al obtener el perfil del rol")
+		return
+	}
+
+	c.JSON(http.StatusOK, ResponseData{
+		Success: true,
+		Message: "Perfil del rol obtenido",
+		Data:    datos,
+	})
+}
+
+// UpdateRoleProfileHandler - Reemplaza los datos de perfil del usuario para uno de sus roles
+func (h *Handler) UpdateRoleProfileHandler(c *gin.Context) {
+	idPersona, ok := getIDPersona(c)
+	if !ok {
+		return
+	}
+
+	var datos map[string]any
+	if err := c.ShouldBindJSON(&datos); err != nil {
+		c.JSON(http.StatusBadRequest, ResponseData{Success: false, Message: "Datos inválidos: " + err.Error()})
+		return
+	}
+
+	if err := h.roleService.UpdateRoleProfile(context.Background(), idPersona, c.Param("rol"), datos); err != nil {
+		respondRoleError(c, err, "Error al actualizar el perfil del rol")
+		return
+	}
+
+	c.JSON(http.StatusOK, ResponseData{
+		Success: true,
+		Message: "Perfil del rol actualizado",
+	})
+}
+
+// activeRole devuelve el rol activo de la sesión. Con tokens personales (sin sesión)
+// o si la sesión no tiene uno, se usa el primer rol del usuario.
+func activeRole(c *gin.Context, roles []string) string {
+	if rol := c.GetString("rol_activo"); rol != "" {
+		return rol
+	}
+	if len(roles) > 0 {
+		return roles[0]
+	}
+	return ""
+}
+
+// respondRoleError traduce los errores de roles a respuestas HTTP
+func respondRoleError(c *gin.Context, err error, message string) {
+	switch {
+	case errors.Is(err, services.ErrRoleNotFound):
+		c.JSON(http.StatusBadRequest, ResponseData{Success: false, Message: "Rol no válido"})
+	case errors.Is(err, services.ErrUserNotFound):
+		c.JSON(http.StatusNotFound, ResponseData{Success: false, Message: "Usuario no encontrado"})
+	case errors.Is(err, services.ErrRoleNotSelectable), errors.Is(err, services.ErrRoleExceedsActor):
+		c.JSON(http.StatusForbidden, ResponseData{Success: false, Message: err.Error()})
+	case errors.Is(err, services.ErrRoleNotAssigned):
+		c.JSON(http.StatusNotFound, ResponseData{Success: false, Message: err.Error()})
+	case errors.Is(err, services.ErrRoleAlreadyAssigned):
+		c.JSON(http.StatusConflict, ResponseData{Success: false, Message: err.Error()})
+	case errors.Is(err, services.ErrRoleProfileTooLarge):
+		c.JSON(http.StatusRequestEntityTooLarge, ResponseData{Success: false, Message: err.Error()})
+	default:
+		c.JSON(http.StatusInternalServerError, ResponseData{Success: false, Message: message})
+	}
+}
@@ -3,6 +3,7 @@ package handlers
 import (
 	"context"
 	"errors"
+	"log"
 	"mentorly-backend/models"
 	"mentorly-backend/services"
 	"net/http"
@@ -14,11 +15,27 @@ import (
 )
 
 type Handler struct {
-	authService         *services.AuthService
-	userService         *services.UserService
-	roleService         *services.RoleService
-	planService         *services.PlanService
-	subscriptionService *services.SubscriptionService
+	authService              *services.AuthService
+	userService              *services.UserService
+	roleService              *services.RoleService
+	planService              *services.PlanService
+	subscriptionService      *services.SubscriptionService
+	tokenService             *services.TokenService
+	verificationService      *services.EmailVerificationService
+	passwordService          *services.PasswordService
+	loginGuard               *services.LoginGuardService
+	mfaService               *services.MFAService
+	personalTokenService     *services.PersonalTokenService
+	sessionService           *services.SessionService
+	mentorApplicationService *services.MentorApplicationService
+	userAdminService         *services.UserAdminService
+	accountService           *services.AccountService
+	auditService             *services.AuditService
+	impersonationService     *services.ImpersonationService
+	mentorProfileService     *services.MentorProfileService
+	mentorService            *services.MentorService
+	skillService             *services.SkillService
+	mentorshipRequestService *services.MentorshipRequestService
 }
 
 // RegisterRequest - Estructura para registro con campos en minúsculas
@@ -45,13 +62,29 @@ type UpdateProfileRequest struct {
 	Apellido string `json:"apellido" binding:"omitempty,min=2"`
 }
 
-func NewHandler(db *pgxpool.Pool) *Handler {
+func NewHandler(db *pgxpool.Pool, mailer services.Mailer, encryptor *services.Encryptor) *Handler {
 	return &Handler{
-		authService:         services.NewAuthService(db),
-		userService:         services.NewUserService(db),
-		roleService:         services.NewRoleService(db),
-		planService:         services.NewPlanService(db),
-		subscriptionService: services.NewSubscriptionService(db),
+		authService:              services.NewAuthService(db),
+		userService:              services.NewUserService(db),
+		roleService:              services.NewRoleService(db),
+		planService:              services.NewPlanService(db),
+		subscriptionService:      services.NewSubscriptionService(db),
+		tokenService:             services.NewTokenService(db),
+		verificationService:      services.NewEmailVerificationService(db, mailer, getFrontendURL()),
+		passwordService:          services.NewPasswordService(db, mailer, getFrontendURL()),
+		loginGuard:               services.NewLoginGuardService(db, mailer, getFrontendURL(), services.LoginGuardConfigFromEnv()),
+		mfaService:               services.NewMFAService(db, encryptor),
+		personalTokenService:     services.NewPersonalTokenService(db),
+		sessionService:           services.NewSessionService(db),
+		mentorApplicationService: services.NewMentorApplicationService(db, mailer, getFrontendURL()),
+		userAdminService:         services.NewUserAdminService(db),
+		accountService:           services.NewAccountService(db, mailer, services.AccountDeletionGraceFromEnv()),
+		auditService:             services.NewAuditService(db),
+		impersonationService:     services.NewImpersonationService(db),
+		mentorProfileService:     services.NewMentorProfileService(db),
+		mentorService:            services.NewMentorService(db),
+		skillService:             services.NewSkillService(db),
+		mentorshipRequestService: services.NewMentorshipRequestService(db, mailer, getFrontendURL(), services.MentorshipConfigFromEnv()),
 	}
 }
 
@@ -103,8 +136,13 @@ func (h *Handler) RegisterHandler(c *gin.Context) {
 		return
 	}
 
-	// Generar token
-	token, err := GenerateToken(idPersona, req.Email)
+	// Enviar el email de verificación; si falla, el usuario puede pedir el reenvío
+	if err := h.verificationService.SendVerification(context.Background(), idPersona); err != nil {
+		log.Printf("Error al enviar email de verificación: %v", err)
+	}
+
+	// Generar tokens
+	tokens, err := issueTokens(context.Background(), h.tokenService, sessionClient(c), idPersona, req.Email, req.Nombre)
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, ResponseData{
 			Success: false,
@@ -116,12 +154,7 @@ func (h *Handler) RegisterHandler(c *gin.Context) {
 	c.JSON(http.StatusCreated, ResponseData{
 		Success: true,
 		Message: "Usuario registrado exitosamente",
-		Data: TokenResponse{
-			Token:     token,
-			IDPersona: idPersona,
-			Email:     req.Email,
-			Nombre:    req.Nombre,
-		},
+		Data:    tokens,
 	})
 }
 
@@ -137,9 +170,38 @@ func (h *Handler) LoginHandler(c *gin.Context) {
 		return
 	}
 
+	// Frenar intentos repetidos por email o por IP. La respuesta es la misma exista o no la cuenta.
+	ip := c.ClientIP()
+	if err := h.loginGuard.Check(context.Background(), req.Email, ip); err != nil {
+		var retryErr *services.RetryAfterError
+		if errors.As(err, &retryErr) {
+			c.Header("Retry-After", strconv.Itoa(int(retryErr.RetryAfter.Seconds())+1))
+			c.JSON(http.StatusTooManyRequests, ResponseData{
+				Success: false,
+				Message: "Demasiados intentos fallidos. Probá de nuevo más tarde",
+			})
+		} else {
+			c.JSON(http.StatusInternalServerError, ResponseData{
+				Success: false,
+				Message: "Error al iniciar sesión",
+			})
+		}
+		return
+	}
+
 	// Verificar credenciales
 	idPersona, nombre, err := h.authService.LoginUser(context.Background(), req.Email, req.Contrasena)
 	if err != nil {
+		if errors.Is(err, services.ErrAccountSuspended) {
+			c.JSON(http.StatusForbidden, ResponseData{
+				Success: false,
+				Message: "La cuenta está suspendida",
+			})
+			return
+		}
+		if errors.Is(err, services.ErrInvalidCredentials) {
+			h.registerLoginFailure(req.Email, ip)
+		}
 		c.JSON(http.StatusUnauthorized, ResponseData{
 			Success: false,
 			Message: "Email o contraseña incorrectos",
@@ -147,8 +209,43 @@ func (h *Handler) LoginHandler(c *gin.Context) {
 		return
 	}
 
-	// Generar token
-	token, err := GenerateToken(idPersona, req.Email)
+	// Con segundo factor activo se devuelve un desafío en lugar de la sesión
+	mfaEnabled, err := h.mfaService.IsEnabled(context.Background(), idPersona)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, ResponseData{
+			Success: false,
+			Message: "Error al iniciar sesión",
+		})
+		return
+	}
+	if mfaEnabled {
+		mfaToken, err := h.mfaService.CreateChallenge(context.Background(), idPersona, false)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, ResponseData{
+				Success: false,
+				Message: "Error al iniciar sesión",
+			})
+			return
+		}
+
+		c.JSON(http.StatusOK, ResponseData{
+			Success: true,
+			Message: "Ingresá el código de verificación",
+			Data: MFAChallengeResponse{
+				MFARequired: true,
+				MFAToken:    mfaToken,
+				ExpiresIn:   int(services.MFAChallengeDuration.Seconds()),
+			},
+		})
+		return
+	}
+
+	if err := h.loginGuard.RegisterSuccess(context.Background(), req.Email, ip); err != nil {
+		log.Printf("Error al reiniciar intentos de login: %v", err)
+	}
+
+	// Generar tokens
+	tokens, err := issueTokens(context.Background(), h.tokenService, sessionClient(c), idPersona, req.Email, nombre)
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, ResponseData{
 			Success: false,
@@ -157,19 +254,17 @@ func (h *Handler) LoginHandler(c *gin.Context) {
 		return
 	}
 
+	recordLogin(c, h.auditService, idPersona, "contrasena")
+
 	c.JSON(http.StatusOK, ResponseData{
 		Success: true,
 		Message: "Sesión iniciada correctamente",
-		Data: TokenResponse{
-			Token:     token,
-			IDPersona: idPersona,
-			Email:     req.Email,
-			Nombre:    nombre,
-		},
+		Data:    tokens,
 	})
 }
 
-// SelectRoleHandler - Seleccionar rol
+// SelectRoleHandler - Agregar un rol a los que ya tiene el usuario.
+// Solo se pueden elegir los roles seleccionables.
 func (h *Handler) SelectRoleHandler(c *gin.Context) {
 	var req SelectRoleRequest
 
@@ -200,22 +295,52 @@ func (h *Handler) SelectRoleHandler(c *gin.Context) {
 		return
 	}
 
-	// Obtener ID del rol
-	idRol, err := h.userService.GetRoleIDByName(context.Background(), req.Rol)
+	// Agregar rol (el rol admin no se puede elegir)
+	_, err := h.roleService.SelectRole(context.Background(), idPersona, req.Rol)
 	if err != nil {
-		c.JSON(http.StatusBadRequest, ResponseData{
-			Success: false,
-			Message: "Rol no válido",
-		})
+		switch {
+		case errors.Is(err, services.ErrRoleNotFound):
+			c.JSON(http.StatusBadRequest, ResponseData{
+				Success: false,
+				Message: "Rol no válido",
+			})
+		case errors.Is(err, services.ErrRoleNotSelectable) && req.Rol == services.MentorRoleName:
+			c.JSON(http.StatusForbidden, ResponseData{
+				Success: false,
+				Message: "Para ser mentor tenés que enviar una solicitud y esperar la aprobación",
+			})
+		case errors.Is(err, services.ErrRoleNotSelectable):
+			c.JSON(http.StatusForbidden, ResponseData{
+				Success: false,
+				Message: "Ese rol no se puede elegir",
+			})
+		case errors.Is(err, services.ErrRoleAlreadyAssigned):
+			c.JSON(http.StatusConflict, ResponseData{
+				Success: false,
+				Message: "Ya tenés ese rol",
+			})
+		default:
+			c.JSON(http.StatusInternalServerError, ResponseData{
+				Success: false,
+				Message: "Error al actualizar rol",
+			})
+		}
 		return
 	}
 
-	// Actualizar rol
-	err = h.userService.UpdateUserRole(context.Background(), idPersona, idRol)
+	recordAudit(c, h.auditService, services.AuditEntry{
+		IDPersona:    &idPersona,
+		Accion:       services.AuditRoleSelected,
+		TipoObjetivo: services.AuditTargetUser,
+		IDObjetivo:   strconv.Itoa(idPersona),
+		Detalle:      map[string]any{"rol": req.Rol},
+	})
+
+	profile, err := h.userService.GetUserProfile(context.Background(), idPersona)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, ResponseData{
+		c.JSON(http.StatusNotFound, ResponseData{
 			Success: false,
-			Message: "Error al actualizar rol",
+			Message: "Usuario no encontrado",
 		})
 		return
 	}
@@ -224,7 +349,8 @@ func (h *Handler) SelectRoleHandler(c *gin.Context) {
 		Success: true,
 		Message: "Rol asignado correctamente",
 		Data: gin.H{
-			"rol": req.Rol,
+			"rol":   req.Rol,
+			"roles": profile.Roles,
 		},
 	})
 }
@@ -259,16 +385,35 @@ func (h *Handler) GetProfileHandler(c *gin.Context) {
 		return
 	}
 
+	permisos, err := h.roleService.GetUserPermissions(context.Background(), idPersona)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, ResponseData{
+			Success: false,
+			Message: "Error al obtener permisos",
+		})
+		return
+	}
+
+	data := gin.H{
+		"id_persona":       profile.IDPersona,
+		"nombre":           profile.Nombre,
+		"apellido":         profile.Apellido,
+		"email":            profile.Email,
+		"email_verificado": profile.EmailVerificado,
+		"tiene_contrasena": profile.TieneContrasena,
+		"rol":              activeRole(c, profile.Roles),
+		"roles":            profile.Roles,
+		"permisos":         permisos,
+	}
+	// El frontend muestra un aviso mientras un administrador actúa como el usuario
+	if isImpersonating(c) {
+		data["suplantado_por"] = getIDActor(c)
+	}
+
 	c.JSON(http.StatusOK, ResponseData{
 		Success: true,
 		Message: "Perfil obtenido",
-		Data: gin.H{
-			"id_persona": profile.IDPersona,
-			"nombre":     profile.Nombre,
-			"apellido":   profile.Apellido,
-			"email":      profile.Email,
-			"rol":        profile.Rol,
-		},
+		Data:    data,
 	})
 }
 
@@ -360,6 +505,13 @@ func (h *Handler) CreatePlanHandler(c *gin.Context) {
 		return
 	}
 
+	recordAudit(c, h.auditService, services.AuditEntry{
+		Accion:       services.AuditPlanCreated,
+		TipoObjetivo: services.AuditTargetPlan,
+		IDObjetivo:   strconv.Itoa(createdPlan.ID),
+		Despues:      createdPlan,
+	})
+
 	c.JSON(http.StatusCreated, ResponseData{
 		Success: true,
 		Message: "Plan creado exitosamente",
@@ -418,12 +570,32 @@ func (h *Handler) UpdatePlanHandler(c *gin.Context) {
 		return
 	}
 
+	// Estado anterior para la auditoría
+	before, err := h.planService.GetPlanByID(context.Background(), id)
+	if err != nil {
+		if errors.Is(err, services.ErrNotFound) {
+			c.JSON(http.StatusNotFound, ResponseData{Success: false, Message: "Plan no encontrado"})
+		} else {
+			c.JSON(http.StatusInternalServerError, ResponseData{Success: false, Message: "Error al actualizar el plan"})
+		}
+		return
+	}
+
 	err = h.planService.UpdatePlan(context.Background(), id, plan)
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, ResponseData{Success: false, Message: "Error al actualizar el plan"})
 		return
 	}
 
+	plan.ID = id
+	recordAudit(c, h.auditService, services.AuditEntry{
+		Accion:       services.AuditPlanUpdated,
+		TipoObjetivo: services.AuditTargetPlan,
+		IDObjetivo:   strconv.Itoa(id),
+		Antes:        before,
+		Despues:      plan,
+	})
+
 	c.JSON(http.StatusOK, ResponseData{
 		Success: true,
 		Message: "Plan actualizado correctamente",
@@ -438,7 +610,11 @@ func (h *Handler) DeletePlanHandler(c *gin.Context) {
 		return
 	}
 
-	err = h.planService.DeletePlan(context.Background(), id)
+	// Estado anterior para la auditoría
+	before, err := h.planService.GetPlanByID(context.Background(), id)
+	if err == nil {
+		err = h.planService.DeletePlan(context.Background(), id)
+	}
 	if err != nil {
 		if errors.Is(err, services.ErrNotFound) {
 			c.JSON(http.StatusNotFound, ResponseData{Success: false, Message: "Plan no encontrado"})
@@ -448,14 +624,24 @@ func (h *Handler) DeletePlanHandler(c *gin.Context) {
 		return
 	}
 
+	recordAudit(c, h.auditService, services.AuditEntry{
+		Accion:       services.AuditPlanDeleted,
+		TipoObjetivo: services.AuditTargetPlan,
+		IDObjetivo:   strconv.Itoa(id),
+		Antes:        before,
+	})
+
 	c.JSON(http.StatusOK, ResponseData{
 		Success: true,
 		Message: "Plan eliminado correctamente",
 	})
 }
 
-// AuthMiddleware - acepta Authorization: Bearer <token> O cookie "auth_token"
-func AuthMiddleware() gin.HandlerFunc {
+// AuthMiddleware - acepta Authorization: Bearer <token> O cookie "auth_token".
+// Sin scopes la ruta solo admite la sesión del usuario (JWT). Con scopes también
+// admite tokens personales que tengan todos los scopes indicados.
+// Deja en el contexto id_persona (el usuario) e id_actor (quien actúa: distinto solo al suplantar).
+func (h *Handler) AuthMiddleware(scopes ...string) gin.HandlerFunc {
 	return func(c *gin.Context) {
 		token := ""
 
@@ -482,7 +668,13 @@ func AuthMiddleware() gin.HandlerFunc {
 			return
 		}
 
-		// 4) Validar JWT
+		// 4) Token personal: validar y exigir los scopes de la ruta
+		if services.IsPersonalToken(token) {
+			h.authenticatePersonalToken(c, token, scopes)
+			return
+		}
+
+		// 5) Validar JWT
 		claims, err := VerifyToken(token)
 		if err != nil {
 			c.JSON(http.StatusUnauthorized, ResponseData{
@@ -493,43 +685,38 @@ func AuthMiddleware() gin.HandlerFunc {
 			return
 		}
 
-		// 5) Dejar datos en contexto
-		c.Set("id_persona", claims.IDPersona)
-		c.Set("email", claims.Email)
-
-		c.Next()
-	}
-}
-
-// AdminMiddleware - Middleware para proteger rutas de administrador
-func (h *Handler) AdminMiddleware() gin.HandlerFunc {
-	return func(c *gin.Context) {
-		idPersonaInterface, exists := c.Get("id_persona")
-		if !exists {
-			c.JSON(http.StatusUnauthorized, ResponseData{Success: false, Message: "No autenticado"})
-			c.Abort()
+		// 6) Token de suplantación: se valida contra tb_suplantacion en lugar de la sesión
+		if claims.IDSuplantacion != 0 {
+			h.authenticateImpersonation(c, claims)
 			return
 		}
-		idPersona := idPersonaInterface.(int)
 
-		// Obtener el perfil del usuario para verificar su rol
-		profile, err := h.userService.GetUserProfile(context.Background(), idPersona)
-		if err != nil {
-			c.JSON(http.StatusForbidden, ResponseData{Success: false, Message: "Usuario no válido"})
+		// 7) La sesión del token no debe haber sido cerrada
+		rolActivo, err := h.sessionService.Validate(context.Background(), claims.IDSesion, claims.IDPersona)
+		if errors.Is(err, services.ErrAccountSuspended) {
+			c.JSON(http.StatusForbidden, ResponseData{
+				Success: false,
+				Message: "La cuenta está suspendida",
+			})
 			c.Abort()
 			return
 		}
-
-		// Permitir que los mentores sean administradores
-		if profile.Rol != "mentor" {
-			c.JSON(http.StatusForbidden, ResponseData{
+		if err != nil {
+			c.JSON(http.StatusUnauthorized, ResponseData{
 				Success: false,
-				Message: "Acceso denegado. Se requiere rol de mentor.",
+				Message: "La sesión fue cerrada. Iniciá sesión nuevamente",
 			})
 			c.Abort()
 			return
 		}
 
+		// 8) Dejar datos en contexto
+		c.Set("id_persona", claims.IDPersona)
+		c.Set("id_actor", claims.IDPersona)
+		c.Set("email", claims.Email)
+		c.Set("id_sesion", claims.IDSesion)
+		c.Set("rol_activo", rolActivo)
+
 		c.Next()
 	}
 }
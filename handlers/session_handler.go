@@ -0,0 +1,98 @@
+package handlers
+
+import (
+	"context"
+	"errors"
+	"mentorly-backend/services"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+)
+
+// GetSessionsHandler - Lista las sesiones activas del usuario
+func (h *Handler) GetSessionsHandler(c *gin.Context) {
+	idPersona, ok := getIDPersona(c)
+	if !ok {
+		return
+	}
+
+	sessions, err := h.sessionService.List(context.Background(), idPersona, getIDSesion(c))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, ResponseData{Success: false, Message: "Error al obtener las sesiones"})
+		return
+	}
+
+	c.JSON(http.StatusOK, ResponseData{
+		Success: true,
+		Message: "Sesiones obtenidas correctamente",
+		Data:    sessions,
+	})
+}
+
+// RevokeSessionHandler - Cierra una sesión del usuario
+func (h *Handler) RevokeSessionHandler(c *gin.Context) {
+	idPersona, ok := getIDPersona(c)
+	if !ok {
+		return
+	}
+
+	idSesion, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, ResponseData{Success: false, Message: "ID de sesión inválido"})
+		return
+	}
+
+	err = h.sessionService.Revoke(context.Background(), idPersona, idSesion)
+	if err != nil {
+		if errors.Is(err, services.ErrSessionNotFound) {
+			c.JSON(http.StatusNotFound, ResponseData{Success: false, Message: err.Error()})
+		} else {
+			c.JSON(http.StatusInternalServerError, ResponseData{Success: false, Message: "Error al cerrar la sesión"})
+		}
+		return
+	}
+
+	recordAudit(c, h.auditService, services.AuditEntry{
+		IDPersona:    &idPersona,
+		Accion:       services.AuditSessionRevoked,
+		TipoObjetivo: services.AuditTargetSession,
+		IDObjetivo:   strconv.Itoa(idSesion),
+	})
+
+	// Si cerró la sesión actual también se borran las cookies
+	if idSesion == getIDSesion(c) {
+		clearAuthCookies(c)
+	}
+
+	c.JSON(http.StatusOK, ResponseData{
+		Success: true,
+		Message: "Sesión cerrada correctamente",
+	})
+}
+
+// RevokeOtherSessionsHandler - Cierra todas las sesiones menos la actual
+func (h *Handler) RevokeOtherSessionsHandler(c *gin.Context) {
+	idPersona, ok := getIDPersona(c)
+	if !ok {
+		return
+	}
+
+	count, err := h.sessionService.RevokeOthers(context.Background(), idPersona, getIDSesion(c))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, ResponseData{Success: false, Message: "Error al cerrar las sesiones"})
+		return
+	}
+
+	recordAudit(c, h.auditService, services.AuditEntry{
+		IDPersona: &idPersona,
+		Accion:    services.AuditSessionsRevoked,
+		Detalle:   map[string]any{"sesiones_cerradas": count},
+	})
+
+	c.JSON(http.StatusOK, ResponseData{
+		Success: true,
+		Message: "Se cerraron las demás sesiones",
+		Data:    gin.H{"sesiones_cerradas": count},
+	})
+}
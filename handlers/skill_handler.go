@@ -0,0 +1,429 @@
+package handlers
+
+import (
+	"context"
+	"errors"
+	"mentorly-backend/services"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+)
+
+const (
+	defaultSkillsLimit = 10
+	maxSkillsLimit     = 25
+)
+
+type SkillCategoryRequest struct {
+	Nombre string `json:"nombre" binding:"required,min=2,max=80"`
+	// Slug vacío se genera a partir del nombre
+	Slug string `json:"slug" binding:"max=80"`
+	// IDPadre solo se usa al crear; las subcategorías no pueden tener hijas
+	IDPadre *int `json:"id_padre"`
+}
+
+type SkillRequest struct {
+	Nombre      string   `json:"nombre" binding:"required,min=1,max=80"`
+	Slug        string   `json:"slug" binding:"max=80"`
+	IDCategoria int      `json:"id_categoria" binding:"required"`
+	Alias       []string `json:"alias" binding:"max=20,dive,min=1,max=80"`
+}
+
+func (r SkillRequest) input() services.SkillInput {
+	return services.SkillInput{
+		Nombre:      r.Nombre,
+		Slug:        r.Slug,
+		IDCategoria: r.IDCategoria,
+		Alias:       r.Alias,
+	}
+}
+
+type MergeSkillRequest struct {
+	IDDestino int `json:"id_destino" binding:"required"`
+}
+
+type UserSkillsRequest struct {
+	Habilidades []UserSkillItem `json:"habilidades" binding:"max=60,dive"`
+}
+
+type UserSkillItem struct {
+	IDHabilidad int    `json:"id_habilidad" binding:"required"`
+	Tipo        string `json:"tipo" binding:"required,oneof=experiencia interes"`
+	Nivel       string `json:"nivel" binding:"required,oneof=basico intermedio avanzado experto"`
+}
+
+// GetSkillsHandler - Autocompletado del catálogo de habilidades. Busca por nombre, slug o alias (q).
+func (h *Handler) GetSkillsHandler(c *gin.Context) {
+	limit := defaultSkillsLimit
+	if v := c.Query("limit"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n <= 0 {
+			c.JSON(http.StatusBadRequest, ResponseData{Success: false, Message: "Límite inválido"})
+			return
+		}
+		limit = min(n, maxSkillsLimit)
+	}
+
+	q := strings.TrimSpace(c.Query("q"))
+	if len(q) > 80 {
+		c.JSON(http.StatusBadRequest, ResponseData{Success: false, Message: "La búsqueda es demasiado larga"})
+		return
+	}
+
+	skills, err := h.skillService.Search(context.Background(), q, limit)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, ResponseData{Success: false, Message: "Error al buscar habilidades"})
+		return
+	}
+
+	c.JSON(http.StatusOK, ResponseData{
+		Success: true,
+		Message: "Habilidades obtenidas correctamente",
+		Data:    skills,
+	})
+}
+
+// GetSkillCategoriesHandler - Árbol de categorías del catálogo
+func (h *Handler) GetSkillCategoriesHandler(c *gin.Context) {
+	categories, err := h.skillService.ListCategories(context.Background())
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, ResponseData{Success: false, Message: "Error al obtener las categorías"})
+		return
+	}
+
+	c.JSON(http.StatusOK, ResponseData{
+		Success: true,
+		Message: "Categorías obtenidas correctamente",
+		Data:    categories,
+	})
+}
+
+// CreateSkillCategoryHandler - Crea una categoría o, con id_padre, una subcategoría
+func (h *Handler) CreateSkillCategoryHandler(c *gin.Context) {
+	var req SkillCategoryRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, ResponseData{Success: false, Message: "Datos inválidos: " + err.Error()})
+		return
+	}
+
+	category, err := h.skillService.CreateCategory(context.Background(), req.Nombre, req.Slug, req.IDPadre)
+	if err != nil {
+		respondSkillError(c, err, "Error al crear la categoría")
+		return
+	}
+
+	recordAudit(c, h.auditService, services.AuditEntry{
+		Accion:       services.AuditSkillCategoryCreated,
+		TipoObjetivo: services.AuditTargetSkillCategory,
+		IDObjetivo:   strconv.Itoa(category.IDCategoria),
+		Despues:      category,
+	})
+
+	c.JSON(http.StatusCreated, ResponseData{
+		Success: true,
+		Message: "Categoría creada correctamente",
+		Data:    category,
+	})
+}
+
+// UpdateSkillCategoryHandler - Cambia el nombre y el slug de una categoría
+func (h *Handler) UpdateSkillCategoryHandler(c *gin.Context) {
+	idCategoria, ok := getSkillIDParam(c)
+	if !ok {
+		return
+	}
+
+	var req SkillCategoryRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, ResponseData{Success: false, Message: "Datos inválidos: " + err.Error()})
+		return
+	}
+
+	// Estado anterior para la auditoría
+	before, err := h.skillService.GetCategory(context.Background(), idCategoria)
+	if err != nil {
+		respondSkillError(c, err, "Error al actualizar la categoría")
+		return
+	}
+
+	category, err := h.skillService.UpdateCategory(context.Background(), idCategoria, req.Nombre, req.Slug)
+	if err != nil {
+		respondSkillError(c, err, "Error al actualizar la categoría")
+		return
+	}
+
+	recordAudit(c, h.auditService, services.AuditEntry{
+		Accion:       services.AuditSkillCategoryUpdated,
+		TipoObjetivo: services.AuditTargetSkillCategory,
+		IDObjetivo:   strconv.Itoa(idCategoria),
+		Antes:        before,
+		Despues:      category,
+	})
+
+	c.JSON(http.StatusOK, ResponseData{
+		Success: true,
+		Message: "Categoría actualizada correctamente",
+		Data:    category,
+	})
+}
+
+// DeleteSkillCategoryHandler - Elimina una categoría sin subcategorías ni habilidades
+func (h *Handler) DeleteSkillCategoryHandler(c *gin.Context) {
+	idCategoria, ok := getSkillIDParam(c)
+	if !ok {
+		return
+	}
+
+	// Estado anterior para la auditoría
+	before, err := h.skillService.GetCategory(context.Background(), idCategoria)
+	if err == nil {
+		err = h.skillService.DeleteCategory(context.Background(), idCategoria)
+	}
+	if err != nil {
+		respondSkillError(c, err, "Error al eliminar la categoría")
+		return
+	}
+
+	recordAudit(c, h.auditService, services.AuditEntry{
+		Accion:       services.AuditSkillCategoryDeleted,
+		TipoObjetivo: services.AuditTargetSkillCategory,
+		IDObjetivo:   strconv.Itoa(idCategoria),
+		Antes:        before,
+	})
+
+	c.JSON(http.StatusOK, ResponseData{
+		Success: true,
+		Message: "Categoría eliminada correctamente",
+	})
+}
+
+// CreateSkillHandler - Agrega una habilidad al catálogo
+func (h *Handler) CreateSkillHandler(c *gin.Context) {
+	var req SkillRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, ResponseData{Success: false, Message: "Datos inválidos: " + err.Error()})
+		return
+	}
+
+	skill, err := h.skillService.CreateSkill(context.Background(), req.input())
+	if err != nil {
+		respondSkillError(c, err, "Error al crear la habilidad")
+		return
+	}
+
+	recordAudit(c, h.auditService, services.AuditEntry{
+		Accion:       services.AuditSkillCreated,
+		TipoObjetivo: services.AuditTargetSkill,
+		IDObjetivo:   strconv.Itoa(skill.IDHabilidad),
+		Despues:      skill,
+	})
+
+	c.JSON(http.StatusCreated, ResponseData{
+		Success: true,
+		Message: "Habilidad creada correctamente",
+		Data:    skill,
+	})
+}
+
+// UpdateSkillHandler - Cambia una habilidad del catálogo; los alias enviados reemplazan a los actuales
+func (h *Handler) UpdateSkillHandler(c *gin.Context) {
+	idHabilidad, ok := getSkillIDParam(c)
+	if !ok {
+		return
+	}
+
+	var req SkillRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, ResponseData{Success: false, Message: "Datos inválidos: " + err.Error()})
+		return
+	}
+
+	// Estado anterior para la auditoría
+	before, err := h.skillService.GetSkill(context.Background(), idHabilidad)
+	if err != nil {
+		respondSkillError(c, err, "Error al actualizar la habilidad")
+		return
+	}
+
+	skill, err := h.skillService.UpdateSkill(context.Background(), idHabilidad, req.input())
+	if err != nil {
+		respondSkillError(c, err, "Error al actualizar la habilidad")
+		return
+	}
+
+	recordAudit(c, h.auditService, services.AuditEntry{
+		Accion:       services.AuditSkillUpdated,
+		TipoObjetivo: services.AuditTargetSkill,
+		IDObjetivo:   strconv.Itoa(idHabilidad),
+		Antes:        before,
+		Despues:      skill,
+	})
+
+	c.JSON(http.StatusOK, ResponseData{
+		Success: true,
+		Message: "Habilidad actualizada correctamente",
+		Data:    skill,
+	})
+}
+
+// DeleteSkillHandler - Elimina una habilidad que ningún usuario tiene. Si está en uso hay que fusionarla.
+func (h *Handler) DeleteSkillHandler(c *gin.Context) {
+	idHabilidad, ok := getSkillIDParam(c)
+	if !ok {
+		return
+	}
+
+	// Estado anterior para la auditoría
+	before, err := h.skillService.GetSkill(context.Background(), idHabilidad)
+	if err == nil {
+		err = h.skillService.DeleteSkill(context.Background(), idHabilidad)
+	}
+	if err != nil {
+		respondSkillError(c, err, "Error al eliminar la habilidad")
+		return
+	}
+
+	recordAudit(c, h.auditService, services.AuditEntry{
+		Accion:       services.AuditSkillDeleted,
+		TipoObjetivo: services.AuditTargetSkill,
+		IDObjetivo:   strconv.Itoa(idHabilidad),
+		Antes:        before,
+	})
+
+	c.JSON(http.StatusOK, ResponseData{
+		Success: true,
+		Message: "Habilidad eliminada correctamente",
+	})
+}
+
+// MergeSkillHandler - Fusiona la habilidad en id_destino: los usuarios pasan a tener la de destino
+// y el nombre y slug de la eliminada quedan como alias
+func (h *Handler) MergeSkillHandler(c *gin.Context) {
+	idHabilidad, ok := getSkillIDParam(c)
+	if !ok {
+		return
+	}
+
+	var req MergeSkillRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, ResponseData{Success: false, Message: "Datos inválidos: " + err.Error()})
+		return
+	}
+
+	// Estado anterior para la auditoría
+	before, err := h.skillService.GetSkill(context.Background(), idHabilidad)
+	if err != nil {
+		respondSkillError(c, err, "Error al fusionar las habilidades")
+		return
+	}
+
+	skill, referencias, err := h.skillService.Merge(context.Background(), idHabilidad, req.IDDestino)
+	if err != nil {
+		respondSkillError(c, err, "Error al fusionar las habilidades")
+		return
+	}
+
+	recordAudit(c, h.auditService, services.AuditEntry{
+		Accion:       services.AuditSkillMerged,
+		TipoObjetivo: services.AuditTargetSkill,
+		IDObjetivo:   strconv.Itoa(idHabilidad),
+		Antes:        before,
+		Detalle: map[string]any{
+			"id_destino":  req.IDDestino,
+			"referencias": referencias,
+		},
+	})
+
+	c.JSON(http.StatusOK, ResponseData{
+		Success: true,
+		Message: "Habilidades fusionadas correctamente",
+		Data: gin.H{
+			"habilidad":   skill,
+			"referencias": referencias,
+		},
+	})
+}
+
+// GetUserSkillsHandler - Habilidades del usuario autenticado (experiencia e intereses)
+func (h *Handler) GetUserSkillsHandler(c *gin.Context) {
+	idPersona, ok := getIDPersona(c)
+	if !ok {
+		return
+	}
+
+	skills, err := h.skillService.GetUserSkills(context.Background(), idPersona)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, ResponseData{Success: false, Message: "Error al obtener las habilidades"})
+		return
+	}
+
+	c.JSON(http.StatusOK, ResponseData{
+		Success: true,
+		Message: "Habilidades obtenidas correctamente",
+		Data:    skills,
+	})
+}
+
+// SetUserSkillsHandler - Reemplaza las habilidades del usuario autenticado
+func (h *Handler) SetUserSkillsHandler(c *gin.Context) {
+	idPersona, ok := getIDPersona(c)
+	if !ok {
+		return
+	}
+
+	var req UserSkillsRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, ResponseData{Success: false, Message: "Datos inválidos: " + err.Error()})
+		return
+	}
+
+	items := make([]services.UserSkillInput, len(req.Habilidades))
+	for i, item := range req.Habilidades {
+		items[i] = services.UserSkillInput{IDHabilidad: item.IDHabilidad, Tipo: item.Tipo, Nivel: item.Nivel}
+	}
+
+	skills, err := h.skillService.SetUserSkills(context.Background(), idPersona, items)
+	if err != nil {
+		respondSkillError(c, err, "Error al guardar las habilidades")
+		return
+	}
+
+	c.JSON(http.StatusOK, ResponseData{
+		Success: true,
+		Message: "Habilidades actualizadas correctamente",
+		Data:    skills,
+	})
+}
+
+func getSkillIDParam(c *gin.Context) (int, bool) {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, ResponseData{Success: false, Message: "ID inválido"})
+		return 0, false
+	}
+	return id, true
+}
+
+// respondSkillError traduce los errores del catálogo de habilidades a respuestas HTTP
+func respondSkillError(c *gin.Context, err error, message string) {
+	switch {
+	case errors.Is(err, services.ErrSkillNotFound), errors.Is(err, services.ErrSkillCategoryNotFound):
+		c.JSON(http.StatusNotFound, ResponseData{Success: false, Message: err.Error()})
+	case errors.Is(err, services.ErrSlugTaken),
+		errors.Is(err, services.ErrSkillCategoryInUse),
+		errors.Is(err, services.ErrSkillInUse):
+		c.JSON(http.StatusConflict, ResponseData{Success: false, Message: err.Error()})
+	case errors.Is(err, services.ErrInvalidSlug),
+		errors.Is(err, services.ErrSkillCategoryTooDeep),
+		errors.Is(err, services.ErrCannotMergeSameSkill),
+		errors.Is(err, services.ErrInvalidSkillType),
+		errors.Is(err, services.ErrInvalidSkillLevel),
+		errors.Is(err, services.ErrDuplicateUserSkill),
+		errors.Is(err, services.ErrTooManyUserSkills):
+		c.JSON(http.StatusBadRequest, ResponseData{Success: false, Message: err.Error()})
+	default:
+		c.JSON(http.StatusInternalServerError, ResponseData{Success: false, Message: message})
+	}
+}
@@ -0,0 +1,201 @@
+package handlers
+
+import (
+	"context"
+	"errors"
+	"log"
+	"mentorly-backend/services"
+	"net/http"
+	"os"
+
+	"github.com/gin-gonic/gin"
+)
+
+const (
+	accessTokenCookie  = "auth_token"
+	refreshTokenCookie = "refresh_token"
+	// El refresh token solo viaja a las rutas de /auth
+	refreshTokenCookiePath = "/auth"
+)
+
+type RefreshRequest struct {
+	RefreshToken string `json:"refresh_token"`
+}
+
+// issueTokens inicia una sesión y genera el par access/refresh token para un usuario autenticado
+func issueTokens(ctx context.Context, tokenService *services.TokenService, client services.SessionClient, idPersona int, email string, nombre string) (*TokenResponse, error) {
+	idSesion, refreshToken, err := tokenService.StartSession(ctx, idPersona, client)
+	if err != nil {
+		return nil, err
+	}
+
+	token, err := GenerateToken(idPersona, email, idSesion)
+	if err != nil {
+		return nil, err
+	}
+
+	return &TokenResponse{
+		Token:        token,
+		RefreshToken: refreshToken,
+		ExpiresIn:    int(AccessTokenDuration.Seconds()),
+		IDPersona:    idPersona,
+		Email:        email,
+		Nombre:       nombre,
+	}, nil
+}
+
+// sessionClient obtiene el dispositivo que inició el request, para registrar la sesión
+func sessionClient(c *gin.Context) services.SessionClient {
+	return services.SessionClient{
+		UserAgent: c.Request.UserAgent(),
+		IP:        c.ClientIP(),
+	}
+}
+
+// setAuthCookies guarda ambos tokens en cookies HttpOnly
+func setAuthCookies(c *gin.Context, tokens *TokenResponse) {
+	setSessionCookie(c, accessTokenCookie, tokens.Token, int(AccessTokenDuration.Seconds()), "/")
+	setSessionCookie(c, refreshTokenCookie, tokens.RefreshToken, int(services.RefreshTokenDuration.Seconds()), refreshTokenCookiePath)
+}
+
+// clearAuthCookies elimina las cookies de sesión
+func clearAuthCookies(c *gin.Context) {
+	setSessionCookie(c, accessTokenCookie, "", -1, "/")
+	setSessionCookie(c, refreshTokenCookie, "", -1, refreshTokenCookiePath)
+}
+
+// setSessionCookie escribe una cookie de sesión HttpOnly con Secure y SameSite según la configuración
+func setSessionCookie(c *gin.Context, name string, value string, maxAge int, path string) {
+	// SameSite=None solo se acepta con Secure
+	sameSite := cookieSameSite()
+	http.SetCookie(c.Writer, &http.Cookie{
+		Name:     name,
+		Value:    value,
+		MaxAge:   maxAge,
+		Path:     path,
+		Secure:   cookieSecure() || sameSite == http.SameSiteNoneMode,
+		HttpOnly: true,
+		SameSite: sameSite,
+	})
+}
+
+// cookieSecure indica si las cookies de sesión llevan Secure. Siempre, salvo COOKIE_SECURE=false
+// para desarrollo local sin https.
+func cookieSecure() bool {
+	return os.Getenv("COOKIE_SECURE") != "false"
+}
+
+// cookieSameSite lee COOKIE_SAMESITE: "lax" (por defecto), "strict" o "none". "none" hace falta
+// si el front está en otro sitio y usa las cookies en fetch con credentials.
+func cookieSameSite() http.SameSite {
+	switch os.Getenv("COOKIE_SAMESITE") {
+	case "strict":
+		return http.SameSiteStrictMode
+	case "none":
+		return http.SameSiteNoneMode
+	default:
+		return http.SameSiteLaxMode
+	}
+}
+
+// readRefreshToken obtiene el refresh token del body o, si no viene, de la cookie.
+// El segundo valor indica si se leyó de la cookie.
+func readRefreshToken(c *gin.Context) (string, bool) {
+	var req RefreshRequest
+	if err := c.ShouldBindJSON(&req); err == nil && req.RefreshToken != "" {
+		return req.RefreshToken, false
+	}
+
+	if cookie, err := c.Cookie(refreshTokenCookie); err == nil && cookie != "" {
+		return cookie, true
+	}
+
+	return "", false
+}
+
+// RefreshHandler - Rota el refresh token y emite un nuevo access token
+func (h *Handler) RefreshHandler(c *gin.Context) {
+	rawToken, fromCookie := readRefreshToken(c)
+	if rawToken == "" {
+		c.JSON(http.StatusBadRequest, ResponseData{
+			Success: false,
+			Message: "Refresh token requerido",
+		})
+		return
+	}
+
+	session, err := h.tokenService.RotateRefreshToken(context.Background(), rawToken, sessionClient(c))
+	if err != nil {
+		if errors.Is(err, services.ErrRefreshTokenReused) {
+			log.Printf("Reutilización de refresh token detectada, familia revocada")
+		}
+		if errors.Is(err, services.ErrInvalidRefreshToken) || errors.Is(err, services.ErrRefreshTokenReused) {
+			clearAuthCookies(c)
+			c.JSON(http.StatusUnauthorized, ResponseData{
+				Success: false,
+				Message: "Refresh token inválido o expirado",
+			})
+		} else if errors.Is(err, services.ErrAccountSuspended) {
+			clearAuthCookies(c)
+			c.JSON(http.StatusForbidden, ResponseData{
+				Success: false,
+				Message: "La cuenta está suspendida",
+			})
+		} else {
+			c.JSON(http.StatusInternalServerError, ResponseData{
+				Success: false,
+				Message: "Error al renovar la sesión",
+			})
+		}
+		return
+	}
+
+	token, err := GenerateToken(session.IDPersona, session.Email, session.IDSesion)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, ResponseData{
+			Success: false,
+			Message: "Error al generar token",
+		})
+		return
+	}
+
+	tokens := &TokenResponse{
+		Token:        token,
+		RefreshToken: session.RefreshToken,
+		ExpiresIn:    int(AccessTokenDuration.Seconds()),
+		IDPersona:    session.IDPersona,
+		Email:        session.Email,
+	}
+
+	if fromCookie {
+		setAuthCookies(c, tokens)
+	}
+
+	c.JSON(http.StatusOK, ResponseData{
+		Success: true,
+		Message: "Sesión renovada",
+		Data:    tokens,
+	})
+}
+
+// LogoutHandler - Revoca la familia del refresh token y limpia las cookies
+func (h *Handler) LogoutHandler(c *gin.Context) {
+	rawToken, _ := readRefreshToken(c)
+	clearAuthCookies(c)
+
+	if rawToken != "" {
+		err := h.tokenService.RevokeFamily(context.Background(), rawToken)
+		if err != nil && !errors.Is(err, services.ErrInvalidRefreshToken) {
+			c.JSON(http.StatusInternalServerError, ResponseData{
+				Success: false,
+				Message: "Error al cerrar sesión",
+			})
+			return
+		}
+	}
+
+	c.JSON(http.StatusOK, ResponseData{
+		Success: true,
+		Message: "Sesión cerrada correctamente",
+	})
+}
@@ -0,0 +1,258 @@
+package handlers
+
+import (
+	"context"
+	"errors"
+	"mentorly-backend/services"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+const (
+	defaultUsersLimit = 50
+	maxUsersLimit     = 200
+
+	// adminDateLayout es el formato de las fechas de los filtros (desde/hasta)
+	adminDateLayout = "2006-01-02"
+)
+
+type SuspendUserRequest struct {
+	Motivo string `json:"motivo" binding:"max=1000"`
+}
+
+type EmailVerificationOverrideRequest struct {
+	Verificado *bool `json:"verificado" binding:"required"`
+}
+
+// GetUsersHandler - Lista y busca usuarios. Filtros opcionales: q (nombre o email), rol,
+// desde y hasta (fecha de registro, AAAA-MM-DD, hasta inclusive) y estado (activo o suspendido).
+func (h *Handler) GetUsersHandler(c *gin.Context) {
+	limit, offset, ok := getPagination(c, defaultUsersLimit, maxUsersLimit)
+	if !ok {
+		return
+	}
+
+	filter := services.UserFilter{
+		Query:  c.Query("q"),
+		Rol:    c.Query("rol"),
+		Limit:  limit,
+		Offset: offset,
+	}
+
+	if v := c.Query("desde"); v != "" {
+		desde, err := time.Parse(adminDateLayout, v)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, ResponseData{Success: false, Message: "Fecha desde inválida (AAAA-MM-DD)"})
+			return
+		}
+		filter.RegistradoDesde = &desde
+	}
+	if v := c.Query("hasta"); v != "" {
+		hasta, err := time.Parse(adminDateLayout, v)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, ResponseData{Success: false, Message: "Fecha hasta inválida (AAAA-MM-DD)"})
+			return
+		}
+		// Se incluye el día completo
+		hasta = hasta.AddDate(0, 0, 1)
+		filter.RegistradoHasta = &hasta
+	}
+
+	switch c.Query("estado") {
+	case "":
+	case "activo":
+		suspendido := false
+		filter.Suspendido = &suspendido
+	case "suspendido":
+		suspendido := true
+		filter.Suspendido = &suspendido
+	default:
+		c.JSON(http.StatusBadRequest, ResponseData{Success: false, Message: "Estado inválido"})
+		return
+	}
+
+	users, err := h.userAdminService.Search(context.Background(), filter)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, ResponseData{Success: false, Message: "Error al obtener los usuarios"})
+		return
+	}
+
+	c.JSON(http.StatusOK, ResponseData{
+		Success: true,
+		Message: "Usuarios obtenidos correctamente",
+		Data:    users,
+	})
+}
+
+// GetUserDetailHandler - Obtiene el perfil, los roles y las suscripciones de un usuario
+func (h *Handler) GetUserDetailHandler(c *gin.Context) {
+	idPersona, ok := getUserIDParam(c)
+	if !ok {
+		return
+	}
+
+	user, err := h.userAdminService.GetDetail(context.Background(), idPersona)
+	if err != nil {
+		respondUserAdminError(c, err, "Error al obtener el usuario")
+		return
+	}
+
+	c.JSON(http.StatusOK, ResponseData{
+		Success: true,
+		Message: "Usuario obtenido correctamente",
+		Data:    user,
+	})
+}
+
+// SuspendUserHandler - Suspende la cuenta y cierra todas sus sesiones
+func (h *Handler) SuspendUserHandler(c *gin.Context) {
+	idAdmin, ok := getIDPersona(c)
+	if !ok {
+		return
+	}
+
+	idPersona, ok := getUserIDParam(c)
+	if !ok {
+		return
+	}
+
+	var req SuspendUserRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, ResponseData{Success: false, Message: "Datos inválidos: " + err.Error()})
+		return
+	}
+
+	if idPersona == idAdmin {
+		c.JSON(http.StatusConflict, ResponseData{Success: false, Message: services.ErrCannotSuspendSelf.Error()})
+		return
+	}
+
+	if err := h.userAdminService.Suspend(context.Background(), idPersona, idAdmin, req.Motivo); err != nil {
+		respondUserAdminError(c, err, "Error al suspender la cuenta")
+		return
+	}
+
+	recordAudit(c, h.auditService, services.AuditEntry{
+		IDPersona:    &idPersona,
+		Accion:       services.AuditUserSuspended,
+		TipoObjetivo: services.AuditTargetUser,
+		IDObjetivo:   strconv.Itoa(idPersona),
+		Detalle:      map[string]any{"motivo": req.Motivo},
+	})
+
+	c.JSON(http.StatusOK, ResponseData{
+		Success: true,
+		Message: "Cuenta suspendida",
+	})
+}
+
+// ReactivateUserHandler - Levanta la suspensión de una cuenta
+func (h *Handler) ReactivateUserHandler(c *gin.Context) {
+	idPersona, ok := getUserIDParam(c)
+	if !ok {
+		return
+	}
+
+	if err := h.userAdminService.Reactivate(context.Background(), idPersona); err != nil {
+		respondUserAdminError(c, err, "Error al reactivar la cuenta")
+		return
+	}
+
+	recordAudit(c, h.auditService, services.AuditEntry{
+		IDPersona:    &idPersona,
+		Accion:       services.AuditUserReactivated,
+		TipoObjetivo: services.AuditTargetUser,
+		IDObjetivo:   strconv.Itoa(idPersona),
+	})
+
+	c.JSON(http.StatusOK, ResponseData{
+		Success: true,
+		Message: "Cuenta reactivada",
+	})
+}
+
+// ForceLogoutUserHandler - Cierra todas las sesiones de un usuario
+func (h *Handler) ForceLogoutUserHandler(c *gin.Context) {
+	idPersona, ok := getUserIDParam(c)
+	if !ok {
+		return
+	}
+
+	count, err := h.userAdminService.ForceLogout(context.Background(), idPersona)
+	if err != nil {
+		respondUserAdminError(c, err, "Error al cerrar las sesiones")
+		return
+	}
+
+	recordAudit(c, h.auditService, services.AuditEntry{
+		IDPersona:    &idPersona,
+		Accion:       services.AuditUserLoggedOut,
+		TipoObjetivo: services.AuditTargetUser,
+		IDObjetivo:   strconv.Itoa(idPersona),
+		Detalle:      map[string]any{"sesiones_cerradas": count},
+	})
+
+	c.JSON(http.StatusOK, ResponseData{
+		Success: true,
+		Message: "Sesiones cerradas",
+		Data:    gin.H{"sesiones_cerradas": count},
+	})
+}
+
+// SetEmailVerifiedHandler - Marca manualmente el email de un usuario como verificado o no
+func (h *Handler) SetEmailVerifiedHandler(c *gin.Context) {
+	idPersona, ok := getUserIDParam(c)
+	if !ok {
+		return
+	}
+
+	var req EmailVerificationOverrideRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, ResponseData{Success: false, Message: "Datos inválidos: " + err.Error()})
+		return
+	}
+
+	if err := h.userAdminService.SetEmailVerified(context.Background(), idPersona, *req.Verificado); err != nil {
+		respondUserAdminError(c, err, "Error al actualizar la verificación del email")
+		return
+	}
+
+	recordAudit(c, h.auditService, services.AuditEntry{
+		IDPersona:    &idPersona,
+		Accion:       services.AuditUserEmailVerified,
+		TipoObjetivo: services.AuditTargetUser,
+		IDObjetivo:   strconv.Itoa(idPersona),
+		Despues:      map[string]any{"email_verificado": *req.Verificado},
+	})
+
+	c.JSON(http.StatusOK, ResponseData{
+		Success: true,
+		Message: "Verificación del email actualizada",
+		Data:    gin.H{"email_verificado": *req.Verificado},
+	})
+}
+
+// getUserIDParam lee el ID de usuario de la ruta. Si es inválido responde 400 y devuelve false.
+func getUserIDParam(c *gin.Context) (int, bool) {
+	idPersona, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, ResponseData{Success: false, Message: "ID de usuario inválido"})
+		return 0, false
+	}
+	return idPersona, true
+}
+
+// respondUserAdminError traduce los errores de administración de usuarios a respuestas HTTP
+func respondUserAdminError(c *gin.Context, err error, message string) {
+	switch {
+	case errors.Is(err, services.ErrUserNotFound):
+		c.JSON(http.StatusNotFound, ResponseData{Success: false, Message: "Usuario no encontrado"})
+	case errors.Is(err, services.ErrUserAlreadySuspended), errors.Is(err, services.ErrUserNotSuspended):
+		c.JSON(http.StatusConflict, ResponseData{Success: false, Message: err.Error()})
+	default:
+		c.JSON(http.StatusInternalServerError, ResponseData{Success: false, Message: message})
+	}
+}
@@ -0,0 +1,87 @@
+package handlers
+
+import (
+	"context"
+	"errors"
+	"mentorly-backend/services"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+)
+
+// VerifyEmailHandler - Confirma el email con el token recibido por correo
+func (h *Handler) VerifyEmailHandler(c *gin.Context) {
+	err := h.verificationService.VerifyEmail(context.Background(), c.Query("token"))
+	if err != nil {
+		if errors.Is(err, services.ErrInvalidUserToken) {
+			c.JSON(http.StatusBadRequest, ResponseData{Success: false, Message: "El enlace de verificación es inválido o expiró"})
+		} else {
+			c.JSON(http.StatusInternalServerError, ResponseData{Success: false, Message: "Error al verificar el email"})
+		}
+		return
+	}
+
+	c.JSON(http.StatusOK, ResponseData{
+		Success: true,
+		Message: "Email verificado correctamente",
+	})
+}
+
+// ResendVerificationHandler - Reenvía el email de verificación al usuario autenticado
+func (h *Handler) ResendVerificationHandler(c *gin.Context) {
+	idPersona, ok := getIDPersona(c)
+	if !ok {
+		return
+	}
+
+	err := h.verificationService.ResendVerification(context.Background(), idPersona)
+	if err != nil {
+		var retryErr *services.RetryAfterError
+		switch {
+		case errors.As(err, &retryErr):
+			c.Header("Retry-After", strconv.Itoa(int(retryErr.RetryAfter.Seconds())+1))
+			c.JSON(http.StatusTooManyRequests, ResponseData{Success: false, Message: err.Error()})
+		case errors.Is(err, services.ErrEmailAlreadyVerified):
+			c.JSON(http.StatusConflict, ResponseData{Success: false, Message: err.Error()})
+		default:
+			c.JSON(http.StatusInternalServerError, ResponseData{Success: false, Message: "Error al enviar el email de verificación"})
+		}
+		return
+	}
+
+	c.JSON(http.StatusOK, ResponseData{
+		Success: true,
+		Message: "Email de verificación enviado",
+	})
+}
+
+// RequireVerifiedEmail - Middleware para rutas que exigen email verificado.
+// Debe usarse después de AuthMiddleware.
+func (h *Handler) RequireVerifiedEmail() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		idPersona, ok := getIDPersona(c)
+		if !ok {
+			c.Abort()
+			return
+		}
+
+		verificado, err := h.verificationService.IsVerified(context.Background(), idPersona)
+		if err != nil {
+			c.JSON(http.StatusForbidden, ResponseData{Success: false, Message: "Usuario no válido"})
+			c.Abort()
+			return
+		}
+
+		if !verificado {
+			c.JSON(http.StatusForbidden, ResponseData{
+				Success: false,
+				Message: "Tenés que verificar tu email para continuar",
+			})
+			c.Abort()
+			return
+		}
+
+		c.Next()
+	}
+}
@@ -5,7 +5,9 @@ import (
 	"fmt"
 	"log"
 	"mentorly-backend/handlers"
+	"mentorly-backend/services"
 	"os"
+	"strings"
 	"time"
 
 	"github.com/gin-contrib/cors"
@@ -27,9 +29,10 @@ func main() {
 		log.Fatal("Error: DATABASE_URL no está configurada")
 	}
 
-	// Verificar que el secreto del JWT esté configurado
-	if os.Getenv("JWT_SECRET") == "" {
-		log.Fatal("Error: JWT_SECRET no está configurada")
+	// Clave para cifrar datos sensibles en la base (secretos TOTP y claves de firma)
+	encryptor, err := services.NewEncryptorFromEnv()
+	if err != nil {
+		log.Fatalf("Error: %v", err)
 	}
 
 	pool, err := pgxpool.New(context.Background(), databaseURL)
@@ -46,6 +49,14 @@ func main() {
 
 	fmt.Println("✓ Conexión a base de datos exitosa")
 
+	// Claves de firma de los JWT: se crean si no existen y se rotan periódicamente
+	signingKeys := services.NewSigningKeyService(pool, encryptor, services.SigningKeyConfigFromEnv())
+	if err := signingKeys.Init(context.Background()); err != nil {
+		log.Fatalf("Error al inicializar las claves de firma: %v", err)
+	}
+	signingKeys.StartRotation(context.Background())
+	handlers.SetSigningKeys(signingKeys)
+
 	// --- BLOQUE TEMPORAL PARA GENERAR HASH ---
 	// Descomenta las siguientes líneas para generar un nuevo hash de contraseña
 	//tempAuthService := services.NewAuthService(pool)
@@ -57,60 +68,224 @@ func main() {
 	// --- FIN DEL BLOQUE TEMPORAL ---
 
 	// Inicializar Handlers
-	authHandler := handlers.NewHandler(pool)
-	oauthHandler := handlers.NewOAuthHandler(pool)
+	mailer, err := services.NewMailerFromEnv()
+	if err != nil {
+		log.Fatalf("Error: %v", err)
+	}
+	authHandler := handlers.NewHandler(pool, mailer, encryptor)
+
+	// Eliminación definitiva de las cuentas cuyo período de gracia terminó
+	services.NewAccountService(pool, mailer, services.AccountDeletionGraceFromEnv()).StartPurge(context.Background())
+	// Vencimiento de las solicitudes de mentoría sin respuesta
+	authHandler.StartMentorshipRequestExpiry(context.Background())
+	oauthHandler := handlers.NewOAuthHandler(pool, services.NewOAuthRegistryFromEnv(), encryptor)
 
 	// Inicializar Gin
 	router := gin.Default()
 
+	// Header con la IP real del cliente cuando hay un proxy delante (en Fly: Fly-Client-IP).
+	// Sin esto la IP puede falsificarse con X-Forwarded-For y el rate limiting por IP no sirve.
+	if header := os.Getenv("CLIENT_IP_HEADER"); header != "" {
+		router.TrustedPlatform = header
+	}
+	// X-Forwarded-For solo se lee si viene de uno de los proxies de TRUSTED_PROXIES (IPs o CIDRs
+	// separados por comas). Sin la lista se usa la IP de la conexión.
+	var trustedProxies []string
+	if v := os.Getenv("TRUSTED_PROXIES"); v != "" {
+		for _, proxy := range strings.Split(v, ",") {
+			trustedProxies = append(trustedProxies, strings.TrimSpace(proxy))
+		}
+	}
+	if err := router.SetTrustedProxies(trustedProxies); err != nil {
+		log.Fatalf("Error: TRUSTED_PROXIES inválido: %v", err)
+	}
+
+	// ID de cada solicitud (X-Request-ID) para relacionar la auditoría con los logs
+	router.Use(handlers.RequestID())
+
 	// Configurar CORS
 	router.Use(cors.New(cors.Config{
 		AllowOrigins:     []string{"http://localhost:5173", "http://localhost:3000", "https://mentorly-web.vercel.app/", "https://mentorly-web.vercel.app", "http://localhost:5174"},
 		AllowMethods:     []string{"GET", "POST", "PUT", "DELETE", "OPTIONS"},
-		AllowHeaders:     []string{"Content-Type", "Authorization"},
-		ExposeHeaders:    []string{"Content-Length"},
+		AllowHeaders:     []string{"Content-Type", "Authorization", handlers.RequestIDHeader},
+		ExposeHeaders:    []string{"Content-Length", handlers.RequestIDHeader, "Retry-After", "RateLimit-Policy", "RateLimit-Limit", "RateLimit-Remaining", "RateLimit-Reset"},
 		AllowCredentials: true,
 		MaxAge:           12 * time.Hour,
 	}))
 
+	// Rate limiting: políticas configurables con RATE_LIMIT_<NOMBRE>="cantidad/ventana"
+	rateLimitStore := services.NewRateLimitStoreFromEnv(pool)
+	authLimit := handlers.RateLimit(rateLimitStore, services.RateLimitPolicyFromEnv("auth", 10, time.Minute), handlers.RateLimitByIP)
+	sessionLimit := handlers.RateLimit(rateLimitStore, services.RateLimitPolicyFromEnv("session", 60, time.Minute), handlers.RateLimitByIP)
+	oauthLimit := handlers.RateLimit(rateLimitStore, services.RateLimitPolicyFromEnv("oauth", 30, time.Minute), handlers.RateLimitByIP)
+	publicLimit := handlers.RateLimit(rateLimitStore, services.RateLimitPolicyFromEnv("public", 120, time.Minute), handlers.RateLimitByIP)
+	apiLimit := handlers.RateLimit(rateLimitStore, services.RateLimitPolicyFromEnv("api", 300, time.Minute), handlers.RateLimitByClient)
+
 	// Health check
 	router.GET("/health", func(c *gin.Context) {
 		c.JSON(200, gin.H{"status": "ok"})
 	})
 
+	// Claves públicas para que otros servicios validen nuestros tokens
+	router.GET("/.well-known/jwks.json", handlers.JWKSHandler)
+
 	// Rutas públicas - Autenticación tradicional
-	router.POST("/auth/register", authHandler.RegisterHandler)
-	router.POST("/auth/login", authHandler.LoginHandler)
+	authRoutes := router.Group("/auth")
+	authRoutes.Use(authLimit)
+	{
+		authRoutes.POST("/register", authHandler.RegisterHandler)
+		authRoutes.POST("/login", authHandler.LoginHandler)
+		authRoutes.GET("/verify-email", authHandler.VerifyEmailHandler)
+		authRoutes.POST("/forgot-password", authHandler.ForgotPasswordHandler)
+		authRoutes.POST("/reset-password", authHandler.ResetPasswordHandler)
+		authRoutes.POST("/unlock-account", authHandler.UnlockAccountHandler)
+		authRoutes.POST("/unlock-account/request", authHandler.RequestUnlockHandler)
+		authRoutes.POST("/mfa/verify", authHandler.VerifyMFAHandler)
+	}
+
+	// Renovación y cierre de sesión (límite más alto: varias pestañas renuevan a la vez)
+	router.POST("/auth/refresh", sessionLimit, authHandler.RefreshHandler)
+	router.POST("/auth/logout", sessionLimit, authHandler.LogoutHandler)
+
+	// Rutas de OAuth - Redirección al proveedor (se abre con una navegación) y callback de cada proveedor registrado
+	router.GET("/oauth/:provider/url", oauthLimit, oauthHandler.GetAuthURLHandler)
+	router.GET("/auth/:provider/callback", oauthLimit, oauthHandler.CallbackHandler)
+
+	// Búsqueda y perfiles públicos de los mentores
+	router.GET("/mentors", publicLimit, authHandler.GetMentorsHandler)
+	router.GET("/mentors/:id", publicLimit, authHandler.GetMentorProfileHandler)
 
-	// Rutas de OAuth - URLs de autenticación
-	router.GET("/oauth/google/url", oauthHandler.GetGoogleAuthURL)
-	router.GET("/oauth/github/url", oauthHandler.GetGitHubAuthURL)
-	router.GET("/oauth/linkedin/url", oauthHandler.GetLinkedInAuthURL)
+	// Catálogo de habilidades
+	router.GET("/skills", publicLimit, authHandler.GetSkillsHandler)
+	router.GET("/skills/categories", publicLimit, authHandler.GetSkillCategoriesHandler)
 
-	// Callbacks de OAuth
-	router.GET("/auth/google/callback", oauthHandler.GoogleCallbackHandler)
-	router.GET("/auth/github/callback", oauthHandler.GitHubCallbackHandler)
-	router.GET("/auth/linkedin/callback", oauthHandler.LinkedInCallbackHandler)
+	// Rutas protegidas que también aceptan tokens personales con el scope indicado
+	router.GET("/user/profile", authHandler.AuthMiddleware(services.ScopeProfileRead), apiLimit, authHandler.GetProfileHandler)
+	router.PUT("/user/profile", authHandler.AuthMiddleware(services.ScopeProfileWrite), apiLimit, authHandler.UpdateProfileHandler)
+	router.POST("/auth/subscribe/:plan_id", authHandler.AuthMiddleware(services.ScopeSubscriptionsWrite), apiLimit, authHandler.RequireVerifiedEmail(), authHandler.SubscribeToPlanHandler)
 
-	// Rutas protegidas
+	// Rutas protegidas (solo con la sesión del usuario). Las que tocan credenciales o
+	// datos de la cuenta no se pueden usar durante una suplantación.
 	userRoutes := router.Group("/")
-	userRoutes.Use(handlers.AuthMiddleware())
+	userRoutes.Use(authHandler.AuthMiddleware(), apiLimit)
 	{
 		userRoutes.POST("/auth/select-role", authHandler.SelectRoleHandler)
-		userRoutes.GET("/user/profile", authHandler.GetProfileHandler)
-		userRoutes.PUT("/user/profile", authHandler.UpdateProfileHandler)
-		userRoutes.POST("/auth/subscribe/:plan_id", authHandler.SubscribeToPlanHandler)
+		userRoutes.PUT("/user/password", authHandler.RejectImpersonation(), authHandler.ChangePasswordHandler)
+		userRoutes.POST("/user/password", authHandler.RejectImpersonation(), authHandler.RequireVerifiedEmail(), authHandler.SetPasswordHandler)
+		userRoutes.POST("/auth/resend-verification", authHandler.ResendVerificationHandler)
+
+		// Roles del usuario: agregar, dejar, cambiar el activo y datos de perfil por rol
+		userRoutes.GET("/user/roles", authHandler.GetUserRolesHandler)
+		userRoutes.POST("/user/roles", authHandler.SelectRoleHandler)
+		userRoutes.DELETE("/user/roles/:rol", authHandler.RemoveUserRoleHandler)
+		userRoutes.PUT("/user/roles/active", authHandler.SetActiveRoleHandler)
+		userRoutes.GET("/user/roles/:rol/profile", authHandler.GetRoleProfileHandler)
+		userRoutes.PUT("/user/roles/:rol/profile", authHandler.UpdateRoleProfileHandler)
+
+		// Solicitud para ser mentor
+		userRoutes.GET("/user/mentor-application", authHandler.GetMentorApplicationHandler)
+		userRoutes.POST("/user/mentor-application", authHandler.RequireVerifiedEmail(), authHandler.SubmitMentorApplicationHandler)
+		userRoutes.PUT("/user/mentor-application", authHandler.UpdateMentorApplicationHandler)
+
+		// Perfil de mentor (requiere el rol mentor para crearlo)
+		userRoutes.GET("/user/mentor-profile", authHandler.GetOwnMentorProfileHandler)
+		userRoutes.POST("/user/mentor-profile", authHandler.CreateMentorProfileHandler)
+		userRoutes.PUT("/user/mentor-profile", authHandler.UpdateMentorProfileHandler)
+		userRoutes.DELETE("/user/mentor-profile", authHandler.DeleteMentorProfileHandler)
+
+		// Habilidades del usuario (experiencia e intereses)
+		userRoutes.GET("/user/skills", authHandler.GetUserSkillsHandler)
+		userRoutes.PUT("/user/skills", authHandler.SetUserSkillsHandler)
+
+		// Solicitudes de mentoría: enviadas por el mentorado y recibidas por el mentor
+		userRoutes.POST("/user/mentorship-requests", authHandler.RequireVerifiedEmail(), authHandler.CreateMentorshipRequestHandler)
+		userRoutes.GET("/user/mentorship-requests/sent", authHandler.GetSentMentorshipRequestsHandler)
+		userRoutes.GET("/user/mentorship-requests/received", authHandler.GetReceivedMentorshipRequestsHandler)
+		userRoutes.GET("/user/mentorship-requests/:id", authHandler.GetMentorshipRequestHandler)
+		userRoutes.POST("/user/mentorship-requests/:id/accept", authHandler.AcceptMentorshipRequestHandler)
+		userRoutes.POST("/user/mentorship-requests/:id/decline", authHandler.DeclineMentorshipRequestHandler)
+		userRoutes.POST("/user/mentorship-requests/:id/withdraw", authHandler.WithdrawMentorshipRequestHandler)
+
+		// Verificación en dos pasos
+		userRoutes.GET("/user/mfa", authHandler.GetMFAStatusHandler)
+		userRoutes.POST("/user/mfa/totp", authHandler.RejectImpersonation(), authHandler.SetupTOTPHandler)
+		userRoutes.POST("/user/mfa/totp/confirm", authHandler.RejectImpersonation(), authHandler.ConfirmTOTPHandler)
+		userRoutes.POST("/user/mfa/recovery-codes", authHandler.RejectImpersonation(), authHandler.RegenerateRecoveryCodesHandler)
+		userRoutes.DELETE("/user/mfa", authHandler.RejectImpersonation(), authHandler.DisableMFAHandler)
+
+		// Cuentas externas vinculadas
+		userRoutes.GET("/user/identities", oauthHandler.GetIdentitiesHandler)
+		userRoutes.POST("/user/identities/:provider", authHandler.RejectImpersonation(), oauthHandler.LinkIdentityHandler)
+		userRoutes.DELETE("/user/identities/:provider", authHandler.RejectImpersonation(), oauthHandler.UnlinkIdentityHandler)
+
+		// Sesiones y dispositivos
+		userRoutes.GET("/user/sessions", authHandler.GetSessionsHandler)
+		userRoutes.DELETE("/user/sessions", authHandler.RejectImpersonation(), authHandler.RevokeOtherSessionsHandler)
+		userRoutes.DELETE("/user/sessions/:id", authHandler.RejectImpersonation(), authHandler.RevokeSessionHandler)
+
+		// Tokens personales para integraciones
+		userRoutes.GET("/user/tokens/scopes", authHandler.GetScopesHandler)
+		userRoutes.GET("/user/tokens", authHandler.GetPersonalTokensHandler)
+		userRoutes.POST("/user/tokens", authHandler.RejectImpersonation(), authHandler.CreatePersonalTokenHandler)
+		userRoutes.DELETE("/user/tokens/:id", authHandler.RejectImpersonation(), authHandler.RevokePersonalTokenHandler)
+
+		// Datos personales: exportación y eliminación de la cuenta
+		userRoutes.GET("/user/export", authHandler.RejectImpersonation(), authHandler.ExportAccountHandler)
+		userRoutes.DELETE("/user", authHandler.RejectImpersonation(), authHandler.DeleteAccountHandler)
+		userRoutes.GET("/user/deletion", authHandler.GetAccountDeletionHandler)
+		userRoutes.DELETE("/user/deletion", authHandler.RejectImpersonation(), authHandler.CancelAccountDeletionHandler)
 	}
 
-	// Rutas de administración (protegidas por rol de admin)
-	admin := router.Group("/")
-	admin.Use(handlers.AuthMiddleware(), authHandler.AdminMiddleware())
+	// Rutas de administración (protegidas por permisos del rol)
+	// Los planes también se pueden administrar con tokens personales
+	router.POST("/plans", authHandler.AuthMiddleware(services.ScopePlansWrite), apiLimit, authHandler.RequirePermission(services.PermissionPlansWrite), authHandler.CreatePlanHandler)
+	router.GET("/plans", authHandler.AuthMiddleware(services.ScopePlansRead), apiLimit, authHandler.RequirePermission(services.PermissionPlansRead), authHandler.GetAllPlansHandler)
+	router.GET("/plans/:id", authHandler.AuthMiddleware(services.ScopePlansRead), apiLimit, authHandler.RequirePermission(services.PermissionPlansRead), authHandler.GetPlanByIDHandler)
+	router.PUT("/plans/:id", authHandler.AuthMiddleware(services.ScopePlansWrite), apiLimit, authHandler.RequirePermission(services.PermissionPlansWrite), authHandler.UpdatePlanHandler)
+	router.DELETE("/plans/:id", authHandler.AuthMiddleware(services.ScopePlansWrite), apiLimit, authHandler.RequirePermission(services.PermissionPlansWrite), authHandler.DeletePlanHandler)
+
+	admin := router.Group("/admin")
+	admin.Use(authHandler.AuthMiddleware(), apiLimit, authHandler.RejectImpersonation())
 	{
-		admin.POST("/plans", authHandler.CreatePlanHandler)
-		admin.GET("/plans", authHandler.GetAllPlansHandler)
-		admin.GET("/plans/:id", authHandler.GetPlanByIDHandler)
-		admin.PUT("/plans/:id", authHandler.UpdatePlanHandler)
-		admin.DELETE("/plans/:id", authHandler.DeletePlanHandler)
+		// Protección de inicio de sesión
+		admin.GET("/login/events", authHandler.RequirePermission(services.PermissionUsersRead), authHandler.GetLoginEventsHandler)
+		admin.POST("/login/unlock", authHandler.RequirePermission(services.PermissionUsersWrite), authHandler.AdminUnlockLoginHandler)
+
+		// Usuarios: búsqueda, ficha, suspensión, cierre de sesiones y verificación manual del email
+		admin.GET("/users", authHandler.RequirePermission(services.PermissionUsersRead), authHandler.GetUsersHandler)
+		admin.GET("/users/:id", authHandler.RequirePermission(services.PermissionUsersRead), authHandler.GetUserDetailHandler)
+		admin.POST("/users/:id/suspend", authHandler.RequirePermission(services.PermissionUsersWrite), authHandler.SuspendUserHandler)
+		admin.POST("/users/:id/reactivate", authHandler.RequirePermission(services.PermissionUsersWrite), authHandler.ReactivateUserHandler)
+		admin.POST("/users/:id/logout", authHandler.RequirePermission(services.PermissionUsersWrite), authHandler.ForceLogoutUserHandler)
+		admin.PUT("/users/:id/email-verification", authHandler.RequirePermission(services.PermissionUsersWrite), authHandler.SetEmailVerifiedHandler)
+
+		// Suplantación para soporte (cada solicitud suplantada queda en la auditoría)
+		admin.POST("/users/:id/impersonate", authHandler.RequirePermission(services.PermissionUsersImpersonate), authHandler.StartImpersonationHandler)
+		admin.GET("/impersonations", authHandler.RequirePermission(services.PermissionUsersImpersonate), authHandler.GetImpersonationsHandler)
+		admin.DELETE("/impersonations/:id", authHandler.RequirePermission(services.PermissionUsersImpersonate), authHandler.EndImpersonationHandler)
+
+		// Roles y permisos
+		admin.GET("/roles", authHandler.RequirePermission(services.PermissionUsersRead), authHandler.GetRolesHandler)
+		admin.POST("/users/:id/roles", authHandler.RequirePermission(services.PermissionUsersWrite), authHandler.AssignRoleHandler)
+		admin.DELETE("/users/:id/roles/:rol", authHandler.RequirePermission(services.PermissionUsersWrite), authHandler.RevokeRoleHandler)
+
+		// Revisión de solicitudes para ser mentor
+		admin.GET("/mentor-applications", authHandler.RequirePermission(services.PermissionMentorsReview), authHandler.GetMentorApplicationsHandler)
+		admin.GET("/mentor-applications/:id", authHandler.RequirePermission(services.PermissionMentorsReview), authHandler.GetMentorApplicationByIDHandler)
+		admin.POST("/mentor-applications/:id/review", authHandler.RequirePermission(services.PermissionMentorsReview), authHandler.ReviewMentorApplicationHandler)
+
+		// Catálogo de habilidades
+		admin.POST("/skill-categories", authHandler.RequirePermission(services.PermissionSkillsWrite), authHandler.CreateSkillCategoryHandler)
+		admin.PUT("/skill-categories/:id", authHandler.RequirePermission(services.PermissionSkillsWrite), authHandler.UpdateSkillCategoryHandler)
+		admin.DELETE("/skill-categories/:id", authHandler.RequirePermission(services.PermissionSkillsWrite), authHandler.DeleteSkillCategoryHandler)
+		admin.POST("/skills", authHandler.RequirePermission(services.PermissionSkillsWrite), authHandler.CreateSkillHandler)
+		admin.PUT("/skills/:id", authHandler.RequirePermission(services.PermissionSkillsWrite), authHandler.UpdateSkillHandler)
+		admin.DELETE("/skills/:id", authHandler.RequirePermission(services.PermissionSkillsWrite), authHandler.DeleteSkillHandler)
+		admin.POST("/skills/:id/merge", authHandler.RequirePermission(services.PermissionSkillsWrite), authHandler.MergeSkillHandler)
+
+		// Registro de auditoría
+		admin.GET("/audit", authHandler.RequirePermission(services.PermissionAuditRead), authHandler.GetAuditLogHandler)
+		admin.GET("/audit/export", authHandler.RequirePermission(services.PermissionAuditRead), authHandler.ExportAuditLogHandler)
 	}
 
 	fmt.Println("✓ Servidor iniciado en http://localhost:8080")
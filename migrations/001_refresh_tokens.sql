@@ -0,0 +1,15 @@
+-- Refresh tokens rotativos. Cada login crea una "familia"; cada rotación marca
+-- el token anterior como usado e inserta uno nuevo en la misma familia.
+CREATE TABLE IF NOT EXISTS tb_refresh_token (
+    id_refresh_token SERIAL PRIMARY KEY,
+    id_persona       INTEGER NOT NULL REFERENCES tb_persona (id_persona) ON DELETE CASCADE,
+    familia          VARCHAR(64) NOT NULL,
+    token_hash       VARCHAR(64) NOT NULL UNIQUE,
+    fecha_creacion   TIMESTAMP NOT NULL DEFAULT NOW(),
+    fecha_expiracion TIMESTAMP NOT NULL,
+    usado_en         TIMESTAMP,
+    revocado_en      TIMESTAMP
+);
+
+CREATE INDEX IF NOT EXISTS idx_refresh_token_familia ON tb_refresh_token (familia);
+CREATE INDEX IF NOT EXISTS idx_refresh_token_persona ON tb_refresh_token (id_persona);
@@ -0,0 +1,12 @@
+-- Estado de los flujos OAuth en curso: protege contra CSRF (state) y guarda
+-- el code_verifier de PKCE hasta que vuelve el callback. Cada state se usa una sola vez.
+CREATE TABLE IF NOT EXISTS tb_oauth_state (
+    state_hash       VARCHAR(64) PRIMARY KEY,
+    proveedor        VARCHAR(32) NOT NULL,
+    code_verifier    VARCHAR(128) NOT NULL,
+    fecha_creacion   TIMESTAMP NOT NULL DEFAULT NOW(),
+    fecha_expiracion TIMESTAMP NOT NULL,
+    usado_en         TIMESTAMP
+);
+
+CREATE INDEX IF NOT EXISTS idx_oauth_state_expiracion ON tb_oauth_state (fecha_expiracion);
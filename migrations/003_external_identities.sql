@@ -0,0 +1,16 @@
+-- Identidades externas (Google, GitHub, LinkedIn...) vinculadas a una persona.
+-- El login OAuth se resuelve por (proveedor, sujeto) y ya no por email.
+CREATE TABLE IF NOT EXISTS tb_identidad_externa (
+    id_identidad      SERIAL PRIMARY KEY,
+    id_persona        INTEGER NOT NULL REFERENCES tb_persona (id_persona) ON DELETE CASCADE,
+    proveedor         VARCHAR(32) NOT NULL,
+    sujeto            VARCHAR(255) NOT NULL,
+    email             VARCHAR(255),
+    fecha_vinculacion TIMESTAMP NOT NULL DEFAULT NOW(),
+    ultimo_login      TIMESTAMP,
+    UNIQUE (proveedor, sujeto),
+    UNIQUE (id_persona, proveedor)
+);
+
+-- Un state con id_persona corresponde a un flujo de vinculación y no de login
+ALTER TABLE tb_oauth_state ADD COLUMN IF NOT EXISTS id_persona INTEGER REFERENCES tb_persona (id_persona) ON DELETE CASCADE;
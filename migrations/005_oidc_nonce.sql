@@ -0,0 +1,3 @@
+-- Nonce OpenID Connect: se envía en la URL de autorización y se compara con el claim
+-- "nonce" del id_token para evitar la reutilización de tokens.
+ALTER TABLE tb_oauth_state ADD COLUMN IF NOT EXISTS nonce VARCHAR(64) NOT NULL DEFAULT '';
@@ -0,0 +1,27 @@
+-- Verificación de email. Las cuentas existentes se consideran verificadas para no
+-- bloquearlas en las rutas que ahora exigen email verificado. Se agrega la columna con
+-- DEFAULT TRUE para completar las filas existentes y después el valor por defecto pasa a
+-- FALSE; si la columna ya existe no se toca, así volver a correr el script no verifica a nadie.
+DO $$
+BEGIN
+    IF NOT EXISTS (SELECT 1 FROM information_schema.columns
+                   WHERE table_schema = current_schema() AND table_name = 'tb_persona'
+                     AND column_name = 'email_verificado') THEN
+        ALTER TABLE tb_persona ADD COLUMN email_verificado BOOLEAN NOT NULL DEFAULT TRUE;
+        ALTER TABLE tb_persona ALTER COLUMN email_verificado SET DEFAULT FALSE;
+    END IF;
+END $$;
+
+-- Tokens de un solo uso enviados por email (verificación, recuperación de contraseña, ...).
+-- Solo se guarda el hash; el token en claro viaja únicamente en el enlace.
+CREATE TABLE IF NOT EXISTS tb_token_usuario (
+    id_token         SERIAL PRIMARY KEY,
+    id_persona       INTEGER NOT NULL REFERENCES tb_persona (id_persona) ON DELETE CASCADE,
+    proposito        VARCHAR(32) NOT NULL,
+    token_hash       VARCHAR(64) NOT NULL UNIQUE,
+    fecha_creacion   TIMESTAMP NOT NULL DEFAULT NOW(),
+    fecha_expiracion TIMESTAMP NOT NULL,
+    usado_en         TIMESTAMP
+);
+
+CREATE INDEX IF NOT EXISTS idx_token_usuario_persona ON tb_token_usuario (id_persona, proposito, fecha_creacion);
@@ -0,0 +1,4 @@
+-- Las cuentas sin contraseña (solo OAuth) pasan a tener contrasena NULL en lugar de ''.
+-- Un NULL nunca coincide con ninguna contraseña en el login.
+ALTER TABLE tb_persona ALTER COLUMN contrasena DROP NOT NULL;
+UPDATE tb_persona SET contrasena = NULL WHERE contrasena = '';
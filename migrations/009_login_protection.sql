@@ -0,0 +1,28 @@
+-- Registro de intentos de inicio de sesión, para poder detectar ataques.
+-- id_persona queda en NULL cuando el email no corresponde a ninguna cuenta.
+CREATE TABLE IF NOT EXISTS tb_evento_login (
+    id_evento  BIGSERIAL PRIMARY KEY,
+    email      VARCHAR(255) NOT NULL,
+    id_persona INTEGER REFERENCES tb_persona (id_persona) ON DELETE SET NULL,
+    ip         VARCHAR(64) NOT NULL,
+    resultado  VARCHAR(32) NOT NULL,
+    fecha      TIMESTAMP NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX IF NOT EXISTS idx_evento_login_email ON tb_evento_login (email, fecha);
+CREATE INDEX IF NOT EXISTS idx_evento_login_ip ON tb_evento_login (ip, fecha);
+
+-- Contadores de intentos fallidos y bloqueos temporales, por email y por IP.
+-- Se indexan por el email ingresado (no por id_persona) para que un email
+-- inexistente se comporte igual que uno registrado.
+CREATE TABLE IF NOT EXISTS tb_bloqueo_login (
+    tipo              VARCHAR(8) NOT NULL,
+    clave             VARCHAR(255) NOT NULL,
+    intentos_fallidos INTEGER NOT NULL DEFAULT 0,
+    ultimo_fallo      TIMESTAMP NOT NULL,
+    bloqueado_hasta   TIMESTAMP,
+    PRIMARY KEY (tipo, clave)
+);
+
+-- Las búsquedas por email de la protección de login no distinguen mayúsculas
+CREATE INDEX IF NOT EXISTS idx_persona_email_lower ON tb_persona (LOWER(email));
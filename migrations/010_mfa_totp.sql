@@ -0,0 +1,33 @@
+-- Segundo factor TOTP. El secreto se guarda cifrado con ENCRYPTION_KEY (AES-256-GCM).
+-- ultimo_paso evita que un mismo código se use dos veces.
+CREATE TABLE IF NOT EXISTS tb_mfa_totp (
+    id_persona         INTEGER PRIMARY KEY REFERENCES tb_persona (id_persona) ON DELETE CASCADE,
+    secreto_cifrado    TEXT NOT NULL,
+    confirmado         BOOLEAN NOT NULL DEFAULT FALSE,
+    ultimo_paso        BIGINT NOT NULL DEFAULT 0,
+    fecha_creacion     TIMESTAMP NOT NULL DEFAULT NOW(),
+    fecha_confirmacion TIMESTAMP
+);
+
+-- Códigos de recuperación de un solo uso (solo el hash)
+CREATE TABLE IF NOT EXISTS tb_codigo_recuperacion (
+    id_codigo   SERIAL PRIMARY KEY,
+    id_persona  INTEGER NOT NULL REFERENCES tb_persona (id_persona) ON DELETE CASCADE,
+    codigo_hash VARCHAR(64) NOT NULL,
+    usado_en    TIMESTAMP
+);
+
+CREATE INDEX IF NOT EXISTS idx_codigo_recuperacion_persona ON tb_codigo_recuperacion (id_persona);
+
+-- Desafíos de segundo factor emitidos después de validar la contraseña u OAuth.
+-- Son de un solo uso, vencen a los pocos minutos y admiten pocos intentos.
+CREATE TABLE IF NOT EXISTS tb_desafio_mfa (
+    id_desafio       SERIAL PRIMARY KEY,
+    id_persona       INTEGER NOT NULL REFERENCES tb_persona (id_persona) ON DELETE CASCADE,
+    token_hash       VARCHAR(64) NOT NULL UNIQUE,
+    intentos         INTEGER NOT NULL DEFAULT 0,
+    usar_cookies     BOOLEAN NOT NULL DEFAULT FALSE,
+    fecha_creacion   TIMESTAMP NOT NULL DEFAULT NOW(),
+    fecha_expiracion TIMESTAMP NOT NULL,
+    usado_en         TIMESTAMP
+);
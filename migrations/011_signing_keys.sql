@@ -0,0 +1,12 @@
+-- Claves asimétricas para firmar los JWT de acceso. La privada se guarda cifrada con
+-- ENCRYPTION_KEY y la pública en DER (PKIX) codificada en base64.
+-- retirada_en: desde cuándo la clave ya no firma; expira_en: desde cuándo deja de publicarse.
+CREATE TABLE IF NOT EXISTS tb_clave_firma (
+    kid                   VARCHAR(64) PRIMARY KEY,
+    algoritmo             VARCHAR(16) NOT NULL,
+    clave_privada_cifrada TEXT NOT NULL,
+    clave_publica         TEXT NOT NULL,
+    fecha_creacion        TIMESTAMP NOT NULL DEFAULT NOW(),
+    retirada_en           TIMESTAMP,
+    expira_en             TIMESTAMP
+);
@@ -0,0 +1,16 @@
+-- Tokens personales (API keys) para integraciones. Solo se guarda el hash;
+-- prefijo es el comienzo del token, para que el usuario lo reconozca en el listado.
+CREATE TABLE IF NOT EXISTS tb_token_personal (
+    id_token         SERIAL PRIMARY KEY,
+    id_persona       INTEGER NOT NULL REFERENCES tb_persona (id_persona) ON DELETE CASCADE,
+    nombre           VARCHAR(100) NOT NULL,
+    token_hash       VARCHAR(64) NOT NULL UNIQUE,
+    prefijo          VARCHAR(32) NOT NULL,
+    scopes           TEXT[] NOT NULL,
+    fecha_creacion   TIMESTAMP NOT NULL DEFAULT NOW(),
+    fecha_expiracion TIMESTAMP NOT NULL,
+    ultimo_uso       TIMESTAMP,
+    revocado_en      TIMESTAMP
+);
+
+CREATE INDEX IF NOT EXISTS idx_token_personal_persona ON tb_token_personal (id_persona);
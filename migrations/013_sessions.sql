@@ -0,0 +1,14 @@
+-- Sesiones activas. Cada sesión corresponde a una familia de refresh tokens;
+-- el JWT de acceso lleva el id de la sesión (claim "sid") y se rechaza si fue revocada.
+CREATE TABLE IF NOT EXISTS tb_sesion (
+    id_sesion        SERIAL PRIMARY KEY,
+    id_persona       INTEGER NOT NULL REFERENCES tb_persona (id_persona) ON DELETE CASCADE,
+    familia          VARCHAR(64) NOT NULL UNIQUE,
+    user_agent       VARCHAR(512) NOT NULL DEFAULT '',
+    ip               VARCHAR(64) NOT NULL DEFAULT '',
+    fecha_creacion   TIMESTAMP NOT NULL DEFAULT NOW(),
+    ultima_actividad TIMESTAMP NOT NULL DEFAULT NOW(),
+    revocada_en      TIMESTAMP
+);
+
+CREATE INDEX IF NOT EXISTS idx_sesion_persona ON tb_sesion (id_persona);
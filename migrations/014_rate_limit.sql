@@ -0,0 +1,11 @@
+-- Contadores de rate limiting compartidos entre instancias (RATE_LIMIT_STORE=postgres).
+-- ventana es el número de ventana (tiempo Unix dividido por la duración de la ventana).
+CREATE UNLOGGED TABLE IF NOT EXISTS tb_rate_limit (
+    clave     VARCHAR(255) NOT NULL,
+    ventana   BIGINT NOT NULL,
+    contador  INTEGER NOT NULL,
+    expira_en TIMESTAMP NOT NULL,
+    PRIMARY KEY (clave, ventana)
+);
+
+CREATE INDEX IF NOT EXISTS idx_rate_limit_expira ON tb_rate_limit (expira_en);
@@ -0,0 +1,42 @@
+-- Permisos por rol. Hasta ahora cualquier "mentor" podía administrar los planes y
+-- cualquier usuario podía elegir ese rol. Ahora la administración depende de permisos
+-- y el rol admin no se puede elegir desde la aplicación.
+ALTER TABLE tb_rol ADD COLUMN IF NOT EXISTS seleccionable BOOLEAN NOT NULL DEFAULT TRUE;
+
+INSERT INTO tb_rol (nombre_rol, descripcion, seleccionable)
+SELECT 'admin', 'Administrador de la plataforma', FALSE
+WHERE NOT EXISTS (SELECT 1 FROM tb_rol WHERE nombre_rol = 'admin');
+
+UPDATE tb_rol SET seleccionable = FALSE WHERE nombre_rol = 'admin';
+
+CREATE TABLE IF NOT EXISTS tb_permiso (
+    id_permiso  SERIAL PRIMARY KEY,
+    nombre      VARCHAR(50) NOT NULL UNIQUE,
+    descripcion VARCHAR(255) NOT NULL
+);
+
+CREATE TABLE IF NOT EXISTS tb_rol_permiso (
+    id_rol     INTEGER NOT NULL REFERENCES tb_rol (id_rol) ON DELETE CASCADE,
+    id_permiso INTEGER NOT NULL REFERENCES tb_permiso (id_permiso) ON DELETE CASCADE,
+    PRIMARY KEY (id_rol, id_permiso)
+);
+
+INSERT INTO tb_permiso (nombre, descripcion) VALUES
+    ('plans:read', 'Ver todos los planes'),
+    ('plans:write', 'Crear, modificar y eliminar planes'),
+    ('users:read', 'Ver usuarios, roles y eventos de inicio de sesión'),
+    ('users:write', 'Asignar roles y desbloquear cuentas')
+ON CONFLICT (nombre) DO NOTHING;
+
+INSERT INTO tb_rol_permiso (id_rol, id_permiso)
+SELECT r.id_rol, p.id_permiso
+FROM tb_rol r CROSS JOIN tb_permiso p
+WHERE r.nombre_rol = 'admin'
+ON CONFLICT DO NOTHING;
+
+-- El primer administrador se asigna a mano, después de correr todas las migraciones
+-- (desde 016 los roles de cada usuario están en tb_persona_rol):
+-- INSERT INTO tb_persona_rol (id_persona, id_rol)
+-- SELECT p.id_persona, r.id_rol FROM tb_persona p, tb_rol r
+-- WHERE p.email = '...' AND r.nombre_rol = 'admin'
+-- ON CONFLICT DO NOTHING;
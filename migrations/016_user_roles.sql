@@ -0,0 +1,21 @@
+-- Un usuario puede tener varios roles a la vez (por ejemplo mentor en un área y
+-- mentorado en otra). datos guarda la información del perfil propia de cada rol.
+CREATE TABLE IF NOT EXISTS tb_persona_rol (
+    id_persona       INTEGER NOT NULL REFERENCES tb_persona (id_persona) ON DELETE CASCADE,
+    id_rol           INTEGER NOT NULL REFERENCES tb_rol (id_rol) ON DELETE CASCADE,
+    datos            JSONB NOT NULL DEFAULT '{}',
+    fecha_asignacion TIMESTAMP NOT NULL DEFAULT NOW(),
+    PRIMARY KEY (id_persona, id_rol)
+);
+
+CREATE INDEX IF NOT EXISTS idx_persona_rol_rol ON tb_persona_rol (id_rol);
+
+INSERT INTO tb_persona_rol (id_persona, id_rol)
+SELECT id_persona, id_rol FROM tb_persona WHERE id_rol IS NOT NULL
+ON CONFLICT DO NOTHING;
+
+ALTER TABLE tb_persona DROP COLUMN IF EXISTS id_rol;
+
+-- Rol con el que el usuario está usando la aplicación en cada sesión.
+-- NULL significa el primer rol asignado.
+ALTER TABLE tb_sesion ADD COLUMN IF NOT EXISTS id_rol_activo INTEGER REFERENCES tb_rol (id_rol) ON DELETE SET NULL;
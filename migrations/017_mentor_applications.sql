@@ -0,0 +1,35 @@
+-- El rol mentor deja de poder elegirse: se otorga al aprobar una solicitud.
+-- Los mentores que ya existen conservan el rol.
+UPDATE tb_rol SET seleccionable = FALSE WHERE nombre_rol = 'mentor';
+
+CREATE TABLE IF NOT EXISTS tb_solicitud_mentor (
+    id_solicitud        SERIAL PRIMARY KEY,
+    id_persona          INTEGER NOT NULL REFERENCES tb_persona (id_persona) ON DELETE CASCADE,
+    areas_experiencia   TEXT NOT NULL,
+    experiencia         TEXT NOT NULL,
+    linkedin_url        VARCHAR(255) NOT NULL,
+    motivacion          TEXT NOT NULL,
+    estado              VARCHAR(20) NOT NULL DEFAULT 'pendiente'
+                        CHECK (estado IN ('pendiente', 'cambios_solicitados', 'aprobada', 'rechazada')),
+    comentario_revision TEXT,
+    id_revisor          INTEGER REFERENCES tb_persona (id_persona) ON DELETE SET NULL,
+    fecha_creacion      TIMESTAMP NOT NULL DEFAULT NOW(),
+    fecha_actualizacion TIMESTAMP NOT NULL DEFAULT NOW(),
+    fecha_revision      TIMESTAMP
+);
+
+-- Una sola solicitud abierta por usuario
+CREATE UNIQUE INDEX IF NOT EXISTS idx_solicitud_mentor_abierta
+    ON tb_solicitud_mentor (id_persona) WHERE estado IN ('pendiente', 'cambios_solicitados');
+
+CREATE INDEX IF NOT EXISTS idx_solicitud_mentor_estado ON tb_solicitud_mentor (estado, fecha_actualizacion);
+
+INSERT INTO tb_permiso (nombre, descripcion) VALUES
+    ('mentors:review', 'Revisar las solicitudes para ser mentor')
+ON CONFLICT (nombre) DO NOTHING;
+
+INSERT INTO tb_rol_permiso (id_rol, id_permiso)
+SELECT r.id_rol, p.id_permiso
+FROM tb_rol r CROSS JOIN tb_permiso p
+WHERE r.nombre_rol = 'admin' AND p.nombre = 'mentors:review'
+ON CONFLICT DO NOTHING;
@@ -0,0 +1,11 @@
+-- Suspensión de cuentas por un administrador. Una cuenta suspendida no puede iniciar
+-- sesión ni usar sus tokens hasta que se reactive.
+ALTER TABLE tb_persona
+    ADD COLUMN IF NOT EXISTS suspendido_en     TIMESTAMP,
+    ADD COLUMN IF NOT EXISTS motivo_suspension TEXT,
+    ADD COLUMN IF NOT EXISTS suspendido_por    INTEGER REFERENCES tb_persona (id_persona) ON DELETE SET NULL;
+
+CREATE INDEX IF NOT EXISTS idx_persona_fecha_registro ON tb_persona (fecha_registro);
+
+UPDATE tb_permiso SET descripcion = 'Asignar roles, desbloquear, suspender y cerrar sesiones de cuentas'
+WHERE nombre = 'users:write';
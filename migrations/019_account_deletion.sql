@@ -0,0 +1,24 @@
+-- Eliminación de cuentas a pedido del usuario. Durante el período de gracia la
+-- eliminación se puede cancelar; después la cuenta se borra, o se anonimiza si tiene
+-- suscripciones (los registros de facturación se conservan).
+ALTER TABLE tb_persona
+    ADD COLUMN IF NOT EXISTS eliminacion_solicitada_en   TIMESTAMP,
+    ADD COLUMN IF NOT EXISTS eliminacion_programada_para TIMESTAMP,
+    ADD COLUMN IF NOT EXISTS anonimizado_en              TIMESTAMP;
+
+CREATE INDEX IF NOT EXISTS idx_persona_eliminacion_programada
+    ON tb_persona (eliminacion_programada_para) WHERE eliminacion_programada_para IS NOT NULL;
+
+-- Registro de auditoría. Los IDs no tienen clave foránea para que el registro
+-- sobreviva al borrado de la cuenta.
+CREATE TABLE IF NOT EXISTS tb_auditoria (
+    id_auditoria BIGSERIAL PRIMARY KEY,
+    id_actor     INTEGER,
+    id_persona   INTEGER,
+    accion       VARCHAR(50) NOT NULL,
+    detalle      JSONB NOT NULL DEFAULT '{}',
+    ip           VARCHAR(45),
+    fecha        TIMESTAMP NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX IF NOT EXISTS idx_auditoria_persona ON tb_auditoria (id_persona, fecha);
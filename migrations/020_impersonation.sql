@@ -0,0 +1,27 @@
+-- Suplantación de usuarios por soporte. Un administrador con el permiso users:impersonate
+-- obtiene un token de corta duración que actúa como el usuario; cada suplantación y cada
+-- solicitud hecha con ella queda en tb_auditoria.
+CREATE TABLE IF NOT EXISTS tb_suplantacion (
+    id_suplantacion  SERIAL PRIMARY KEY,
+    id_actor         INTEGER NOT NULL REFERENCES tb_persona (id_persona) ON DELETE CASCADE,
+    id_persona       INTEGER NOT NULL REFERENCES tb_persona (id_persona) ON DELETE CASCADE,
+    motivo           TEXT NOT NULL,
+    solo_lectura     BOOLEAN NOT NULL DEFAULT TRUE,
+    fecha_creacion   TIMESTAMP NOT NULL DEFAULT NOW(),
+    fecha_expiracion TIMESTAMP NOT NULL,
+    finalizada_en    TIMESTAMP
+);
+
+CREATE INDEX IF NOT EXISTS idx_suplantacion_actor ON tb_suplantacion (id_actor, fecha_creacion);
+CREATE INDEX IF NOT EXISTS idx_suplantacion_persona ON tb_suplantacion (id_persona);
+
+INSERT INTO tb_permiso (nombre, descripcion) VALUES
+    ('users:impersonate', 'Actuar como otro usuario para dar soporte')
+ON CONFLICT (nombre) DO NOTHING;
+
+-- Se otorga al rol admin; se puede quitar de tb_rol_permiso para reservarlo a un rol de soporte
+INSERT INTO tb_rol_permiso (id_rol, id_permiso)
+SELECT r.id_rol, p.id_permiso
+FROM tb_rol r CROSS JOIN tb_permiso p
+WHERE r.nombre_rol = 'admin' AND p.nombre = 'users:impersonate'
+ON CONFLICT DO NOTHING;
@@ -0,0 +1,41 @@
+-- Registro de auditoría de seguridad: quién hizo qué, sobre qué objeto, qué cambió y
+-- desde dónde. Amplía tb_auditoria (019) y la vuelve de solo inserción.
+ALTER TABLE tb_auditoria
+    ADD COLUMN IF NOT EXISTS tipo_objetivo VARCHAR(50),
+    ADD COLUMN IF NOT EXISTS id_objetivo   VARCHAR(100),
+    ADD COLUMN IF NOT EXISTS antes         JSONB,
+    ADD COLUMN IF NOT EXISTS despues       JSONB,
+    ADD COLUMN IF NOT EXISTS user_agent    VARCHAR(500),
+    ADD COLUMN IF NOT EXISTS id_solicitud  VARCHAR(64);
+
+CREATE INDEX IF NOT EXISTS idx_auditoria_fecha ON tb_auditoria (fecha);
+CREATE INDEX IF NOT EXISTS idx_auditoria_actor ON tb_auditoria (id_actor, fecha);
+CREATE INDEX IF NOT EXISTS idx_auditoria_accion ON tb_auditoria (accion, fecha);
+CREATE INDEX IF NOT EXISTS idx_auditoria_objetivo ON tb_auditoria (tipo_objetivo, id_objetivo);
+
+-- Los registros no se pueden modificar ni borrar desde la aplicación
+CREATE OR REPLACE FUNCTION fn_auditoria_solo_insercion() RETURNS trigger AS $$
+BEGIN
+    RAISE EXCEPTION 'tb_auditoria es de solo inserción';
+END;
+$$ LANGUAGE plpgsql;
+
+DROP TRIGGER IF EXISTS tg_auditoria_solo_insercion ON tb_auditoria;
+CREATE TRIGGER tg_auditoria_solo_insercion
+    BEFORE UPDATE OR DELETE ON tb_auditoria
+    FOR EACH ROW EXECUTE FUNCTION fn_auditoria_solo_insercion();
+
+DROP TRIGGER IF EXISTS tg_auditoria_sin_truncate ON tb_auditoria;
+CREATE TRIGGER tg_auditoria_sin_truncate
+    BEFORE TRUNCATE ON tb_auditoria
+    FOR EACH STATEMENT EXECUTE FUNCTION fn_auditoria_solo_insercion();
+
+INSERT INTO tb_permiso (nombre, descripcion) VALUES
+    ('audit:read', 'Consultar y exportar el registro de auditoría')
+ON CONFLICT (nombre) DO NOTHING;
+
+INSERT INTO tb_rol_permiso (id_rol, id_permiso)
+SELECT r.id_rol, p.id_permiso
+FROM tb_rol r CROSS JOIN tb_permiso p
+WHERE r.nombre_rol = 'admin' AND p.nombre = 'audit:read'
+ON CONFLICT DO NOTHING;
@@ -0,0 +1,17 @@
+-- Perfil público de los mentores. Solo lo puede crear quien tiene el rol mentor y
+-- solo se muestra mientras lo conserve y la cuenta esté activa.
+CREATE TABLE IF NOT EXISTS tb_perfil_mentor (
+    id_persona          INTEGER PRIMARY KEY REFERENCES tb_persona (id_persona) ON DELETE CASCADE,
+    titular             VARCHAR(120) NOT NULL,
+    biografia           TEXT NOT NULL DEFAULT '',
+    areas_experiencia   TEXT[] NOT NULL,
+    anios_experiencia   SMALLINT NOT NULL CHECK (anios_experiencia BETWEEN 0 AND 60),
+    idiomas             TEXT[] NOT NULL,
+    zona_horaria        VARCHAR(64) NOT NULL,
+    precio_sesion       NUMERIC(10, 2) NOT NULL CHECK (precio_sesion >= 0),
+    linkedin_url        VARCHAR(255),
+    github_url          VARCHAR(255),
+    avatar_url          VARCHAR(500),
+    fecha_creacion      TIMESTAMP NOT NULL DEFAULT NOW(),
+    fecha_actualizacion TIMESTAMP NOT NULL DEFAULT NOW()
+);
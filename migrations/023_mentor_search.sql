@@ -0,0 +1,55 @@
+-- Búsqueda de mentores. disponible lo maneja el mentor (acepta o no nuevos mentorados).
+-- La calificación es un resumen que se completa cuando haya reseñas; mientras tanto es NULL.
+ALTER TABLE tb_perfil_mentor
+    ADD COLUMN IF NOT EXISTS disponible              BOOLEAN NOT NULL DEFAULT TRUE,
+    ADD COLUMN IF NOT EXISTS calificacion_promedio   NUMERIC(3, 2) CHECK (calificacion_promedio BETWEEN 1 AND 5),
+    ADD COLUMN IF NOT EXISTS cantidad_calificaciones INTEGER NOT NULL DEFAULT 0,
+    ADD COLUMN IF NOT EXISTS busqueda                TSVECTOR;
+
+-- Texto indexado con los diccionarios español e inglés. El nombre va sin stemming y
+-- con el mayor peso, después el titular, las áreas y por último la biografía.
+CREATE OR REPLACE FUNCTION fn_perfil_mentor_tsvector(nombre TEXT, apellido TEXT, titular TEXT,
+                                                     areas TEXT[], biografia TEXT)
+RETURNS TSVECTOR AS $$
+    SELECT setweight(to_tsvector('simple', coalesce(nombre, '') || ' ' || coalesce(apellido, '')), 'A')
+        || setweight(to_tsvector('spanish', titular), 'A')
+        || setweight(to_tsvector('english', titular), 'A')
+        || setweight(to_tsvector('spanish', array_to_string(areas, ' ')), 'B')
+        || setweight(to_tsvector('english', array_to_string(areas, ' ')), 'B')
+        || setweight(to_tsvector('spanish', biografia), 'C')
+        || setweight(to_tsvector('english', biografia), 'C')
+$$ LANGUAGE sql STABLE;
+
+CREATE OR REPLACE FUNCTION fn_perfil_mentor_busqueda() RETURNS TRIGGER AS $$
+BEGIN
+    SELECT fn_perfil_mentor_tsvector(p.nombre, p.apellido, NEW.titular, NEW.areas_experiencia, NEW.biografia)
+    INTO NEW.busqueda
+    FROM tb_persona p WHERE p.id_persona = NEW.id_persona;
+    RETURN NEW;
+END;
+$$ LANGUAGE plpgsql;
+
+DROP TRIGGER IF EXISTS tr_perfil_mentor_busqueda ON tb_perfil_mentor;
+CREATE TRIGGER tr_perfil_mentor_busqueda
+    BEFORE INSERT OR UPDATE ON tb_perfil_mentor
+    FOR EACH ROW EXECUTE FUNCTION fn_perfil_mentor_busqueda();
+
+-- Si cambia el nombre se recalcula el texto del perfil
+CREATE OR REPLACE FUNCTION fn_persona_busqueda_mentor() RETURNS TRIGGER AS $$
+BEGIN
+    UPDATE tb_perfil_mentor SET busqueda = NULL WHERE id_persona = NEW.id_persona;
+    RETURN NULL;
+END;
+$$ LANGUAGE plpgsql;
+
+DROP TRIGGER IF EXISTS tr_persona_busqueda_mentor ON tb_persona;
+CREATE TRIGGER tr_persona_busqueda_mentor
+    AFTER UPDATE OF nombre, apellido ON tb_persona
+    FOR EACH ROW EXECUTE FUNCTION fn_persona_busqueda_mentor();
+
+UPDATE tb_perfil_mentor SET busqueda = NULL;
+
+CREATE INDEX IF NOT EXISTS idx_perfil_mentor_busqueda ON tb_perfil_mentor USING GIN (busqueda);
+CREATE INDEX IF NOT EXISTS idx_perfil_mentor_precio ON tb_perfil_mentor (precio_sesion, id_persona);
+CREATE INDEX IF NOT EXISTS idx_perfil_mentor_calificacion
+    ON tb_perfil_mentor ((coalesce(calificacion_promedio, 0)), id_persona);
@@ -0,0 +1,118 @@
+-- Catálogo de habilidades compartido por mentores (experiencia) y mentorados (intereses).
+-- Las categorías tienen a lo sumo dos niveles: categoría y subcategoría.
+CREATE TABLE IF NOT EXISTS tb_categoria_habilidad (
+    id_categoria   SERIAL PRIMARY KEY,
+    id_padre       INTEGER REFERENCES tb_categoria_habilidad (id_categoria) ON DELETE RESTRICT,
+    nombre         VARCHAR(80) NOT NULL,
+    slug           VARCHAR(80) NOT NULL UNIQUE,
+    fecha_creacion TIMESTAMP NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX IF NOT EXISTS idx_categoria_habilidad_padre ON tb_categoria_habilidad (id_padre);
+
+CREATE TABLE IF NOT EXISTS tb_habilidad (
+    id_habilidad   SERIAL PRIMARY KEY,
+    id_categoria   INTEGER NOT NULL REFERENCES tb_categoria_habilidad (id_categoria) ON DELETE RESTRICT,
+    nombre         VARCHAR(80) NOT NULL,
+    slug           VARCHAR(80) NOT NULL UNIQUE,
+    fecha_creacion TIMESTAMP NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX IF NOT EXISTS idx_habilidad_categoria ON tb_habilidad (id_categoria);
+CREATE INDEX IF NOT EXISTS idx_habilidad_slug_prefijo ON tb_habilidad (slug varchar_pattern_ops);
+
+-- Sinónimos y nombres alternativos. Al fusionar habilidades el slug de la eliminada
+-- queda como alias de la que se conserva.
+CREATE TABLE IF NOT EXISTS tb_habilidad_alias (
+    slug         VARCHAR(80) PRIMARY KEY,
+    alias        VARCHAR(80) NOT NULL,
+    id_habilidad INTEGER NOT NULL REFERENCES tb_habilidad (id_habilidad) ON DELETE CASCADE
+);
+
+CREATE INDEX IF NOT EXISTS idx_habilidad_alias_habilidad ON tb_habilidad_alias (id_habilidad);
+CREATE INDEX IF NOT EXISTS idx_habilidad_alias_prefijo ON tb_habilidad_alias (slug varchar_pattern_ops);
+
+CREATE TABLE IF NOT EXISTS tb_persona_habilidad (
+    id_persona   INTEGER NOT NULL REFERENCES tb_persona (id_persona) ON DELETE CASCADE,
+    id_habilidad INTEGER NOT NULL REFERENCES tb_habilidad (id_habilidad) ON DELETE CASCADE,
+    tipo         VARCHAR(20) NOT NULL CHECK (tipo IN ('experiencia', 'interes')),
+    nivel        VARCHAR(20) NOT NULL CHECK (nivel IN ('basico', 'intermedio', 'avanzado', 'experto')),
+    PRIMARY KEY (id_persona, tipo, id_habilidad)
+);
+
+CREATE INDEX IF NOT EXISTS idx_persona_habilidad_habilidad ON tb_persona_habilidad (id_habilidad, tipo);
+
+-- La búsqueda de mentores también indexa los nombres de sus habilidades de experiencia
+DROP TRIGGER IF EXISTS tr_perfil_mentor_busqueda ON tb_perfil_mentor;
+DROP FUNCTION IF EXISTS fn_perfil_mentor_tsvector(TEXT, TEXT, TEXT, TEXT[], TEXT);
+
+CREATE OR REPLACE FUNCTION fn_perfil_mentor_tsvector(nombre TEXT, apellido TEXT, titular TEXT,
+                                                     areas TEXT[], habilidades TEXT, biografia TEXT)
+RETURNS TSVECTOR AS $$
+    SELECT setweight(to_tsvector('simple', coalesce(nombre, '') || ' ' || coalesce(apellido, '')), 'A')
+        || setweight(to_tsvector('spanish', titular), 'A')
+        || setweight(to_tsvector('english', titular), 'A')
+        || setweight(to_tsvector('simple', coalesce(habilidades, '')), 'B')
+        || setweight(to_tsvector('spanish', array_to_string(areas, ' ')), 'B')
+        || setweight(to_tsvector('english', array_to_string(areas, ' ')), 'B')
+        || setweight(to_tsvector('spanish', biografia), 'C')
+        || setweight(to_tsvector('english', biografia), 'C')
+$$ LANGUAGE sql STABLE;
+
+CREATE OR REPLACE FUNCTION fn_perfil_mentor_busqueda() RETURNS TRIGGER AS $$
+BEGIN
+    SELECT fn_perfil_mentor_tsvector(p.nombre, p.apellido, NEW.titular, NEW.areas_experiencia,
+               (SELECT string_agg(h.nombre, ' ')
+                FROM tb_persona_habilidad ph JOIN tb_habilidad h ON h.id_habilidad = ph.id_habilidad
+                WHERE ph.id_persona = NEW.id_persona AND ph.tipo = 'experiencia'),
+               NEW.biografia)
+    INTO NEW.busqueda
+    FROM tb_persona p WHERE p.id_persona = NEW.id_persona;
+    RETURN NEW;
+END;
+$$ LANGUAGE plpgsql;
+
+CREATE TRIGGER tr_perfil_mentor_busqueda
+    BEFORE INSERT OR UPDATE ON tb_perfil_mentor
+    FOR EACH ROW EXECUTE FUNCTION fn_perfil_mentor_busqueda();
+
+CREATE OR REPLACE FUNCTION fn_persona_habilidad_busqueda() RETURNS TRIGGER AS $$
+BEGIN
+    IF TG_OP <> 'INSERT' THEN
+        UPDATE tb_perfil_mentor SET busqueda = NULL WHERE id_persona = OLD.id_persona;
+    END IF;
+    IF TG_OP <> 'DELETE' AND (TG_OP = 'INSERT' OR NEW.id_persona <> OLD.id_persona) THEN
+        UPDATE tb_perfil_mentor SET busqueda = NULL WHERE id_persona = NEW.id_persona;
+    END IF;
+    RETURN NULL;
+END;
+$$ LANGUAGE plpgsql;
+
+DROP TRIGGER IF EXISTS tr_persona_habilidad_busqueda ON tb_persona_habilidad;
+CREATE TRIGGER tr_persona_habilidad_busqueda
+    AFTER INSERT OR UPDATE OR DELETE ON tb_persona_habilidad
+    FOR EACH ROW EXECUTE FUNCTION fn_persona_habilidad_busqueda();
+
+CREATE OR REPLACE FUNCTION fn_habilidad_busqueda() RETURNS TRIGGER AS $$
+BEGIN
+    UPDATE tb_perfil_mentor SET busqueda = NULL
+    WHERE id_persona IN (SELECT id_persona FROM tb_persona_habilidad
+                         WHERE id_habilidad = NEW.id_habilidad AND tipo = 'experiencia');
+    RETURN NULL;
+END;
+$$ LANGUAGE plpgsql;
+
+DROP TRIGGER IF EXISTS tr_habilidad_busqueda ON tb_habilidad;
+CREATE TRIGGER tr_habilidad_busqueda
+    AFTER UPDATE OF nombre ON tb_habilidad
+    FOR EACH ROW EXECUTE FUNCTION fn_habilidad_busqueda();
+
+INSERT INTO tb_permiso (nombre, descripcion) VALUES
+    ('skills:write', 'Administrar el catálogo de habilidades y sus categorías')
+ON CONFLICT (nombre) DO NOTHING;
+
+INSERT INTO tb_rol_permiso (id_rol, id_permiso)
+SELECT r.id_rol, p.id_permiso
+FROM tb_rol r CROSS JOIN tb_permiso p
+WHERE r.nombre_rol = 'admin' AND p.nombre = 'skills:write'
+ON CONFLICT DO NOTHING;
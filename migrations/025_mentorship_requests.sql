@@ -0,0 +1,27 @@
+-- Solicitudes de mentoría de un mentorado a un mentor.
+-- Solo las pendientes cambian de estado; aceptada, rechazada, expirada y retirada son finales.
+CREATE TABLE IF NOT EXISTS tb_solicitud_mentoria (
+    id_solicitud        SERIAL PRIMARY KEY,
+    id_mentorado        INTEGER NOT NULL REFERENCES tb_persona (id_persona) ON DELETE CASCADE,
+    id_mentor           INTEGER NOT NULL REFERENCES tb_persona (id_persona) ON DELETE CASCADE,
+    mensaje             TEXT NOT NULL,
+    objetivos           TEXT[] NOT NULL,
+    estado              VARCHAR(20) NOT NULL DEFAULT 'pendiente'
+                        CHECK (estado IN ('pendiente', 'aceptada', 'rechazada', 'expirada', 'retirada')),
+    motivo_rechazo      TEXT,
+    fecha_creacion      TIMESTAMP NOT NULL DEFAULT NOW(),
+    fecha_actualizacion TIMESTAMP NOT NULL DEFAULT NOW(),
+    fecha_expiracion    TIMESTAMP NOT NULL,
+    fecha_respuesta     TIMESTAMP,
+    CHECK (id_mentorado <> id_mentor),
+    CHECK (estado <> 'rechazada' OR motivo_rechazo IS NOT NULL)
+);
+
+-- Una sola solicitud pendiente por mentorado y mentor
+CREATE UNIQUE INDEX IF NOT EXISTS idx_solicitud_mentoria_pendiente
+    ON tb_solicitud_mentoria (id_mentorado, id_mentor) WHERE estado = 'pendiente';
+
+CREATE INDEX IF NOT EXISTS idx_solicitud_mentoria_mentor ON tb_solicitud_mentoria (id_mentor, estado, fecha_creacion);
+CREATE INDEX IF NOT EXISTS idx_solicitud_mentoria_mentorado ON tb_solicitud_mentoria (id_mentorado, estado, fecha_creacion);
+CREATE INDEX IF NOT EXISTS idx_solicitud_mentoria_expiracion
+    ON tb_solicitud_mentoria (fecha_expiracion) WHERE estado = 'pendiente';
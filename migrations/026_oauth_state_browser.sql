@@ -0,0 +1,3 @@
+-- Hash del nonce de la cookie del navegador que inició el flujo OAuth. El callback tiene que
+-- venir del mismo navegador, así un state obtenido por otra persona no sirve.
+ALTER TABLE tb_oauth_state ADD COLUMN IF NOT EXISTS navegador_hash VARCHAR(64);
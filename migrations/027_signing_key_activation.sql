@@ -0,0 +1,3 @@
+-- Desde cuándo firma cada clave. Una clave nueva se publica en el JWKS un tiempo antes de
+-- empezar a firmar, para que las demás instancias y los que cachean el JWKS ya la conozcan.
+ALTER TABLE tb_clave_firma ADD COLUMN IF NOT EXISTS activa_desde TIMESTAMP NOT NULL DEFAULT NOW();
@@ -0,0 +1,41 @@
+package models
+
+import "time"
+
+// AccountExport son todos los datos guardados sobre un usuario (derecho de acceso).
+type AccountExport struct {
+	FechaExportacion  time.Time           `json:"fecha_exportacion"`
+	Perfil            AccountProfile      `json:"perfil"`
+	Roles             []UserRole          `json:"roles"`
+	Suscripciones     []Subscription      `json:"suscripciones"`
+	Identidades       []Identity          `json:"identidades"`
+	Sesiones          []Session           `json:"sesiones"`
+	TokensPersonales  []PersonalToken     `json:"tokens_personales"`
+	SolicitudesMentor []MentorApplication `json:"solicitudes_mentor"`
+	PerfilMentor      *MentorProfile      `json:"perfil_mentor,omitempty"`
+	Habilidades       []UserSkill         `json:"habilidades"`
+	// SolicitudesMentoria son las enviadas y las recibidas
+	SolicitudesMentoria []MentorshipRequest `json:"solicitudes_mentoria"`
+	EventosLogin        []LoginEvent        `json:"eventos_login"`
+	SegundoFactor       bool                `json:"segundo_factor"`
+}
+
+// AccountProfile son los datos de tb_persona incluidos en la exportación.
+type AccountProfile struct {
+	IDPersona                 int        `json:"id_persona"`
+	Nombre                    string     `json:"nombre"`
+	Apellido                  string     `json:"apellido"`
+	Email                     string     `json:"email"`
+	EmailVerificado           bool       `json:"email_verificado"`
+	TieneContrasena           bool       `json:"tiene_contrasena"`
+	FechaRegistro             time.Time  `json:"fecha_registro"`
+	SuspendidoEn              *time.Time `json:"suspendido_en,omitempty"`
+	EliminacionProgramadaPara *time.Time `json:"eliminacion_programada_para,omitempty"`
+}
+
+// AccountDeletion es el estado de una eliminación de cuenta pendiente.
+type AccountDeletion struct {
+	Pendiente      bool       `json:"pendiente"`
+	SolicitadaEn   *time.Time `json:"solicitada_en,omitempty"`
+	ProgramadaPara *time.Time `json:"programada_para,omitempty"`
+}
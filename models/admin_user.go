@@ -0,0 +1,30 @@
+package models
+
+import "time"
+
+// UserSummary es un usuario en el listado de administración.
+type UserSummary struct {
+	IDPersona       int        `json:"id_persona"`
+	Nombre          string     `json:"nombre"`
+	Apellido        string     `json:"apellido"`
+	Email           string     `json:"email"`
+	EmailVerificado bool       `json:"email_verificado"`
+	Roles           []string   `json:"roles"`
+	FechaRegistro   time.Time  `json:"fecha_registro"`
+	SuspendidoEn    *time.Time `json:"suspendido_en,omitempty"`
+}
+
+// UserDetail es la ficha de un usuario para administración.
+type UserDetail struct {
+	UserSummary
+	TieneContrasena  bool           `json:"tiene_contrasena"`
+	MotivoSuspension *string        `json:"motivo_suspension,omitempty"`
+	SesionesActivas  int            `json:"sesiones_activas"`
+	Suscripciones    []Subscription `json:"suscripciones"`
+}
+
+// UserList es una página del listado de usuarios.
+type UserList struct {
+	Usuarios []UserSummary `json:"usuarios"`
+	Total    int           `json:"total"`
+}
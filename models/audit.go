@@ -0,0 +1,26 @@
+package models
+
+import "time"
+
+// AuditEvent es un registro del log de auditoría.
+type AuditEvent struct {
+	IDAuditoria  int64          `json:"id_auditoria"`
+	Fecha        time.Time      `json:"fecha"`
+	IDActor      *int           `json:"id_actor,omitempty"`
+	IDPersona    *int           `json:"id_persona,omitempty"`
+	Accion       string         `json:"accion"`
+	TipoObjetivo *string        `json:"tipo_objetivo,omitempty"`
+	IDObjetivo   *string        `json:"id_objetivo,omitempty"`
+	Antes        any            `json:"antes,omitempty"`
+	Despues      any            `json:"despues,omitempty"`
+	Detalle      map[string]any `json:"detalle"`
+	IP           *string        `json:"ip,omitempty"`
+	UserAgent    *string        `json:"user_agent,omitempty"`
+	IDSolicitud  *string        `json:"id_solicitud,omitempty"`
+}
+
+// AuditLog es una página del log de auditoría.
+type AuditLog struct {
+	Eventos []AuditEvent `json:"eventos"`
+	Total   int          `json:"total"`
+}
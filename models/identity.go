@@ -0,0 +1,11 @@
+package models
+
+import "time"
+
+// Identity representa una identidad externa (proveedor OAuth) vinculada a una cuenta.
+type Identity struct {
+	Proveedor        string     `json:"proveedor"`
+	Email            string     `json:"email"`
+	FechaVinculacion time.Time  `json:"fecha_vinculacion"`
+	UltimoLogin      *time.Time `json:"ultimo_login,omitempty"`
+}
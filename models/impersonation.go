@@ -0,0 +1,16 @@
+package models
+
+import "time"
+
+// Impersonation es una suplantación de un usuario por un administrador de soporte.
+type Impersonation struct {
+	IDSuplantacion  int        `json:"id_suplantacion"`
+	IDActor         int        `json:"id_actor"`
+	IDPersona       int        `json:"id_persona"`
+	Email           string     `json:"email"`
+	Motivo          string     `json:"motivo"`
+	SoloLectura     bool       `json:"solo_lectura"`
+	FechaCreacion   time.Time  `json:"fecha_creacion"`
+	FechaExpiracion time.Time  `json:"fecha_expiracion"`
+	FinalizadaEn    *time.Time `json:"finalizada_en,omitempty"`
+}
@@ -0,0 +1,13 @@
+package models
+
+import "time"
+
+// LoginEvent es un intento de inicio de sesión registrado para auditoría.
+type LoginEvent struct {
+	IDEvento  int64     `json:"id_evento"`
+	Email     string    `json:"email"`
+	IDPersona *int      `json:"id_persona,omitempty"`
+	IP        string    `json:"ip"`
+	Resultado string    `json:"resultado"`
+	Fecha     time.Time `json:"fecha"`
+}
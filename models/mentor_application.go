@@ -0,0 +1,28 @@
+package models
+
+import "time"
+
+// Estados de una solicitud para ser mentor
+const (
+	MentorApplicationPending          = "pendiente"
+	MentorApplicationChangesRequested = "cambios_solicitados"
+	MentorApplicationApproved         = "aprobada"
+	MentorApplicationRejected         = "rechazada"
+)
+
+// MentorApplication es la solicitud de un usuario para ser mentor.
+type MentorApplication struct {
+	IDSolicitud        int        `json:"id_solicitud"`
+	IDPersona          int        `json:"id_persona"`
+	Nombre             string     `json:"nombre,omitempty"`
+	Email              string     `json:"email,omitempty"`
+	AreasExperiencia   string     `json:"areas_experiencia"`
+	Experiencia        string     `json:"experiencia"`
+	LinkedInURL        string     `json:"linkedin_url"`
+	Motivacion         string     `json:"motivacion"`
+	Estado             string     `json:"estado"`
+	ComentarioRevision *string    `json:"comentario_revision,omitempty"`
+	FechaCreacion      time.Time  `json:"fecha_creacion"`
+	FechaActualizacion time.Time  `json:"fecha_actualizacion"`
+	FechaRevision      *time.Time `json:"fecha_revision,omitempty"`
+}
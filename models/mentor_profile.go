@@ -0,0 +1,29 @@
+package models
+
+import "time"
+
+// MentorProfile es el perfil público de un mentor. Nombre y apellido vienen de tb_persona.
+type MentorProfile struct {
+	IDPersona        int      `json:"id_persona"`
+	Nombre           string   `json:"nombre"`
+	Apellido         string   `json:"apellido"`
+	Titular          string   `json:"titular"`
+	Biografia        string   `json:"biografia"`
+	AreasExperiencia []string `json:"areas_experiencia"`
+	AniosExperiencia int      `json:"anios_experiencia"`
+	Idiomas          []string `json:"idiomas"`
+	ZonaHoraria      string   `json:"zona_horaria"`
+	PrecioSesion     float64  `json:"precio_sesion"`
+	LinkedInURL      *string  `json:"linkedin_url,omitempty"`
+	GitHubURL        *string  `json:"github_url,omitempty"`
+	AvatarURL        *string  `json:"avatar_url,omitempty"`
+	// Habilidades son las del catálogo marcadas como experiencia
+	Habilidades []UserSkill `json:"habilidades"`
+	// Disponible indica si acepta nuevos mentorados
+	Disponible bool `json:"disponible"`
+	// CalificacionPromedio es nil mientras el mentor no tenga calificaciones
+	CalificacionPromedio   *float64  `json:"calificacion_promedio"`
+	CantidadCalificaciones int       `json:"cantidad_calificaciones"`
+	FechaCreacion          time.Time `json:"fecha_creacion"`
+	FechaActualizacion     time.Time `json:"fecha_actualizacion"`
+}
@@ -0,0 +1,27 @@
+package models
+
+// MentorSearchResult es una página de resultados de la búsqueda de mentores.
+type MentorSearchResult struct {
+	Mentores []MentorProfile `json:"mentores"`
+	// SiguienteCursor es nil en la última página
+	SiguienteCursor *string `json:"siguiente_cursor"`
+	// Facetas solo se calculan en la primera página (sin cursor)
+	Facetas *MentorFacets `json:"facetas,omitempty"`
+}
+
+// MentorFacets son los conteos de los mentores que cumplen los filtros de la búsqueda.
+type MentorFacets struct {
+	Total       int          `json:"total"`
+	Disponibles int          `json:"disponibles"`
+	Habilidades []FacetCount `json:"habilidades"`
+	Idiomas     []FacetCount `json:"idiomas"`
+	Precios     []FacetCount `json:"precios"`
+}
+
+// FacetCount es la cantidad de resultados para un valor de una faceta.
+type FacetCount struct {
+	Valor string `json:"valor"`
+	// Nombre es el texto a mostrar cuando Valor es un slug
+	Nombre   string `json:"nombre,omitempty"`
+	Cantidad int    `json:"cantidad"`
+}
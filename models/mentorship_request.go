@@ -0,0 +1,29 @@
+package models
+
+import "time"
+
+// Estados de una solicitud de mentoría. Solo se sale de pendiente; el resto son finales.
+const (
+	MentorshipRequestPending   = "pendiente"
+	MentorshipRequestAccepted  = "aceptada"
+	MentorshipRequestDeclined  = "rechazada"
+	MentorshipRequestExpired   = "expirada"
+	MentorshipRequestWithdrawn = "retirada"
+)
+
+// MentorshipRequest es el pedido de un mentorado para que un mentor lo acompañe.
+type MentorshipRequest struct {
+	IDSolicitud        int        `json:"id_solicitud"`
+	IDMentorado        int        `json:"id_mentorado"`
+	NombreMentorado    string     `json:"nombre_mentorado"`
+	IDMentor           int        `json:"id_mentor"`
+	NombreMentor       string     `json:"nombre_mentor"`
+	Mensaje            string     `json:"mensaje"`
+	Objetivos          []string   `json:"objetivos"`
+	Estado             string     `json:"estado"`
+	MotivoRechazo      *string    `json:"motivo_rechazo,omitempty"`
+	FechaCreacion      time.Time  `json:"fecha_creacion"`
+	FechaActualizacion time.Time  `json:"fecha_actualizacion"`
+	FechaExpiracion    time.Time  `json:"fecha_expiracion"`
+	FechaRespuesta     *time.Time `json:"fecha_respuesta,omitempty"`
+}
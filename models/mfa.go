@@ -0,0 +1,14 @@
+package models
+
+// MFAStatus es el estado de la verificación en dos pasos de un usuario.
+type MFAStatus struct {
+	Habilitado       bool `json:"habilitado"`
+	CodigosRestantes int  `json:"codigos_restantes"`
+}
+
+// TOTPSetup son los datos para registrar la cuenta en la app de autenticación.
+// OtpauthURI es el contenido del código QR.
+type TOTPSetup struct {
+	Secreto    string `json:"secreto"`
+	OtpauthURI string `json:"otpauth_uri"`
+}
@@ -0,0 +1,15 @@
+package models
+
+import "time"
+
+// PersonalToken es un token personal (API key) sin el valor secreto.
+type PersonalToken struct {
+	IDToken         int        `json:"id_token"`
+	Nombre          string     `json:"nombre"`
+	Prefijo         string     `json:"prefijo"`
+	Scopes          []string   `json:"scopes"`
+	FechaCreacion   time.Time  `json:"fecha_creacion"`
+	FechaExpiracion time.Time  `json:"fecha_expiracion"`
+	UltimoUso       *time.Time `json:"ultimo_uso,omitempty"`
+	Revocado        bool       `json:"revocado"`
+}
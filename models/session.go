@@ -0,0 +1,13 @@
+package models
+
+import "time"
+
+// Session es una sesión iniciada por el usuario en un dispositivo.
+type Session struct {
+	IDSesion        int       `json:"id_sesion"`
+	UserAgent       string    `json:"user_agent"`
+	IP              string    `json:"ip"`
+	FechaCreacion   time.Time `json:"fecha_creacion"`
+	UltimaActividad time.Time `json:"ultima_actividad"`
+	Actual          bool      `json:"actual"`
+}
@@ -0,0 +1,47 @@
+package models
+
+import "time"
+
+// Tipos de relación entre un usuario y una habilidad
+const (
+	SkillTypeExpertise = "experiencia"
+	SkillTypeInterest  = "interes"
+)
+
+// Niveles de dominio de una habilidad, de menor a mayor
+const (
+	SkillLevelBasic        = "basico"
+	SkillLevelIntermediate = "intermedio"
+	SkillLevelAdvanced     = "avanzado"
+	SkillLevelExpert       = "experto"
+)
+
+// SkillCategory es una categoría del catálogo. Las de primer nivel traen sus subcategorías.
+type SkillCategory struct {
+	IDCategoria   int             `json:"id_categoria"`
+	IDPadre       *int            `json:"id_padre,omitempty"`
+	Nombre        string          `json:"nombre"`
+	Slug          string          `json:"slug"`
+	Subcategorias []SkillCategory `json:"subcategorias,omitempty"`
+}
+
+// Skill es una habilidad del catálogo.
+type Skill struct {
+	IDHabilidad int    `json:"id_habilidad"`
+	Nombre      string `json:"nombre"`
+	Slug        string `json:"slug"`
+	IDCategoria int    `json:"id_categoria"`
+	// Categoria es la ruta de la categoría, por ejemplo "Programación / Backend"
+	Categoria     string    `json:"categoria"`
+	Alias         []string  `json:"alias"`
+	FechaCreacion time.Time `json:"fecha_creacion"`
+}
+
+// UserSkill es una habilidad de un usuario con su nivel.
+type UserSkill struct {
+	IDHabilidad int    `json:"id_habilidad"`
+	Nombre      string `json:"nombre"`
+	Slug        string `json:"slug"`
+	Tipo        string `json:"tipo"`
+	Nivel       string `json:"nivel"`
+}
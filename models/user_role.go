@@ -0,0 +1,12 @@
+package models
+
+import "time"
+
+// UserRole es un rol asignado a un usuario, con los datos de perfil propios de ese rol.
+type UserRole struct {
+	NombreRol       string         `json:"nombre_rol"`
+	Descripcion     string         `json:"descripcion"`
+	Datos           map[string]any `json:"datos"`
+	FechaAsignacion time.Time      `json:"fecha_asignacion"`
+	Activo          bool           `json:"activo"`
+}
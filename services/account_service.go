@@ -0,0 +1,412 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"mentorly-backend/models"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+const (
+	// DefaultAccountDeletionGrace es el tiempo para arrepentirse antes de borrar la cuenta
+	DefaultAccountDeletionGrace = 30 * 24 * time.Hour
+
+	accountPurgeInterval  = time.Hour
+	accountPurgeBatchSize = 50
+)
+
+// AccountDeletionGraceFromEnv lee ACCOUNT_DELETION_GRACE (por ejemplo "720h")
+func AccountDeletionGraceFromEnv() time.Duration {
+	grace := DefaultAccountDeletionGrace
+	envDuration("ACCOUNT_DELETION_GRACE", &grace)
+	return grace
+}
+
+// AccountService maneja los derechos del titular de los datos: exportación y eliminación de la cuenta
+type AccountService struct {
+	db                 *pgxpool.Pool
+	mailer             Mailer
+	grace              time.Duration
+	roles              *RoleService
+	subscriptions      *SubscriptionService
+	identities         *IdentityService
+	sessions           *SessionService
+	personalTokens     *PersonalTokenService
+	mentorApplications *MentorApplicationService
+	mentorProfiles     *MentorProfileService
+	skills             *SkillService
+	mentorshipRequests *MentorshipRequestService
+}
+
+// NewAccountService crea el servicio. grace es el período en que la eliminación se puede cancelar.
+func NewAccountService(db *pgxpool.Pool, mailer Mailer, grace time.Duration) *AccountService {
+	return &AccountService{
+		db:                 db,
+		mailer:             mailer,
+		grace:              grace,
+		roles:              NewRoleService(db),
+		subscriptions:      NewSubscriptionService(db),
+		identities:         NewIdentityService(db),
+		sessions:           NewSessionService(db),
+		personalTokens:     NewPersonalTokenService(db),
+		mentorApplications: &MentorApplicationService{db: db}, // solo lectura, no envía emails
+		mentorProfiles:     NewMentorProfileService(db),
+		skills:             NewSkillService(db),
+		mentorshipRequests: &MentorshipRequestService{db: db}, // solo lectura, no envía emails
+	}
+}
+
+// Export reúne todos los datos guardados sobre el usuario. No incluye secretos
+// (contraseña, secreto TOTP, hashes de tokens).
+func (s *AccountService) Export(ctx context.Context, idPersona int) (*models.AccountExport, error) {
+	export := &models.AccountExport{FechaExportacion: time.Now()}
+
+	p := &export.Perfil
+	err := s.db.QueryRow(ctx,
+		`SELECT id_persona, nombre, apellido, email, email_verificado, contrasena IS NOT NULL,
+		        fecha_registro, suspendido_en, eliminacion_programada_para,
+		        EXISTS (SELECT 1 FROM tb_mfa_totp WHERE id_persona = $1 AND confirmado)
+		 FROM tb_persona WHERE id_persona = $1`,
+		idPersona,
+	).Scan(&p.IDPersona, &p.Nombre, &p.Apellido, &p.Email, &p.EmailVerificado, &p.TieneContrasena,
+		&p.FechaRegistro, &p.SuspendidoEn, &p.EliminacionProgramadaPara, &export.SegundoFactor)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, ErrUserNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	if export.Roles, err = s.roles.GetUserRoles(ctx, idPersona); err != nil {
+		return nil, err
+	}
+	if export.Suscripciones, err = s.subscriptions.GetUserSubscriptions(ctx, idPersona); err != nil {
+		return nil, err
+	}
+	if export.Identidades, err = s.identities.ListIdentities(ctx, idPersona); err != nil {
+		return nil, err
+	}
+	if export.Sesiones, err = s.sessions.List(ctx, idPersona, 0); err != nil {
+		return nil, err
+	}
+	if export.TokensPersonales, err = s.personalTokens.List(ctx, idPersona); err != nil {
+		return nil, err
+	}
+	if export.SolicitudesMentor, err = s.mentorApplications.ListByUser(ctx, idPersona); err != nil {
+		return nil, err
+	}
+	if export.PerfilMentor, err = s.mentorProfiles.Get(ctx, idPersona); err != nil && !errors.Is(err, ErrMentorProfileNotFound) {
+		return nil, err
+	}
+	if export.Habilidades, err = s.skills.GetUserSkills(ctx, idPersona); err != nil {
+		return nil, err
+	}
+	if export.SolicitudesMentoria, err = s.mentorshipRequests.ListByUser(ctx, idPersona); err != nil {
+		return nil, err
+	}
+	if export.EventosLogin, err = s.loginEvents(ctx, idPersona, p.Email); err != nil {
+		return nil, err
+	}
+
+	return export, nil
+}
+
+func (s *AccountService) loginEvents(ctx context.Context, idPersona int, email string) ([]models.LoginEvent, error) {
+	rows, err := s.db.Query(ctx,
+		`SELECT id_evento, email, id_persona, ip, resultado, fecha
+		 FROM tb_evento_login
+		 WHERE id_persona = $1 OR email = $2
+		 ORDER BY fecha DESC, id_evento DESC`,
+		idPersona, normalizeEmail(email),
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	events := []models.LoginEvent{}
+	for rows.Next() {
+		var e models.LoginEvent
+		if err := rows.Scan(&e.IDEvento, &e.Email, &e.IDPersona, &e.IP, &e.Resultado, &e.Fecha); err != nil {
+			return nil, err
+		}
+		events = append(events, e)
+	}
+	return events, rows.Err()
+}
+
+// DeletionStatus indica si la cuenta tiene una eliminación pendiente
+func (s *AccountService) DeletionStatus(ctx context.Context, idPersona int) (*models.AccountDeletion, error) {
+	status := &models.AccountDeletion{}
+	err := s.db.QueryRow(ctx,
+		"SELECT eliminacion_solicitada_en, eliminacion_programada_para FROM tb_persona WHERE id_persona = $1",
+		idPersona,
+	).Scan(&status.SolicitadaEn, &status.ProgramadaPara)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, ErrUserNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	status.Pendiente = status.ProgramadaPara != nil
+	return status, nil
+}
+
+// RequestDeletion programa la eliminación de la cuenta al terminar el período de gracia.
+// Cierra todas las sesiones y revoca los tokens personales; el usuario puede volver a
+// iniciar sesión para cancelarla.
+func (s *AccountService) RequestDeletion(ctx context.Context, idPersona int, req AuditRequest) (*models.AccountDeletion, error) {
+	tx, err := s.db.Begin(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback(ctx)
+
+	status := &models.AccountDeletion{Pendiente: true}
+	var email, nombre string
+	err = tx.QueryRow(ctx,
+		`UPDATE tb_persona
+		 SET eliminacion_solicitada_en = NOW(), eliminacion_programada_para = NOW() + $2::float8 * INTERVAL '1 second'
+		 WHERE id_persona = $1 AND eliminacion_programada_para IS NULL
+		 RETURNING email, nombre, eliminacion_solicitada_en, eliminacion_programada_para`,
+		idPersona, s.grace.Seconds(),
+	).Scan(&email, &nombre, &status.SolicitadaEn, &status.ProgramadaPara)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, ErrAccountDeletionPending
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	if _, err := revokeAllForUser(ctx, tx, idPersona); err != nil {
+		return nil, err
+	}
+	if _, err := tx.Exec(ctx,
+		"UPDATE tb_token_personal SET revocado_en = NOW() WHERE id_persona = $1 AND revocado_en IS NULL",
+		idPersona,
+	); err != nil {
+		return nil, err
+	}
+
+	err = recordAudit(ctx, tx, AuditEntry{
+		IDActor:   &idPersona,
+		IDPersona: &idPersona,
+		Accion:    AuditAccountDeletionRequested,
+		Detalle:   map[string]any{"programada_para": status.ProgramadaPara},
+		Request:   req,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return nil, err
+	}
+
+	err = s.mailer.Send(ctx, EmailMessage{
+		To:      email,
+		Subject: "Tu cuenta de Mentorly se va a eliminar",
+		Body: fmt.Sprintf("Hola %s,\n\nRecibimos tu pedido para eliminar tu cuenta. La vamos a eliminar el %s.\n\n"+
+			"Si cambiás de opinión, iniciá sesión antes de esa fecha y cancelá la eliminación desde tu perfil.\n",
+			nombre, status.ProgramadaPara.Format("02/01/2006")),
+	})
+	if err != nil {
+		log.Printf("Error al enviar aviso de eliminación de cuenta: %v", err)
+	}
+
+	return status, nil
+}
+
+// CancelDeletion cancela una eliminación pendiente
+func (s *AccountService) CancelDeletion(ctx context.Context, idPersona int, req AuditRequest) error {
+	tx, err := s.db.Begin(ctx)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback(ctx)
+
+	result, err := tx.Exec(ctx,
+		`UPDATE tb_persona SET eliminacion_solicitada_en = NULL, eliminacion_programada_para = NULL
+		 WHERE id_persona = $1 AND eliminacion_programada_para IS NOT NULL`,
+		idPersona,
+	)
+	if err != nil {
+		return err
+	}
+	if result.RowsAffected() == 0 {
+		return ErrAccountDeletionNotPending
+	}
+
+	err = recordAudit(ctx, tx, AuditEntry{
+		IDActor:   &idPersona,
+		IDPersona: &idPersona,
+		Accion:    AuditAccountDeletionCancelled,
+		Request:   req,
+	})
+	if err != nil {
+		return err
+	}
+
+	return tx.Commit(ctx)
+}
+
+// StartPurge procesa periódicamente las eliminaciones vencidas, hasta que se cancele ctx
+func (s *AccountService) StartPurge(ctx context.Context) {
+	ticker := time.NewTicker(accountPurgeInterval)
+	go func() {
+		defer ticker.Stop()
+		for {
+			if n, err := s.PurgeDue(ctx); err != nil {
+				log.Printf("Error al eliminar cuentas: %v", err)
+			} else if n > 0 {
+				log.Printf("Cuentas eliminadas o anonimizadas: %d", n)
+			}
+
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+			}
+		}
+	}()
+}
+
+// PurgeDue elimina las cuentas cuyo período de gracia terminó. Devuelve cuántas procesó.
+func (s *AccountService) PurgeDue(ctx context.Context) (int, error) {
+	total := 0
+	for {
+		n, err := s.purgeBatch(ctx)
+		total += n
+		if err != nil || n < accountPurgeBatchSize {
+			return total, err
+		}
+	}
+}
+
+func (s *AccountService) purgeBatch(ctx context.Context) (int, error) {
+	rows, err := s.db.Query(ctx,
+		`SELECT id_persona FROM tb_persona
+		 WHERE eliminacion_programada_para <= NOW()
+		 ORDER BY eliminacion_programada_para
+		 LIMIT $1`,
+		accountPurgeBatchSize,
+	)
+	if err != nil {
+		return 0, err
+	}
+	ids, err := pgx.CollectRows(rows, pgx.RowTo[int])
+	if err != nil {
+		return 0, err
+	}
+
+	for i, idPersona := range ids {
+		if err := s.purge(ctx, idPersona); err != nil {
+			return i, fmt.Errorf("cuenta %d: %w", idPersona, err)
+		}
+	}
+	return len(ids), nil
+}
+
+// purge borra la cuenta. Si tiene suscripciones la anonimiza para conservar los
+// registros de facturación.
+func (s *AccountService) purge(ctx context.Context, idPersona int) error {
+	tx, err := s.db.Begin(ctx)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback(ctx)
+
+	// SKIP LOCKED evita que dos instancias procesen la misma cuenta
+	var email string
+	var hasSubscriptions bool
+	err = tx.QueryRow(ctx,
+		`SELECT email, EXISTS (SELECT 1 FROM tb_suscripcion WHERE id_persona = $1)
+		 FROM tb_persona
+		 WHERE id_persona = $1 AND eliminacion_programada_para <= NOW()
+		 FOR UPDATE SKIP LOCKED`,
+		idPersona,
+	).Scan(&email, &hasSubscriptions)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+
+	// Los eventos y bloqueos de login guardan el email y no se borran en cascada
+	if _, err := tx.Exec(ctx,
+		"DELETE FROM tb_evento_login WHERE id_persona = $1 OR email = $2",
+		idPersona, normalizeEmail(email),
+	); err != nil {
+		return err
+	}
+	if _, err := tx.Exec(ctx,
+		"DELETE FROM tb_bloqueo_login WHERE tipo = $1 AND clave = $2",
+		lockKeyEmail, normalizeEmail(email),
+	); err != nil {
+		return err
+	}
+
+	accion := AuditAccountDeleted
+	if hasSubscriptions {
+		accion = AuditAccountAnonymized
+		err = anonymizeAccount(ctx, tx, idPersona)
+	} else {
+		_, err = tx.Exec(ctx, "DELETE FROM tb_persona WHERE id_persona = $1", idPersona)
+	}
+	if err != nil {
+		return err
+	}
+
+	if err := recordAudit(ctx, tx, AuditEntry{IDPersona: &idPersona, Accion: accion}); err != nil {
+		return err
+	}
+
+	return tx.Commit(ctx)
+}
+
+// anonymizeAccount borra los datos personales y deja la fila de tb_persona para las suscripciones
+func anonymizeAccount(ctx context.Context, tx pgx.Tx, idPersona int) error {
+	for _, table := range []string{
+		"tb_persona_rol",
+		"tb_identidad_externa",
+		"tb_refresh_token",
+		"tb_sesion",
+		"tb_token_personal",
+		"tb_token_usuario",
+		"tb_oauth_state",
+		"tb_mfa_totp",
+		"tb_codigo_recuperacion",
+		"tb_desafio_mfa",
+		"tb_solicitud_mentor",
+		"tb_perfil_mentor",
+		"tb_persona_habilidad",
+	} {
+		if _, err := tx.Exec(ctx, "DELETE FROM "+table+" WHERE id_persona = $1", idPersona); err != nil {
+			return err
+		}
+	}
+	// Los mensajes y objetivos son datos personales de ambas partes
+	if _, err := tx.Exec(ctx,
+		"DELETE FROM tb_solicitud_mentoria WHERE id_mentorado = $1 OR id_mentor = $1",
+		idPersona,
+	); err != nil {
+		return err
+	}
+
+	_, err := tx.Exec(ctx,
+		`UPDATE tb_persona
+		 SET nombre = 'Usuario', apellido = 'eliminado', email = 'eliminado-' || id_persona || '@mentorly.invalid',
+		     contrasena = NULL, email_verificado = FALSE,
+		     eliminacion_solicitada_en = NULL, eliminacion_programada_para = NULL, anonimizado_en = NOW(),
+		     suspendido_en = NULL, motivo_suspension = NULL, suspendido_por = NULL
+		 WHERE id_persona = $1`,
+		idPersona,
+	)
+	return err
+}
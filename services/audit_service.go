@@ -0,0 +1,326 @@
+package services
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"mentorly-backend/models"
+	"reflect"
+	"strings"
+	"time"
+
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+// Acciones registradas en la auditoría
+const (
+	AuditAccountExported          = "cuenta.exportada"
+	AuditAccountDeletionRequested = "cuenta.eliminacion_solicitada"
+	AuditAccountDeletionCancelled = "cuenta.eliminacion_cancelada"
+	AuditAccountDeleted           = "cuenta.eliminada"
+	AuditAccountAnonymized        = "cuenta.anonimizada"
+
+	AuditImpersonationStarted = "suplantacion.iniciada"
+	AuditImpersonationEnded   = "suplantacion.finalizada"
+	AuditImpersonatedRequest  = "suplantacion.solicitud"
+
+	// Los intentos fallidos de inicio de sesión quedan en tb_evento_login
+	AuditLogin            = "sesion.iniciada"
+	AuditSessionRevoked   = "sesion.cerrada"
+	AuditSessionsRevoked  = "sesion.otras_cerradas"
+	AuditPasswordChange   = "contrasena.cambiada"
+	AuditPasswordSet      = "contrasena.establecida"
+	AuditPasswordReset    = "contrasena.restablecida"
+	AuditMFAEnabled       = "mfa.activado"
+	AuditMFADisabled      = "mfa.desactivado"
+	AuditReauthFailed     = "reautenticacion.fallida"
+	AuditIdentityLinked   = "identidad.vinculada"
+	AuditIdentityUnlinked = "identidad.desvinculada"
+	AuditTokenCreated     = "token_personal.creado"
+	AuditTokenRevoked     = "token_personal.revocado"
+
+	AuditRoleSelected      = "rol.elegido"
+	AuditRoleRemoved       = "rol.dejado"
+	AuditActiveRoleChanged = "rol.activo_cambiado"
+	AuditRoleAssigned      = "rol.asignado"
+	AuditRoleRevoked       = "rol.quitado"
+
+	AuditUserSuspended             = "usuario.suspendido"
+	AuditUserReactivated           = "usuario.reactivado"
+	AuditUserLoggedOut             = "usuario.sesiones_cerradas"
+	AuditUserEmailVerified         = "usuario.verificacion_email"
+	AuditLoginUnlocked             = "login.desbloqueado"
+	AuditMentorApplicationReviewed = "solicitud_mentor.revisada"
+
+	AuditPlanCreated = "plan.creado"
+	AuditPlanUpdated = "plan.modificado"
+	AuditPlanDeleted = "plan.eliminado"
+
+	AuditSkillCategoryCreated = "categoria_habilidad.creada"
+	AuditSkillCategoryUpdated = "categoria_habilidad.modificada"
+	AuditSkillCategoryDeleted = "categoria_habilidad.eliminada"
+	AuditSkillCreated         = "habilidad.creada"
+	AuditSkillUpdated         = "habilidad.modificada"
+	AuditSkillDeleted         = "habilidad.eliminada"
+	AuditSkillMerged          = "habilidad.fusionada"
+
+	AuditLogExported = "auditoria.exportada"
+)
+
+// Tipos de objeto afectados por una acción
+const (
+	AuditTargetUser              = "usuario"
+	AuditTargetSession           = "sesion"
+	AuditTargetPlan              = "plan"
+	AuditTargetRole              = "rol"
+	AuditTargetIdentity          = "identidad"
+	AuditTargetPersonalToken     = "token_personal"
+	AuditTargetMentorApplication = "solicitud_mentor"
+	AuditTargetImpersonation     = "suplantacion"
+	AuditTargetSkillCategory     = "categoria_habilidad"
+	AuditTargetSkill             = "habilidad"
+)
+
+const auditUserAgentMaxLength = 500
+
+// AuditRequest identifica la solicitud HTTP que originó un evento
+type AuditRequest struct {
+	IP          string
+	UserAgent   string
+	IDSolicitud string
+}
+
+// AuditEntry es un evento a registrar. IDActor es quien hizo la acción (nil si fue el sistema)
+// e IDPersona la cuenta afectada.
+type AuditEntry struct {
+	IDActor   *int
+	IDPersona *int
+	Accion    string
+	// Objeto sobre el que se hizo la acción, por ejemplo "plan" y "7"
+	TipoObjetivo string
+	IDObjetivo   string
+	// Estado del objeto antes y después. Si hay ambos se guardan solo los campos que cambiaron.
+	Antes   any
+	Despues any
+	Detalle map[string]any
+	Request AuditRequest
+}
+
+// AuditFilter son los criterios de búsqueda en el log. Los campos vacíos no filtran.
+type AuditFilter struct {
+	IDActor   *int
+	IDPersona *int
+	// Accion es exacta, o un prefijo si termina en punto ("plan." son todas las de planes)
+	Accion       string
+	TipoObjetivo string
+	IDObjetivo   string
+	IDSolicitud  string
+	Desde        *time.Time
+	Hasta        *time.Time
+	Limit        int
+	Offset       int
+}
+
+// AuditService registra las acciones sensibles para auditoría
+type AuditService struct {
+	db *pgxpool.Pool
+}
+
+// NewAuditService crea una nueva instancia del servicio de auditoría
+func NewAuditService(db *pgxpool.Pool) *AuditService {
+	return &AuditService{db: db}
+}
+
+// Record guarda el evento. Un error se registra en el log y no interrumpe la operación auditada;
+// cuando el registro tiene que ser atómico con la operación se usa recordAudit dentro de la transacción.
+func (s *AuditService) Record(ctx context.Context, entry AuditEntry) {
+	if err := recordAudit(ctx, s.db, entry); err != nil {
+		log.Printf("Error al registrar auditoría %s: %v", entry.Accion, err)
+	}
+}
+
+// recordAudit guarda el evento con el pool o dentro de una transacción
+func recordAudit(ctx context.Context, db dbExecutor, entry AuditEntry) error {
+	detalle := entry.Detalle
+	if detalle == nil {
+		detalle = map[string]any{}
+	}
+	encoded, err := json.Marshal(detalle)
+	if err != nil {
+		return err
+	}
+
+	antes, despues, err := auditDiff(entry.Antes, entry.Despues)
+	if err != nil {
+		return err
+	}
+
+	_, err = db.Exec(ctx,
+		`INSERT INTO tb_auditoria (id_actor, id_persona, accion, tipo_objetivo, id_objetivo, antes, despues,
+		                           detalle, ip, user_agent, id_solicitud)
+		 VALUES ($1, $2, $3, NULLIF($4, ''), NULLIF($5, ''), $6, $7, $8, NULLIF($9, ''), NULLIF($10, ''), NULLIF($11, ''))`,
+		entry.IDActor, entry.IDPersona, entry.Accion, entry.TipoObjetivo, entry.IDObjetivo, antes, despues,
+		encoded, entry.Request.IP, truncate(entry.Request.UserAgent, auditUserAgentMaxLength), entry.Request.IDSolicitud,
+	)
+	return err
+}
+
+// auditDiff serializa el estado anterior y el nuevo. Si los dos son objetos deja solo las
+// claves que cambiaron. Devuelve nil para los que no se indicaron.
+func auditDiff(antes any, despues any) ([]byte, []byte, error) {
+	a, err := marshalAuditState(antes)
+	if err != nil {
+		return nil, nil, err
+	}
+	d, err := marshalAuditState(despues)
+	if err != nil {
+		return nil, nil, err
+	}
+	if a == nil || d == nil {
+		return a, d, nil
+	}
+
+	var antesMap, despuesMap map[string]any
+	if json.Unmarshal(a, &antesMap) != nil || json.Unmarshal(d, &despuesMap) != nil {
+		return a, d, nil
+	}
+	for key, value := range antesMap {
+		if other, ok := despuesMap[key]; ok && reflect.DeepEqual(value, other) {
+			delete(antesMap, key)
+			delete(despuesMap, key)
+		}
+	}
+
+	if a, err = json.Marshal(antesMap); err != nil {
+		return nil, nil, err
+	}
+	if d, err = json.Marshal(despuesMap); err != nil {
+		return nil, nil, err
+	}
+	return a, d, nil
+}
+
+func marshalAuditState(state any) ([]byte, error) {
+	if state == nil {
+		return nil, nil
+	}
+	return json.Marshal(state)
+}
+
+const auditEventColumns = `id_auditoria, fecha, id_actor, id_persona, accion, tipo_objetivo, id_objetivo,
+	antes, despues, detalle, ip, user_agent, id_solicitud`
+
+// Search lista los eventos que cumplen el filtro, los más recientes primero, junto con el total
+func (s *AuditService) Search(ctx context.Context, filter AuditFilter) (*models.AuditLog, error) {
+	where, args := auditConditions(filter)
+	argID := len(args) + 1
+
+	rows, err := s.db.Query(ctx,
+		fmt.Sprintf(`SELECT %s, COUNT(*) OVER ()
+		 FROM tb_auditoria
+		 %s
+		 ORDER BY fecha DESC, id_auditoria DESC
+		 LIMIT $%d OFFSET $%d`, auditEventColumns, where, argID, argID+1),
+		append(args, filter.Limit, filter.Offset)...,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	list := &models.AuditLog{Eventos: []models.AuditEvent{}}
+	for rows.Next() {
+		var e models.AuditEvent
+		if err := rows.Scan(auditEventFields(&e, &list.Total)...); err != nil {
+			return nil, err
+		}
+		list.Eventos = append(list.Eventos, e)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	// Con un offset fuera de rango no vuelven filas, pero el total sigue siendo útil
+	if len(list.Eventos) == 0 && filter.Offset > 0 {
+		if err := s.db.QueryRow(ctx, "SELECT COUNT(*) FROM tb_auditoria "+where, args...).Scan(&list.Total); err != nil {
+			return nil, err
+		}
+	}
+
+	return list, nil
+}
+
+// Export recorre todos los eventos que cumplen el filtro, del más antiguo al más reciente,
+// sin paginar. Se usa para generar el CSV sin cargar todo en memoria.
+func (s *AuditService) Export(ctx context.Context, filter AuditFilter, fn func(models.AuditEvent) error) error {
+	where, args := auditConditions(filter)
+
+	rows, err := s.db.Query(ctx,
+		fmt.Sprintf("SELECT %s FROM tb_auditoria %s ORDER BY fecha, id_auditoria", auditEventColumns, where),
+		args...,
+	)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var e models.AuditEvent
+		if err := rows.Scan(auditEventFields(&e)...); err != nil {
+			return err
+		}
+		if err := fn(e); err != nil {
+			return err
+		}
+	}
+	return rows.Err()
+}
+
+func auditEventFields(e *models.AuditEvent, extra ...any) []any {
+	return append([]any{&e.IDAuditoria, &e.Fecha, &e.IDActor, &e.IDPersona, &e.Accion, &e.TipoObjetivo,
+		&e.IDObjetivo, &e.Antes, &e.Despues, &e.Detalle, &e.IP, &e.UserAgent, &e.IDSolicitud}, extra...)
+}
+
+func auditConditions(filter AuditFilter) (string, []any) {
+	var conditions []string
+	var args []any
+	add := func(condition string, arg any) {
+		args = append(args, arg)
+		conditions = append(conditions, fmt.Sprintf(condition, len(args)))
+	}
+
+	if filter.IDActor != nil {
+		add("id_actor = $%d", *filter.IDActor)
+	}
+	if filter.IDPersona != nil {
+		add("id_persona = $%d", *filter.IDPersona)
+	}
+	if filter.Accion != "" {
+		if strings.HasSuffix(filter.Accion, ".") {
+			add("accion LIKE $%d", escapeLike(filter.Accion)+"%")
+		} else {
+			add("accion = $%d", filter.Accion)
+		}
+	}
+	if filter.TipoObjetivo != "" {
+		add("tipo_objetivo = $%d", filter.TipoObjetivo)
+	}
+	if filter.IDObjetivo != "" {
+		add("id_objetivo = $%d", filter.IDObjetivo)
+	}
+	if filter.IDSolicitud != "" {
+		add("id_solicitud = $%d", filter.IDSolicitud)
+	}
+	if filter.Desde != nil {
+		add("fecha >= $%d", *filter.Desde)
+	}
+	if filter.Hasta != nil {
+		add("fecha < $%d", *filter.Hasta)
+	}
+
+	if len(conditions) == 0 {
+		return "", args
+	}
+	return "WHERE " + strings.Join(conditions, " AND "), args
+}
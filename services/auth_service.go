@@ -9,6 +9,9 @@ import (
 	"golang.org/x/crypto/bcrypt"
 )
 
+// dummyPasswordHash se usa para igualar el tiempo de respuesta cuando no hay hash que comparar
+var dummyPasswordHash, _ = bcrypt.GenerateFromPassword([]byte("mentorly-dummy-password"), bcrypt.DefaultCost)
+
 // AuthService maneja la autenticación
 type AuthService struct {
 	db *pgxpool.Pool
@@ -56,15 +59,18 @@ func (s *AuthService) RegisterUser(ctx context.Context, nombre string, apellido
 func (s *AuthService) LoginUser(ctx context.Context, email string, password string) (int, string, error) {
 	var idPersona int
 	var nombre string
-	var hashedPassword string
+	var hashedPassword *string
+	var suspendido bool
 
 	// Obtener usuario de tb_persona
 	err := s.db.QueryRow(ctx,
-		"SELECT id_persona, nombre, contrasena FROM tb_persona WHERE email = $1",
+		"SELECT id_persona, nombre, contrasena, suspendido_en IS NOT NULL FROM tb_persona WHERE email = $1",
 		email,
-	).Scan(&idPersona, &nombre, &hashedPassword)
+	).Scan(&idPersona, &nombre, &hashedPassword, &suspendido)
 
 	if err == pgx.ErrNoRows {
+		// Comparar igual contra un hash ficticio para no revelar por tiempo si el email existe
+		bcrypt.CompareHashAndPassword(dummyPasswordHash, []byte(password))
 		return 0, "", ErrInvalidCredentials
 	}
 
@@ -72,20 +78,87 @@ func (s *AuthService) LoginUser(ctx context.Context, email string, password stri
 		return 0, "", err
 	}
 
-	// Si el password está vacío (login OAuth), y el hash también, es un login válido.
-	if password == "" && hashedPassword == "" {
-		return idPersona, nombre, nil
+	// Las cuentas sin contraseña (solo OAuth) no pueden iniciar sesión con email y contraseña
+	if hashedPassword == nil {
+		bcrypt.CompareHashAndPassword(dummyPasswordHash, []byte(password))
+		return 0, "", ErrInvalidCredentials
 	}
 
 	// Verificar contraseña
-	err = bcrypt.CompareHashAndPassword([]byte(hashedPassword), []byte(password))
+	err = bcrypt.CompareHashAndPassword([]byte(*hashedPassword), []byte(password))
 	if err != nil {
 		return 0, "", ErrInvalidCredentials
 	}
 
+	// La suspensión se informa solo con la contraseña correcta
+	if suspendido {
+		return 0, "", ErrAccountSuspended
+	}
+
 	return idPersona, nombre, nil
 }
 
+// CheckActive devuelve ErrAccountSuspended si la cuenta está suspendida.
+// Se usa en los inicios de sesión que no pasan por LoginUser (OAuth y segundo factor).
+func (s *AuthService) CheckActive(ctx context.Context, idPersona int) error {
+	var suspendido bool
+	err := s.db.QueryRow(ctx,
+		"SELECT suspendido_en IS NOT NULL FROM tb_persona WHERE id_persona = $1",
+		idPersona,
+	).Scan(&suspendido)
+
+	if err == pgx.ErrNoRows {
+		return ErrUserNotFound
+	}
+	if err != nil {
+		return err
+	}
+
+	if suspendido {
+		return ErrAccountSuspended
+	}
+	return nil
+}
+
+// VerifyPassword comprueba la contraseña actual de un usuario autenticado
+func (s *AuthService) VerifyPassword(ctx context.Context, idPersona int, password string) error {
+	var hashedPassword *string
+	err := s.db.QueryRow(ctx,
+		"SELECT contrasena FROM tb_persona WHERE id_persona = $1",
+		idPersona,
+	).Scan(&hashedPassword)
+
+	if err == pgx.ErrNoRows {
+		return ErrUserNotFound
+	}
+	if err != nil {
+		return err
+	}
+
+	if hashedPassword == nil {
+		return ErrPasswordNotSet
+	}
+
+	if bcrypt.CompareHashAndPassword([]byte(*hashedPassword), []byte(password)) != nil {
+		return ErrWrongPassword
+	}
+	return nil
+}
+
+// HasPassword indica si la cuenta tiene una contraseña configurada
+func (s *AuthService) HasPassword(ctx context.Context, idPersona int) (bool, error) {
+	var hasPassword bool
+	err := s.db.QueryRow(ctx,
+		"SELECT contrasena IS NOT NULL FROM tb_persona WHERE id_persona = $1",
+		idPersona,
+	).Scan(&hasPassword)
+
+	if err == pgx.ErrNoRows {
+		return false, ErrUserNotFound
+	}
+	return hasPassword, err
+}
+
 // HashPassword genera el hash de una contraseña
 func (s *AuthService) HashPassword(password string) (string, error) {
 	hashedPassword, err := bcrypt.GenerateFromPassword([]byte(password), bcrypt.DefaultCost)
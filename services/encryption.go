@@ -0,0 +1,76 @@
+package services
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"os"
+)
+
+// Encryptor cifra datos sensibles antes de guardarlos en la base (AES-256-GCM)
+type Encryptor struct {
+	aead cipher.AEAD
+}
+
+// NewEncryptor crea un Encryptor con una clave de 32 bytes
+func NewEncryptor(key []byte) (*Encryptor, error) {
+	if len(key) != 32 {
+		return nil, fmt.Errorf("la clave de cifrado debe tener 32 bytes, tiene %d", len(key))
+	}
+
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	aead, err := cipher.NewGCM(block)
+	if err != nil {
+		return nil, err
+	}
+
+	return &Encryptor{aead: aead}, nil
+}
+
+// NewEncryptorFromEnv lee la clave de ENCRYPTION_KEY, codificada en base64
+// (por ejemplo, generada con "openssl rand -base64 32").
+func NewEncryptorFromEnv() (*Encryptor, error) {
+	encoded := os.Getenv("ENCRYPTION_KEY")
+	if encoded == "" {
+		return nil, errors.New("ENCRYPTION_KEY no está configurada")
+	}
+
+	key, err := base64.StdEncoding.DecodeString(encoded)
+	if err != nil {
+		return nil, fmt.Errorf("ENCRYPTION_KEY no es base64 válido: %w", err)
+	}
+
+	return NewEncryptor(key)
+}
+
+// Encrypt devuelve nonce + texto cifrado en base64
+func (e *Encryptor) Encrypt(plaintext []byte) (string, error) {
+	nonce := make([]byte, e.aead.NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		return "", err
+	}
+
+	sealed := e.aead.Seal(nonce, nonce, plaintext, nil)
+	return base64.StdEncoding.EncodeToString(sealed), nil
+}
+
+// Decrypt revierte Encrypt
+func (e *Encryptor) Decrypt(encoded string) ([]byte, error) {
+	sealed, err := base64.StdEncoding.DecodeString(encoded)
+	if err != nil {
+		return nil, err
+	}
+
+	nonceSize := e.aead.NonceSize()
+	if len(sealed) < nonceSize {
+		return nil, errors.New("dato cifrado inválido")
+	}
+
+	return e.aead.Open(nil, sealed[:nonceSize], sealed[nonceSize:], nil)
+}
@@ -1,6 +1,9 @@
 package services
 
-import "errors"
+import (
+	"errors"
+	"time"
+)
 
 var (
 	ErrEmailAlreadyExists = errors.New("el email ya está registrado")
@@ -9,4 +12,122 @@ var (
 	ErrRoleNotFound       = errors.New("rol no encontrado")
 	ErrInvalidRole        = errors.New("rol inválido")
 	ErrNotFound           = errors.New("recurso no encontrado")
+	ErrWrongPassword      = errors.New("la contraseña actual es incorrecta")
+	ErrPasswordNotSet     = errors.New("la cuenta no tiene contraseña configurada")
+	ErrPasswordAlreadySet = errors.New("la cuenta ya tiene contraseña configurada")
+	ErrAccountSuspended   = errors.New("la cuenta está suspendida")
+
+	ErrRoleNotSelectable   = errors.New("ese rol no se puede elegir")
+	ErrCannotChangeOwnRole = errors.New("no podés cambiar tu propio rol")
+	ErrPermissionDenied    = errors.New("no tenés permiso para realizar esta acción")
+	ErrRoleExceedsActor    = errors.New("no podés asignar un rol con permisos que vos no tenés")
+	ErrRoleAlreadyAssigned = errors.New("el usuario ya tiene ese rol")
+	ErrRoleNotAssigned     = errors.New("el usuario no tiene ese rol")
+	ErrRoleProfileTooLarge = errors.New("los datos del perfil son demasiado grandes")
+
+	ErrUserAlreadySuspended = errors.New("la cuenta ya está suspendida")
+	ErrUserNotSuspended     = errors.New("la cuenta no está suspendida")
+	ErrCannotSuspendSelf    = errors.New("no podés suspender tu propia cuenta")
+
+	ErrAccountDeletionPending    = errors.New("la eliminación de la cuenta ya está programada")
+	ErrAccountDeletionNotPending = errors.New("la cuenta no tiene una eliminación programada")
+	ErrDeletionNotConfirmed      = errors.New("hay que escribir ELIMINAR para confirmar")
+
+	ErrCannotImpersonateSelf    = errors.New("no podés suplantarte a vos mismo")
+	ErrCannotImpersonateAdmin   = errors.New("no se puede suplantar a un usuario que también puede suplantar")
+	ErrImpersonationNotFound    = errors.New("suplantación no encontrada")
+	ErrImpersonationEnded       = errors.New("la suplantación terminó o expiró")
+	ErrInvalidImpersonationTime = errors.New("la duración de la suplantación es inválida")
+
+	ErrAlreadyMentor                = errors.New("ya tenés el rol de mentor")
+	ErrMentorApplicationOpen        = errors.New("ya tenés una solicitud para ser mentor en curso")
+	ErrMentorApplicationNotFound    = errors.New("solicitud para ser mentor no encontrada")
+	ErrMentorApplicationNotEditable = errors.New("la solicitud solo se puede modificar cuando se piden cambios")
+	ErrMentorApplicationNotPending  = errors.New("la solicitud ya fue revisada")
+	ErrInvalidLinkedInURL           = errors.New("la URL de LinkedIn es inválida")
+	ErrInvalidReviewDecision        = errors.New("decisión de revisión inválida")
+	ErrReviewCommentRequired        = errors.New("hay que dejar un comentario para rechazar o pedir cambios")
+
+	ErrNotMentor              = errors.New("necesitás el rol de mentor para tener un perfil de mentor")
+	ErrMentorProfileNotFound  = errors.New("perfil de mentor no encontrado")
+	ErrMentorProfileExists    = errors.New("ya tenés un perfil de mentor")
+	ErrInvalidTimezone        = errors.New("la zona horaria es inválida")
+	ErrInvalidLanguage        = errors.New("código de idioma inválido (por ejemplo es, en o pt-BR)")
+	ErrInvalidGitHubURL       = errors.New("la URL de GitHub es inválida")
+	ErrInvalidAvatarURL       = errors.New("la URL del avatar es inválida")
+	ErrDuplicateExpertiseArea = errors.New("hay áreas de experiencia repetidas")
+	ErrInvalidMentorSort      = errors.New("orden inválido (relevancia, calificacion, precio_asc o precio_desc)")
+	ErrInvalidCursor          = errors.New("cursor inválido; volvé a buscar desde la primera página")
+
+	ErrSkillNotFound         = errors.New("habilidad no encontrada")
+	ErrSkillCategoryNotFound = errors.New("categoría de habilidades no encontrada")
+	ErrSkillCategoryTooDeep  = errors.New("una subcategoría no puede tener subcategorías")
+	ErrSkillCategoryInUse    = errors.New("la categoría tiene subcategorías o habilidades")
+	ErrSkillInUse            = errors.New("la habilidad está en uso; fusionala con otra en lugar de borrarla")
+	ErrSlugTaken             = errors.New("el slug ya está en uso")
+	ErrInvalidSlug           = errors.New("slug inválido (minúsculas, números y guiones)")
+	ErrCannotMergeSameSkill  = errors.New("no se puede fusionar una habilidad consigo misma")
+	ErrInvalidSkillType      = errors.New("tipo de habilidad inválido (experiencia o interes)")
+	ErrInvalidSkillLevel     = errors.New("nivel inválido (basico, intermedio, avanzado o experto)")
+	ErrDuplicateUserSkill    = errors.New("hay habilidades repetidas")
+	ErrTooManyUserSkills     = errors.New("se alcanzó el máximo de habilidades")
+
+	ErrMentorshipRequestNotFound   = errors.New("solicitud de mentoría no encontrada")
+	ErrMentorshipRequestOpen       = errors.New("ya tenés una solicitud pendiente con este mentor")
+	ErrMentorshipRequestNotPending = errors.New("la solicitud ya no está pendiente")
+	ErrMentorshipRequestExpired    = errors.New("la solicitud expiró")
+	ErrMentorNotAvailable          = errors.New("el mentor no está disponible para nuevas solicitudes")
+	ErrCannotRequestSelf           = errors.New("no podés enviarte una solicitud de mentoría")
+	ErrMentorRequestLimit          = errors.New("el mentor tiene demasiadas solicitudes pendientes; probá más tarde")
+	ErrTooManyOpenRequests         = errors.New("alcanzaste el máximo de solicitudes pendientes")
+	ErrDeclineReasonRequired       = errors.New("hay que indicar el motivo del rechazo")
+	ErrInvalidMentorshipGoals      = errors.New("los objetivos están vacíos o repetidos")
+	ErrInvalidMentorshipRole       = errors.New("rol inválido (mentor o mentorado)")
+
+	ErrInvalidRefreshToken = errors.New("refresh token inválido o expirado")
+	ErrRefreshTokenReused  = errors.New("refresh token reutilizado")
+	ErrInvalidOAuthState   = errors.New("state de OAuth inválido, expirado o ya utilizado")
+	ErrSessionRevoked      = errors.New("la sesión fue cerrada")
+	ErrSessionNotFound     = errors.New("sesión no encontrada")
+	ErrSessionNotRecent    = errors.New("por seguridad, iniciá sesión nuevamente para continuar")
+
+	ErrIdentityNotFound      = errors.New("identidad externa no vinculada")
+	ErrIdentityAlreadyLinked = errors.New("la identidad externa ya está vinculada a otra cuenta")
+	ErrProviderAlreadyLinked = errors.New("la cuenta ya tiene vinculado este proveedor")
+	ErrIdentityEmailConflict = errors.New("ya existe una cuenta con este email; iniciá sesión y vinculá el proveedor")
+	ErrOAuthEmailRequired    = errors.New("el proveedor no devolvió un email")
+	ErrProviderNotFound      = errors.New("proveedor OAuth no soportado")
+	ErrInvalidIDToken        = errors.New("id_token inválido")
+	ErrLastLoginMethod       = errors.New("no se puede quitar el último método de inicio de sesión")
+
+	ErrInvalidUserToken     = errors.New("token inválido, expirado o ya utilizado")
+	ErrEmailAlreadyVerified = errors.New("el email ya está verificado")
+	ErrEmailNotVerified     = errors.New("el email no está verificado")
+	ErrTooManyRequests      = errors.New("demasiadas solicitudes, intentá más tarde")
+	ErrLockoutNotFound      = errors.New("no hay un bloqueo activo para ese email o IP")
+
+	ErrMFANotEnabled       = errors.New("la verificación en dos pasos no está activada")
+	ErrMFAAlreadyEnabled   = errors.New("la verificación en dos pasos ya está activada")
+	ErrMFASetupNotStarted  = errors.New("no hay una configuración de verificación en dos pasos pendiente")
+	ErrInvalidMFACode      = errors.New("código de verificación incorrecto")
+	ErrInvalidMFAChallenge = errors.New("el desafío de verificación es inválido o expiró; iniciá sesión nuevamente")
+
+	ErrInvalidPersonalToken  = errors.New("token personal inválido, expirado o revocado")
+	ErrInvalidScope          = errors.New("scope inválido")
+	ErrInvalidTokenDuration  = errors.New("la vigencia del token es inválida")
+	ErrPersonalTokenNotFound = errors.New("token personal no encontrado")
+	ErrTooManyPersonalTokens = errors.New("se alcanzó el máximo de tokens personales activos")
 )
+
+// RetryAfterError indica cuánto hay que esperar antes de reintentar una operación limitada
+type RetryAfterError struct {
+	RetryAfter time.Duration
+}
+
+func (e *RetryAfterError) Error() string {
+	return ErrTooManyRequests.Error()
+}
+
+func (e *RetryAfterError) Unwrap() error {
+	return ErrTooManyRequests
+}
@@ -0,0 +1,212 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"mentorly-backend/models"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+// IdentityService maneja las identidades externas vinculadas a cada persona
+type IdentityService struct {
+	db *pgxpool.Pool
+}
+
+// NewIdentityService crea una nueva instancia del servicio de identidades
+func NewIdentityService(db *pgxpool.Pool) *IdentityService {
+	return &IdentityService{db: db}
+}
+
+// ResolveOAuthUser obtiene la persona asociada a una identidad externa, creándola si no existe.
+// Nunca vincula automáticamente una identidad nueva a una cuenta existente por coincidir el email,
+// salvo para cuentas creadas por OAuth antes de existir las identidades (sin contraseña ni
+// identidades) y siempre que el proveedor garantice que el email está verificado.
+func (s *IdentityService) ResolveOAuthUser(ctx context.Context, info *OAuthUserInfo) (int, string, error) {
+	idPersona, nombre, err := s.findByIdentity(ctx, info.Provider, info.ID)
+	if err == nil {
+		return idPersona, nombre, nil
+	}
+	if !errors.Is(err, ErrIdentityNotFound) {
+		return 0, "", err
+	}
+
+	if info.Email == "" {
+		return 0, "", ErrOAuthEmailRequired
+	}
+
+	tx, err := s.db.Begin(ctx)
+	if err != nil {
+		return 0, "", err
+	}
+	defer tx.Rollback(ctx)
+
+	var hasPassword bool
+	var identidades int
+	err = tx.QueryRow(ctx,
+		`SELECT p.id_persona, p.nombre, p.contrasena IS NOT NULL,
+		        (SELECT COUNT(*) FROM tb_identidad_externa ie WHERE ie.id_persona = p.id_persona)
+		 FROM tb_persona p WHERE p.email = $1
+		 FOR UPDATE OF p`,
+		info.Email,
+	).Scan(&idPersona, &nombre, &hasPassword, &identidades)
+
+	switch {
+	case err == nil:
+		// Cuenta existente: solo se reclama si es una cuenta OAuth previa a las identidades
+		if hasPassword || identidades > 0 || !info.EmailVerified {
+			return 0, "", ErrIdentityEmailConflict
+		}
+		if _, err := tx.Exec(ctx, "UPDATE tb_persona SET email_verificado = TRUE WHERE id_persona = $1", idPersona); err != nil {
+			return 0, "", err
+		}
+	case errors.Is(err, pgx.ErrNoRows):
+		nombre = info.Name
+		err = tx.QueryRow(ctx,
+			`INSERT INTO tb_persona (nombre, apellido, email, contrasena, fecha_registro, email_verificado)
+			 VALUES ($1, $2, $3, $4, $5, $6)
+			 RETURNING id_persona`,
+			info.Name, "", info.Email, nil, time.Now(), info.EmailVerified,
+		).Scan(&idPersona)
+		if err != nil {
+			return 0, "", err
+		}
+	default:
+		return 0, "", err
+	}
+
+	if err := insertIdentity(ctx, tx, idPersona, info); err != nil {
+		return 0, "", err
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return 0, "", err
+	}
+
+	return idPersona, nombre, nil
+}
+
+// LinkIdentity vincula una identidad externa a una cuenta existente
+func (s *IdentityService) LinkIdentity(ctx context.Context, idPersona int, info *OAuthUserInfo) error {
+	var owner int
+	err := s.db.QueryRow(ctx,
+		"SELECT id_persona FROM tb_identidad_externa WHERE proveedor = $1 AND sujeto = $2",
+		string(info.Provider), info.ID,
+	).Scan(&owner)
+
+	if err == nil {
+		if owner == idPersona {
+			return nil
+		}
+		return ErrIdentityAlreadyLinked
+	}
+	if !errors.Is(err, pgx.ErrNoRows) {
+		return err
+	}
+
+	err = insertIdentity(ctx, s.db, idPersona, info)
+	var pgErr *pgconn.PgError
+	if errors.As(err, &pgErr) && pgErr.Code == "23505" {
+		return ErrProviderAlreadyLinked
+	}
+	return err
+}
+
+// ListIdentities lista las identidades externas vinculadas a una persona
+func (s *IdentityService) ListIdentities(ctx context.Context, idPersona int) ([]models.Identity, error) {
+	rows, err := s.db.Query(ctx,
+		`SELECT proveedor, COALESCE(email, ''), fecha_vinculacion, ultimo_login
+		 FROM tb_identidad_externa WHERE id_persona = $1 ORDER BY fecha_vinculacion`,
+		idPersona,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	identities := []models.Identity{}
+	for rows.Next() {
+		var identity models.Identity
+		if err := rows.Scan(&identity.Proveedor, &identity.Email, &identity.FechaVinculacion, &identity.UltimoLogin); err != nil {
+			return nil, err
+		}
+		identities = append(identities, identity)
+	}
+	return identities, rows.Err()
+}
+
+// UnlinkIdentity desvincula un proveedor de la cuenta.
+// No permite quitar el último método de inicio de sesión disponible.
+func (s *IdentityService) UnlinkIdentity(ctx context.Context, idPersona int, provider OAuthProviderName) error {
+	tx, err := s.db.Begin(ctx)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback(ctx)
+
+	var hasPassword bool
+	var identidades int
+	err = tx.QueryRow(ctx,
+		`SELECT p.contrasena IS NOT NULL,
+		        (SELECT COUNT(*) FROM tb_identidad_externa ie WHERE ie.id_persona = p.id_persona)
+		 FROM tb_persona p WHERE p.id_persona = $1
+		 FOR UPDATE OF p`,
+		idPersona,
+	).Scan(&hasPassword, &identidades)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return ErrUserNotFound
+	}
+	if err != nil {
+		return err
+	}
+
+	if !hasPassword && identidades <= 1 {
+		return ErrLastLoginMethod
+	}
+
+	result, err := tx.Exec(ctx,
+		"DELETE FROM tb_identidad_externa WHERE id_persona = $1 AND proveedor = $2",
+		idPersona, string(provider),
+	)
+	if err != nil {
+		return err
+	}
+	if result.RowsAffected() == 0 {
+		return ErrIdentityNotFound
+	}
+
+	return tx.Commit(ctx)
+}
+
+// findByIdentity busca la persona vinculada a (proveedor, sujeto) y registra el login
+func (s *IdentityService) findByIdentity(ctx context.Context, provider OAuthProviderName, subject string) (int, string, error) {
+	var idPersona int
+	var nombre string
+	err := s.db.QueryRow(ctx,
+		`UPDATE tb_identidad_externa ie SET ultimo_login = NOW()
+		 FROM tb_persona p
+		 WHERE p.id_persona = ie.id_persona AND ie.proveedor = $1 AND ie.sujeto = $2
+		 RETURNING p.id_persona, p.nombre`,
+		string(provider), subject,
+	).Scan(&idPersona, &nombre)
+
+	if errors.Is(err, pgx.ErrNoRows) {
+		return 0, "", ErrIdentityNotFound
+	}
+	if err != nil {
+		return 0, "", err
+	}
+	return idPersona, nombre, nil
+}
+
+func insertIdentity(ctx context.Context, db dbExecutor, idPersona int, info *OAuthUserInfo) error {
+	_, err := db.Exec(ctx,
+		`INSERT INTO tb_identidad_externa (id_persona, proveedor, sujeto, email, fecha_vinculacion, ultimo_login)
+		 VALUES ($1, $2, $3, $4, $5, $5)`,
+		idPersona, string(info.Provider), info.ID, info.Email, time.Now(),
+	)
+	return err
+}
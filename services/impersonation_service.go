@@ -0,0 +1,236 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"mentorly-backend/models"
+	"strconv"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+const (
+	// DefaultImpersonationDuration es la vigencia del token de suplantación si no se indica otra
+	DefaultImpersonationDuration = 15 * time.Minute
+	// MaxImpersonationDuration es la vigencia máxima; el token no se puede renovar
+	MaxImpersonationDuration = time.Hour
+)
+
+// ImpersonationService maneja las suplantaciones de usuarios para soporte
+type ImpersonationService struct {
+	db *pgxpool.Pool
+}
+
+// NewImpersonationService crea una nueva instancia del servicio de suplantación
+func NewImpersonationService(db *pgxpool.Pool) *ImpersonationService {
+	return &ImpersonationService{db: db}
+}
+
+const impersonationColumns = `i.id_suplantacion, i.id_actor, i.id_persona, p.email, i.motivo, i.solo_lectura,
+	i.fecha_creacion, i.fecha_expiracion, i.finalizada_en`
+
+// Start registra una suplantación de idPersona por idActor. No se puede suplantar a una
+// cuenta suspendida ni a otro usuario con permiso de suplantación.
+func (s *ImpersonationService) Start(ctx context.Context, idActor int, idPersona int, motivo string, soloLectura bool, duracion time.Duration, req AuditRequest) (*models.Impersonation, error) {
+	if idActor == idPersona {
+		return nil, ErrCannotImpersonateSelf
+	}
+	if duracion <= 0 || duracion > MaxImpersonationDuration {
+		return nil, ErrInvalidImpersonationTime
+	}
+
+	tx, err := s.db.Begin(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback(ctx)
+
+	var suspendido, privilegiado bool
+	err = tx.QueryRow(ctx,
+		`SELECT p.suspendido_en IS NOT NULL,
+		        EXISTS (SELECT 1 FROM tb_persona_rol pr
+		                JOIN tb_rol_permiso rp ON rp.id_rol = pr.id_rol
+		                JOIN tb_permiso pe ON pe.id_permiso = rp.id_permiso
+		                WHERE pr.id_persona = p.id_persona AND pe.nombre = $2)
+		 FROM tb_persona p WHERE p.id_persona = $1`,
+		idPersona, PermissionUsersImpersonate,
+	).Scan(&suspendido, &privilegiado)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, ErrUserNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	if suspendido {
+		return nil, ErrAccountSuspended
+	}
+	if privilegiado {
+		return nil, ErrCannotImpersonateAdmin
+	}
+
+	var id int
+	err = tx.QueryRow(ctx,
+		`INSERT INTO tb_suplantacion (id_actor, id_persona, motivo, solo_lectura, fecha_expiracion)
+		 VALUES ($1, $2, $3, $4, NOW() + $5::float8 * INTERVAL '1 second')
+		 RETURNING id_suplantacion`,
+		idActor, idPersona, motivo, soloLectura, duracion.Seconds(),
+	).Scan(&id)
+	if err != nil {
+		return nil, err
+	}
+
+	err = recordAudit(ctx, tx, AuditEntry{
+		IDActor:      &idActor,
+		IDPersona:    &idPersona,
+		Accion:       AuditImpersonationStarted,
+		TipoObjetivo: AuditTargetImpersonation,
+		IDObjetivo:   strconv.Itoa(id),
+		Detalle: map[string]any{
+			"motivo":       motivo,
+			"solo_lectura": soloLectura,
+		},
+		Request: req,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	impersonation, err := getImpersonation(ctx, tx, id)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return nil, err
+	}
+	return impersonation, nil
+}
+
+// Validate comprueba que la suplantación siga vigente para el actor y el usuario del token.
+// Si al actor le quitaron el permiso de suplantar, la suplantación deja de valer en el acto.
+// Devuelve si es de solo lectura.
+func (s *ImpersonationService) Validate(ctx context.Context, idSuplantacion int, idActor int, idPersona int) (bool, error) {
+	var soloLectura, vigente, suspendido bool
+	err := s.db.QueryRow(ctx,
+		`SELECT i.solo_lectura,
+		        i.finalizada_en IS NULL AND i.fecha_expiracion > NOW() AND a.suspendido_en IS NULL
+		        AND EXISTS (SELECT 1 FROM tb_persona_rol pr
+		                    JOIN tb_rol_permiso rp ON rp.id_rol = pr.id_rol
+		                    JOIN tb_permiso pe ON pe.id_permiso = rp.id_permiso
+		                    WHERE pr.id_persona = i.id_actor AND pe.nombre = $4),
+		        p.suspendido_en IS NOT NULL
+		 FROM tb_suplantacion i
+		 JOIN tb_persona a ON a.id_persona = i.id_actor
+		 JOIN tb_persona p ON p.id_persona = i.id_persona
+		 WHERE i.id_suplantacion = $1 AND i.id_actor = $2 AND i.id_persona = $3`,
+		idSuplantacion, idActor, idPersona, PermissionUsersImpersonate,
+	).Scan(&soloLectura, &vigente, &suspendido)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return false, ErrImpersonationEnded
+	}
+	if err != nil {
+		return false, err
+	}
+	if !vigente {
+		return false, ErrImpersonationEnded
+	}
+	if suspendido {
+		return false, ErrAccountSuspended
+	}
+	return soloLectura, nil
+}
+
+// End termina la suplantación antes de que expire. Solo la puede terminar quien la inició.
+func (s *ImpersonationService) End(ctx context.Context, idSuplantacion int, idActor int, req AuditRequest) error {
+	tx, err := s.db.Begin(ctx)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback(ctx)
+
+	var idPersona int
+	var vigente bool
+	err = tx.QueryRow(ctx,
+		`SELECT id_persona, finalizada_en IS NULL AND fecha_expiracion > NOW()
+		 FROM tb_suplantacion WHERE id_suplantacion = $1 AND id_actor = $2
+		 FOR UPDATE`,
+		idSuplantacion, idActor,
+	).Scan(&idPersona, &vigente)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return ErrImpersonationNotFound
+	}
+	if err != nil {
+		return err
+	}
+	if !vigente {
+		return ErrImpersonationEnded
+	}
+
+	if _, err := tx.Exec(ctx,
+		"UPDATE tb_suplantacion SET finalizada_en = NOW() WHERE id_suplantacion = $1",
+		idSuplantacion,
+	); err != nil {
+		return err
+	}
+
+	err = recordAudit(ctx, tx, AuditEntry{
+		IDActor:      &idActor,
+		IDPersona:    &idPersona,
+		Accion:       AuditImpersonationEnded,
+		TipoObjetivo: AuditTargetImpersonation,
+		IDObjetivo:   strconv.Itoa(idSuplantacion),
+		Request:      req,
+	})
+	if err != nil {
+		return err
+	}
+
+	return tx.Commit(ctx)
+}
+
+// ListActive devuelve las suplantaciones vigentes iniciadas por el actor
+func (s *ImpersonationService) ListActive(ctx context.Context, idActor int) ([]models.Impersonation, error) {
+	rows, err := s.db.Query(ctx,
+		`SELECT `+impersonationColumns+`
+		 FROM tb_suplantacion i JOIN tb_persona p ON p.id_persona = i.id_persona
+		 WHERE i.id_actor = $1 AND i.finalizada_en IS NULL AND i.fecha_expiracion > NOW()
+		 ORDER BY i.fecha_creacion DESC`,
+		idActor,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	impersonations := []models.Impersonation{}
+	for rows.Next() {
+		var i models.Impersonation
+		err := rows.Scan(&i.IDSuplantacion, &i.IDActor, &i.IDPersona, &i.Email, &i.Motivo, &i.SoloLectura,
+			&i.FechaCreacion, &i.FechaExpiracion, &i.FinalizadaEn)
+		if err != nil {
+			return nil, err
+		}
+		impersonations = append(impersonations, i)
+	}
+	return impersonations, rows.Err()
+}
+
+func getImpersonation(ctx context.Context, db dbExecutor, idSuplantacion int) (*models.Impersonation, error) {
+	var i models.Impersonation
+	err := db.QueryRow(ctx,
+		`SELECT `+impersonationColumns+`
+		 FROM tb_suplantacion i JOIN tb_persona p ON p.id_persona = i.id_persona
+		 WHERE i.id_suplantacion = $1`,
+		idSuplantacion,
+	).Scan(&i.IDSuplantacion, &i.IDActor, &i.IDPersona, &i.Email, &i.Motivo, &i.SoloLectura,
+		&i.FechaCreacion, &i.FechaExpiracion, &i.FinalizadaEn)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, ErrImpersonationNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &i, nil
+}
@@ -0,0 +1,455 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"math"
+	"mentorly-backend/models"
+	"net/url"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+// Resultados que se guardan en tb_evento_login
+const (
+	LoginResultSuccess         = "exito"
+	LoginResultInvalidPassword = "contrasena_incorrecta"
+	LoginResultUnknownEmail    = "email_inexistente"
+	LoginResultBlocked         = "bloqueado"
+)
+
+const (
+	lockKeyEmail  = "email"
+	lockKeyIP     = "ip"
+	lockKeyReauth = "reauth"
+
+	// AccountUnlockTokenDuration es la vigencia del enlace de desbloqueo
+	AccountUnlockTokenDuration  = time.Hour
+	accountUnlockResendInterval = time.Minute
+)
+
+// LoginGuardConfig define los umbrales de la protección contra fuerza bruta
+type LoginGuardConfig struct {
+	// Fallos seguidos permitidos antes de bloquear el email o la IP
+	MaxFailuresPerEmail int
+	MaxFailuresPerIP    int
+	// Fallos seguidos permitidos al reautenticarse (contraseña actual o código del segundo factor)
+	// con una sesión ya iniciada
+	MaxReauthFailures int
+	// Los contadores vuelven a cero si no hubo fallos en esta ventana
+	FailureWindow time.Duration
+	// Duración del bloqueo al alcanzar el máximo de fallos
+	LockoutDuration time.Duration
+	// Espera mínima entre intentos: se duplica con cada fallo a partir del segundo, hasta DelayMax
+	DelayBase time.Duration
+	DelayMax  time.Duration
+}
+
+// DefaultLoginGuardConfig devuelve los valores por defecto
+func DefaultLoginGuardConfig() LoginGuardConfig {
+	return LoginGuardConfig{
+		MaxFailuresPerEmail: 5,
+		MaxFailuresPerIP:    20,
+		MaxReauthFailures:   5,
+		FailureWindow:       15 * time.Minute,
+		LockoutDuration:     15 * time.Minute,
+		DelayBase:           time.Second,
+		DelayMax:            30 * time.Second,
+	}
+}
+
+// LoginGuardConfigFromEnv lee la configuración de LOGIN_MAX_FAILURES_EMAIL, LOGIN_MAX_FAILURES_IP,
+// LOGIN_MAX_FAILURES_REAUTH, LOGIN_FAILURE_WINDOW, LOGIN_LOCKOUT_DURATION, LOGIN_DELAY_BASE y LOGIN_DELAY_MAX.
+// Las duraciones usan el formato de Go ("15m", "2s").
+func LoginGuardConfigFromEnv() LoginGuardConfig {
+	config := DefaultLoginGuardConfig()
+	envInt("LOGIN_MAX_FAILURES_EMAIL", &config.MaxFailuresPerEmail)
+	envInt("LOGIN_MAX_FAILURES_IP", &config.MaxFailuresPerIP)
+	envInt("LOGIN_MAX_FAILURES_REAUTH", &config.MaxReauthFailures)
+	envDuration("LOGIN_FAILURE_WINDOW", &config.FailureWindow)
+	envDuration("LOGIN_LOCKOUT_DURATION", &config.LockoutDuration)
+	envDuration("LOGIN_DELAY_BASE", &config.DelayBase)
+	envDuration("LOGIN_DELAY_MAX", &config.DelayMax)
+	return config
+}
+
+func envInt(key string, dst *int) {
+	v := os.Getenv(key)
+	if v == "" {
+		return
+	}
+	n, err := strconv.Atoi(v)
+	if err != nil || n <= 0 {
+		log.Printf("Advertencia: %s inválido (%q), se usa %d", key, v, *dst)
+		return
+	}
+	*dst = n
+}
+
+func envDuration(key string, dst *time.Duration) {
+	v := os.Getenv(key)
+	if v == "" {
+		return
+	}
+	d, err := time.ParseDuration(v)
+	if err != nil || d < 0 {
+		log.Printf("Advertencia: %s inválido (%q), se usa %s", key, v, *dst)
+		return
+	}
+	*dst = d
+}
+
+// LoginGuardService limita los intentos de inicio de sesión por email y por IP.
+// Los contadores se guardan por el email ingresado, exista o no la cuenta, para
+// que las respuestas no revelen qué emails están registrados.
+type LoginGuardService struct {
+	db          *pgxpool.Pool
+	tokens      *UserTokenService
+	mailer      Mailer
+	linkBaseURL string
+	config      LoginGuardConfig
+}
+
+// NewLoginGuardService crea el servicio. linkBaseURL es la URL del frontend
+// donde está la pantalla que completa el desbloqueo de la cuenta.
+func NewLoginGuardService(db *pgxpool.Pool, mailer Mailer, linkBaseURL string, config LoginGuardConfig) *LoginGuardService {
+	return &LoginGuardService{
+		db:          db,
+		tokens:      NewUserTokenService(db),
+		mailer:      mailer,
+		linkBaseURL: linkBaseURL,
+		config:      config,
+	}
+}
+
+func normalizeEmail(email string) string {
+	return strings.ToLower(strings.TrimSpace(email))
+}
+
+// delayFor devuelve la espera exigida después de "failures" fallos seguidos
+func (s *LoginGuardService) delayFor(failures int) time.Duration {
+	if failures < 2 || s.config.DelayBase <= 0 {
+		return 0
+	}
+	delay := float64(s.config.DelayBase) * math.Pow(2, float64(failures-2))
+	if delay > float64(s.config.DelayMax) {
+		return s.config.DelayMax
+	}
+	return time.Duration(delay)
+}
+
+// retryAfter devuelve cuánto falta para poder reintentar con una clave que tiene "failures" fallos
+// seguidos, está bloqueada por lockedFor más y tuvo el último fallo hace sinceLastFailure
+func (s *LoginGuardService) retryAfter(failures int, lockedFor time.Duration, sinceLastFailure time.Duration) time.Duration {
+	wait := max(lockedFor, 0)
+	if sinceLastFailure < s.config.FailureWindow {
+		wait = max(wait, s.delayFor(failures)-sinceLastFailure)
+	}
+	return wait
+}
+
+// reachesLockout indica si el fallo número "failures" bloquea la clave. Se bloquea una sola vez,
+// al llegar justo al máximo; cuando el bloqueo termina el contador vuelve a empezar.
+func reachesLockout(failures int, maxFailures int) bool {
+	return failures == maxFailures
+}
+
+// Check devuelve un *RetryAfterError si el email o la IP están bloqueados o
+// todavía no pasó la espera progresiva desde el último fallo.
+func (s *LoginGuardService) Check(ctx context.Context, email string, ip string) error {
+	email = normalizeEmail(email)
+
+	// Las diferencias de tiempo se calculan en la base para no depender de la zona horaria del servidor
+	rows, err := s.db.Query(ctx,
+		`SELECT intentos_fallidos,
+		        COALESCE(EXTRACT(EPOCH FROM (bloqueado_hasta - NOW())), 0)::float8,
+		        EXTRACT(EPOCH FROM (NOW() - ultimo_fallo))::float8
+		 FROM tb_bloqueo_login
+		 WHERE (tipo = $1 AND clave = $2) OR (tipo = $3 AND clave = $4)`,
+		lockKeyEmail, email, lockKeyIP, ip,
+	)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	var wait time.Duration
+	for rows.Next() {
+		var failures int
+		var lockedFor, sinceLastFailure float64
+		if err := rows.Scan(&failures, &lockedFor, &sinceLastFailure); err != nil {
+			return err
+		}
+
+		d := s.retryAfter(failures, time.Duration(lockedFor*float64(time.Second)), time.Duration(sinceLastFailure*float64(time.Second)))
+		if d > wait {
+			wait = d
+		}
+	}
+	if err := rows.Err(); err != nil {
+		return err
+	}
+
+	if wait > 0 {
+		s.recordEvent(ctx, email, ip, LoginResultBlocked)
+		return &RetryAfterError{RetryAfter: wait}
+	}
+	return nil
+}
+
+// RegisterFailure suma un intento fallido al email y a la IP, bloqueándolos al
+// alcanzar el máximo. Devuelve true si el email quedó bloqueado en este intento.
+func (s *LoginGuardService) RegisterFailure(ctx context.Context, email string, ip string) (bool, error) {
+	email = normalizeEmail(email)
+
+	var exists bool
+	err := s.db.QueryRow(ctx,
+		"SELECT EXISTS (SELECT 1 FROM tb_persona WHERE LOWER(email) = $1)",
+		email,
+	).Scan(&exists)
+	if err != nil {
+		return false, err
+	}
+
+	result := LoginResultInvalidPassword
+	if !exists {
+		result = LoginResultUnknownEmail
+	}
+	s.recordEvent(ctx, email, ip, result)
+
+	emailLocked, err := s.incrementFailures(ctx, lockKeyEmail, email, s.config.MaxFailuresPerEmail)
+	if err != nil {
+		return false, err
+	}
+	if _, err := s.incrementFailures(ctx, lockKeyIP, ip, s.config.MaxFailuresPerIP); err != nil {
+		return false, err
+	}
+
+	return emailLocked, nil
+}
+
+// incrementFailures actualiza el contador de una clave y devuelve true si se acaba de bloquear
+func (s *LoginGuardService) incrementFailures(ctx context.Context, tipo string, clave string, maxFailures int) (bool, error) {
+	// Si la ventana venció o el bloqueo anterior ya terminó, el contador vuelve a empezar
+	var failures int
+	err := s.db.QueryRow(ctx,
+		`INSERT INTO tb_bloqueo_login (tipo, clave, intentos_fallidos, ultimo_fallo)
+		 VALUES ($1, $2, 1, NOW())
+		 ON CONFLICT (tipo, clave) DO UPDATE SET
+		     intentos_fallidos = CASE
+		         WHEN tb_bloqueo_login.ultimo_fallo < NOW() - $3::float8 * INTERVAL '1 second'
+		           OR tb_bloqueo_login.bloqueado_hasta <= NOW() THEN 1
+		         ELSE tb_bloqueo_login.intentos_fallidos + 1
+		     END,
+		     bloqueado_hasta = CASE
+		         WHEN tb_bloqueo_login.bloqueado_hasta <= NOW() THEN NULL
+		         ELSE tb_bloqueo_login.bloqueado_hasta
+		     END,
+		     ultimo_fallo = NOW()
+		 RETURNING intentos_fallidos`,
+		tipo, clave, s.config.FailureWindow.Seconds(),
+	).Scan(&failures)
+	if err != nil {
+		return false, err
+	}
+
+	if !reachesLockout(failures, maxFailures) {
+		return false, nil
+	}
+
+	_, err = s.db.Exec(ctx,
+		`UPDATE tb_bloqueo_login SET bloqueado_hasta = NOW() + $3::float8 * INTERVAL '1 second'
+		 WHERE tipo = $1 AND clave = $2`,
+		tipo, clave, s.config.LockoutDuration.Seconds(),
+	)
+	if err != nil {
+		return false, err
+	}
+
+	log.Printf("Bloqueo de inicio de sesión por %s: %s (%d intentos fallidos)", tipo, clave, failures)
+	return true, nil
+}
+
+// RegisterSuccess reinicia el contador del email. El de la IP se mantiene para que
+// una cuenta propia no sirva para seguir probando contraseñas de otras.
+func (s *LoginGuardService) RegisterSuccess(ctx context.Context, email string, ip string) error {
+	email = normalizeEmail(email)
+	s.recordEvent(ctx, email, ip, LoginResultSuccess)
+
+	_, err := s.db.Exec(ctx,
+		"DELETE FROM tb_bloqueo_login WHERE tipo = $1 AND clave = $2",
+		lockKeyEmail, email,
+	)
+	return err
+}
+
+// CheckReauth devuelve un *RetryAfterError si el usuario agotó los intentos de reautenticación
+// o todavía no pasó la espera progresiva desde el último fallo. Protege los códigos del segundo
+// factor y la contraseña actual de una sesión robada.
+func (s *LoginGuardService) CheckReauth(ctx context.Context, idPersona int) error {
+	var failures int
+	var lockedFor, sinceLastFailure float64
+	err := s.db.QueryRow(ctx,
+		`SELECT intentos_fallidos,
+		        COALESCE(EXTRACT(EPOCH FROM (bloqueado_hasta - NOW())), 0)::float8,
+		        EXTRACT(EPOCH FROM (NOW() - ultimo_fallo))::float8
+		 FROM tb_bloqueo_login
+		 WHERE tipo = $1 AND clave = $2`,
+		lockKeyReauth, strconv.Itoa(idPersona),
+	).Scan(&failures, &lockedFor, &sinceLastFailure)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+
+	wait := s.retryAfter(failures, time.Duration(lockedFor*float64(time.Second)), time.Duration(sinceLastFailure*float64(time.Second)))
+	if wait > 0 {
+		return &RetryAfterError{RetryAfter: wait}
+	}
+	return nil
+}
+
+// RegisterReauthFailure suma un intento de reautenticación fallido del usuario.
+// Devuelve true si el usuario quedó bloqueado en este intento.
+func (s *LoginGuardService) RegisterReauthFailure(ctx context.Context, idPersona int) (bool, error) {
+	return s.incrementFailures(ctx, lockKeyReauth, strconv.Itoa(idPersona), s.config.MaxReauthFailures)
+}
+
+// RegisterReauthSuccess reinicia el contador de reautenticación del usuario
+func (s *LoginGuardService) RegisterReauthSuccess(ctx context.Context, idPersona int) error {
+	_, err := s.db.Exec(ctx,
+		"DELETE FROM tb_bloqueo_login WHERE tipo = $1 AND clave = $2",
+		lockKeyReauth, strconv.Itoa(idPersona),
+	)
+	return err
+}
+
+// recordEvent guarda el intento. Un error acá no debe impedir el inicio de sesión.
+func (s *LoginGuardService) recordEvent(ctx context.Context, email string, ip string, result string) {
+	_, err := s.db.Exec(ctx,
+		`INSERT INTO tb_evento_login (email, id_persona, ip, resultado, fecha)
+		 VALUES ($1, (SELECT id_persona FROM tb_persona WHERE LOWER(email) = $1 LIMIT 1), $2, $3, NOW())`,
+		email, ip, result,
+	)
+	if err != nil {
+		log.Printf("Error al registrar evento de login: %v", err)
+	}
+}
+
+// Unlock quita el bloqueo y los intentos fallidos de un email y/o una IP
+func (s *LoginGuardService) Unlock(ctx context.Context, email string, ip string) error {
+	result, err := s.db.Exec(ctx,
+		`DELETE FROM tb_bloqueo_login
+		 WHERE (tipo = $1 AND clave = $2) OR (tipo = $3 AND clave = $4)`,
+		lockKeyEmail, normalizeEmail(email), lockKeyIP, ip,
+	)
+	if err != nil {
+		return err
+	}
+	if result.RowsAffected() == 0 {
+		return ErrLockoutNotFound
+	}
+	return nil
+}
+
+// RequestUnlock envía un enlace de desbloqueo si el email existe y está bloqueado.
+// En cualquier otro caso no hace nada y no devuelve error.
+func (s *LoginGuardService) RequestUnlock(ctx context.Context, email string) error {
+	email = normalizeEmail(email)
+
+	var locked bool
+	err := s.db.QueryRow(ctx,
+		`SELECT EXISTS (SELECT 1 FROM tb_bloqueo_login
+		 WHERE tipo = $1 AND clave = $2 AND bloqueado_hasta > NOW())`,
+		lockKeyEmail, email,
+	).Scan(&locked)
+	if err != nil || !locked {
+		return err
+	}
+
+	var idPersona int
+	var nombre, to string
+	err = s.db.QueryRow(ctx,
+		"SELECT id_persona, nombre, email FROM tb_persona WHERE LOWER(email) = $1",
+		email,
+	).Scan(&idPersona, &nombre, &to)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+
+	_, last, err := s.tokens.RecentIssues(ctx, idPersona, PurposeAccountUnlock, time.Now().Add(-accountUnlockResendInterval))
+	if err != nil {
+		return err
+	}
+	if last != nil {
+		return nil
+	}
+
+	rawToken, err := s.tokens.Issue(ctx, idPersona, PurposeAccountUnlock, AccountUnlockTokenDuration)
+	if err != nil {
+		return err
+	}
+
+	link := fmt.Sprintf("%s/unlock-account?token=%s", s.linkBaseURL, url.QueryEscape(rawToken))
+	return s.mailer.Send(ctx, EmailMessage{
+		To:      to,
+		Subject: "Tu cuenta de Mentorly fue bloqueada temporalmente",
+		Body: fmt.Sprintf("Hola %s,\n\nBloqueamos temporalmente el inicio de sesión en tu cuenta por varios intentos fallidos.\n"+
+			"Si fuiste vos, podés desbloquearla ahora desde el siguiente enlace:\n\n%s\n\n"+
+			"Si no fuiste vos, te recomendamos cambiar tu contraseña.\n",
+			nombre, link),
+	})
+}
+
+// UnlockWithToken consume el token del email de desbloqueo y quita el bloqueo de la cuenta
+func (s *LoginGuardService) UnlockWithToken(ctx context.Context, rawToken string) error {
+	idPersona, err := s.tokens.Consume(ctx, PurposeAccountUnlock, rawToken)
+	if err != nil {
+		return err
+	}
+
+	_, err = s.db.Exec(ctx,
+		`DELETE FROM tb_bloqueo_login
+		 WHERE tipo = $1 AND clave = (SELECT LOWER(email) FROM tb_persona WHERE id_persona = $2)`,
+		lockKeyEmail, idPersona,
+	)
+	return err
+}
+
+// ListEvents devuelve los últimos intentos de inicio de sesión, filtrando por email y/o IP
+func (s *LoginGuardService) ListEvents(ctx context.Context, email string, ip string, limit int) ([]models.LoginEvent, error) {
+	rows, err := s.db.Query(ctx,
+		`SELECT id_evento, email, id_persona, ip, resultado, fecha
+		 FROM tb_evento_login
+		 WHERE ($1 = '' OR email = $1) AND ($2 = '' OR ip = $2)
+		 ORDER BY fecha DESC, id_evento DESC
+		 LIMIT $3`,
+		normalizeEmail(email), ip, limit,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	events := []models.LoginEvent{}
+	for rows.Next() {
+		var e models.LoginEvent
+		if err := rows.Scan(&e.IDEvento, &e.Email, &e.IDPersona, &e.IP, &e.Resultado, &e.Fecha); err != nil {
+			return nil, err
+		}
+		events = append(events, e)
+	}
+	return events, rows.Err()
+}
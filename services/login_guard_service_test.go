@@ -0,0 +1,93 @@
+package services
+
+import (
+	"testing"
+	"time"
+)
+
+func TestLoginGuardDelayFor(t *testing.T) {
+	guard := &LoginGuardService{config: DefaultLoginGuardConfig()}
+
+	tests := []struct {
+		failures int
+		expected time.Duration
+	}{
+		{failures: 0, expected: 0},
+		{failures: 1, expected: 0},
+		{failures: 2, expected: time.Second},
+		{failures: 3, expected: 2 * time.Second},
+		{failures: 4, expected: 4 * time.Second},
+		{failures: 6, expected: 16 * time.Second},
+		// 32s supera DelayMax
+		{failures: 7, expected: 30 * time.Second},
+		{failures: 100, expected: 30 * time.Second},
+	}
+
+	for _, tt := range tests {
+		if got := guard.delayFor(tt.failures); got != tt.expected {
+			t.Errorf("delayFor(%d) = %s, se esperaba %s", tt.failures, got, tt.expected)
+		}
+	}
+
+	guard.config.DelayBase = 0
+	if got := guard.delayFor(10); got != 0 {
+		t.Errorf("sin DelayBase la espera debería ser 0, es %s", got)
+	}
+}
+
+func TestLoginGuardRetryAfter(t *testing.T) {
+	guard := &LoginGuardService{config: DefaultLoginGuardConfig()}
+
+	tests := []struct {
+		name      string
+		failures  int
+		lockedFor time.Duration
+		since     time.Duration
+		expected  time.Duration
+	}{
+		{name: "un fallo no exige espera", failures: 1, since: 0, expected: 0},
+		{name: "espera progresiva pendiente", failures: 3, since: 500 * time.Millisecond, expected: 1500 * time.Millisecond},
+		{name: "espera progresiva cumplida", failures: 3, since: 5 * time.Second, expected: 0},
+		{name: "fallos fuera de la ventana no cuentan", failures: 4, since: 20 * time.Minute, expected: 0},
+		{name: "bloqueo vigente", failures: 5, lockedFor: 10 * time.Minute, since: time.Second, expected: 10 * time.Minute},
+		{name: "bloqueo vencido", failures: 5, lockedFor: -time.Minute, since: 16 * time.Minute, expected: 0},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := guard.retryAfter(tt.failures, tt.lockedFor, tt.since); got != tt.expected {
+				t.Errorf("retryAfter = %s, se esperaba %s", got, tt.expected)
+			}
+		})
+	}
+}
+
+func TestLoginGuardLockoutThreshold(t *testing.T) {
+	config := DefaultLoginGuardConfig()
+
+	tests := []struct {
+		name        string
+		maxFailures int
+	}{
+		{name: "email", maxFailures: config.MaxFailuresPerEmail},
+		{name: "ip", maxFailures: config.MaxFailuresPerIP},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			locks := 0
+			for failures := 1; failures <= tt.maxFailures+3; failures++ {
+				locked := reachesLockout(failures, tt.maxFailures)
+				if locked {
+					locks++
+				}
+				if locked != (failures == tt.maxFailures) {
+					t.Errorf("fallo %d: bloqueo = %v con máximo %d", failures, locked, tt.maxFailures)
+				}
+			}
+			if locks != 1 {
+				t.Errorf("la clave se bloqueó %d veces, se esperaba 1", locks)
+			}
+		})
+	}
+}
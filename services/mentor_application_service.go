@@ -0,0 +1,312 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"mentorly-backend/models"
+	"strings"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+// MentorApplicationInput son los datos que completa quien quiere ser mentor
+type MentorApplicationInput struct {
+	AreasExperiencia string
+	Experiencia      string
+	LinkedInURL      string
+	Motivacion       string
+}
+
+// MentorApplicationService maneja las solicitudes para ser mentor y su revisión
+type MentorApplicationService struct {
+	db          *pgxpool.Pool
+	mailer      Mailer
+	linkBaseURL string
+}
+
+// NewMentorApplicationService crea el servicio. linkBaseURL es la URL del frontend,
+// que se incluye en los avisos al solicitante.
+func NewMentorApplicationService(db *pgxpool.Pool, mailer Mailer, linkBaseURL string) *MentorApplicationService {
+	return &MentorApplicationService{db: db, mailer: mailer, linkBaseURL: linkBaseURL}
+}
+
+const mentorApplicationColumns = `s.id_solicitud, s.id_persona, p.nombre, p.email, s.areas_experiencia, s.experiencia,
+	s.linkedin_url, s.motivacion, s.estado, s.comentario_revision, s.fecha_creacion, s.fecha_actualizacion, s.fecha_revision`
+
+func scanMentorApplication(row pgx.Row) (*models.MentorApplication, error) {
+	var a models.MentorApplication
+	err := row.Scan(&a.IDSolicitud, &a.IDPersona, &a.Nombre, &a.Email, &a.AreasExperiencia, &a.Experiencia,
+		&a.LinkedInURL, &a.Motivacion, &a.Estado, &a.ComentarioRevision, &a.FechaCreacion, &a.FechaActualizacion, &a.FechaRevision)
+	if err != nil {
+		return nil, err
+	}
+	return &a, nil
+}
+
+// Submit crea una solicitud pendiente. Falla si el usuario ya es mentor o ya tiene una abierta.
+func (s *MentorApplicationService) Submit(ctx context.Context, idPersona int, input MentorApplicationInput) (*models.MentorApplication, error) {
+	input, err := normalizeMentorApplication(input)
+	if err != nil {
+		return nil, err
+	}
+
+	var isMentor bool
+	err = s.db.QueryRow(ctx,
+		`SELECT EXISTS (
+		     SELECT 1 FROM tb_persona_rol pr JOIN tb_rol r ON r.id_rol = pr.id_rol
+		     WHERE pr.id_persona = $1 AND r.nombre_rol = $2
+		 )`,
+		idPersona, MentorRoleName,
+	).Scan(&isMentor)
+	if err != nil {
+		return nil, err
+	}
+	if isMentor {
+		return nil, ErrAlreadyMentor
+	}
+
+	var idSolicitud int
+	err = s.db.QueryRow(ctx,
+		`INSERT INTO tb_solicitud_mentor (id_persona, areas_experiencia, experiencia, linkedin_url, motivacion)
+		 VALUES ($1, $2, $3, $4, $5)
+		 RETURNING id_solicitud`,
+		idPersona, input.AreasExperiencia, input.Experiencia, input.LinkedInURL, input.Motivacion,
+	).Scan(&idSolicitud)
+	var pgErr *pgconn.PgError
+	if errors.As(err, &pgErr) && pgErr.Code == "23505" {
+		return nil, ErrMentorApplicationOpen
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	application, err := s.Get(ctx, idSolicitud)
+	if err != nil {
+		return nil, err
+	}
+	s.notify(ctx, application)
+	return application, nil
+}
+
+// Resubmit actualiza una solicitud a la que se le pidieron cambios y la vuelve a dejar pendiente
+func (s *MentorApplicationService) Resubmit(ctx context.Context, idPersona int, input MentorApplicationInput) (*models.MentorApplication, error) {
+	input, err := normalizeMentorApplication(input)
+	if err != nil {
+		return nil, err
+	}
+
+	var idSolicitud int
+	err = s.db.QueryRow(ctx,
+		`UPDATE tb_solicitud_mentor
+		 SET areas_experiencia = $2, experiencia = $3, linkedin_url = $4, motivacion = $5,
+		     estado = $6, fecha_actualizacion = NOW()
+		 WHERE id_persona = $1 AND estado = $7
+		 RETURNING id_solicitud`,
+		idPersona, input.AreasExperiencia, input.Experiencia, input.LinkedInURL, input.Motivacion,
+		models.MentorApplicationPending, models.MentorApplicationChangesRequested,
+	).Scan(&idSolicitud)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, ErrMentorApplicationNotEditable
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	application, err := s.Get(ctx, idSolicitud)
+	if err != nil {
+		return nil, err
+	}
+	s.notify(ctx, application)
+	return application, nil
+}
+
+// GetLatest obtiene la última solicitud del usuario
+func (s *MentorApplicationService) GetLatest(ctx context.Context, idPersona int) (*models.MentorApplication, error) {
+	application, err := scanMentorApplication(s.db.QueryRow(ctx,
+		`SELECT `+mentorApplicationColumns+`
+		 FROM tb_solicitud_mentor s JOIN tb_persona p ON p.id_persona = s.id_persona
+		 WHERE s.id_persona = $1
+		 ORDER BY s.fecha_creacion DESC, s.id_solicitud DESC
+		 LIMIT 1`,
+		idPersona,
+	))
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, ErrMentorApplicationNotFound
+	}
+	return application, err
+}
+
+// Get obtiene una solicitud por su ID
+func (s *MentorApplicationService) Get(ctx context.Context, idSolicitud int) (*models.MentorApplication, error) {
+	application, err := scanMentorApplication(s.db.QueryRow(ctx,
+		`SELECT `+mentorApplicationColumns+`
+		 FROM tb_solicitud_mentor s JOIN tb_persona p ON p.id_persona = s.id_persona
+		 WHERE s.id_solicitud = $1`,
+		idSolicitud,
+	))
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, ErrMentorApplicationNotFound
+	}
+	return application, err
+}
+
+// ListByUser devuelve todas las solicitudes del usuario, las más recientes primero
+func (s *MentorApplicationService) ListByUser(ctx context.Context, idPersona int) ([]models.MentorApplication, error) {
+	rows, err := s.db.Query(ctx,
+		`SELECT `+mentorApplicationColumns+`
+		 FROM tb_solicitud_mentor s JOIN tb_persona p ON p.id_persona = s.id_persona
+		 WHERE s.id_persona = $1
+		 ORDER BY s.fecha_creacion DESC, s.id_solicitud DESC`,
+		idPersona,
+	)
+	if err != nil {
+		return nil, err
+	}
+	return collectMentorApplications(rows)
+}
+
+// List devuelve las solicitudes en un estado, las más antiguas primero (cola de revisión)
+func (s *MentorApplicationService) List(ctx context.Context, estado string, limit int, offset int) ([]models.MentorApplication, error) {
+	rows, err := s.db.Query(ctx,
+		`SELECT `+mentorApplicationColumns+`
+		 FROM tb_solicitud_mentor s JOIN tb_persona p ON p.id_persona = s.id_persona
+		 WHERE s.estado = $1
+		 ORDER BY s.fecha_actualizacion, s.id_solicitud
+		 LIMIT $2 OFFSET $3`,
+		estado, limit, offset,
+	)
+	if err != nil {
+		return nil, err
+	}
+	return collectMentorApplications(rows)
+}
+
+func collectMentorApplications(rows pgx.Rows) ([]models.MentorApplication, error) {
+	defer rows.Close()
+
+	applications := []models.MentorApplication{}
+	for rows.Next() {
+		application, err := scanMentorApplication(rows)
+		if err != nil {
+			return nil, err
+		}
+		applications = append(applications, *application)
+	}
+	return applications, rows.Err()
+}
+
+// Review resuelve una solicitud pendiente: la aprueba (y otorga el rol mentor), la rechaza
+// o pide cambios. Rechazar y pedir cambios requieren un comentario para el solicitante.
+func (s *MentorApplicationService) Review(ctx context.Context, idSolicitud int, idRevisor int, estado string, comentario string) (*models.MentorApplication, error) {
+	comentario = strings.TrimSpace(comentario)
+	switch estado {
+	case models.MentorApplicationApproved:
+	case models.MentorApplicationRejected, models.MentorApplicationChangesRequested:
+		if comentario == "" {
+			return nil, ErrReviewCommentRequired
+		}
+	default:
+		return nil, ErrInvalidReviewDecision
+	}
+
+	tx, err := s.db.Begin(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback(ctx)
+
+	var idPersona int
+	err = tx.QueryRow(ctx,
+		`UPDATE tb_solicitud_mentor
+		 SET estado = $2, comentario_revision = NULLIF($3, ''), id_revisor = $4,
+		     fecha_revision = NOW(), fecha_actualizacion = NOW()
+		 WHERE id_solicitud = $1 AND estado = $5
+		 RETURNING id_persona`,
+		idSolicitud, estado, comentario, idRevisor, models.MentorApplicationPending,
+	).Scan(&idPersona)
+	if errors.Is(err, pgx.ErrNoRows) {
+		if _, getErr := s.Get(ctx, idSolicitud); errors.Is(getErr, ErrMentorApplicationNotFound) {
+			return nil, getErr
+		}
+		return nil, ErrMentorApplicationNotPending
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	if estado == models.MentorApplicationApproved {
+		_, err = tx.Exec(ctx,
+			`INSERT INTO tb_persona_rol (id_persona, id_rol)
+			 SELECT $1, id_rol FROM tb_rol WHERE nombre_rol = $2
+			 ON CONFLICT DO NOTHING`,
+			idPersona, MentorRoleName,
+		)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return nil, err
+	}
+
+	application, err := s.Get(ctx, idSolicitud)
+	if err != nil {
+		return nil, err
+	}
+	s.notify(ctx, application)
+	return application, nil
+}
+
+// notify avisa al solicitante del estado de su solicitud. Un error al enviar el email
+// no revierte el cambio de estado.
+func (s *MentorApplicationService) notify(ctx context.Context, a *models.MentorApplication) {
+	link := s.linkBaseURL + "/mentor-application"
+
+	var subject, body string
+	switch a.Estado {
+	case models.MentorApplicationPending:
+		subject = "Recibimos tu solicitud para ser mentor"
+		body = "Recibimos tu solicitud para ser mentor en Mentorly. Te vamos a avisar cuando el equipo la revise."
+	case models.MentorApplicationApproved:
+		subject = "¡Tu solicitud para ser mentor fue aprobada!"
+		body = "Tu solicitud fue aprobada y ya tenés el rol de mentor en Mentorly."
+	case models.MentorApplicationRejected:
+		subject = "Tu solicitud para ser mentor fue rechazada"
+		body = "Revisamos tu solicitud y por ahora no fue aprobada."
+	case models.MentorApplicationChangesRequested:
+		subject = "Tu solicitud para ser mentor necesita cambios"
+		body = "Revisamos tu solicitud y necesitamos que cambies algunos datos antes de aprobarla."
+	default:
+		return
+	}
+	if a.ComentarioRevision != nil && a.Estado != models.MentorApplicationPending {
+		body += "\n\nComentario del equipo:\n" + *a.ComentarioRevision
+	}
+
+	err := s.mailer.Send(ctx, EmailMessage{
+		To:      a.Email,
+		Subject: subject,
+		Body:    fmt.Sprintf("Hola %s,\n\n%s\n\nPodés ver el estado de tu solicitud en:\n\n%s\n", a.Nombre, body, link),
+	})
+	if err != nil {
+		log.Printf("Error al notificar la solicitud de mentor %d: %v", a.IDSolicitud, err)
+	}
+}
+
+// normalizeMentorApplication recorta los campos y valida el perfil de LinkedIn
+func normalizeMentorApplication(input MentorApplicationInput) (MentorApplicationInput, error) {
+	input.AreasExperiencia = strings.TrimSpace(input.AreasExperiencia)
+	input.Experiencia = strings.TrimSpace(input.Experiencia)
+	input.LinkedInURL = strings.TrimSpace(input.LinkedInURL)
+	input.Motivacion = strings.TrimSpace(input.Motivacion)
+
+	if !isHTTPSURLOnHost(input.LinkedInURL, "linkedin.com") {
+		return input, ErrInvalidLinkedInURL
+	}
+	return input, nil
+}
@@ -0,0 +1,259 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"mentorly-backend/models"
+	"net/url"
+	"regexp"
+	"strings"
+	"time"
+	_ "time/tzdata" // para validar zonas horarias aunque el sistema no tenga la base de datos de zonas
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+// validLanguage acepta códigos ISO 639 con región opcional: es, en, pt-BR
+var validLanguage = regexp.MustCompile(`^[a-z]{2,3}(-[A-Z]{2})?$`)
+
+// MentorProfileInput son los datos que completa el mentor en su perfil
+type MentorProfileInput struct {
+	Titular          string
+	Biografia        string
+	AreasExperiencia []string
+	AniosExperiencia int
+	Idiomas          []string
+	ZonaHoraria      string
+	PrecioSesion     float64
+	LinkedInURL      string
+	GitHubURL        string
+	AvatarURL        string
+	// Disponible nil deja el valor actual (al crear el perfil, disponible)
+	Disponible *bool
+}
+
+// MentorProfileService maneja los perfiles de los mentores
+type MentorProfileService struct {
+	db *pgxpool.Pool
+}
+
+// NewMentorProfileService crea una nueva instancia del servicio de perfiles de mentor
+func NewMentorProfileService(db *pgxpool.Pool) *MentorProfileService {
+	return &MentorProfileService{db: db}
+}
+
+const mentorProfileColumns = `m.id_persona, p.nombre, p.apellido, m.titular, m.biografia, m.areas_experiencia,
+	m.anios_experiencia, m.idiomas, m.zona_horaria, m.precio_sesion, m.linkedin_url, m.github_url, m.avatar_url,
+	m.disponible, m.calificacion_promedio::float8, m.cantidad_calificaciones, m.fecha_creacion, m.fecha_actualizacion`
+
+// mentorVisible son las condiciones para mostrar un perfil públicamente: el usuario conserva
+// el rol mentor y la cuenta no está suspendida, anonimizada ni por eliminarse
+const mentorVisible = `EXISTS (SELECT 1 FROM tb_persona_rol pr JOIN tb_rol r ON r.id_rol = pr.id_rol
+	                WHERE pr.id_persona = p.id_persona AND r.nombre_rol = '` + MentorRoleName + `')
+	AND p.suspendido_en IS NULL AND p.anonimizado_en IS NULL AND p.eliminacion_programada_para IS NULL`
+
+// attachMentorSkills completa las habilidades de experiencia de los perfiles con una sola consulta
+func attachMentorSkills(ctx context.Context, db *pgxpool.Pool, profiles ...*models.MentorProfile) error {
+	ids := make([]int, len(profiles))
+	for i, profile := range profiles {
+		ids[i] = profile.IDPersona
+	}
+	skills, err := userSkills(ctx, db, ids, models.SkillTypeExpertise)
+	if err != nil {
+		return err
+	}
+	for _, profile := range profiles {
+		profile.Habilidades = skills[profile.IDPersona]
+		if profile.Habilidades == nil {
+			profile.Habilidades = []models.UserSkill{}
+		}
+	}
+	return nil
+}
+
+func scanMentorProfile(row pgx.Row) (*models.MentorProfile, error) {
+	var m models.MentorProfile
+	err := row.Scan(&m.IDPersona, &m.Nombre, &m.Apellido, &m.Titular, &m.Biografia, &m.AreasExperiencia,
+		&m.AniosExperiencia, &m.Idiomas, &m.ZonaHoraria, &m.PrecioSesion, &m.LinkedInURL, &m.GitHubURL, &m.AvatarURL,
+		&m.Disponible, &m.CalificacionPromedio, &m.CantidadCalificaciones, &m.FechaCreacion, &m.FechaActualizacion)
+	if err != nil {
+		return nil, err
+	}
+	return &m, nil
+}
+
+// Get obtiene el perfil de mentor del usuario, sea visible o no
+func (s *MentorProfileService) Get(ctx context.Context, idPersona int) (*models.MentorProfile, error) {
+	profile, err := scanMentorProfile(s.db.QueryRow(ctx,
+		`SELECT `+mentorProfileColumns+`
+		 FROM tb_perfil_mentor m JOIN tb_persona p ON p.id_persona = m.id_persona
+		 WHERE m.id_persona = $1`,
+		idPersona,
+	))
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, ErrMentorProfileNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	return profile, attachMentorSkills(ctx, s.db, profile)
+}
+
+// GetPublic obtiene el perfil de un mentor solo si se puede mostrar públicamente
+func (s *MentorProfileService) GetPublic(ctx context.Context, idPersona int) (*models.MentorProfile, error) {
+	profile, err := scanMentorProfile(s.db.QueryRow(ctx,
+		`SELECT `+mentorProfileColumns+`
+		 FROM tb_perfil_mentor m JOIN tb_persona p ON p.id_persona = m.id_persona
+		 WHERE m.id_persona = $1 AND `+mentorVisible,
+		idPersona,
+	))
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, ErrMentorProfileNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	return profile, attachMentorSkills(ctx, s.db, profile)
+}
+
+// Create crea el perfil. Falla si el usuario no tiene el rol mentor o ya tiene un perfil.
+func (s *MentorProfileService) Create(ctx context.Context, idPersona int, input MentorProfileInput) (*models.MentorProfile, error) {
+	input, err := normalizeMentorProfile(input)
+	if err != nil {
+		return nil, err
+	}
+
+	tag, err := s.db.Exec(ctx,
+		`INSERT INTO tb_perfil_mentor (id_persona, titular, biografia, areas_experiencia, anios_experiencia, idiomas,
+		                               zona_horaria, precio_sesion, linkedin_url, github_url, avatar_url, disponible)
+		 SELECT $1, $2, $3, $4, $5, $6, $7, $8, NULLIF($9, ''), NULLIF($10, ''), NULLIF($11, ''), COALESCE($12, TRUE)
+		 WHERE EXISTS (SELECT 1 FROM tb_persona_rol pr JOIN tb_rol r ON r.id_rol = pr.id_rol
+		               WHERE pr.id_persona = $1 AND r.nombre_rol = $13)`,
+		idPersona, input.Titular, input.Biografia, input.AreasExperiencia, input.AniosExperiencia, input.Idiomas,
+		input.ZonaHoraria, input.PrecioSesion, input.LinkedInURL, input.GitHubURL, input.AvatarURL, input.Disponible,
+		MentorRoleName,
+	)
+	var pgErr *pgconn.PgError
+	if errors.As(err, &pgErr) && pgErr.Code == "23505" {
+		return nil, ErrMentorProfileExists
+	}
+	if err != nil {
+		return nil, err
+	}
+	if tag.RowsAffected() == 0 {
+		return nil, ErrNotMentor
+	}
+
+	return s.Get(ctx, idPersona)
+}
+
+// Update reemplaza los datos del perfil
+func (s *MentorProfileService) Update(ctx context.Context, idPersona int, input MentorProfileInput) (*models.MentorProfile, error) {
+	input, err := normalizeMentorProfile(input)
+	if err != nil {
+		return nil, err
+	}
+
+	tag, err := s.db.Exec(ctx,
+		`UPDATE tb_perfil_mentor
+		 SET titular = $2, biografia = $3, areas_experiencia = $4, anios_experiencia = $5, idiomas = $6,
+		     zona_horaria = $7, precio_sesion = $8, linkedin_url = NULLIF($9, ''), github_url = NULLIF($10, ''),
+		     avatar_url = NULLIF($11, ''), disponible = COALESCE($12, disponible), fecha_actualizacion = NOW()
+		 WHERE id_persona = $1`,
+		idPersona, input.Titular, input.Biografia, input.AreasExperiencia, input.AniosExperiencia, input.Idiomas,
+		input.ZonaHoraria, input.PrecioSesion, input.LinkedInURL, input.GitHubURL, input.AvatarURL, input.Disponible,
+	)
+	if err != nil {
+		return nil, err
+	}
+	if tag.RowsAffected() == 0 {
+		return nil, ErrMentorProfileNotFound
+	}
+
+	return s.Get(ctx, idPersona)
+}
+
+// Delete borra el perfil del usuario
+func (s *MentorProfileService) Delete(ctx context.Context, idPersona int) error {
+	tag, err := s.db.Exec(ctx, "DELETE FROM tb_perfil_mentor WHERE id_persona = $1", idPersona)
+	if err != nil {
+		return err
+	}
+	if tag.RowsAffected() == 0 {
+		return ErrMentorProfileNotFound
+	}
+	return nil
+}
+
+// normalizeMentorProfile recorta los campos, quita repetidos y valida zona horaria, idiomas y URLs.
+// Los largos y cantidades ya los valida el handler.
+func normalizeMentorProfile(input MentorProfileInput) (MentorProfileInput, error) {
+	input.Titular = strings.TrimSpace(input.Titular)
+	input.Biografia = strings.TrimSpace(input.Biografia)
+	input.ZonaHoraria = strings.TrimSpace(input.ZonaHoraria)
+	input.LinkedInURL = strings.TrimSpace(input.LinkedInURL)
+	input.GitHubURL = strings.TrimSpace(input.GitHubURL)
+	input.AvatarURL = strings.TrimSpace(input.AvatarURL)
+
+	areas := make([]string, 0, len(input.AreasExperiencia))
+	seen := map[string]bool{}
+	for _, area := range input.AreasExperiencia {
+		area = strings.TrimSpace(area)
+		key := strings.ToLower(area)
+		if seen[key] {
+			return input, ErrDuplicateExpertiseArea
+		}
+		seen[key] = true
+		areas = append(areas, area)
+	}
+	input.AreasExperiencia = areas
+
+	idiomas := make([]string, 0, len(input.Idiomas))
+	seen = map[string]bool{}
+	for _, idioma := range input.Idiomas {
+		idioma = strings.TrimSpace(idioma)
+		if !validLanguage.MatchString(idioma) {
+			return input, ErrInvalidLanguage
+		}
+		if !seen[idioma] {
+			seen[idioma] = true
+			idiomas = append(idiomas, idioma)
+		}
+	}
+	input.Idiomas = idiomas
+
+	// LoadLocation acepta "" y "Local", que no identifican una zona
+	if input.ZonaHoraria == "" || input.ZonaHoraria == "Local" {
+		return input, ErrInvalidTimezone
+	}
+	if _, err := time.LoadLocation(input.ZonaHoraria); err != nil {
+		return input, ErrInvalidTimezone
+	}
+
+	if input.LinkedInURL != "" && !isHTTPSURLOnHost(input.LinkedInURL, "linkedin.com") {
+		return input, ErrInvalidLinkedInURL
+	}
+	if input.GitHubURL != "" && !isHTTPSURLOnHost(input.GitHubURL, "github.com") {
+		return input, ErrInvalidGitHubURL
+	}
+	if input.AvatarURL != "" && !isHTTPSURLOnHost(input.AvatarURL, "") {
+		return input, ErrInvalidAvatarURL
+	}
+	return input, nil
+}
+
+// isHTTPSURLOnHost indica si rawURL es una URL https del dominio indicado o de un subdominio.
+// Con domain vacío acepta cualquier dominio.
+func isHTTPSURLOnHost(rawURL string, domain string) bool {
+	u, err := url.Parse(rawURL)
+	if err != nil || u.Scheme != "https" || u.Host == "" {
+		return false
+	}
+	if domain == "" {
+		return true
+	}
+	host := strings.ToLower(u.Hostname())
+	return host == domain || strings.HasSuffix(host, "."+domain)
+}
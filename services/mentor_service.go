@@ -0,0 +1,355 @@
+package services
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"mentorly-backend/models"
+	"strings"
+	"time"
+
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+// Órdenes de la búsqueda de mentores
+const (
+	MentorSortRelevance = "relevancia"
+	MentorSortRating    = "calificacion"
+	MentorSortPriceAsc  = "precio_asc"
+	MentorSortPriceDesc = "precio_desc"
+)
+
+// mentorFacetLimit es la cantidad máxima de valores por faceta de habilidades o idiomas
+const mentorFacetLimit = 20
+
+// mentorPriceRanges son los rangos de la faceta de precios
+var mentorPriceRanges = []struct {
+	valor     string
+	condicion string
+}{
+	{"gratis", "m.precio_sesion = 0"},
+	{"hasta_25", "m.precio_sesion > 0 AND m.precio_sesion <= 25"},
+	{"25_a_50", "m.precio_sesion > 25 AND m.precio_sesion <= 50"},
+	{"50_a_100", "m.precio_sesion > 50 AND m.precio_sesion <= 100"},
+	{"mas_de_100", "m.precio_sesion > 100"},
+}
+
+// MentorSearchFilter son los criterios de la búsqueda. Los campos vacíos no filtran.
+type MentorSearchFilter struct {
+	// Texto se busca en nombre, titular, áreas y biografía, en español e inglés
+	Texto string
+	// Habilidades son slugs del catálogo y tienen que estar todas; de Idiomas alcanza con uno
+	Habilidades []string
+	// Categoria es el slug de una categoría o subcategoría del catálogo (incluye sus subcategorías)
+	Categoria string
+	Idiomas   []string
+	PrecioMin *float64
+	PrecioMax *float64
+	// ZonaHoraria es la del mentorado: se buscan mentores a lo sumo DiferenciaHoraria horas de distancia
+	ZonaHoraria       string
+	DiferenciaHoraria int
+	CalificacionMin   *float64
+	SoloDisponibles   bool
+	// Orden es uno de los MentorSort*; vacío es relevancia si hay texto y calificación si no
+	Orden  string
+	Cursor string
+	Limit  int
+}
+
+// mentorCursor es la posición de la última fila devuelta. Se envía codificado al cliente.
+type mentorCursor struct {
+	Orden string  `json:"o"`
+	Valor float64 `json:"v"`
+	ID    int     `json:"id"`
+}
+
+// MentorService implementa la búsqueda de mentores
+type MentorService struct {
+	db *pgxpool.Pool
+}
+
+// NewMentorService crea una nueva instancia del servicio de búsqueda de mentores
+func NewMentorService(db *pgxpool.Pool) *MentorService {
+	return &MentorService{db: db}
+}
+
+// Search devuelve una página de mentores visibles que cumplen el filtro. La paginación es por
+// cursor: SiguienteCursor se pasa en la próxima llamada con los mismos filtros.
+func (s *MentorService) Search(ctx context.Context, filter MentorSearchFilter) (*models.MentorSearchResult, error) {
+	if filter.Orden == "" {
+		filter.Orden = MentorSortRating
+		if filter.Texto != "" {
+			filter.Orden = MentorSortRelevance
+		}
+	}
+
+	q := newMentorQuery()
+	if err := q.filter(filter); err != nil {
+		return nil, err
+	}
+
+	sortExpr, desc, err := q.sort(filter)
+	if err != nil {
+		return nil, err
+	}
+	dir, cmp := "ASC", ">"
+	if desc {
+		dir, cmp = "DESC", "<"
+	}
+
+	var facets *models.MentorFacets
+	if filter.Cursor == "" {
+		if facets, err = s.facets(ctx, q); err != nil {
+			return nil, err
+		}
+	} else {
+		cursor, err := decodeMentorCursor(filter.Cursor, filter.Orden)
+		if err != nil {
+			return nil, err
+		}
+		q.add(fmt.Sprintf("(%s, m.id_persona) %s ($%%d, $%%d)", sortExpr, cmp), cursor.Valor, cursor.ID)
+	}
+
+	args := append(q.args, filter.Limit+1)
+	rows, err := s.db.Query(ctx,
+		fmt.Sprintf(`SELECT %s, %s AS orden_valor
+		 FROM tb_perfil_mentor m JOIN tb_persona p ON p.id_persona = m.id_persona
+		 WHERE %s
+		 ORDER BY orden_valor %s, m.id_persona %s
+		 LIMIT $%d`, mentorProfileColumns, sortExpr, q.where(), dir, dir, len(args)),
+		args...,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	result := &models.MentorSearchResult{Mentores: []models.MentorProfile{}, Facetas: facets}
+	var last mentorCursor
+	for rows.Next() {
+		if len(result.Mentores) == filter.Limit {
+			// Hay una fila más: la página no es la última
+			encoded, err := encodeMentorCursor(last)
+			if err != nil {
+				return nil, err
+			}
+			result.SiguienteCursor = &encoded
+			break
+		}
+
+		var m models.MentorProfile
+		last = mentorCursor{Orden: filter.Orden}
+		err := rows.Scan(&m.IDPersona, &m.Nombre, &m.Apellido, &m.Titular, &m.Biografia, &m.AreasExperiencia,
+			&m.AniosExperiencia, &m.Idiomas, &m.ZonaHoraria, &m.PrecioSesion, &m.LinkedInURL, &m.GitHubURL, &m.AvatarURL,
+			&m.Disponible, &m.CalificacionPromedio, &m.CantidadCalificaciones, &m.FechaCreacion, &m.FechaActualizacion,
+			&last.Valor)
+		if err != nil {
+			return nil, err
+		}
+		last.ID = m.IDPersona
+		result.Mentores = append(result.Mentores, m)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	rows.Close()
+
+	profiles := make([]*models.MentorProfile, len(result.Mentores))
+	for i := range result.Mentores {
+		profiles[i] = &result.Mentores[i]
+	}
+	if err := attachMentorSkills(ctx, s.db, profiles...); err != nil {
+		return nil, err
+	}
+
+	return result, nil
+}
+
+// facets cuenta los resultados por disponibilidad, rango de precio, habilidad e idioma
+func (s *MentorService) facets(ctx context.Context, q *mentorQuery) (*models.MentorFacets, error) {
+	facets := &models.MentorFacets{Habilidades: []models.FacetCount{}, Idiomas: []models.FacetCount{}}
+
+	counts := []string{"COUNT(*)", "COUNT(*) FILTER (WHERE m.disponible)"}
+	for _, r := range mentorPriceRanges {
+		counts = append(counts, "COUNT(*) FILTER (WHERE "+r.condicion+")")
+	}
+	precios := make([]int, len(mentorPriceRanges))
+	dest := []any{&facets.Total, &facets.Disponibles}
+	for i := range precios {
+		dest = append(dest, &precios[i])
+	}
+	err := s.db.QueryRow(ctx,
+		fmt.Sprintf(`SELECT %s
+		 FROM tb_perfil_mentor m JOIN tb_persona p ON p.id_persona = m.id_persona
+		 WHERE %s`, strings.Join(counts, ", "), q.where()),
+		q.args...,
+	).Scan(dest...)
+	if err != nil {
+		return nil, err
+	}
+	for i, r := range mentorPriceRanges {
+		facets.Precios = append(facets.Precios, models.FacetCount{Valor: r.valor, Cantidad: precios[i]})
+	}
+
+	if facets.Habilidades, err = s.facetValues(ctx, q,
+		`h.slug, h.nombre`,
+		`JOIN tb_persona_habilidad ph ON ph.id_persona = m.id_persona AND ph.tipo = '`+models.SkillTypeExpertise+`'
+		 JOIN tb_habilidad h ON h.id_habilidad = ph.id_habilidad`,
+		"h.slug, h.nombre"); err != nil {
+		return nil, err
+	}
+	if facets.Idiomas, err = s.facetValues(ctx, q, "a.valor, ''", "CROSS JOIN LATERAL unnest(m.idiomas) AS a(valor)", "a.valor"); err != nil {
+		return nil, err
+	}
+	return facets, nil
+}
+
+// facetValues cuenta los resultados por cada valor de join; value son las columnas valor y nombre
+func (s *MentorService) facetValues(ctx context.Context, q *mentorQuery, value string, join string, group string) ([]models.FacetCount, error) {
+	rows, err := s.db.Query(ctx,
+		fmt.Sprintf(`SELECT %s, COUNT(*)
+		 FROM tb_perfil_mentor m JOIN tb_persona p ON p.id_persona = m.id_persona
+		 %s
+		 WHERE %s
+		 GROUP BY %s
+		 ORDER BY 3 DESC, 1
+		 LIMIT %d`, value, join, q.where(), group, mentorFacetLimit),
+		q.args...,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	values := []models.FacetCount{}
+	for rows.Next() {
+		var f models.FacetCount
+		if err := rows.Scan(&f.Valor, &f.Nombre, &f.Cantidad); err != nil {
+			return nil, err
+		}
+		values = append(values, f)
+	}
+	return values, rows.Err()
+}
+
+// mentorQuery arma las condiciones de la búsqueda con sus argumentos numerados
+type mentorQuery struct {
+	conditions []string
+	args       []any
+	// tsquery es la expresión de la búsqueda de texto, vacía si no hay texto
+	tsquery string
+}
+
+func newMentorQuery() *mentorQuery {
+	return &mentorQuery{conditions: []string{mentorVisible}}
+}
+
+// add agrega una condición; cada %d se reemplaza por el número de uno de los argumentos
+func (q *mentorQuery) add(condition string, args ...any) {
+	numbers := make([]any, len(args))
+	for i, arg := range args {
+		q.args = append(q.args, arg)
+		numbers[i] = len(q.args)
+	}
+	q.conditions = append(q.conditions, fmt.Sprintf(condition, numbers...))
+}
+
+func (q *mentorQuery) where() string {
+	return strings.Join(q.conditions, " AND ")
+}
+
+func (q *mentorQuery) filter(filter MentorSearchFilter) error {
+	if texto := strings.TrimSpace(filter.Texto); texto != "" {
+		q.args = append(q.args, texto)
+		n := len(q.args)
+		q.tsquery = fmt.Sprintf("(websearch_to_tsquery('spanish', $%d) || websearch_to_tsquery('english', $%d))", n, n)
+		q.conditions = append(q.conditions, "m.busqueda @@ "+q.tsquery)
+	}
+	// Las habilidades se buscan por slug o por alias, así los slugs de habilidades fusionadas siguen sirviendo
+	for _, habilidad := range filter.Habilidades {
+		q.add(`EXISTS (SELECT 1 FROM tb_persona_habilidad ph
+		               WHERE ph.id_persona = m.id_persona AND ph.tipo = '`+models.SkillTypeExpertise+`'
+		                 AND ph.id_habilidad IN (SELECT id_habilidad FROM tb_habilidad WHERE slug = $%[1]d
+		                                         UNION SELECT id_habilidad FROM tb_habilidad_alias WHERE slug = $%[1]d))`,
+			slugify(habilidad))
+	}
+	if filter.Categoria != "" {
+		q.add(`EXISTS (SELECT 1 FROM tb_persona_habilidad ph
+		               JOIN tb_habilidad h ON h.id_habilidad = ph.id_habilidad
+		               JOIN tb_categoria_habilidad c ON c.id_categoria = h.id_categoria
+		               LEFT JOIN tb_categoria_habilidad cp ON cp.id_categoria = c.id_padre
+		               WHERE ph.id_persona = m.id_persona AND ph.tipo = '`+models.SkillTypeExpertise+`'
+		                 AND (c.slug = $%[1]d OR cp.slug = $%[1]d))`,
+			slugify(filter.Categoria))
+	}
+	if len(filter.Idiomas) > 0 {
+		q.add("m.idiomas && $%d::text[]", filter.Idiomas)
+	}
+	if filter.PrecioMin != nil {
+		q.add("m.precio_sesion >= $%d", *filter.PrecioMin)
+	}
+	if filter.PrecioMax != nil {
+		q.add("m.precio_sesion <= $%d", *filter.PrecioMax)
+	}
+	if filter.CalificacionMin != nil {
+		q.add("m.calificacion_promedio >= $%d", *filter.CalificacionMin)
+	}
+	if filter.SoloDisponibles {
+		q.conditions = append(q.conditions, "m.disponible")
+	}
+
+	if filter.ZonaHoraria != "" {
+		loc, err := time.LoadLocation(filter.ZonaHoraria)
+		if err != nil || filter.ZonaHoraria == "Local" {
+			return ErrInvalidTimezone
+		}
+		_, offset := time.Now().In(loc).Zone()
+		// Diferencia entre los desfases UTC de las dos zonas, tomando la distancia más corta
+		// alrededor del reloj (UTC+12 y UTC-11 están a una hora). Se reduce módulo 24 h porque
+		// entre UTC+14 y UTC-12 hay más de un día.
+		diff := "mod(abs(extract(epoch FROM (NOW() AT TIME ZONE m.zona_horaria) - (NOW() AT TIME ZONE 'UTC')) - $%d)::numeric, 86400)"
+		q.add(fmt.Sprintf("least(%s, 86400 - %s) <= $%%d", diff, diff), offset, offset, filter.DiferenciaHoraria*3600)
+	}
+	return nil
+}
+
+// sort devuelve la expresión por la que se ordena y si el orden es descendente
+func (q *mentorQuery) sort(filter MentorSearchFilter) (string, bool, error) {
+	switch filter.Orden {
+	case MentorSortRelevance:
+		if q.tsquery == "" {
+			return "0::float8", true, nil
+		}
+		return "ts_rank_cd(m.busqueda, " + q.tsquery + ")::float8", true, nil
+	case MentorSortRating:
+		return "coalesce(m.calificacion_promedio, 0)::float8", true, nil
+	case MentorSortPriceAsc:
+		return "m.precio_sesion::float8", false, nil
+	case MentorSortPriceDesc:
+		return "m.precio_sesion::float8", true, nil
+	default:
+		return "", false, ErrInvalidMentorSort
+	}
+}
+
+func encodeMentorCursor(cursor mentorCursor) (string, error) {
+	encoded, err := json.Marshal(cursor)
+	if err != nil {
+		return "", err
+	}
+	return base64.RawURLEncoding.EncodeToString(encoded), nil
+}
+
+// decodeMentorCursor lee el cursor y comprueba que sea del mismo orden que la búsqueda
+func decodeMentorCursor(value string, orden string) (*mentorCursor, error) {
+	decoded, err := base64.RawURLEncoding.DecodeString(value)
+	if err != nil {
+		return nil, ErrInvalidCursor
+	}
+	var cursor mentorCursor
+	if err := json.Unmarshal(decoded, &cursor); err != nil || cursor.Orden != orden {
+		return nil, ErrInvalidCursor
+	}
+	return &cursor, nil
+}
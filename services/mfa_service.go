@@ -0,0 +1,381 @@
+package services
+
+import (
+	"context"
+	"crypto/rand"
+	"errors"
+	"mentorly-backend/models"
+	"strings"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+const (
+	// MFAChallengeDuration es el tiempo que tiene el usuario para ingresar el código después del login
+	MFAChallengeDuration = 5 * time.Minute
+	// maxMFAChallengeAttempts es la cantidad de códigos incorrectos que admite un desafío
+	maxMFAChallengeAttempts = 5
+
+	recoveryCodeCount  = 10
+	recoveryCodeLength = 10
+)
+
+// MFAService maneja la verificación en dos pasos con TOTP y códigos de recuperación
+type MFAService struct {
+	db        *pgxpool.Pool
+	encryptor *Encryptor
+}
+
+// NewMFAService crea el servicio. El encryptor se usa para guardar los secretos TOTP.
+func NewMFAService(db *pgxpool.Pool, encryptor *Encryptor) *MFAService {
+	return &MFAService{db: db, encryptor: encryptor}
+}
+
+// IsEnabled indica si el usuario tiene TOTP confirmado
+func (s *MFAService) IsEnabled(ctx context.Context, idPersona int) (bool, error) {
+	var enabled bool
+	err := s.db.QueryRow(ctx,
+		"SELECT EXISTS (SELECT 1 FROM tb_mfa_totp WHERE id_persona = $1 AND confirmado)",
+		idPersona,
+	).Scan(&enabled)
+	return enabled, err
+}
+
+// Status devuelve si el segundo factor está activo y cuántos códigos de recuperación quedan
+func (s *MFAService) Status(ctx context.Context, idPersona int) (*models.MFAStatus, error) {
+	status := &models.MFAStatus{}
+	err := s.db.QueryRow(ctx,
+		`SELECT EXISTS (SELECT 1 FROM tb_mfa_totp WHERE id_persona = $1 AND confirmado),
+		        (SELECT COUNT(*) FROM tb_codigo_recuperacion WHERE id_persona = $1 AND usado_en IS NULL)`,
+		idPersona,
+	).Scan(&status.Habilitado, &status.CodigosRestantes)
+	if err != nil {
+		return nil, err
+	}
+	return status, nil
+}
+
+// BeginTOTPSetup genera un secreto nuevo sin confirmar. Si había una configuración
+// pendiente se reemplaza; si TOTP ya está activo devuelve ErrMFAAlreadyEnabled.
+func (s *MFAService) BeginTOTPSetup(ctx context.Context, idPersona int, email string) (*models.TOTPSetup, error) {
+	secret := make([]byte, totpSecretSize)
+	if _, err := rand.Read(secret); err != nil {
+		return nil, err
+	}
+
+	encrypted, err := s.encryptor.Encrypt(secret)
+	if err != nil {
+		return nil, err
+	}
+
+	result, err := s.db.Exec(ctx,
+		`INSERT INTO tb_mfa_totp (id_persona, secreto_cifrado, confirmado, ultimo_paso, fecha_creacion)
+		 VALUES ($1, $2, FALSE, 0, NOW())
+		 ON CONFLICT (id_persona) DO UPDATE
+		 SET secreto_cifrado = EXCLUDED.secreto_cifrado, ultimo_paso = 0, fecha_creacion = NOW()
+		 WHERE NOT tb_mfa_totp.confirmado`,
+		idPersona, encrypted,
+	)
+	if err != nil {
+		return nil, err
+	}
+	if result.RowsAffected() == 0 {
+		return nil, ErrMFAAlreadyEnabled
+	}
+
+	return &models.TOTPSetup{
+		Secreto:    totpEncoding.EncodeToString(secret),
+		OtpauthURI: totpURI(secret, email),
+	}, nil
+}
+
+// ConfirmTOTP activa el segundo factor si el código corresponde al secreto pendiente
+// y devuelve los códigos de recuperación, que solo se muestran esta vez.
+func (s *MFAService) ConfirmTOTP(ctx context.Context, idPersona int, code string) ([]string, error) {
+	tx, err := s.db.Begin(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback(ctx)
+
+	var encrypted string
+	var confirmado bool
+	err = tx.QueryRow(ctx,
+		"SELECT secreto_cifrado, confirmado FROM tb_mfa_totp WHERE id_persona = $1 FOR UPDATE",
+		idPersona,
+	).Scan(&encrypted, &confirmado)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, ErrMFASetupNotStarted
+	}
+	if err != nil {
+		return nil, err
+	}
+	if confirmado {
+		return nil, ErrMFAAlreadyEnabled
+	}
+
+	secret, err := s.encryptor.Decrypt(encrypted)
+	if err != nil {
+		return nil, err
+	}
+
+	counter, ok := validateTOTP(secret, normalizeTOTPCode(code), time.Now(), 0)
+	if !ok {
+		return nil, ErrInvalidMFACode
+	}
+
+	_, err = tx.Exec(ctx,
+		`UPDATE tb_mfa_totp SET confirmado = TRUE, ultimo_paso = $2, fecha_confirmacion = NOW()
+		 WHERE id_persona = $1`,
+		idPersona, counter,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	codes, err := replaceRecoveryCodes(ctx, tx, idPersona)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return nil, err
+	}
+	return codes, nil
+}
+
+// VerifyCode valida un código TOTP o, si no tiene ese formato, un código de recuperación.
+// Ambos son de un solo uso.
+func (s *MFAService) VerifyCode(ctx context.Context, idPersona int, code string) error {
+	if totpCodeValue := normalizeTOTPCode(code); len(totpCodeValue) == totpDigits {
+		return s.verifyTOTP(ctx, idPersona, totpCodeValue)
+	}
+	return s.useRecoveryCode(ctx, idPersona, code)
+}
+
+func (s *MFAService) verifyTOTP(ctx context.Context, idPersona int, code string) error {
+	tx, err := s.db.Begin(ctx)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback(ctx)
+
+	var encrypted string
+	var lastCounter int64
+	err = tx.QueryRow(ctx,
+		"SELECT secreto_cifrado, ultimo_paso FROM tb_mfa_totp WHERE id_persona = $1 AND confirmado FOR UPDATE",
+		idPersona,
+	).Scan(&encrypted, &lastCounter)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return ErrMFANotEnabled
+	}
+	if err != nil {
+		return err
+	}
+
+	secret, err := s.encryptor.Decrypt(encrypted)
+	if err != nil {
+		return err
+	}
+
+	counter, ok := validateTOTP(secret, code, time.Now(), lastCounter)
+	if !ok {
+		return ErrInvalidMFACode
+	}
+
+	_, err = tx.Exec(ctx,
+		"UPDATE tb_mfa_totp SET ultimo_paso = $2 WHERE id_persona = $1",
+		idPersona, counter,
+	)
+	if err != nil {
+		return err
+	}
+
+	return tx.Commit(ctx)
+}
+
+func (s *MFAService) useRecoveryCode(ctx context.Context, idPersona int, code string) error {
+	normalized := normalizeRecoveryCode(code)
+	if normalized == "" {
+		return ErrInvalidMFACode
+	}
+
+	result, err := s.db.Exec(ctx,
+		`UPDATE tb_codigo_recuperacion SET usado_en = NOW()
+		 WHERE id_persona = $1 AND codigo_hash = $2 AND usado_en IS NULL`,
+		idPersona, hashToken(normalized),
+	)
+	if err != nil {
+		return err
+	}
+	if result.RowsAffected() == 0 {
+		return ErrInvalidMFACode
+	}
+	return nil
+}
+
+// RegenerateRecoveryCodes invalida los códigos anteriores y genera nuevos
+func (s *MFAService) RegenerateRecoveryCodes(ctx context.Context, idPersona int) ([]string, error) {
+	tx, err := s.db.Begin(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback(ctx)
+
+	var enabled bool
+	err = tx.QueryRow(ctx,
+		"SELECT EXISTS (SELECT 1 FROM tb_mfa_totp WHERE id_persona = $1 AND confirmado)",
+		idPersona,
+	).Scan(&enabled)
+	if err != nil {
+		return nil, err
+	}
+	if !enabled {
+		return nil, ErrMFANotEnabled
+	}
+
+	codes, err := replaceRecoveryCodes(ctx, tx, idPersona)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return nil, err
+	}
+	return codes, nil
+}
+
+// Disable desactiva el segundo factor. La reautenticación la valida el handler.
+func (s *MFAService) Disable(ctx context.Context, idPersona int) error {
+	tx, err := s.db.Begin(ctx)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback(ctx)
+
+	result, err := tx.Exec(ctx, "DELETE FROM tb_mfa_totp WHERE id_persona = $1", idPersona)
+	if err != nil {
+		return err
+	}
+	if result.RowsAffected() == 0 {
+		return ErrMFANotEnabled
+	}
+
+	if _, err := tx.Exec(ctx, "DELETE FROM tb_codigo_recuperacion WHERE id_persona = $1", idPersona); err != nil {
+		return err
+	}
+	if _, err := tx.Exec(ctx, "DELETE FROM tb_desafio_mfa WHERE id_persona = $1", idPersona); err != nil {
+		return err
+	}
+
+	return tx.Commit(ctx)
+}
+
+// CreateChallenge emite el token de desafío que se devuelve en lugar de la sesión
+// cuando el usuario tiene segundo factor. useCookies indica si al completarlo la
+// sesión se entrega en cookies (flujo OAuth).
+func (s *MFAService) CreateChallenge(ctx context.Context, idPersona int, useCookies bool) (string, error) {
+	rawToken, err := generateRandomToken(32)
+	if err != nil {
+		return "", err
+	}
+
+	_, err = s.db.Exec(ctx,
+		`INSERT INTO tb_desafio_mfa (id_persona, token_hash, usar_cookies, fecha_creacion, fecha_expiracion)
+		 VALUES ($1, $2, $3, NOW(), NOW() + $4::float8 * INTERVAL '1 second')`,
+		idPersona, hashToken(rawToken), useCookies, MFAChallengeDuration.Seconds(),
+	)
+	if err != nil {
+		return "", err
+	}
+
+	return rawToken, nil
+}
+
+// CompleteChallenge valida el código contra el desafío. Cada llamada consume un intento antes
+// de verificar el código, así pedidos simultáneos no superan el máximo; si el código es
+// correcto consume el desafío y devuelve el usuario.
+func (s *MFAService) CompleteChallenge(ctx context.Context, rawToken string, code string) (int, bool, error) {
+	if rawToken == "" {
+		return 0, false, ErrInvalidMFAChallenge
+	}
+
+	var idDesafio, idPersona int
+	var useCookies bool
+	err := s.db.QueryRow(ctx,
+		`UPDATE tb_desafio_mfa SET intentos = intentos + 1
+		 WHERE token_hash = $1 AND usado_en IS NULL AND fecha_expiracion > NOW() AND intentos < $2
+		 RETURNING id_desafio, id_persona, usar_cookies`,
+		hashToken(rawToken), maxMFAChallengeAttempts,
+	).Scan(&idDesafio, &idPersona, &useCookies)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return 0, false, ErrInvalidMFAChallenge
+	}
+	if err != nil {
+		return 0, false, err
+	}
+
+	if err := s.VerifyCode(ctx, idPersona, code); err != nil {
+		return idPersona, false, err
+	}
+
+	// Se marca como usado de forma condicional para que dos pedidos simultáneos no lo consuman ambos
+	result, err := s.db.Exec(ctx,
+		"UPDATE tb_desafio_mfa SET usado_en = NOW() WHERE id_desafio = $1 AND usado_en IS NULL",
+		idDesafio,
+	)
+	if err != nil {
+		return 0, false, err
+	}
+	if result.RowsAffected() == 0 {
+		return 0, false, ErrInvalidMFAChallenge
+	}
+
+	return idPersona, useCookies, nil
+}
+
+// replaceRecoveryCodes borra los códigos del usuario y guarda nuevos (solo el hash)
+func replaceRecoveryCodes(ctx context.Context, tx pgx.Tx, idPersona int) ([]string, error) {
+	if _, err := tx.Exec(ctx, "DELETE FROM tb_codigo_recuperacion WHERE id_persona = $1", idPersona); err != nil {
+		return nil, err
+	}
+
+	codes := make([]string, 0, recoveryCodeCount)
+	for range recoveryCodeCount {
+		raw := make([]byte, recoveryCodeLength)
+		if _, err := rand.Read(raw); err != nil {
+			return nil, err
+		}
+		code := totpEncoding.EncodeToString(raw)[:recoveryCodeLength]
+
+		if _, err := tx.Exec(ctx,
+			"INSERT INTO tb_codigo_recuperacion (id_persona, codigo_hash) VALUES ($1, $2)",
+			idPersona, hashToken(code),
+		); err != nil {
+			return nil, err
+		}
+
+		// Se muestran en dos grupos para que sean más fáciles de copiar
+		codes = append(codes, code[:recoveryCodeLength/2]+"-"+code[recoveryCodeLength/2:])
+	}
+
+	return codes, nil
+}
+
+// normalizeTOTPCode quita los espacios que algunas apps muestran en medio del código
+func normalizeTOTPCode(code string) string {
+	return strings.ReplaceAll(strings.TrimSpace(code), " ", "")
+}
+
+// normalizeRecoveryCode acepta el código con o sin guión y en cualquier combinación de mayúsculas
+func normalizeRecoveryCode(code string) string {
+	code = strings.ToUpper(strings.TrimSpace(code))
+	code = strings.ReplaceAll(code, "-", "")
+	code = strings.ReplaceAll(code, " ", "")
+	if len(code) != recoveryCodeLength {
+		return ""
+	}
+	return code
+}
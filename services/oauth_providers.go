@@ -0,0 +1,135 @@
+package services
+
+import (
+	"context"
+	"fmt"
+)
+
+type GoogleUserInfo struct {
+	ID            string `json:"id"`
+	Email         string `json:"email"`
+	VerifiedEmail bool   `json:"verified_email"`
+	Name          string `json:"name"`
+	Picture       string `json:"picture"`
+}
+
+type GitHubUserInfo struct {
+	ID        int    `json:"id"`
+	Login     string `json:"login"`
+	Email     string `json:"email"`
+	Name      string `json:"name"`
+	AvatarURL string `json:"avatar_url"`
+}
+
+type GitHubEmail struct {
+	Email    string `json:"email"`
+	Primary  bool   `json:"primary"`
+	Verified bool   `json:"verified"`
+}
+
+type LinkedInUserInfo struct {
+	Sub           string `json:"sub"`
+	Name          string `json:"name"`
+	Email         string `json:"email"`
+	EmailVerified bool   `json:"email_verified"`
+	Picture       string `json:"picture"`
+}
+
+// googleProvider implementa el login con Google
+type googleProvider struct {
+	oauth2Client
+}
+
+// NewGoogleProvider crea el proveedor de Google
+func NewGoogleProvider(config OAuthProviderConfig) OAuthProvider {
+	return &googleProvider{newOAuth2Client(GoogleProvider, config)}
+}
+
+func (p *googleProvider) UserInfo(ctx context.Context, token *OAuthToken, nonce string) (*OAuthUserInfo, error) {
+	var userInfo GoogleUserInfo
+	if err := p.getJSON(ctx, p.config.UserInfoURL, token.AccessToken, "", &userInfo); err != nil {
+		return nil, err
+	}
+
+	return &OAuthUserInfo{
+		ID:            userInfo.ID,
+		Email:         userInfo.Email,
+		EmailVerified: userInfo.VerifiedEmail,
+		Name:          userInfo.Name,
+		Avatar:        userInfo.Picture,
+		Provider:      GoogleProvider,
+	}, nil
+}
+
+// githubProvider implementa el login con GitHub
+type githubProvider struct {
+	oauth2Client
+}
+
+// NewGitHubProvider crea el proveedor de GitHub
+func NewGitHubProvider(config OAuthProviderConfig) OAuthProvider {
+	return &githubProvider{newOAuth2Client(GitHubProvider, config)}
+}
+
+func (p *githubProvider) UserInfo(ctx context.Context, token *OAuthToken, nonce string) (*OAuthUserInfo, error) {
+	var userInfo GitHubUserInfo
+	if err := p.getJSON(ctx, p.config.UserInfoURL, token.AccessToken, "application/vnd.github.v3+json", &userInfo); err != nil {
+		return nil, err
+	}
+
+	// GitHub solo permite publicar emails verificados en el perfil.
+	// Si no hay email en el perfil, obtenerlo del endpoint de emails
+	emailVerified := userInfo.Email != ""
+	if userInfo.Email == "" && p.config.EmailsURL != "" {
+		var emails []GitHubEmail
+		if err := p.getJSON(ctx, p.config.EmailsURL, token.AccessToken, "application/vnd.github.v3+json", &emails); err == nil {
+			for _, email := range emails {
+				if email.Primary {
+					userInfo.Email = email.Email
+					emailVerified = email.Verified
+					break
+				}
+			}
+		}
+	}
+
+	name := userInfo.Name
+	if name == "" {
+		name = userInfo.Login
+	}
+
+	return &OAuthUserInfo{
+		ID:            fmt.Sprintf("%d", userInfo.ID),
+		Email:         userInfo.Email,
+		EmailVerified: emailVerified,
+		Name:          name,
+		Avatar:        userInfo.AvatarURL,
+		Provider:      GitHubProvider,
+	}, nil
+}
+
+// linkedinProvider implementa el login con LinkedIn (OpenID Connect)
+type linkedinProvider struct {
+	oauth2Client
+}
+
+// NewLinkedInProvider crea el proveedor de LinkedIn
+func NewLinkedInProvider(config OAuthProviderConfig) OAuthProvider {
+	return &linkedinProvider{newOAuth2Client(LinkedInProvider, config)}
+}
+
+func (p *linkedinProvider) UserInfo(ctx context.Context, token *OAuthToken, nonce string) (*OAuthUserInfo, error) {
+	var userInfo LinkedInUserInfo
+	if err := p.getJSON(ctx, p.config.UserInfoURL, token.AccessToken, "", &userInfo); err != nil {
+		return nil, err
+	}
+
+	return &OAuthUserInfo{
+		ID:            userInfo.Sub,
+		Email:         userInfo.Email,
+		EmailVerified: userInfo.EmailVerified,
+		Name:          userInfo.Name,
+		Avatar:        userInfo.Picture,
+		Provider:      LinkedInProvider,
+	}, nil
+}
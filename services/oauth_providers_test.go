@@ -0,0 +1,197 @@
+package services
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+)
+
+// fakeOAuthServer simula los endpoints de token y de datos del usuario de los proveedores.
+// Guarda el último formulario recibido en el endpoint de token.
+type fakeOAuthServer struct {
+	*httptest.Server
+	lastTokenForm url.Values
+}
+
+const (
+	fakeCode         = "codigo-de-prueba"
+	fakeCodeVerifier = "verificador-pkce-de-prueba"
+	fakeAccessToken  = "access-token-de-prueba"
+)
+
+func newFakeOAuthServer(t *testing.T) *fakeOAuthServer {
+	t.Helper()
+	fake := &fakeOAuthServer{}
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("POST /token", func(w http.ResponseWriter, r *http.Request) {
+		if err := r.ParseForm(); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		fake.lastTokenForm = r.PostForm
+		w.Header().Set("Content-Type", "application/json")
+		if r.PostForm.Get("code") != fakeCode {
+			// Como GitHub: 200 con el error en el cuerpo
+			json.NewEncoder(w).Encode(map[string]string{"error": "bad_verification_code"})
+			return
+		}
+		json.NewEncoder(w).Encode(map[string]any{"access_token": fakeAccessToken, "token_type": "Bearer"})
+	})
+
+	userinfo := map[string]any{
+		"/google/userinfo": map[string]any{
+			"id": "g-123", "email": "ana@gmail.com", "verified_email": true, "name": "Ana Pérez", "picture": "https://img/ana.png",
+		},
+		"/github/user": map[string]any{
+			"id": 4567, "login": "anap", "email": "", "name": "", "avatar_url": "https://img/anap.png",
+		},
+		"/github/user/emails": []map[string]any{
+			{"email": "vieja@example.com", "primary": false, "verified": true},
+			{"email": "ana@example.com", "primary": true, "verified": true},
+		},
+		"/linkedin/userinfo": map[string]any{
+			"sub": "li-789", "name": "Ana P.", "email": "ana@linkedin.example", "email_verified": false, "picture": "https://img/li.png",
+		},
+	}
+	for path, body := range userinfo {
+		mux.HandleFunc("GET "+path, func(w http.ResponseWriter, r *http.Request) {
+			if r.Header.Get("Authorization") != "Bearer "+fakeAccessToken {
+				http.Error(w, "unauthorized", http.StatusUnauthorized)
+				return
+			}
+			w.Header().Set("Content-Type", "application/json")
+			json.NewEncoder(w).Encode(body)
+		})
+	}
+
+	fake.Server = httptest.NewServer(mux)
+	t.Cleanup(fake.Close)
+	return fake
+}
+
+// setProviderEnv configura el proveedor para que use el servidor falso
+func setProviderEnv(t *testing.T, prefix string, baseURL string, userinfoPath string) {
+	t.Helper()
+	t.Setenv(prefix+"_CLIENT_ID", strings.ToLower(prefix)+"-client")
+	t.Setenv(prefix+"_CLIENT_SECRET", strings.ToLower(prefix)+"-secret")
+	t.Setenv(prefix+"_REDIRECT_URL", "http://localhost:8080/auth/"+strings.ToLower(prefix)+"/callback")
+	t.Setenv(prefix+"_AUTH_URL", baseURL+"/authorize")
+	t.Setenv(prefix+"_TOKEN_URL", baseURL+"/token")
+	t.Setenv(prefix+"_USERINFO_URL", baseURL+userinfoPath)
+}
+
+func TestOAuthProvidersAgainstFakeServer(t *testing.T) {
+	fake := newFakeOAuthServer(t)
+	t.Setenv("OIDC_PROVIDERS", "")
+	setProviderEnv(t, "GOOGLE", fake.URL, "/google/userinfo")
+	setProviderEnv(t, "GITHUB", fake.URL, "/github/user")
+	t.Setenv("GITHUB_EMAILS_URL", fake.URL+"/github/user/emails")
+	setProviderEnv(t, "LINKEDIN", fake.URL, "/linkedin/userinfo")
+
+	registry := NewOAuthRegistryFromEnv()
+
+	tests := []struct {
+		name     OAuthProviderName
+		prefix   string
+		expected OAuthUserInfo
+	}{
+		{
+			name:   GoogleProvider,
+			prefix: "google",
+			expected: OAuthUserInfo{
+				ID: "g-123", Email: "ana@gmail.com", EmailVerified: true, Name: "Ana Pérez",
+				Avatar: "https://img/ana.png", Provider: GoogleProvider,
+			},
+		},
+		{
+			// Sin email ni nombre en el perfil: el email sale de /user/emails y el nombre del login
+			name:   GitHubProvider,
+			prefix: "github",
+			expected: OAuthUserInfo{
+				ID: "4567", Email: "ana@example.com", EmailVerified: true, Name: "anap",
+				Avatar: "https://img/anap.png", Provider: GitHubProvider,
+			},
+		},
+		{
+			name:   LinkedInProvider,
+			prefix: "linkedin",
+			expected: OAuthUserInfo{
+				ID: "li-789", Email: "ana@linkedin.example", EmailVerified: false, Name: "Ana P.",
+				Avatar: "https://img/li.png", Provider: LinkedInProvider,
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(string(tt.name), func(t *testing.T) {
+			provider, err := registry.Get(tt.name)
+			if err != nil {
+				t.Fatalf("el proveedor no se registró: %v", err)
+			}
+
+			authURL, err := url.Parse(provider.AuthCodeURL(&AuthRequest{State: "st", CodeChallenge: "cc", Nonce: "nn"}))
+			if err != nil {
+				t.Fatalf("URL de autorización inválida: %v", err)
+			}
+			if got := authURL.Scheme + "://" + authURL.Host + authURL.Path; got != fake.URL+"/authorize" {
+				t.Errorf("AuthCodeURL apunta a %s, se esperaba el servidor falso", got)
+			}
+			query := authURL.Query()
+			if query.Get("state") != "st" || query.Get("code_challenge") != "cc" || query.Get("code_challenge_method") != "S256" {
+				t.Errorf("faltan parámetros de state o PKCE: %v", query)
+			}
+
+			token, err := provider.Exchange(context.Background(), fakeCode, fakeCodeVerifier)
+			if err != nil {
+				t.Fatalf("Exchange: %v", err)
+			}
+			if token.AccessToken != fakeAccessToken {
+				t.Errorf("access token = %q, se esperaba %q", token.AccessToken, fakeAccessToken)
+			}
+
+			form := fake.lastTokenForm
+			expectedForm := map[string]string{
+				"grant_type":    "authorization_code",
+				"code":          fakeCode,
+				"code_verifier": fakeCodeVerifier,
+				"client_id":     tt.prefix + "-client",
+				"client_secret": tt.prefix + "-secret",
+				"redirect_uri":  "http://localhost:8080/auth/" + tt.prefix + "/callback",
+			}
+			for key, want := range expectedForm {
+				if got := form.Get(key); got != want {
+					t.Errorf("%s enviado al endpoint de token = %q, se esperaba %q", key, got, want)
+				}
+			}
+
+			info, err := provider.UserInfo(context.Background(), token, "nn")
+			if err != nil {
+				t.Fatalf("UserInfo: %v", err)
+			}
+			if *info != tt.expected {
+				t.Errorf("UserInfo = %+v, se esperaba %+v", *info, tt.expected)
+			}
+		})
+	}
+}
+
+func TestOAuthExchangeRejectsErrorInBody(t *testing.T) {
+	fake := newFakeOAuthServer(t)
+	provider := NewGitHubProvider(OAuthProviderConfig{ClientID: "id", TokenURL: fake.URL + "/token"})
+
+	if _, err := provider.Exchange(context.Background(), "codigo-invalido", fakeCodeVerifier); err == nil {
+		t.Fatal("se esperaba un error cuando el proveedor devuelve error con status 200")
+	}
+}
+
+func TestProviderConfigFromEnvWithoutClientID(t *testing.T) {
+	t.Setenv("GOOGLE_CLIENT_ID", "")
+	if _, ok := providerConfigFromEnv("GOOGLE", OAuthProviderConfig{}); ok {
+		t.Error("un proveedor sin client id no se debe registrar")
+	}
+}
@@ -1,319 +1,304 @@
 package services
 
 import (
+	"context"
 	"encoding/json"
 	"fmt"
 	"io"
+	"log"
 	"net/http"
 	"net/url"
 	"os"
+	"sort"
 	"strings"
+	"time"
 )
 
-type OAuthProvider string
+type OAuthProviderName string
 
 const (
-	GoogleProvider   OAuthProvider = "google"
-	GitHubProvider   OAuthProvider = "github"
-	LinkedInProvider OAuthProvider = "linkedin"
+	GoogleProvider   OAuthProviderName = "google"
+	GitHubProvider   OAuthProviderName = "github"
+	LinkedInProvider OAuthProviderName = "linkedin"
 )
 
 type OAuthUserInfo struct {
-	ID       string
-	Email    string
-	Name     string
-	Avatar   string
-	Provider OAuthProvider
+	ID            string
+	Email         string
+	EmailVerified bool
+	Name          string
+	Avatar        string
+	Provider      OAuthProviderName
 }
 
-type GoogleTokenResponse struct {
-	AccessToken string `json:"access_token"`
-	TokenType   string `json:"token_type"`
-	ExpiresIn   int    `json:"expires_in"`
+// OAuthToken es la respuesta del endpoint de token de un proveedor
+type OAuthToken struct {
+	AccessToken      string `json:"access_token"`
+	TokenType        string `json:"token_type"`
+	ExpiresIn        int    `json:"expires_in"`
+	Scope            string `json:"scope"`
+	IDToken          string `json:"id_token"`
+	Error            string `json:"error"`
+	ErrorDescription string `json:"error_description"`
 }
 
-type GoogleUserInfo struct {
-	ID      string `json:"id"`
-	Email   string `json:"email"`
-	Name    string `json:"name"`
-	Picture string `json:"picture"`
+// AuthRequest son los parámetros de un flujo de autorización en curso
+type AuthRequest struct {
+	State         string
+	CodeChallenge string
+	Nonce         string
+	// BrowserNonce no va en la URL: se guarda en una cookie del navegador que inicia el flujo
+	BrowserNonce string
 }
 
-type GitHubTokenResponse struct {
-	AccessToken string `json:"access_token"`
-	TokenType   string `json:"token_type"`
-	Scope       string `json:"scope"`
+// OAuthProvider es un proveedor de login externo
+type OAuthProvider interface {
+	// Name es el identificador usado en las rutas (/oauth/:provider/url)
+	Name() OAuthProviderName
+	// AuthCodeURL construye la URL de autorización con state, code_challenge PKCE y nonce
+	AuthCodeURL(req *AuthRequest) string
+	// Exchange intercambia el código de autorización por un token
+	Exchange(ctx context.Context, code string, codeVerifier string) (*OAuthToken, error)
+	// UserInfo obtiene los datos del usuario y los mapea a OAuthUserInfo.
+	// El nonce solo lo usan los proveedores que validan un id_token.
+	UserInfo(ctx context.Context, token *OAuthToken, nonce string) (*OAuthUserInfo, error)
 }
 
-type GitHubUserInfo struct {
-	ID        int    `json:"id"`
-	Login     string `json:"login"`
-	Email     string `json:"email"`
-	Name      string `json:"name"`
-	AvatarURL string `json:"avatar_url"`
+// OAuthProviderConfig contiene credenciales y endpoints de un proveedor.
+// Los endpoints se pueden sobreescribir, por ejemplo para apuntar a un servidor falso en tests.
+type OAuthProviderConfig struct {
+	ClientID     string
+	ClientSecret string
+	RedirectURL  string
+	AuthURL      string
+	TokenURL     string
+	UserInfoURL  string
+	// EmailsURL solo lo usa GitHub, cuando el perfil no tiene email público
+	EmailsURL string
+	Scopes    []string
+	// HTTPClient es opcional; por defecto se usa un cliente con timeout
+	HTTPClient *http.Client
 }
 
-type LinkedInTokenResponse struct {
-	AccessToken string `json:"access_token"`
-	TokenType   string `json:"token_type"`
-	ExpiresIn   int    `json:"expires_in"`
+// oauth2Client implementa la parte común del flujo authorization code
+type oauth2Client struct {
+	name   OAuthProviderName
+	config OAuthProviderConfig
+	client *http.Client
 }
 
-type LinkedInUserInfo struct {
-	ID                 string `json:"id"`
-	LocalizedFirstName string `json:"localizedFirstName"`
-	LocalizedLastName  string `json:"localizedLastName"`
-	ProfilePicture     struct {
-		DisplayImage string `json:"displayImage"`
-	} `json:"profilePicture"`
+func newOAuth2Client(name OAuthProviderName, config OAuthProviderConfig) oauth2Client {
+	client := config.HTTPClient
+	if client == nil {
+		client = &http.Client{Timeout: 10 * time.Second}
+	}
+	return oauth2Client{name: name, config: config, client: client}
 }
 
-type LinkedInEmailResponse struct {
-	Elements []struct {
-		Handle string `json:"handle"`
-	} `json:"elements"`
+func (o *oauth2Client) Name() OAuthProviderName {
+	return o.name
 }
 
-// ExchangeGoogleCode intercambia el código de Google por token y obtiene info del usuario
-func ExchangeGoogleCode(code string) (*OAuthUserInfo, error) {
-	clientID := os.Getenv("GOOGLE_CLIENT_ID")
-	clientSecret := os.Getenv("GOOGLE_CLIENT_SECRET")
-	redirectURL := os.Getenv("GOOGLE_REDIRECT_URL")
-
-	tokenURL := "https://oauth2.googleapis.com/token"
-	data := url.Values{}
-	data.Set("code", code)
-	data.Set("client_id", clientID)
-	data.Set("client_secret", clientSecret)
-	data.Set("redirect_uri", redirectURL)
-	data.Set("grant_type", "authorization_code")
-
-	resp, err := http.PostForm(tokenURL, data)
-	if err != nil {
-		return nil, fmt.Errorf("error al obtener token de Google: %w", err)
-	}
-	defer resp.Body.Close()
-
-	var tokenResp GoogleTokenResponse
-	if err := json.NewDecoder(resp.Body).Decode(&tokenResp); err != nil {
-		return nil, fmt.Errorf("error al decodificar token de Google: %w", err)
-	}
-
-	// Obtener información del usuario
-	userInfoURL := "https://www.googleapis.com/oauth2/v2/userinfo"
-	req, _ := http.NewRequest("GET", userInfoURL, nil)
-	req.Header.Add("Authorization", "Bearer "+tokenResp.AccessToken)
-
-	client := &http.Client{}
-	userResp, err := client.Do(req)
-	if err != nil {
-		return nil, fmt.Errorf("error al obtener info de usuario de Google: %w", err)
-	}
-	defer userResp.Body.Close()
-
-	var userInfo GoogleUserInfo
-	if err := json.NewDecoder(userResp.Body).Decode(&userInfo); err != nil {
-		return nil, fmt.Errorf("error al decodificar info de usuario de Google: %w", err)
+func (o *oauth2Client) AuthCodeURL(req *AuthRequest) string {
+	params := url.Values{}
+	params.Set("response_type", "code")
+	params.Set("client_id", o.config.ClientID)
+	params.Set("redirect_uri", o.config.RedirectURL)
+	params.Set("scope", strings.Join(o.config.Scopes, " "))
+	params.Set("state", req.State)
+	params.Set("code_challenge", req.CodeChallenge)
+	params.Set("code_challenge_method", "S256")
+	if req.Nonce != "" {
+		params.Set("nonce", req.Nonce)
 	}
 
-	return &OAuthUserInfo{
-		ID:       userInfo.ID,
-		Email:    userInfo.Email,
-		Name:     userInfo.Name,
-		Avatar:   userInfo.Picture,
-		Provider: GoogleProvider,
-	}, nil
+	return o.config.AuthURL + "?" + params.Encode()
 }
 
-// ExchangeGitHubCode intercambia el código de GitHub por token y obtiene info del usuario
-func ExchangeGitHubCode(code string) (*OAuthUserInfo, error) {
-	clientID := os.Getenv("GITHUB_CLIENT_ID")
-	clientSecret := os.Getenv("GITHUB_CLIENT_SECRET")
-
-	tokenURL := "https://github.com/login/oauth/access_token"
+func (o *oauth2Client) Exchange(ctx context.Context, code string, codeVerifier string) (*OAuthToken, error) {
 	data := url.Values{}
+	data.Set("grant_type", "authorization_code")
 	data.Set("code", code)
-	data.Set("client_id", clientID)
-	data.Set("client_secret", clientSecret)
+	data.Set("redirect_uri", o.config.RedirectURL)
+	data.Set("client_id", o.config.ClientID)
+	data.Set("client_secret", o.config.ClientSecret)
+	data.Set("code_verifier", codeVerifier)
 
-	req, _ := http.NewRequest("POST", tokenURL, strings.NewReader(data.Encode()))
-	req.Header.Add("Accept", "application/json")
-	req.Header.Add("Content-Type", "application/x-www-form-urlencoded")
-
-	client := &http.Client{}
-	resp, err := client.Do(req)
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, o.config.TokenURL, strings.NewReader(data.Encode()))
 	if err != nil {
-		return nil, fmt.Errorf("error al obtener token de GitHub: %w", err)
-	}
-	defer resp.Body.Close()
-
-	var tokenResp GitHubTokenResponse
-	if err := json.NewDecoder(resp.Body).Decode(&tokenResp); err != nil {
-		return nil, fmt.Errorf("error al decodificar token de GitHub: %w", err)
+		return nil, err
 	}
+	req.Header.Set("Accept", "application/json")
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
 
-	// Obtener información del usuario
-	userInfoURL := "https://api.github.com/user"
-	req, _ = http.NewRequest("GET", userInfoURL, nil)
-	req.Header.Add("Authorization", "Bearer "+tokenResp.AccessToken)
-	req.Header.Add("Accept", "application/vnd.github.v3+json")
-
-	userResp, err := client.Do(req)
+	resp, err := o.client.Do(req)
 	if err != nil {
-		return nil, fmt.Errorf("error al obtener info de usuario de GitHub: %w", err)
+		return nil, fmt.Errorf("error al obtener token de %s: %w", o.name, err)
 	}
-	defer userResp.Body.Close()
+	defer resp.Body.Close()
 
-	var userInfo GitHubUserInfo
-	if err := json.NewDecoder(userResp.Body).Decode(&userInfo); err != nil {
-		return nil, fmt.Errorf("error al decodificar info de usuario de GitHub: %w", err)
+	if resp.StatusCode != http.StatusOK {
+		bodyBytes, _ := io.ReadAll(resp.Body)
+		return nil, fmt.Errorf("%s token error (status %d): %s", o.name, resp.StatusCode, string(bodyBytes))
 	}
 
-	// Si no hay email en el perfil, obtenerlo del endpoint de emails
-	if userInfo.Email == "" {
-		userInfo.Email = getGitHubUserEmail(tokenResp.AccessToken)
+	var token OAuthToken
+	if err := json.NewDecoder(resp.Body).Decode(&token); err != nil {
+		return nil, fmt.Errorf("error al decodificar token de %s: %w", o.name, err)
 	}
 
-	name := userInfo.Name
-	if name == "" {
-		name = userInfo.Login
+	// GitHub responde 200 incluso cuando el código es inválido
+	if token.Error != "" {
+		return nil, fmt.Errorf("%s token error: %s - %s", o.name, token.Error, token.ErrorDescription)
+	}
+	if token.AccessToken == "" {
+		return nil, fmt.Errorf("%s no devolvió access token", o.name)
 	}
 
-	return &OAuthUserInfo{
-		ID:       fmt.Sprintf("%d", userInfo.ID),
-		Email:    userInfo.Email,
-		Name:     name,
-		Avatar:   userInfo.AvatarURL,
-		Provider: GitHubProvider,
-	}, nil
+	return &token, nil
 }
 
-// getGitHubUserEmail obtiene el email del usuario de GitHub
-func getGitHubUserEmail(accessToken string) string {
-	emailURL := "https://api.github.com/user/emails"
-	req, _ := http.NewRequest("GET", emailURL, nil)
-	req.Header.Add("Authorization", "Bearer "+accessToken)
-	req.Header.Add("Accept", "application/vnd.github.v3+json")
+// getJSON hace un GET autenticado con el access token y decodifica la respuesta en out
+func (o *oauth2Client) getJSON(ctx context.Context, endpoint string, accessToken string, accept string, out interface{}) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, endpoint, nil)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Authorization", "Bearer "+accessToken)
+	if accept != "" {
+		req.Header.Set("Accept", accept)
+	}
 
-	client := &http.Client{}
-	resp, err := client.Do(req)
+	resp, err := o.client.Do(req)
 	if err != nil {
-		return ""
+		return fmt.Errorf("error al obtener info de usuario de %s: %w", o.name, err)
 	}
 	defer resp.Body.Close()
 
-	var emails []map[string]interface{}
-	if err := json.NewDecoder(resp.Body).Decode(&emails); err != nil {
-		return ""
+	if resp.StatusCode != http.StatusOK {
+		bodyBytes, _ := io.ReadAll(resp.Body)
+		return fmt.Errorf("%s userinfo error (status %d): %s", o.name, resp.StatusCode, string(bodyBytes))
 	}
 
-	for _, email := range emails {
-		if primary, ok := email["primary"].(bool); ok && primary {
-			if e, ok := email["email"].(string); ok {
-				return e
-			}
-		}
+	if err := json.NewDecoder(resp.Body).Decode(out); err != nil {
+		return fmt.Errorf("error al decodificar info de usuario de %s: %w", o.name, err)
 	}
+	return nil
+}
 
-	return ""
+// OAuthRegistry contiene los proveedores disponibles indexados por nombre
+type OAuthRegistry struct {
+	providers map[OAuthProviderName]OAuthProvider
 }
 
-// ExchangeLinkedInCode intercambia el código de LinkedIn por token y obtiene info del usuario
-func ExchangeLinkedInCode(code string) (*OAuthUserInfo, error) {
-	clientID := os.Getenv("LINKEDIN_CLIENT_ID")
-	clientSecret := os.Getenv("LINKEDIN_CLIENT_SECRET")
-	redirectURL := os.Getenv("LINKEDIN_REDIRECT_URL")
+// NewOAuthRegistry crea un registro con los proveedores indicados
+func NewOAuthRegistry(providers ...OAuthProvider) *OAuthRegistry {
+	registry := &OAuthRegistry{providers: make(map[OAuthProviderName]OAuthProvider)}
+	for _, provider := range providers {
+		registry.Register(provider)
+	}
+	return registry
+}
 
-	// Intercambiar código por token
-	tokenURL := "https://www.linkedin.com/oauth/v2/accessToken"
-	data := url.Values{}
-	data.Set("grant_type", "authorization_code")
-	data.Set("code", code)
-	data.Set("redirect_uri", redirectURL)
-	data.Set("client_id", clientID)
-	data.Set("client_secret", clientSecret)
+// Register agrega (o reemplaza) un proveedor
+func (r *OAuthRegistry) Register(provider OAuthProvider) {
+	r.providers[provider.Name()] = provider
+}
 
-	resp, err := http.PostForm(tokenURL, data)
-	if err != nil {
-		return nil, fmt.Errorf("error al obtener token de LinkedIn: %w", err)
+// Get devuelve el proveedor con ese nombre
+func (r *OAuthRegistry) Get(name OAuthProviderName) (OAuthProvider, error) {
+	provider, ok := r.providers[name]
+	if !ok {
+		return nil, ErrProviderNotFound
 	}
-	defer resp.Body.Close()
+	return provider, nil
+}
 
-	if resp.StatusCode != http.StatusOK {
-		bodyBytes, _ := io.ReadAll(resp.Body)
-		return nil, fmt.Errorf("linkedin token error (status %d): %s", resp.StatusCode, string(bodyBytes))
+// Names devuelve los nombres de los proveedores registrados, ordenados
+func (r *OAuthRegistry) Names() []OAuthProviderName {
+	names := make([]OAuthProviderName, 0, len(r.providers))
+	for name := range r.providers {
+		names = append(names, name)
 	}
+	sort.Slice(names, func(i, j int) bool { return names[i] < names[j] })
+	return names
+}
 
-	var tokenResp LinkedInTokenResponse
-	if err := json.NewDecoder(resp.Body).Decode(&tokenResp); err != nil {
-		return nil, fmt.Errorf("error al decodificar token de LinkedIn: %w", err)
+// NewOAuthRegistryFromEnv registra los proveedores que tengan credenciales configuradas.
+// Los endpoints por defecto se pueden sobreescribir con <PROVEEDOR>_AUTH_URL, _TOKEN_URL,
+// _USERINFO_URL y, para GitHub, GITHUB_EMAILS_URL. Los IdPs OpenID Connect genéricos
+// se agregan con OIDC_PROVIDERS (ver oidcConfigsFromEnv).
+func NewOAuthRegistryFromEnv() *OAuthRegistry {
+	registry := NewOAuthRegistry()
+
+	if config, ok := providerConfigFromEnv("GOOGLE", OAuthProviderConfig{
+		AuthURL:     "https://accounts.google.com/o/oauth2/v2/auth",
+		TokenURL:    "https://oauth2.googleapis.com/token",
+		UserInfoURL: "https://www.googleapis.com/oauth2/v2/userinfo",
+		Scopes:      []string{"openid", "profile", "email"},
+	}); ok {
+		registry.Register(NewGoogleProvider(config))
 	}
 
-	// Obtener información del usuario usando OpenID Connect
-	userURL := "https://api.linkedin.com/v2/userinfo"
-	req, _ := http.NewRequest("GET", userURL, nil)
-	req.Header.Set("Authorization", "Bearer "+tokenResp.AccessToken)
-
-	client := &http.Client{}
-	userResp, err := client.Do(req)
-	if err != nil {
-		return nil, fmt.Errorf("error al obtener info de usuario de LinkedIn: %w", err)
+	if config, ok := providerConfigFromEnv("GITHUB", OAuthProviderConfig{
+		AuthURL:     "https://github.com/login/oauth/authorize",
+		TokenURL:    "https://github.com/login/oauth/access_token",
+		UserInfoURL: "https://api.github.com/user",
+		EmailsURL:   "https://api.github.com/user/emails",
+		Scopes:      []string{"user:email"},
+	}); ok {
+		registry.Register(NewGitHubProvider(config))
 	}
-	defer userResp.Body.Close()
 
-	if userResp.StatusCode != http.StatusOK {
-		bodyBytes, _ := io.ReadAll(userResp.Body)
-		return nil, fmt.Errorf("linkedin userinfo error (status %d): %s", userResp.StatusCode, string(bodyBytes))
+	if config, ok := providerConfigFromEnv("LINKEDIN", OAuthProviderConfig{
+		AuthURL:     "https://www.linkedin.com/oauth/v2/authorization",
+		TokenURL:    "https://www.linkedin.com/oauth/v2/accessToken",
+		UserInfoURL: "https://api.linkedin.com/v2/userinfo",
+		Scopes:      []string{"openid", "profile", "email"},
+	}); ok {
+		registry.Register(NewLinkedInProvider(config))
 	}
 
-	var userInfo struct {
-		Sub     string `json:"sub"`
-		Name    string `json:"name"`
-		Email   string `json:"email"`
-		Picture string `json:"picture"`
-	}
+	for _, config := range oidcConfigsFromEnv() {
+		if config.Issuer == "" || config.ClientID == "" {
+			log.Printf("Proveedor OIDC %s sin issuer o client id, se omite", config.Name)
+			continue
+		}
 
-	if err := json.NewDecoder(userResp.Body).Decode(&userInfo); err != nil {
-		return nil, fmt.Errorf("error al decodificar info de usuario de LinkedIn: %w", err)
+		ctx, cancel := context.WithTimeout(context.Background(), 15*time.Second)
+		provider, err := NewOIDCProvider(ctx, config)
+		cancel()
+		if err != nil {
+			log.Printf("No se pudo inicializar el proveedor OIDC %s: %v", config.Name, err)
+			continue
+		}
+		registry.Register(provider)
 	}
 
-	return &OAuthUserInfo{
-		ID:       userInfo.Sub,
-		Email:    userInfo.Email,
-		Name:     userInfo.Name,
-		Avatar:   userInfo.Picture,
-		Provider: LinkedInProvider,
-	}, nil
+	log.Printf("Proveedores OAuth habilitados: %v", registry.Names())
+	return registry
 }
 
-// getLinkedInUserEmail obtiene el email del usuario de LinkedIn
-func getLinkedInUserEmail(accessToken string) string {
-	emailURL := "https://api.linkedin.com/v2/emailAddress?q=members&projection=(elements*(handle~))"
-	req, _ := http.NewRequest("GET", emailURL, nil)
-	req.Header.Add("Authorization", "Bearer "+accessToken)
+// providerConfigFromEnv completa la configuración por defecto con las variables de entorno.
+// Devuelve false si el proveedor no tiene client id configurado.
+func providerConfigFromEnv(prefix string, defaults OAuthProviderConfig) (OAuthProviderConfig, bool) {
+	config := defaults
+	config.ClientID = os.Getenv(prefix + "_CLIENT_ID")
+	config.ClientSecret = os.Getenv(prefix + "_CLIENT_SECRET")
+	config.RedirectURL = os.Getenv(prefix + "_REDIRECT_URL")
 
-	client := &http.Client{}
-	resp, err := client.Do(req)
-	if err != nil {
-		return ""
+	if v := os.Getenv(prefix + "_AUTH_URL"); v != "" {
+		config.AuthURL = v
 	}
-	defer resp.Body.Close()
-
-	var emailResp struct {
-		Elements []struct {
-			Handle struct {
-				EmailAddress string `json:"emailAddress"`
-			} `json:"handle~"`
-		} `json:"elements"`
+	if v := os.Getenv(prefix + "_TOKEN_URL"); v != "" {
+		config.TokenURL = v
 	}
-
-	if err := json.NewDecoder(resp.Body).Decode(&emailResp); err != nil {
-		return ""
+	if v := os.Getenv(prefix + "_USERINFO_URL"); v != "" {
+		config.UserInfoURL = v
 	}
-
-	if len(emailResp.Elements) > 0 {
-		return emailResp.Elements[0].Handle.EmailAddress
+	if v := os.Getenv(prefix + "_EMAILS_URL"); v != "" {
+		config.EmailsURL = v
 	}
 
-	return ""
+	return config, config.ClientID != ""
 }
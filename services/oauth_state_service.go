@@ -0,0 +1,123 @@
+package services
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/base64"
+	"errors"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+// OAuthStateDuration es el tiempo que tiene el usuario para completar el login en el proveedor
+const OAuthStateDuration = 10 * time.Minute
+
+// OAuthState es la información recuperada al validar el state de un callback
+type OAuthState struct {
+	CodeVerifier string
+	Nonce        string
+	// IDPersona es distinto de cero cuando el flujo vincula un proveedor a una cuenta existente
+	IDPersona int
+}
+
+// OAuthStateService guarda el state y el verificador PKCE de cada flujo OAuth
+type OAuthStateService struct {
+	db *pgxpool.Pool
+}
+
+// NewOAuthStateService crea una nueva instancia del servicio de state OAuth
+func NewOAuthStateService(db *pgxpool.Pool) *OAuthStateService {
+	return &OAuthStateService{db: db}
+}
+
+// CreateState genera un state de un solo uso, un code_verifier PKCE, un nonce y el nonce
+// de la cookie del navegador. Devuelve los parámetros que van en la URL de autorización.
+// Si idPersona es distinto de cero, el callback vinculará el proveedor a esa cuenta.
+func (s *OAuthStateService) CreateState(ctx context.Context, provider OAuthProviderName, idPersona int) (*AuthRequest, error) {
+	state, err := generateRandomToken(32)
+	if err != nil {
+		return nil, err
+	}
+
+	verifier, err := generateRandomToken(48)
+	if err != nil {
+		return nil, err
+	}
+
+	nonce, err := generateRandomToken(24)
+	if err != nil {
+		return nil, err
+	}
+
+	browserNonce, err := generateRandomToken(32)
+	if err != nil {
+		return nil, err
+	}
+
+	// Limpiar states vencidos antes de crear uno nuevo
+	if _, err := s.db.Exec(ctx, "DELETE FROM tb_oauth_state WHERE fecha_expiracion < NOW()"); err != nil {
+		return nil, err
+	}
+
+	var linkPersona *int
+	if idPersona != 0 {
+		linkPersona = &idPersona
+	}
+
+	_, err = s.db.Exec(ctx,
+		`INSERT INTO tb_oauth_state (state_hash, proveedor, code_verifier, nonce, id_persona, navegador_hash, fecha_creacion, fecha_expiracion)
+		 VALUES ($1, $2, $3, $4, $5, $6, $7, $8)`,
+		hashToken(state), string(provider), verifier, nonce, linkPersona, hashToken(browserNonce), time.Now(), time.Now().Add(OAuthStateDuration),
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	return &AuthRequest{
+		State:         state,
+		CodeChallenge: codeChallengeS256(verifier),
+		Nonce:         nonce,
+		BrowserNonce:  browserNonce,
+	}, nil
+}
+
+// ConsumeState valida el state recibido en el callback y lo marca como usado.
+// Devuelve el code_verifier que se debe enviar al intercambiar el código.
+// browserNonce es el valor de la cookie puesta al generar la URL de autorización: un state
+// que llega desde otro navegador se rechaza, así no se puede forzar un login (CSRF de login).
+func (s *OAuthStateService) ConsumeState(ctx context.Context, provider OAuthProviderName, state string, browserNonce string) (*OAuthState, error) {
+	if state == "" || browserNonce == "" {
+		return nil, ErrInvalidOAuthState
+	}
+
+	var result OAuthState
+	var linkPersona *int
+	err := s.db.QueryRow(ctx,
+		`UPDATE tb_oauth_state SET usado_en = NOW()
+		 WHERE state_hash = $1 AND proveedor = $2 AND navegador_hash = $3
+		   AND usado_en IS NULL AND fecha_expiracion > NOW()
+		 RETURNING code_verifier, nonce, id_persona`,
+		hashToken(state), string(provider), hashToken(browserNonce),
+	).Scan(&result.CodeVerifier, &result.Nonce, &linkPersona)
+
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, ErrInvalidOAuthState
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	if linkPersona != nil {
+		result.IDPersona = *linkPersona
+	}
+
+	return &result, nil
+}
+
+// codeChallengeS256 calcula el code_challenge PKCE a partir del verificador
+func codeChallengeS256(verifier string) string {
+	sum := sha256.Sum256([]byte(verifier))
+	return base64.RawURLEncoding.EncodeToString(sum[:])
+}
@@ -0,0 +1,410 @@
+package services
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/rsa"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"io"
+	"math/big"
+	"net/http"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+const (
+	// defaultJWKSCacheTTL es cada cuánto se refrescan las claves del IdP aunque no cambie ningún kid
+	defaultJWKSCacheTTL = time.Hour
+	// jwksMinRefreshInterval limita los refrescos forzados por un kid desconocido
+	jwksMinRefreshInterval = time.Minute
+)
+
+// OIDCConfig configura un proveedor OpenID Connect genérico (Entra, Okta, Keycloak, ...)
+type OIDCConfig struct {
+	Name         OAuthProviderName
+	Issuer       string
+	ClientID     string
+	ClientSecret string
+	RedirectURL  string
+	Scopes       []string
+	// EmailClaim y NameClaim permiten mapear claims no estándar; por defecto "email" y "name"
+	EmailClaim   string
+	NameClaim    string
+	JWKSCacheTTL time.Duration
+	HTTPClient   *http.Client
+}
+
+// oidcDiscovery es el subconjunto usado de /.well-known/openid-configuration
+type oidcDiscovery struct {
+	Issuer                string `json:"issuer"`
+	AuthorizationEndpoint string `json:"authorization_endpoint"`
+	TokenEndpoint         string `json:"token_endpoint"`
+	UserInfoEndpoint      string `json:"userinfo_endpoint"`
+	JWKSURI               string `json:"jwks_uri"`
+}
+
+// oidcProvider valida el id_token devuelto por el IdP y mapea sus claims
+type oidcProvider struct {
+	oauth2Client
+	issuer     string
+	emailClaim string
+	nameClaim  string
+	keys       *jwksCache
+}
+
+// NewOIDCProvider lee el documento de discovery del issuer y crea el proveedor
+func NewOIDCProvider(ctx context.Context, config OIDCConfig) (OAuthProvider, error) {
+	client := config.HTTPClient
+	if client == nil {
+		client = &http.Client{Timeout: 10 * time.Second}
+	}
+
+	issuer := strings.TrimSuffix(config.Issuer, "/")
+	var discovery oidcDiscovery
+	if err := fetchJSON(ctx, client, issuer+"/.well-known/openid-configuration", &discovery); err != nil {
+		return nil, fmt.Errorf("error al leer discovery de %s: %w", config.Name, err)
+	}
+
+	// El issuer anunciado debe coincidir exactamente con el configurado
+	if strings.TrimSuffix(discovery.Issuer, "/") != issuer {
+		return nil, fmt.Errorf("issuer de %s no coincide: esperado %s, recibido %s", config.Name, issuer, discovery.Issuer)
+	}
+	if discovery.AuthorizationEndpoint == "" || discovery.TokenEndpoint == "" || discovery.JWKSURI == "" {
+		return nil, fmt.Errorf("discovery de %s incompleto", config.Name)
+	}
+
+	scopes := config.Scopes
+	if len(scopes) == 0 {
+		scopes = []string{"openid", "profile", "email"}
+	}
+
+	ttl := config.JWKSCacheTTL
+	if ttl == 0 {
+		ttl = defaultJWKSCacheTTL
+	}
+
+	emailClaim := config.EmailClaim
+	if emailClaim == "" {
+		emailClaim = "email"
+	}
+	nameClaim := config.NameClaim
+	if nameClaim == "" {
+		nameClaim = "name"
+	}
+
+	return &oidcProvider{
+		oauth2Client: newOAuth2Client(config.Name, OAuthProviderConfig{
+			ClientID:     config.ClientID,
+			ClientSecret: config.ClientSecret,
+			RedirectURL:  config.RedirectURL,
+			AuthURL:      discovery.AuthorizationEndpoint,
+			TokenURL:     discovery.TokenEndpoint,
+			UserInfoURL:  discovery.UserInfoEndpoint,
+			Scopes:       scopes,
+			HTTPClient:   client,
+		}),
+		issuer:     discovery.Issuer,
+		emailClaim: emailClaim,
+		nameClaim:  nameClaim,
+		keys:       &jwksCache{url: discovery.JWKSURI, client: client, ttl: ttl},
+	}, nil
+}
+
+func (p *oidcProvider) UserInfo(ctx context.Context, token *OAuthToken, nonce string) (*OAuthUserInfo, error) {
+	if token.IDToken == "" {
+		return nil, fmt.Errorf("%s no devolvió id_token", p.name)
+	}
+
+	claims, err := p.verifyIDToken(ctx, token.IDToken, nonce)
+	if err != nil {
+		return nil, err
+	}
+
+	subject, _ := claims["sub"].(string)
+	if subject == "" {
+		return nil, fmt.Errorf("%w: falta el claim sub", ErrInvalidIDToken)
+	}
+
+	// Algunos IdPs no incluyen el email en el id_token; se completa con el endpoint userinfo
+	if claimString(claims, p.emailClaim) == "" && p.config.UserInfoURL != "" {
+		extra := map[string]interface{}{}
+		if err := p.getJSON(ctx, p.config.UserInfoURL, token.AccessToken, "application/json", &extra); err == nil {
+			if sub, _ := extra["sub"].(string); sub == subject {
+				for key, value := range extra {
+					if _, exists := claims[key]; !exists {
+						claims[key] = value
+					}
+				}
+			}
+		}
+	}
+
+	name := claimString(claims, p.nameClaim)
+	if name == "" {
+		name = strings.TrimSpace(claimString(claims, "given_name") + " " + claimString(claims, "family_name"))
+	}
+	if name == "" {
+		name = claimString(claims, "preferred_username")
+	}
+
+	return &OAuthUserInfo{
+		ID:            subject,
+		Email:         claimString(claims, p.emailClaim),
+		EmailVerified: claimBool(claims, "email_verified"),
+		Name:          name,
+		Avatar:        claimString(claims, "picture"),
+		Provider:      p.name,
+	}, nil
+}
+
+// verifyIDToken valida firma (contra el JWKS), iss, aud, exp y nonce del id_token
+func (p *oidcProvider) verifyIDToken(ctx context.Context, rawToken string, nonce string) (jwt.MapClaims, error) {
+	claims := jwt.MapClaims{}
+	_, err := jwt.ParseWithClaims(rawToken, claims,
+		func(token *jwt.Token) (interface{}, error) {
+			kid, _ := token.Header["kid"].(string)
+			return p.keys.key(ctx, kid)
+		},
+		jwt.WithValidMethods([]string{"RS256", "RS384", "RS512", "PS256", "PS384", "PS512", "ES256", "ES384", "ES512", "EdDSA"}),
+		jwt.WithIssuer(p.issuer),
+		jwt.WithAudience(p.config.ClientID),
+		jwt.WithExpirationRequired(),
+		jwt.WithIssuedAt(),
+		jwt.WithLeeway(time.Minute),
+	)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidIDToken, err)
+	}
+
+	if tokenNonce, _ := claims["nonce"].(string); tokenNonce != nonce {
+		return nil, fmt.Errorf("%w: nonce inválido", ErrInvalidIDToken)
+	}
+
+	// Con varias audiencias, azp debe identificar a este cliente
+	if audiences, _ := claims.GetAudience(); len(audiences) > 1 {
+		if azp, _ := claims["azp"].(string); azp != p.config.ClientID {
+			return nil, fmt.Errorf("%w: azp inválido", ErrInvalidIDToken)
+		}
+	}
+
+	return claims, nil
+}
+
+// jwksCache mantiene en memoria las claves públicas del IdP indexadas por kid
+type jwksCache struct {
+	url    string
+	client *http.Client
+	ttl    time.Duration
+
+	mu        sync.Mutex
+	keys      map[string]interface{}
+	fetchedAt time.Time
+}
+
+// key devuelve la clave para el kid indicado. Si el kid no se conoce se vuelve a
+// descargar el JWKS (como máximo una vez por minuto) para soportar rotación de claves.
+func (c *jwksCache) key(ctx context.Context, kid string) (interface{}, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.keys == nil || time.Since(c.fetchedAt) > c.ttl {
+		if err := c.refresh(ctx); err != nil && c.keys == nil {
+			return nil, err
+		}
+	}
+
+	if key, ok := c.lookup(kid); ok {
+		return key, nil
+	}
+
+	if time.Since(c.fetchedAt) >= jwksMinRefreshInterval {
+		if err := c.refresh(ctx); err != nil {
+			return nil, err
+		}
+		if key, ok := c.lookup(kid); ok {
+			return key, nil
+		}
+	}
+
+	return nil, fmt.Errorf("clave de firma desconocida: %q", kid)
+}
+
+func (c *jwksCache) lookup(kid string) (interface{}, bool) {
+	if kid == "" && len(c.keys) == 1 {
+		for _, key := range c.keys {
+			return key, true
+		}
+	}
+	key, ok := c.keys[kid]
+	return key, ok
+}
+
+func (c *jwksCache) refresh(ctx context.Context) error {
+	var set struct {
+		Keys []jsonWebKey `json:"keys"`
+	}
+	if err := fetchJSON(ctx, c.client, c.url, &set); err != nil {
+		return fmt.Errorf("error al descargar JWKS: %w", err)
+	}
+
+	keys := make(map[string]interface{}, len(set.Keys))
+	for _, jwk := range set.Keys {
+		if jwk.Use != "" && jwk.Use != "sig" {
+			continue
+		}
+		key, err := jwk.publicKey()
+		if err != nil {
+			continue
+		}
+		keys[jwk.Kid] = key
+	}
+
+	c.keys = keys
+	c.fetchedAt = time.Now()
+	return nil
+}
+
+// jsonWebKey es una clave pública en formato JWK (RFC 7517)
+type jsonWebKey struct {
+	Kty string `json:"kty"`
+	Kid string `json:"kid"`
+	Use string `json:"use,omitempty"`
+	Alg string `json:"alg,omitempty"`
+	N   string `json:"n,omitempty"`
+	E   string `json:"e,omitempty"`
+	Crv string `json:"crv,omitempty"`
+	X   string `json:"x,omitempty"`
+	Y   string `json:"y,omitempty"`
+}
+
+func (k jsonWebKey) publicKey() (interface{}, error) {
+	switch k.Kty {
+	case "RSA":
+		n, err := decodeBigInt(k.N)
+		if err != nil {
+			return nil, err
+		}
+		e, err := decodeBigInt(k.E)
+		if err != nil {
+			return nil, err
+		}
+		return &rsa.PublicKey{N: n, E: int(e.Int64())}, nil
+	case "EC":
+		var curve elliptic.Curve
+		switch k.Crv {
+		case "P-256":
+			curve = elliptic.P256()
+		case "P-384":
+			curve = elliptic.P384()
+		case "P-521":
+			curve = elliptic.P521()
+		default:
+			return nil, fmt.Errorf("curva no soportada: %s", k.Crv)
+		}
+		x, err := decodeBigInt(k.X)
+		if err != nil {
+			return nil, err
+		}
+		y, err := decodeBigInt(k.Y)
+		if err != nil {
+			return nil, err
+		}
+		return &ecdsa.PublicKey{Curve: curve, X: x, Y: y}, nil
+	case "OKP":
+		if k.Crv != "Ed25519" {
+			return nil, fmt.Errorf("curva no soportada: %s", k.Crv)
+		}
+		x, err := base64.RawURLEncoding.DecodeString(k.X)
+		if err != nil || len(x) != ed25519.PublicKeySize {
+			return nil, fmt.Errorf("clave Ed25519 inválida")
+		}
+		return ed25519.PublicKey(x), nil
+	default:
+		return nil, fmt.Errorf("tipo de clave no soportado: %s", k.Kty)
+	}
+}
+
+func decodeBigInt(value string) (*big.Int, error) {
+	raw, err := base64.RawURLEncoding.DecodeString(value)
+	if err != nil {
+		return nil, err
+	}
+	return new(big.Int).SetBytes(raw), nil
+}
+
+// fetchJSON hace un GET sin autenticación y decodifica la respuesta
+func fetchJSON(ctx context.Context, client *http.Client, endpoint string, out interface{}) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, endpoint, nil)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Accept", "application/json")
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		bodyBytes, _ := io.ReadAll(resp.Body)
+		return fmt.Errorf("status %d: %s", resp.StatusCode, string(bodyBytes))
+	}
+
+	return json.NewDecoder(resp.Body).Decode(out)
+}
+
+func claimString(claims jwt.MapClaims, name string) string {
+	value, _ := claims[name].(string)
+	return value
+}
+
+// claimBool acepta booleanos y también "true" como string, que envían algunos IdPs
+func claimBool(claims jwt.MapClaims, name string) bool {
+	switch value := claims[name].(type) {
+	case bool:
+		return value
+	case string:
+		return strings.EqualFold(value, "true")
+	default:
+		return false
+	}
+}
+
+// oidcConfigsFromEnv lee los IdPs listados en OIDC_PROVIDERS (separados por coma).
+// Cada uno se configura con OIDC_<NOMBRE>_ISSUER, _CLIENT_ID, _CLIENT_SECRET,
+// _REDIRECT_URL y opcionalmente _SCOPES, _EMAIL_CLAIM y _NAME_CLAIM.
+func oidcConfigsFromEnv() []OIDCConfig {
+	var configs []OIDCConfig
+	for _, name := range strings.Split(os.Getenv("OIDC_PROVIDERS"), ",") {
+		name = strings.ToLower(strings.TrimSpace(name))
+		if name == "" {
+			continue
+		}
+
+		prefix := "OIDC_" + strings.ToUpper(strings.ReplaceAll(name, "-", "_"))
+		config := OIDCConfig{
+			Name:         OAuthProviderName(name),
+			Issuer:       os.Getenv(prefix + "_ISSUER"),
+			ClientID:     os.Getenv(prefix + "_CLIENT_ID"),
+			ClientSecret: os.Getenv(prefix + "_CLIENT_SECRET"),
+			RedirectURL:  os.Getenv(prefix + "_REDIRECT_URL"),
+			EmailClaim:   os.Getenv(prefix + "_EMAIL_CLAIM"),
+			NameClaim:    os.Getenv(prefix + "_NAME_CLAIM"),
+		}
+		if scopes := os.Getenv(prefix + "_SCOPES"); scopes != "" {
+			config.Scopes = strings.Fields(strings.ReplaceAll(scopes, ",", " "))
+		}
+
+		configs = append(configs, config)
+	}
+	return configs
+}
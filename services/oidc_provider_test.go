@@ -0,0 +1,294 @@
+package services
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/rsa"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+const testOIDCClientID = "mentorly-test"
+
+// oidcStandIn es un IdP OpenID Connect mínimo: sirve discovery y JWKS y firma id_tokens.
+// Las claves publicadas se pueden cambiar para simular una rotación.
+type oidcStandIn struct {
+	*httptest.Server
+	// issuer anunciado en discovery; vacío usa la URL del servidor
+	announcedIssuer string
+
+	mu          sync.Mutex
+	keys        map[string]*rsa.PrivateKey
+	published   []string
+	jwksFetches int
+}
+
+func newOIDCStandIn(t *testing.T) *oidcStandIn {
+	t.Helper()
+	idp := &oidcStandIn{keys: map[string]*rsa.PrivateKey{}}
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /.well-known/openid-configuration", func(w http.ResponseWriter, r *http.Request) {
+		issuer := idp.announcedIssuer
+		if issuer == "" {
+			issuer = idp.URL
+		}
+		json.NewEncoder(w).Encode(oidcDiscovery{
+			Issuer:                issuer,
+			AuthorizationEndpoint: idp.URL + "/authorize",
+			TokenEndpoint:         idp.URL + "/token",
+			UserInfoEndpoint:      idp.URL + "/userinfo",
+			JWKSURI:               idp.URL + "/jwks",
+		})
+	})
+	mux.HandleFunc("GET /jwks", func(w http.ResponseWriter, r *http.Request) {
+		idp.mu.Lock()
+		defer idp.mu.Unlock()
+		idp.jwksFetches++
+
+		set := struct {
+			Keys []jsonWebKey `json:"keys"`
+		}{}
+		for _, kid := range idp.published {
+			public := idp.keys[kid].PublicKey
+			set.Keys = append(set.Keys, jsonWebKey{
+				Kty: "RSA",
+				Kid: kid,
+				Use: "sig",
+				Alg: "RS256",
+				N:   base64.RawURLEncoding.EncodeToString(public.N.Bytes()),
+				E:   base64.RawURLEncoding.EncodeToString(big.NewInt(int64(public.E)).Bytes()),
+			})
+		}
+		json.NewEncoder(w).Encode(set)
+	})
+
+	idp.Server = httptest.NewServer(mux)
+	t.Cleanup(idp.Close)
+	return idp
+}
+
+// addKey genera una clave; si publish es true además la publica en el JWKS
+func (idp *oidcStandIn) addKey(t *testing.T, kid string, publish bool) {
+	t.Helper()
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+	idp.mu.Lock()
+	defer idp.mu.Unlock()
+	idp.keys[kid] = key
+	if publish {
+		idp.published = append(idp.published, kid)
+	}
+}
+
+func (idp *oidcStandIn) publish(kid string) {
+	idp.mu.Lock()
+	defer idp.mu.Unlock()
+	idp.published = append(idp.published, kid)
+}
+
+func (idp *oidcStandIn) fetches() int {
+	idp.mu.Lock()
+	defer idp.mu.Unlock()
+	return idp.jwksFetches
+}
+
+// claims devuelve claims válidos para testOIDCClientID; cada test cambia lo que necesita
+func (idp *oidcStandIn) claims(nonce string) jwt.MapClaims {
+	now := time.Now()
+	return jwt.MapClaims{
+		"iss":            idp.URL,
+		"sub":            "user-42",
+		"aud":            testOIDCClientID,
+		"iat":            now.Unix(),
+		"exp":            now.Add(5 * time.Minute).Unix(),
+		"nonce":          nonce,
+		"email":          "ana@empresa.example",
+		"email_verified": true,
+		"name":           "Ana Pérez",
+	}
+}
+
+func (idp *oidcStandIn) sign(t *testing.T, kid string, claims jwt.MapClaims) string {
+	t.Helper()
+	token := jwt.NewWithClaims(jwt.SigningMethodRS256, claims)
+	token.Header["kid"] = kid
+	idp.mu.Lock()
+	key := idp.keys[kid]
+	idp.mu.Unlock()
+	signed, err := token.SignedString(key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return signed
+}
+
+func newTestOIDCProvider(t *testing.T, idp *oidcStandIn) *oidcProvider {
+	t.Helper()
+	provider, err := NewOIDCProvider(context.Background(), OIDCConfig{
+		Name:     "empresa",
+		Issuer:   idp.URL,
+		ClientID: testOIDCClientID,
+	})
+	if err != nil {
+		t.Fatalf("NewOIDCProvider: %v", err)
+	}
+	return provider.(*oidcProvider)
+}
+
+func TestOIDCUserInfoFromValidIDToken(t *testing.T) {
+	idp := newOIDCStandIn(t)
+	idp.addKey(t, "k1", true)
+	provider := newTestOIDCProvider(t, idp)
+
+	info, err := provider.UserInfo(context.Background(), &OAuthToken{IDToken: idp.sign(t, "k1", idp.claims("n-1"))}, "n-1")
+	if err != nil {
+		t.Fatalf("UserInfo: %v", err)
+	}
+	expected := OAuthUserInfo{
+		ID: "user-42", Email: "ana@empresa.example", EmailVerified: true, Name: "Ana Pérez", Provider: "empresa",
+	}
+	if *info != expected {
+		t.Errorf("UserInfo = %+v, se esperaba %+v", *info, expected)
+	}
+}
+
+func TestOIDCDiscoveryIssuerMismatch(t *testing.T) {
+	idp := newOIDCStandIn(t)
+	idp.announcedIssuer = "https://otro-issuer.example"
+
+	_, err := NewOIDCProvider(context.Background(), OIDCConfig{Name: "empresa", Issuer: idp.URL, ClientID: testOIDCClientID})
+	if err == nil {
+		t.Fatal("se esperaba un error cuando discovery anuncia otro issuer")
+	}
+}
+
+func TestOIDCRejectsInvalidIDTokens(t *testing.T) {
+	idp := newOIDCStandIn(t)
+	idp.addKey(t, "k1", true)
+	provider := newTestOIDCProvider(t, idp)
+
+	tests := []struct {
+		name   string
+		change func(jwt.MapClaims)
+	}{
+		{name: "issuer distinto", change: func(c jwt.MapClaims) { c["iss"] = "https://otro-issuer.example" }},
+		{name: "aud de otro cliente", change: func(c jwt.MapClaims) { c["aud"] = "otro-cliente" }},
+		{
+			name: "varias audiencias sin azp",
+			change: func(c jwt.MapClaims) {
+				c["aud"] = []string{testOIDCClientID, "otro-cliente"}
+			},
+		},
+		{
+			name: "varias audiencias con azp de otro cliente",
+			change: func(c jwt.MapClaims) {
+				c["aud"] = []string{testOIDCClientID, "otro-cliente"}
+				c["azp"] = "otro-cliente"
+			},
+		},
+		{name: "nonce distinto", change: func(c jwt.MapClaims) { c["nonce"] = "otro-nonce" }},
+		{name: "sin nonce", change: func(c jwt.MapClaims) { delete(c, "nonce") }},
+		{
+			name: "expirado",
+			change: func(c jwt.MapClaims) {
+				c["iat"] = time.Now().Add(-time.Hour).Unix()
+				c["exp"] = time.Now().Add(-10 * time.Minute).Unix()
+			},
+		},
+		{name: "sin exp", change: func(c jwt.MapClaims) { delete(c, "exp") }},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			claims := idp.claims("n-1")
+			tt.change(claims)
+
+			_, err := provider.UserInfo(context.Background(), &OAuthToken{IDToken: idp.sign(t, "k1", claims)}, "n-1")
+			if !errors.Is(err, ErrInvalidIDToken) {
+				t.Errorf("error = %v, se esperaba ErrInvalidIDToken", err)
+			}
+		})
+	}
+}
+
+func TestOIDCAcceptsMultipleAudiencesWithMatchingAzp(t *testing.T) {
+	idp := newOIDCStandIn(t)
+	idp.addKey(t, "k1", true)
+	provider := newTestOIDCProvider(t, idp)
+
+	claims := idp.claims("n-1")
+	claims["aud"] = []string{testOIDCClientID, "otro-cliente"}
+	claims["azp"] = testOIDCClientID
+
+	if _, err := provider.UserInfo(context.Background(), &OAuthToken{IDToken: idp.sign(t, "k1", claims)}, "n-1"); err != nil {
+		t.Errorf("UserInfo: %v", err)
+	}
+}
+
+func TestOIDCUnknownKidRefreshesJWKSOnce(t *testing.T) {
+	idp := newOIDCStandIn(t)
+	idp.addKey(t, "k1", true)
+	provider := newTestOIDCProvider(t, idp)
+
+	if _, err := provider.UserInfo(context.Background(), &OAuthToken{IDToken: idp.sign(t, "k1", idp.claims("n"))}, "n"); err != nil {
+		t.Fatalf("UserInfo con k1: %v", err)
+	}
+	if got := idp.fetches(); got != 1 {
+		t.Fatalf("descargas de JWKS = %d, se esperaba 1", got)
+	}
+
+	// Rotación: el IdP publica k2. Pasado el intervalo mínimo, un kid desconocido fuerza un refresco.
+	idp.addKey(t, "k2", false)
+	idp.publish("k2")
+	provider.keys.mu.Lock()
+	provider.keys.fetchedAt = time.Now().Add(-2 * jwksMinRefreshInterval)
+	provider.keys.mu.Unlock()
+
+	if _, err := provider.UserInfo(context.Background(), &OAuthToken{IDToken: idp.sign(t, "k2", idp.claims("n"))}, "n"); err != nil {
+		t.Fatalf("UserInfo con k2 después de la rotación: %v", err)
+	}
+	if got := idp.fetches(); got != 2 {
+		t.Fatalf("descargas de JWKS = %d, se esperaba 2 (un solo refresco)", got)
+	}
+
+	// Otro kid desconocido enseguida: no se vuelve a descargar el JWKS dentro del intervalo mínimo
+	idp.addKey(t, "k3", false)
+	idp.publish("k3")
+	for range 3 {
+		_, err := provider.UserInfo(context.Background(), &OAuthToken{IDToken: idp.sign(t, "k3", idp.claims("n"))}, "n")
+		if !errors.Is(err, ErrInvalidIDToken) {
+			t.Fatalf("error = %v, se esperaba ErrInvalidIDToken por kid desconocido", err)
+		}
+	}
+	if got := idp.fetches(); got != 2 {
+		t.Errorf("descargas de JWKS = %d, los refrescos por kid desconocido deberían estar limitados", got)
+	}
+}
+
+func TestOIDCRejectsTokenSignedWithUnpublishedKey(t *testing.T) {
+	idp := newOIDCStandIn(t)
+	idp.addKey(t, "k1", true)
+	provider := newTestOIDCProvider(t, idp)
+
+	// Misma kid que una publicada pero firmada con otra clave
+	forged := newOIDCStandIn(t)
+	forged.addKey(t, "k1", false)
+	claims := idp.claims("n")
+
+	_, err := provider.UserInfo(context.Background(), &OAuthToken{IDToken: forged.sign(t, "k1", claims)}, "n")
+	if !errors.Is(err, ErrInvalidIDToken) {
+		t.Errorf("error = %v, se esperaba ErrInvalidIDToken por firma inválida", err)
+	}
+}
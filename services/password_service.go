@@ -0,0 +1,131 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/url"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+const (
+	// PasswordResetTokenDuration es la vigencia del enlace de recuperación
+	PasswordResetTokenDuration = time.Hour
+	// passwordResetResendInterval evita enviar varios enlaces seguidos al mismo usuario
+	passwordResetResendInterval = time.Minute
+)
+
+// PasswordService maneja la recuperación y el cambio de contraseñas
+type PasswordService struct {
+	db            *pgxpool.Pool
+	tokens        *UserTokenService
+	refreshTokens *TokenService
+	mailer        Mailer
+	linkBaseURL   string
+}
+
+// NewPasswordService crea el servicio. linkBaseURL es la URL del frontend
+// donde está la pantalla para ingresar la nueva contraseña.
+func NewPasswordService(db *pgxpool.Pool, mailer Mailer, linkBaseURL string) *PasswordService {
+	return &PasswordService{
+		db:            db,
+		tokens:        NewUserTokenService(db),
+		refreshTokens: NewTokenService(db),
+		mailer:        mailer,
+		linkBaseURL:   linkBaseURL,
+	}
+}
+
+// RequestPasswordReset envía un enlace de recuperación si el email existe.
+// Si no existe no hace nada y no devuelve error, para no revelar qué emails están registrados.
+func (s *PasswordService) RequestPasswordReset(ctx context.Context, email string) error {
+	var idPersona int
+	var nombre string
+	err := s.db.QueryRow(ctx,
+		"SELECT id_persona, nombre FROM tb_persona WHERE email = $1",
+		email,
+	).Scan(&idPersona, &nombre)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+
+	_, last, err := s.tokens.RecentIssues(ctx, idPersona, PurposePasswordReset, time.Now().Add(-passwordResetResendInterval))
+	if err != nil {
+		return err
+	}
+	if last != nil {
+		return nil
+	}
+
+	rawToken, err := s.tokens.Issue(ctx, idPersona, PurposePasswordReset, PasswordResetTokenDuration)
+	if err != nil {
+		return err
+	}
+
+	link := fmt.Sprintf("%s/reset-password?token=%s", s.linkBaseURL, url.QueryEscape(rawToken))
+	return s.mailer.Send(ctx, EmailMessage{
+		To:      email,
+		Subject: "Restablecé tu contraseña de Mentorly",
+		Body: fmt.Sprintf("Hola %s,\n\nRecibimos un pedido para restablecer tu contraseña. Ingresá al siguiente enlace:\n\n%s\n\n"+
+			"El enlace vence en %d minutos y se puede usar una sola vez. Si no lo pediste, ignorá este mensaje.\n",
+			nombre, link, int(PasswordResetTokenDuration.Minutes())),
+	})
+}
+
+// ResetPassword consume el token, guarda la nueva contraseña (ya hasheada) y cierra todas las sesiones.
+// Devuelve el usuario al que pertenecía el token.
+func (s *PasswordService) ResetPassword(ctx context.Context, rawToken string, hashedPassword string) (int, error) {
+	idPersona, err := s.tokens.Consume(ctx, PurposePasswordReset, rawToken)
+	if err != nil {
+		return 0, err
+	}
+
+	// Recibir el enlace también demuestra que el email le pertenece
+	_, err = s.db.Exec(ctx,
+		"UPDATE tb_persona SET contrasena = $1, email_verificado = TRUE WHERE id_persona = $2",
+		hashedPassword, idPersona,
+	)
+	if err != nil {
+		return 0, err
+	}
+
+	return idPersona, s.refreshTokens.RevokeAllForUser(ctx, idPersona)
+}
+
+// ChangePassword reemplaza la contraseña (ya hasheada) y revoca todos los refresh tokens.
+// La verificación de la contraseña actual la hace AuthService.VerifyPassword.
+func (s *PasswordService) ChangePassword(ctx context.Context, idPersona int, hashedPassword string) error {
+	result, err := s.db.Exec(ctx,
+		"UPDATE tb_persona SET contrasena = $1 WHERE id_persona = $2 AND contrasena IS NOT NULL",
+		hashedPassword, idPersona,
+	)
+	if err != nil {
+		return err
+	}
+	if result.RowsAffected() == 0 {
+		return ErrPasswordNotSet
+	}
+
+	return s.refreshTokens.RevokeAllForUser(ctx, idPersona)
+}
+
+// SetPassword configura la primera contraseña de una cuenta creada con OAuth
+func (s *PasswordService) SetPassword(ctx context.Context, idPersona int, hashedPassword string) error {
+	result, err := s.db.Exec(ctx,
+		"UPDATE tb_persona SET contrasena = $1 WHERE id_persona = $2 AND contrasena IS NULL",
+		hashedPassword, idPersona,
+	)
+	if err != nil {
+		return err
+	}
+	if result.RowsAffected() == 0 {
+		return ErrPasswordAlreadySet
+	}
+	return nil
+}
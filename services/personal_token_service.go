@@ -0,0 +1,205 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"mentorly-backend/models"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+// Scopes que se pueden otorgar a un token personal
+const (
+	ScopeProfileRead        = "perfil:leer"
+	ScopeProfileWrite       = "perfil:escribir"
+	ScopePlansRead          = "planes:leer"
+	ScopePlansWrite         = "planes:escribir"
+	ScopeSubscriptionsWrite = "suscripciones:escribir"
+)
+
+// AvailableScopes son todos los scopes válidos, en el orden en que se muestran
+var AvailableScopes = []string{
+	ScopeProfileRead,
+	ScopeProfileWrite,
+	ScopePlansRead,
+	ScopePlansWrite,
+	ScopeSubscriptionsWrite,
+}
+
+const (
+	// PersonalTokenPrefix identifica a los tokens personales frente a los JWT
+	PersonalTokenPrefix = "mtly_pat_"
+	// DefaultPersonalTokenDuration y MaxPersonalTokenDuration limitan la vigencia elegida por el usuario
+	DefaultPersonalTokenDuration = 90 * 24 * time.Hour
+	MaxPersonalTokenDuration     = 365 * 24 * time.Hour
+
+	maxActivePersonalTokens = 20
+	// personalTokenDisplayLength es la cantidad de caracteres que se guardan para mostrar en el listado
+	personalTokenDisplayLength = len(PersonalTokenPrefix) + 6
+)
+
+// PersonalTokenAuth es el resultado de validar un token personal
+type PersonalTokenAuth struct {
+	IDToken   int
+	IDPersona int
+	Email     string
+	Scopes    []string
+}
+
+// PersonalTokenService maneja los tokens personales (API keys) de los usuarios
+type PersonalTokenService struct {
+	db *pgxpool.Pool
+}
+
+// NewPersonalTokenService crea una nueva instancia del servicio de tokens personales
+func NewPersonalTokenService(db *pgxpool.Pool) *PersonalTokenService {
+	return &PersonalTokenService{db: db}
+}
+
+// IsPersonalToken indica si la credencial tiene el formato de un token personal
+func IsPersonalToken(raw string) bool {
+	return strings.HasPrefix(raw, PersonalTokenPrefix)
+}
+
+// Create genera un token nuevo. El valor en claro se devuelve solo esta vez.
+func (s *PersonalTokenService) Create(ctx context.Context, idPersona int, nombre string, scopes []string, ttl time.Duration) (string, *models.PersonalToken, error) {
+	scopes, err := normalizeScopes(scopes)
+	if err != nil {
+		return "", nil, err
+	}
+	if ttl <= 0 || ttl > MaxPersonalTokenDuration {
+		return "", nil, ErrInvalidTokenDuration
+	}
+
+	var active int
+	err = s.db.QueryRow(ctx,
+		`SELECT COUNT(*) FROM tb_token_personal
+		 WHERE id_persona = $1 AND revocado_en IS NULL AND fecha_expiracion > NOW()`,
+		idPersona,
+	).Scan(&active)
+	if err != nil {
+		return "", nil, err
+	}
+	if active >= maxActivePersonalTokens {
+		return "", nil, ErrTooManyPersonalTokens
+	}
+
+	secret, err := generateRandomToken(32)
+	if err != nil {
+		return "", nil, err
+	}
+	rawToken := PersonalTokenPrefix + secret
+
+	token := &models.PersonalToken{
+		Nombre:  nombre,
+		Prefijo: rawToken[:personalTokenDisplayLength],
+		Scopes:  scopes,
+	}
+	err = s.db.QueryRow(ctx,
+		`INSERT INTO tb_token_personal (id_persona, nombre, token_hash, prefijo, scopes, fecha_creacion, fecha_expiracion)
+		 VALUES ($1, $2, $3, $4, $5, NOW(), NOW() + $6::float8 * INTERVAL '1 second')
+		 RETURNING id_token, fecha_creacion, fecha_expiracion`,
+		idPersona, nombre, hashToken(rawToken), token.Prefijo, scopes, ttl.Seconds(),
+	).Scan(&token.IDToken, &token.FechaCreacion, &token.FechaExpiracion)
+	if err != nil {
+		return "", nil, err
+	}
+
+	return rawToken, token, nil
+}
+
+// Authenticate valida el token y registra su último uso. Los tokens de cuentas suspendidas no sirven.
+func (s *PersonalTokenService) Authenticate(ctx context.Context, rawToken string) (*PersonalTokenAuth, error) {
+	if !IsPersonalToken(rawToken) {
+		return nil, ErrInvalidPersonalToken
+	}
+
+	auth := &PersonalTokenAuth{}
+	err := s.db.QueryRow(ctx,
+		`UPDATE tb_token_personal tp SET ultimo_uso = NOW()
+		 FROM tb_persona p
+		 WHERE p.id_persona = tp.id_persona AND tp.token_hash = $1
+		   AND tp.revocado_en IS NULL AND tp.fecha_expiracion > NOW()
+		   AND p.suspendido_en IS NULL
+		 RETURNING tp.id_token, tp.id_persona, p.email, tp.scopes`,
+		hashToken(rawToken),
+	).Scan(&auth.IDToken, &auth.IDPersona, &auth.Email, &auth.Scopes)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, ErrInvalidPersonalToken
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	return auth, nil
+}
+
+// List devuelve los tokens del usuario, incluidos los revocados y vencidos
+func (s *PersonalTokenService) List(ctx context.Context, idPersona int) ([]models.PersonalToken, error) {
+	rows, err := s.db.Query(ctx,
+		`SELECT id_token, nombre, prefijo, scopes, fecha_creacion, fecha_expiracion, ultimo_uso, revocado_en IS NOT NULL
+		 FROM tb_token_personal
+		 WHERE id_persona = $1
+		 ORDER BY fecha_creacion DESC`,
+		idPersona,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	tokens := []models.PersonalToken{}
+	for rows.Next() {
+		var t models.PersonalToken
+		if err := rows.Scan(&t.IDToken, &t.Nombre, &t.Prefijo, &t.Scopes, &t.FechaCreacion, &t.FechaExpiracion, &t.UltimoUso, &t.Revocado); err != nil {
+			return nil, err
+		}
+		tokens = append(tokens, t)
+	}
+	return tokens, rows.Err()
+}
+
+// Revoke revoca un token del usuario
+func (s *PersonalTokenService) Revoke(ctx context.Context, idPersona int, idToken int) error {
+	result, err := s.db.Exec(ctx,
+		`UPDATE tb_token_personal SET revocado_en = NOW()
+		 WHERE id_token = $1 AND id_persona = $2 AND revocado_en IS NULL`,
+		idToken, idPersona,
+	)
+	if err != nil {
+		return err
+	}
+	if result.RowsAffected() == 0 {
+		return ErrPersonalTokenNotFound
+	}
+	return nil
+}
+
+// HasScope indica si el conjunto de scopes incluye el pedido
+func HasScope(scopes []string, scope string) bool {
+	return slices.Contains(scopes, scope)
+}
+
+// normalizeScopes valida los scopes y elimina duplicados
+func normalizeScopes(scopes []string) ([]string, error) {
+	if len(scopes) == 0 {
+		return nil, ErrInvalidScope
+	}
+
+	result := make([]string, 0, len(scopes))
+	for _, scope := range scopes {
+		scope = strings.TrimSpace(scope)
+		if !slices.Contains(AvailableScopes, scope) {
+			return nil, fmt.Errorf("%w: %s", ErrInvalidScope, scope)
+		}
+		if !slices.Contains(result, scope) {
+			result = append(result, scope)
+		}
+	}
+	return result, nil
+}
@@ -2,10 +2,12 @@ package services
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"mentorly-backend/models"
 	"strings"
 
+	"github.com/jackc/pgx/v5"
 	"github.com/jackc/pgx/v5/pgxpool"
 )
 
@@ -52,6 +54,9 @@ func (s *PlanService) GetPlanByID(ctx context.Context, id int) (*models.Plan, er
 	var p models.Plan
 	query := `SELECT id_plan, nombre_plan, precio, descripcion, activo FROM tb_plan WHERE id_plan = $1`
 	err := s.db.QueryRow(ctx, query, id).Scan(&p.ID, &p.Nombre, &p.Precio, &p.Descripcion, &p.Activo)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, ErrNotFound
+	}
 	if err != nil {
 		return nil, err
 	}
@@ -0,0 +1,203 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"math/rand/v2"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+// RateLimitPolicy define cuántos requests se permiten por clave en una ventana de tiempo
+type RateLimitPolicy struct {
+	Name   string
+	Limit  int
+	Window time.Duration
+}
+
+// RateLimitPolicyFromEnv lee la política de RATE_LIMIT_<NAME> con el formato "cantidad/ventana"
+// (por ejemplo "10/1m"). Si no está configurada o es inválida usa los valores por defecto.
+func RateLimitPolicyFromEnv(name string, limit int, window time.Duration) RateLimitPolicy {
+	policy := RateLimitPolicy{Name: name, Limit: limit, Window: window}
+
+	key := "RATE_LIMIT_" + strings.ToUpper(name)
+	v := os.Getenv(key)
+	if v == "" {
+		return policy
+	}
+
+	countStr, windowStr, ok := strings.Cut(v, "/")
+	count, countErr := strconv.Atoi(countStr)
+	d, windowErr := time.ParseDuration(windowStr)
+	if !ok || countErr != nil || windowErr != nil || count <= 0 || d <= 0 {
+		log.Printf("Advertencia: %s inválido (%q), se usa %d/%s", key, v, limit, window)
+		return policy
+	}
+
+	policy.Limit = count
+	policy.Window = d
+	return policy
+}
+
+// RateLimitResult es el estado del límite después de contar un request
+type RateLimitResult struct {
+	Allowed   bool
+	Limit     int
+	Remaining int
+	// Reset es el tiempo hasta que se libera capacidad
+	Reset time.Duration
+}
+
+// RateLimitStore cuenta los requests por clave. Usa una ventana deslizante aproximada:
+// el conteo de la ventana anterior se pondera por la parte que todavía se superpone.
+type RateLimitStore interface {
+	Allow(ctx context.Context, key string, policy RateLimitPolicy) (RateLimitResult, error)
+}
+
+// NewRateLimitStoreFromEnv elige el almacenamiento según RATE_LIMIT_STORE: "memory"
+// (por defecto, para una sola instancia) o "postgres" (compartido entre instancias).
+func NewRateLimitStoreFromEnv(db *pgxpool.Pool) RateLimitStore {
+	switch os.Getenv("RATE_LIMIT_STORE") {
+	case "postgres":
+		return NewPostgresRateLimitStore(db)
+	case "", "memory":
+		return NewMemoryRateLimitStore()
+	default:
+		log.Printf("Advertencia: RATE_LIMIT_STORE inválido (%q), se usa memory", os.Getenv("RATE_LIMIT_STORE"))
+		return NewMemoryRateLimitStore()
+	}
+}
+
+// slidingWindow calcula el resultado a partir de los conteos de la ventana actual y la anterior
+func slidingWindow(policy RateLimitPolicy, now time.Time, current int, previous int) RateLimitResult {
+	windowStart := now.Truncate(policy.Window)
+	elapsed := now.Sub(windowStart)
+	weight := 1 - float64(elapsed)/float64(policy.Window)
+
+	estimated := int(float64(previous)*weight) + current
+	result := RateLimitResult{
+		Allowed:   estimated <= policy.Limit,
+		Limit:     policy.Limit,
+		Remaining: max(policy.Limit-estimated, 0),
+		Reset:     policy.Window - elapsed,
+	}
+	return result
+}
+
+func windowIndex(policy RateLimitPolicy, now time.Time) int64 {
+	return now.UnixNano() / int64(policy.Window)
+}
+
+// MemoryRateLimitStore guarda los contadores en memoria. Sirve para una sola instancia.
+type MemoryRateLimitStore struct {
+	mu        sync.Mutex
+	buckets   map[string]*memoryBucket
+	lastSweep time.Time
+}
+
+type memoryBucket struct {
+	// period es la ventana de la política que creó la clave; window se cuenta en esa unidad
+	period   time.Duration
+	window   int64
+	current  int
+	previous int
+}
+
+// memorySweepInterval es cada cuánto como máximo se recorren las claves para borrar las viejas
+const memorySweepInterval = time.Minute
+
+// NewMemoryRateLimitStore crea un almacenamiento en memoria
+func NewMemoryRateLimitStore() *MemoryRateLimitStore {
+	return &MemoryRateLimitStore{buckets: make(map[string]*memoryBucket), lastSweep: time.Now()}
+}
+
+func (s *MemoryRateLimitStore) Allow(ctx context.Context, key string, policy RateLimitPolicy) (RateLimitResult, error) {
+	now := time.Now()
+	window := windowIndex(policy, now)
+	key = policy.Name + ":" + key
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if now.Sub(s.lastSweep) >= memorySweepInterval {
+		s.sweep(now)
+	}
+
+	bucket, ok := s.buckets[key]
+	if !ok || bucket.period != policy.Window {
+		bucket = &memoryBucket{period: policy.Window, window: window}
+		s.buckets[key] = bucket
+	}
+
+	switch {
+	case bucket.window == window:
+	case bucket.window == window-1:
+		bucket.previous, bucket.current = bucket.current, 0
+		bucket.window = window
+	default:
+		bucket.previous, bucket.current = 0, 0
+		bucket.window = window
+	}
+
+	bucket.current++
+	return slidingWindow(policy, now, bucket.current, bucket.previous), nil
+}
+
+// sweep borra las claves que no se usaron en las últimas dos ventanas de su propia política
+func (s *MemoryRateLimitStore) sweep(now time.Time) {
+	for key, bucket := range s.buckets {
+		if now.UnixNano()/int64(bucket.period) > bucket.window+1 {
+			delete(s.buckets, key)
+		}
+	}
+	s.lastSweep = now
+}
+
+// PostgresRateLimitStore guarda los contadores en tb_rate_limit, compartidos entre instancias
+type PostgresRateLimitStore struct {
+	db *pgxpool.Pool
+}
+
+// postgresCleanupProbability es la probabilidad de borrar ventanas viejas en cada request
+const postgresCleanupProbability = 0.01
+
+// NewPostgresRateLimitStore crea un almacenamiento en Postgres
+func NewPostgresRateLimitStore(db *pgxpool.Pool) *PostgresRateLimitStore {
+	return &PostgresRateLimitStore{db: db}
+}
+
+func (s *PostgresRateLimitStore) Allow(ctx context.Context, key string, policy RateLimitPolicy) (RateLimitResult, error) {
+	now := time.Now()
+	window := windowIndex(policy, now)
+	key = fmt.Sprintf("%s:%s", policy.Name, key)
+
+	var current, previous int
+	err := s.db.QueryRow(ctx,
+		`WITH actual AS (
+		     INSERT INTO tb_rate_limit (clave, ventana, contador, expira_en)
+		     VALUES ($1, $2, 1, NOW() + $3::float8 * INTERVAL '1 second')
+		     ON CONFLICT (clave, ventana) DO UPDATE SET contador = tb_rate_limit.contador + 1
+		     RETURNING contador
+		 )
+		 SELECT (SELECT contador FROM actual),
+		        COALESCE((SELECT contador FROM tb_rate_limit WHERE clave = $1 AND ventana = $2 - 1), 0)`,
+		key, window, (2*policy.Window).Seconds(),
+	).Scan(&current, &previous)
+	if err != nil {
+		return RateLimitResult{}, err
+	}
+
+	if rand.Float64() < postgresCleanupProbability {
+		if _, err := s.db.Exec(ctx, "DELETE FROM tb_rate_limit WHERE expira_en < NOW()"); err != nil {
+			log.Printf("Error al limpiar tb_rate_limit: %v", err)
+		}
+	}
+
+	return slidingWindow(policy, now, current, previous), nil
+}
@@ -0,0 +1,50 @@
+package services
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+func TestMemoryRateLimitStoreSweepUsesEachBucketWindow(t *testing.T) {
+	store := NewMemoryRateLimitStore()
+	short := RateLimitPolicy{Name: "corta", Limit: 5, Window: time.Second}
+	long := RateLimitPolicy{Name: "larga", Limit: 5, Window: time.Hour}
+
+	for _, policy := range []RateLimitPolicy{short, long} {
+		if _, err := store.Allow(context.Background(), "1.2.3.4", policy); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	// Pasados unos segundos la clave de la ventana corta ya no sirve; la de una hora sí
+	store.sweep(time.Now().Add(3 * time.Second))
+
+	if _, ok := store.buckets["corta:1.2.3.4"]; ok {
+		t.Error("la clave de la ventana corta debería haberse borrado")
+	}
+	if _, ok := store.buckets["larga:1.2.3.4"]; !ok {
+		t.Error("la clave de la ventana larga no debería borrarse antes de dos ventanas")
+	}
+}
+
+func TestMemoryRateLimitStoreSweepsAtMostOncePerInterval(t *testing.T) {
+	store := NewMemoryRateLimitStore()
+	policy := RateLimitPolicy{Name: "login", Limit: 5, Window: time.Second}
+
+	store.buckets["login:viejo"] = &memoryBucket{period: time.Second, window: 0}
+	if _, err := store.Allow(context.Background(), "nuevo", policy); err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := store.buckets["login:viejo"]; !ok {
+		t.Fatal("no debería limpiarse antes de memorySweepInterval")
+	}
+
+	store.lastSweep = time.Now().Add(-memorySweepInterval)
+	if _, err := store.Allow(context.Background(), "nuevo", policy); err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := store.buckets["login:viejo"]; ok {
+		t.Error("la clave vieja debería borrarse una vez pasado memorySweepInterval")
+	}
+}
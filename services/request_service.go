@@ -0,0 +1,502 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"mentorly-backend/models"
+	"strings"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+// Lados de una solicitud de mentoría
+const (
+	MentorshipRoleMentor = "mentor"
+	MentorshipRoleMentee = "mentorado"
+)
+
+const (
+	mentorshipExpiryInterval  = time.Hour
+	mentorshipExpiryBatchSize = 100
+)
+
+// MentorshipConfig define los límites de las solicitudes de mentoría
+type MentorshipConfig struct {
+	// Solicitudes pendientes que puede tener un mentor antes de dejar de recibir nuevas
+	MaxOpenPerMentor int
+	// Solicitudes pendientes que puede tener enviadas un mentorado
+	MaxOpenPerMentee int
+	// Tiempo que tiene el mentor para responder antes de que la solicitud expire
+	RequestTTL time.Duration
+}
+
+// DefaultMentorshipConfig devuelve los valores por defecto
+func DefaultMentorshipConfig() MentorshipConfig {
+	return MentorshipConfig{
+		MaxOpenPerMentor: 10,
+		MaxOpenPerMentee: 5,
+		RequestTTL:       7 * 24 * time.Hour,
+	}
+}
+
+// MentorshipConfigFromEnv lee la configuración de MENTORSHIP_MAX_OPEN_PER_MENTOR,
+// MENTORSHIP_MAX_OPEN_PER_MENTEE y MENTORSHIP_REQUEST_TTL (por ejemplo "168h").
+func MentorshipConfigFromEnv() MentorshipConfig {
+	config := DefaultMentorshipConfig()
+	envInt("MENTORSHIP_MAX_OPEN_PER_MENTOR", &config.MaxOpenPerMentor)
+	envInt("MENTORSHIP_MAX_OPEN_PER_MENTEE", &config.MaxOpenPerMentee)
+	envDuration("MENTORSHIP_REQUEST_TTL", &config.RequestTTL)
+	return config
+}
+
+// mentorshipTransition indica desde qué estados se llega a un estado y quién puede hacerlo.
+// Actor vacío es el sistema (vencimiento).
+type mentorshipTransition struct {
+	desde []string
+	actor string
+}
+
+// mentorshipTransitions es la máquina de estados de las solicitudes. Todos los estados
+// destino son finales, así que solo se sale de pendiente.
+var mentorshipTransitions = map[string]mentorshipTransition{
+	models.MentorshipRequestAccepted:  {desde: []string{models.MentorshipRequestPending}, actor: MentorshipRoleMentor},
+	models.MentorshipRequestDeclined:  {desde: []string{models.MentorshipRequestPending}, actor: MentorshipRoleMentor},
+	models.MentorshipRequestWithdrawn: {desde: []string{models.MentorshipRequestPending}, actor: MentorshipRoleMentee},
+	models.MentorshipRequestExpired:   {desde: []string{models.MentorshipRequestPending}},
+}
+
+// mentorshipColumns por rol: la columna con el usuario de ese lado de la solicitud
+var mentorshipColumns = map[string]string{
+	MentorshipRoleMentor: "s.id_mentor",
+	MentorshipRoleMentee: "s.id_mentorado",
+}
+
+// MentorshipRequestInput es lo que escribe el mentorado al pedir una mentoría
+type MentorshipRequestInput struct {
+	IDMentor  int
+	Mensaje   string
+	Objetivos []string
+}
+
+// MentorshipRequestService maneja las solicitudes de mentoría entre mentorados y mentores
+type MentorshipRequestService struct {
+	db          *pgxpool.Pool
+	mailer      Mailer
+	linkBaseURL string
+	config      MentorshipConfig
+}
+
+// NewMentorshipRequestService crea el servicio. linkBaseURL es la URL del frontend,
+// que se incluye en los avisos.
+func NewMentorshipRequestService(db *pgxpool.Pool, mailer Mailer, linkBaseURL string, config MentorshipConfig) *MentorshipRequestService {
+	return &MentorshipRequestService{db: db, mailer: mailer, linkBaseURL: linkBaseURL, config: config}
+}
+
+// mentorshipEstado muestra como expiradas las pendientes vencidas que el proceso periódico
+// todavía no actualizó
+const mentorshipEstado = `CASE WHEN s.estado = '` + models.MentorshipRequestPending + `' AND s.fecha_expiracion <= NOW()
+	THEN '` + models.MentorshipRequestExpired + `' ELSE s.estado END`
+
+const mentorshipRequestColumns = `s.id_solicitud, s.id_mentorado, pe.nombre || ' ' || pe.apellido,
+	s.id_mentor, pm.nombre || ' ' || pm.apellido, s.mensaje, s.objetivos, ` + mentorshipEstado + `,
+	s.motivo_rechazo, s.fecha_creacion, s.fecha_actualizacion, s.fecha_expiracion, s.fecha_respuesta`
+
+const mentorshipRequestFrom = `tb_solicitud_mentoria s
+	JOIN tb_persona pe ON pe.id_persona = s.id_mentorado
+	JOIN tb_persona pm ON pm.id_persona = s.id_mentor`
+
+func scanMentorshipRequest(row pgx.Row) (*models.MentorshipRequest, error) {
+	var r models.MentorshipRequest
+	err := row.Scan(&r.IDSolicitud, &r.IDMentorado, &r.NombreMentorado, &r.IDMentor, &r.NombreMentor,
+		&r.Mensaje, &r.Objetivos, &r.Estado, &r.MotivoRechazo,
+		&r.FechaCreacion, &r.FechaActualizacion, &r.FechaExpiracion, &r.FechaRespuesta)
+	if err != nil {
+		return nil, err
+	}
+	return &r, nil
+}
+
+// Create envía una solicitud al mentor. El mentor tiene que tener el perfil visible y
+// disponible, y no superar el máximo de solicitudes pendientes.
+func (s *MentorshipRequestService) Create(ctx context.Context, idMentorado int, input MentorshipRequestInput) (*models.MentorshipRequest, error) {
+	input, err := normalizeMentorshipRequest(input)
+	if err != nil {
+		return nil, err
+	}
+	if input.IDMentor == idMentorado {
+		return nil, ErrCannotRequestSelf
+	}
+
+	tx, err := s.db.Begin(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback(ctx)
+
+	// Bloquear el perfil del mentor y al mentorado serializa las solicitudes concurrentes,
+	// así los límites no se pueden superar
+	var disponible bool
+	err = tx.QueryRow(ctx,
+		`SELECT m.disponible
+		 FROM tb_perfil_mentor m JOIN tb_persona p ON p.id_persona = m.id_persona
+		 WHERE m.id_persona = $1 AND `+mentorVisible+`
+		 FOR UPDATE OF m`,
+		input.IDMentor,
+	).Scan(&disponible)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, ErrMentorProfileNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	if !disponible {
+		return nil, ErrMentorNotAvailable
+	}
+	if _, err := tx.Exec(ctx, "SELECT 1 FROM tb_persona WHERE id_persona = $1 FOR NO KEY UPDATE", idMentorado); err != nil {
+		return nil, err
+	}
+
+	// Una pendiente vencida con el mismo mentor no debe impedir enviar otra
+	if _, err := tx.Exec(ctx,
+		`UPDATE tb_solicitud_mentoria SET estado = $3, fecha_actualizacion = NOW()
+		 WHERE id_mentorado = $1 AND id_mentor = $2 AND estado = $4 AND fecha_expiracion <= NOW()`,
+		idMentorado, input.IDMentor, models.MentorshipRequestExpired, models.MentorshipRequestPending,
+	); err != nil {
+		return nil, err
+	}
+
+	var pendientesMentor, pendientesMentorado int
+	err = tx.QueryRow(ctx,
+		`SELECT COUNT(*) FILTER (WHERE id_mentor = $1), COUNT(*) FILTER (WHERE id_mentorado = $2)
+		 FROM tb_solicitud_mentoria
+		 WHERE (id_mentor = $1 OR id_mentorado = $2) AND estado = $3 AND fecha_expiracion > NOW()`,
+		input.IDMentor, idMentorado, models.MentorshipRequestPending,
+	).Scan(&pendientesMentor, &pendientesMentorado)
+	if err != nil {
+		return nil, err
+	}
+	if pendientesMentor >= s.config.MaxOpenPerMentor {
+		return nil, ErrMentorRequestLimit
+	}
+	if pendientesMentorado >= s.config.MaxOpenPerMentee {
+		return nil, ErrTooManyOpenRequests
+	}
+
+	var idSolicitud int
+	err = tx.QueryRow(ctx,
+		`INSERT INTO tb_solicitud_mentoria (id_mentorado, id_mentor, mensaje, objetivos, fecha_expiracion)
+		 VALUES ($1, $2, $3, $4, NOW() + $5::float8 * INTERVAL '1 second')
+		 RETURNING id_solicitud`,
+		idMentorado, input.IDMentor, input.Mensaje, input.Objetivos, s.config.RequestTTL.Seconds(),
+	).Scan(&idSolicitud)
+	var pgErr *pgconn.PgError
+	if errors.As(err, &pgErr) && pgErr.Code == "23505" {
+		return nil, ErrMentorshipRequestOpen
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return nil, err
+	}
+
+	request, err := s.get(ctx, idSolicitud)
+	if err != nil {
+		return nil, err
+	}
+	s.notify(ctx, request)
+	return request, nil
+}
+
+// Get obtiene una solicitud si el usuario es el mentor o el mentorado
+func (s *MentorshipRequestService) Get(ctx context.Context, idSolicitud int, idPersona int) (*models.MentorshipRequest, error) {
+	request, err := scanMentorshipRequest(s.db.QueryRow(ctx,
+		`SELECT `+mentorshipRequestColumns+`
+		 FROM `+mentorshipRequestFrom+`
+		 WHERE s.id_solicitud = $1 AND $2 IN (s.id_mentorado, s.id_mentor)`,
+		idSolicitud, idPersona,
+	))
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, ErrMentorshipRequestNotFound
+	}
+	return request, err
+}
+
+func (s *MentorshipRequestService) get(ctx context.Context, idSolicitud int) (*models.MentorshipRequest, error) {
+	request, err := scanMentorshipRequest(s.db.QueryRow(ctx,
+		`SELECT `+mentorshipRequestColumns+`
+		 FROM `+mentorshipRequestFrom+`
+		 WHERE s.id_solicitud = $1`,
+		idSolicitud,
+	))
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, ErrMentorshipRequestNotFound
+	}
+	return request, err
+}
+
+// List devuelve las solicitudes recibidas (rol mentor) o enviadas (rol mentorado) por el usuario,
+// las más recientes primero. estado vacío no filtra.
+func (s *MentorshipRequestService) List(ctx context.Context, idPersona int, rol string, estado string, limit int, offset int) ([]models.MentorshipRequest, error) {
+	column, ok := mentorshipColumns[rol]
+	if !ok {
+		return nil, ErrInvalidMentorshipRole
+	}
+
+	rows, err := s.db.Query(ctx,
+		`SELECT `+mentorshipRequestColumns+`
+		 FROM `+mentorshipRequestFrom+`
+		 WHERE `+column+` = $1 AND ($2 = '' OR `+mentorshipEstado+` = $2)
+		 ORDER BY s.fecha_creacion DESC, s.id_solicitud DESC
+		 LIMIT $3 OFFSET $4`,
+		idPersona, estado, limit, offset,
+	)
+	if err != nil {
+		return nil, err
+	}
+	return collectMentorshipRequests(rows)
+}
+
+// ListByUser devuelve todas las solicitudes enviadas y recibidas por el usuario
+func (s *MentorshipRequestService) ListByUser(ctx context.Context, idPersona int) ([]models.MentorshipRequest, error) {
+	rows, err := s.db.Query(ctx,
+		`SELECT `+mentorshipRequestColumns+`
+		 FROM `+mentorshipRequestFrom+`
+		 WHERE $1 IN (s.id_mentorado, s.id_mentor)
+		 ORDER BY s.fecha_creacion DESC, s.id_solicitud DESC`,
+		idPersona,
+	)
+	if err != nil {
+		return nil, err
+	}
+	return collectMentorshipRequests(rows)
+}
+
+func collectMentorshipRequests(rows pgx.Rows) ([]models.MentorshipRequest, error) {
+	defer rows.Close()
+
+	requests := []models.MentorshipRequest{}
+	for rows.Next() {
+		request, err := scanMentorshipRequest(rows)
+		if err != nil {
+			return nil, err
+		}
+		requests = append(requests, *request)
+	}
+	return requests, rows.Err()
+}
+
+// Accept acepta una solicitud pendiente recibida por el mentor
+func (s *MentorshipRequestService) Accept(ctx context.Context, idSolicitud int, idMentor int) (*models.MentorshipRequest, error) {
+	return s.transition(ctx, idSolicitud, idMentor, models.MentorshipRequestAccepted, "")
+}
+
+// Decline rechaza una solicitud pendiente recibida por el mentor. El motivo se le envía al mentorado.
+func (s *MentorshipRequestService) Decline(ctx context.Context, idSolicitud int, idMentor int, motivo string) (*models.MentorshipRequest, error) {
+	motivo = strings.TrimSpace(motivo)
+	if motivo == "" {
+		return nil, ErrDeclineReasonRequired
+	}
+	return s.transition(ctx, idSolicitud, idMentor, models.MentorshipRequestDeclined, motivo)
+}
+
+// Withdraw retira una solicitud pendiente enviada por el mentorado
+func (s *MentorshipRequestService) Withdraw(ctx context.Context, idSolicitud int, idMentorado int) (*models.MentorshipRequest, error) {
+	return s.transition(ctx, idSolicitud, idMentorado, models.MentorshipRequestWithdrawn, "")
+}
+
+// transition aplica un cambio de estado hecho por un usuario. El UPDATE solo afecta a la
+// solicitud si está en un estado de origen válido, no venció y el usuario es del lado que
+// puede hacer el cambio; si no, se averigua el motivo para devolver el error adecuado.
+func (s *MentorshipRequestService) transition(ctx context.Context, idSolicitud int, idPersona int, estado string, motivo string) (*models.MentorshipRequest, error) {
+	t := mentorshipTransitions[estado]
+
+	tag, err := s.db.Exec(ctx,
+		`UPDATE tb_solicitud_mentoria s
+		 SET estado = $2, motivo_rechazo = NULLIF($3, ''), fecha_respuesta = NOW(), fecha_actualizacion = NOW()
+		 WHERE s.id_solicitud = $1 AND `+mentorshipColumns[t.actor]+` = $4
+		   AND s.estado = ANY($5) AND s.fecha_expiracion > NOW()`,
+		idSolicitud, estado, motivo, idPersona, t.desde,
+	)
+	if err != nil {
+		return nil, err
+	}
+	if tag.RowsAffected() == 0 {
+		return nil, s.transitionError(ctx, idSolicitud, idPersona)
+	}
+
+	request, err := s.get(ctx, idSolicitud)
+	if err != nil {
+		return nil, err
+	}
+	s.notify(ctx, request)
+	return request, nil
+}
+
+// transitionError explica por qué no se pudo cambiar el estado de la solicitud
+func (s *MentorshipRequestService) transitionError(ctx context.Context, idSolicitud int, idPersona int) error {
+	request, err := s.Get(ctx, idSolicitud, idPersona)
+	if err != nil {
+		return err
+	}
+	switch request.Estado {
+	case models.MentorshipRequestPending:
+		// Es parte de la solicitud pero no del lado que puede hacer este cambio
+		return ErrPermissionDenied
+	case models.MentorshipRequestExpired:
+		return ErrMentorshipRequestExpired
+	default:
+		return ErrMentorshipRequestNotPending
+	}
+}
+
+// StartExpiry vence periódicamente las solicitudes sin respuesta, hasta que se cancele ctx
+func (s *MentorshipRequestService) StartExpiry(ctx context.Context) {
+	ticker := time.NewTicker(mentorshipExpiryInterval)
+	go func() {
+		defer ticker.Stop()
+		for {
+			if n, err := s.ExpireDue(ctx); err != nil {
+				log.Printf("Error al vencer solicitudes de mentoría: %v", err)
+			} else if n > 0 {
+				log.Printf("Solicitudes de mentoría vencidas: %d", n)
+			}
+
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+			}
+		}
+	}()
+}
+
+// ExpireDue marca como expiradas las solicitudes pendientes vencidas y avisa a los mentorados.
+// Devuelve cuántas procesó.
+func (s *MentorshipRequestService) ExpireDue(ctx context.Context) (int, error) {
+	total := 0
+	for {
+		n, err := s.expireBatch(ctx)
+		total += n
+		if err != nil || n < mentorshipExpiryBatchSize {
+			return total, err
+		}
+	}
+}
+
+func (s *MentorshipRequestService) expireBatch(ctx context.Context) (int, error) {
+	// SKIP LOCKED evita que dos instancias venzan (y notifiquen) la misma solicitud
+	rows, err := s.db.Query(ctx,
+		`UPDATE tb_solicitud_mentoria SET estado = $1, fecha_actualizacion = NOW()
+		 WHERE id_solicitud IN (
+		     SELECT id_solicitud FROM tb_solicitud_mentoria
+		     WHERE estado = ANY($2) AND fecha_expiracion <= NOW()
+		     ORDER BY fecha_expiracion
+		     LIMIT $3
+		     FOR UPDATE SKIP LOCKED
+		 )
+		 RETURNING id_solicitud`,
+		models.MentorshipRequestExpired, mentorshipTransitions[models.MentorshipRequestExpired].desde,
+		mentorshipExpiryBatchSize,
+	)
+	if err != nil {
+		return 0, err
+	}
+	ids, err := pgx.CollectRows(rows, pgx.RowTo[int])
+	if err != nil {
+		return 0, err
+	}
+
+	for _, idSolicitud := range ids {
+		request, err := s.get(ctx, idSolicitud)
+		if err != nil {
+			log.Printf("Error al notificar la solicitud de mentoría %d: %v", idSolicitud, err)
+			continue
+		}
+		s.notify(ctx, request)
+	}
+	return len(ids), nil
+}
+
+// notify avisa del cambio de estado a la otra parte: al mentor de las solicitudes nuevas y
+// retiradas, al mentorado de las respuestas y vencimientos. Un error al enviar el email no
+// revierte el cambio de estado.
+func (s *MentorshipRequestService) notify(ctx context.Context, r *models.MentorshipRequest) {
+	link := fmt.Sprintf("%s/mentorship-requests/%d", s.linkBaseURL, r.IDSolicitud)
+
+	idDestinatario := r.IDMentorado
+	var subject, body string
+	switch r.Estado {
+	case models.MentorshipRequestPending:
+		idDestinatario = r.IDMentor
+		subject = "Nueva solicitud de mentoría"
+		body = fmt.Sprintf("%s te envió una solicitud de mentoría con estos objetivos:\n\n- %s\n\nTenés hasta el %s para responderla.",
+			r.NombreMentorado, strings.Join(r.Objetivos, "\n- "), r.FechaExpiracion.Format("02/01/2006"))
+	case models.MentorshipRequestWithdrawn:
+		idDestinatario = r.IDMentor
+		subject = "Una solicitud de mentoría fue retirada"
+		body = fmt.Sprintf("%s retiró su solicitud de mentoría.", r.NombreMentorado)
+	case models.MentorshipRequestAccepted:
+		subject = "¡Tu solicitud de mentoría fue aceptada!"
+		body = fmt.Sprintf("%s aceptó tu solicitud de mentoría.", r.NombreMentor)
+	case models.MentorshipRequestDeclined:
+		subject = "Tu solicitud de mentoría fue rechazada"
+		body = fmt.Sprintf("%s no puede aceptar tu solicitud de mentoría por ahora.", r.NombreMentor)
+		if r.MotivoRechazo != nil {
+			body += "\n\nMotivo:\n" + *r.MotivoRechazo
+		}
+	case models.MentorshipRequestExpired:
+		subject = "Tu solicitud de mentoría expiró"
+		body = fmt.Sprintf("%s no respondió tu solicitud de mentoría a tiempo. Podés enviarle una nueva o buscar otro mentor.", r.NombreMentor)
+	default:
+		return
+	}
+
+	// Las cuentas anonimizadas no tienen un email real
+	var nombre, email string
+	err := s.db.QueryRow(ctx,
+		"SELECT nombre, email FROM tb_persona WHERE id_persona = $1 AND anonimizado_en IS NULL",
+		idDestinatario,
+	).Scan(&nombre, &email)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return
+	}
+	if err == nil {
+		err = s.mailer.Send(ctx, EmailMessage{
+			To:      email,
+			Subject: subject,
+			Body:    fmt.Sprintf("Hola %s,\n\n%s\n\nPodés ver la solicitud en:\n\n%s\n", nombre, body, link),
+		})
+	}
+	if err != nil {
+		log.Printf("Error al notificar la solicitud de mentoría %d: %v", r.IDSolicitud, err)
+	}
+}
+
+// normalizeMentorshipRequest recorta el mensaje y los objetivos y rechaza objetivos vacíos o repetidos
+func normalizeMentorshipRequest(input MentorshipRequestInput) (MentorshipRequestInput, error) {
+	input.Mensaje = strings.TrimSpace(input.Mensaje)
+
+	objetivos := make([]string, len(input.Objetivos))
+	seen := map[string]bool{}
+	for i, objetivo := range input.Objetivos {
+		objetivo = strings.TrimSpace(objetivo)
+		key := strings.ToLower(objetivo)
+		if objetivo == "" || seen[key] {
+			return input, ErrInvalidMentorshipGoals
+		}
+		seen[key] = true
+		objetivos[i] = objetivo
+	}
+	if len(objetivos) == 0 {
+		return input, ErrInvalidMentorshipGoals
+	}
+	input.Objetivos = objetivos
+	return input, nil
+}
@@ -2,19 +2,49 @@ package services
 
 import (
 	"context"
+	"encoding/json"
+	"errors"
 	"log"
+	"mentorly-backend/models"
+	"slices"
 
+	"github.com/jackc/pgx/v5"
 	"github.com/jackc/pgx/v5/pgxpool"
 )
 
+// Permisos que se pueden asignar a un rol (ver tb_permiso)
+const (
+	PermissionPlansRead  = "plans:read"
+	PermissionPlansWrite = "plans:write"
+	PermissionUsersRead  = "users:read"
+	PermissionUsersWrite = "users:write"
+
+	PermissionMentorsReview    = "mentors:review"
+	PermissionUsersImpersonate = "users:impersonate"
+	PermissionAuditRead        = "audit:read"
+	PermissionSkillsWrite      = "skills:write"
+)
+
+const (
+	// AdminRoleName es el rol con todos los permisos. No se puede elegir desde la aplicación.
+	AdminRoleName = "admin"
+	// MentorRoleName se otorga al aprobar una solicitud para ser mentor
+	MentorRoleName = "mentor"
+)
+
+// maxRoleProfileSize limita el tamaño de los datos de perfil de cada rol (JSON serializado)
+const maxRoleProfileSize = 16 * 1024
+
 type RoleService struct {
 	db *pgxpool.Pool
 }
 
 type Role struct {
-	IDRol       int
-	NombreRol   string
-	Descripcion string
+	IDRol         int      `json:"id_rol"`
+	NombreRol     string   `json:"nombre_rol"`
+	Descripcion   string   `json:"descripcion"`
+	Seleccionable bool     `json:"seleccionable"`
+	Permisos      []string `json:"permisos,omitempty"`
 }
 
 func NewRoleService(db *pgxpool.Pool) *RoleService {
@@ -24,7 +54,13 @@ func NewRoleService(db *pgxpool.Pool) *RoleService {
 // GetAllRoles obtiene todos los roles disponibles
 func (s *RoleService) GetAllRoles(ctx context.Context) ([]Role, error) {
 	rows, err := s.db.Query(ctx,
-		"SELECT id_rol, nombre_rol, descripcion FROM tb_rol ORDER BY id_rol",
+		`SELECT r.id_rol, r.nombre_rol, r.descripcion, r.seleccionable,
+		        COALESCE(array_agg(p.nombre ORDER BY p.nombre) FILTER (WHERE p.nombre IS NOT NULL), '{}')
+		 FROM tb_rol r
+		 LEFT JOIN tb_rol_permiso rp ON rp.id_rol = r.id_rol
+		 LEFT JOIN tb_permiso p ON p.id_permiso = rp.id_permiso
+		 GROUP BY r.id_rol
+		 ORDER BY r.id_rol`,
 	)
 	if err != nil {
 		log.Printf("Error al obtener roles: %v", err)
@@ -35,7 +71,7 @@ func (s *RoleService) GetAllRoles(ctx context.Context) ([]Role, error) {
 	var roles []Role
 	for rows.Next() {
 		var role Role
-		err := rows.Scan(&role.IDRol, &role.NombreRol, &role.Descripcion)
+		err := rows.Scan(&role.IDRol, &role.NombreRol, &role.Descripcion, &role.Seleccionable, &role.Permisos)
 		if err != nil {
 			log.Printf("Error al escanear rol: %v", err)
 			continue
@@ -50,9 +86,9 @@ func (s *RoleService) GetAllRoles(ctx context.Context) ([]Role, error) {
 func (s *RoleService) GetRoleByID(ctx context.Context, idRol int) (*Role, error) {
 	var role Role
 	err := s.db.QueryRow(ctx,
-		"SELECT id_rol, nombre_rol, descripcion FROM tb_rol WHERE id_rol = $1",
+		"SELECT id_rol, nombre_rol, descripcion, seleccionable FROM tb_rol WHERE id_rol = $1",
 		idRol,
-	).Scan(&role.IDRol, &role.NombreRol, &role.Descripcion)
+	).Scan(&role.IDRol, &role.NombreRol, &role.Descripcion, &role.Seleccionable)
 
 	if err != nil {
 		log.Printf("Error al obtener rol: %v", err)
@@ -66,9 +102,9 @@ func (s *RoleService) GetRoleByID(ctx context.Context, idRol int) (*Role, error)
 func (s *RoleService) GetRoleByName(ctx context.Context, nombreRol string) (*Role, error) {
 	var role Role
 	err := s.db.QueryRow(ctx,
-		"SELECT id_rol, nombre_rol, descripcion FROM tb_rol WHERE nombre_rol = $1",
+		"SELECT id_rol, nombre_rol, descripcion, seleccionable FROM tb_rol WHERE nombre_rol = $1",
 		nombreRol,
-	).Scan(&role.IDRol, &role.NombreRol, &role.Descripcion)
+	).Scan(&role.IDRol, &role.NombreRol, &role.Descripcion, &role.Seleccionable)
 
 	if err != nil {
 		log.Printf("Error al obtener rol por nombre: %v", err)
@@ -76,4 +112,216 @@ func (s *RoleService) GetRoleByName(ctx context.Context, nombreRol string) (*Rol
 	}
 
 	return &role, nil
-}
\ No newline at end of file
+}
+
+// GetUserPermissions obtiene los permisos que otorgan todos los roles del usuario
+func (s *RoleService) GetUserPermissions(ctx context.Context, idPersona int) ([]string, error) {
+	rows, err := s.db.Query(ctx,
+		`SELECT DISTINCT p.nombre
+		 FROM tb_persona_rol pr
+		 JOIN tb_rol_permiso rp ON rp.id_rol = pr.id_rol
+		 JOIN tb_permiso p ON p.id_permiso = rp.id_permiso
+		 WHERE pr.id_persona = $1
+		 ORDER BY p.nombre`,
+		idPersona,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	permisos, err := pgx.CollectRows(rows, pgx.RowTo[string])
+	if err != nil {
+		return nil, err
+	}
+	return permisos, nil
+}
+
+// HasPermission indica si alguno de los roles del usuario otorga el permiso
+func (s *RoleService) HasPermission(ctx context.Context, idPersona int, permiso string) (bool, error) {
+	permisos, err := s.GetUserPermissions(ctx, idPersona)
+	if err != nil {
+		return false, err
+	}
+	return slices.Contains(permisos, permiso), nil
+}
+
+// GetUserRoles obtiene los roles del usuario en el orden en que se asignaron
+func (s *RoleService) GetUserRoles(ctx context.Context, idPersona int) ([]models.UserRole, error) {
+	rows, err := s.db.Query(ctx,
+		`SELECT r.nombre_rol, r.descripcion, pr.datos, pr.fecha_asignacion
+		 FROM tb_persona_rol pr
+		 JOIN tb_rol r ON r.id_rol = pr.id_rol
+		 WHERE pr.id_persona = $1
+		 ORDER BY pr.fecha_asignacion, r.id_rol`,
+		idPersona,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	roles := []models.UserRole{}
+	for rows.Next() {
+		var role models.UserRole
+		if err := rows.Scan(&role.NombreRol, &role.Descripcion, &role.Datos, &role.FechaAsignacion); err != nil {
+			return nil, err
+		}
+		roles = append(roles, role)
+	}
+	return roles, rows.Err()
+}
+
+// SelectRole agrega un rol elegido por el propio usuario. Solo se pueden elegir los roles
+// seleccionables; el resto (como admin) los asigna un administrador con AssignRole.
+func (s *RoleService) SelectRole(ctx context.Context, idPersona int, nombreRol string) (*Role, error) {
+	role, err := s.GetRoleByName(ctx, nombreRol)
+	if err != nil {
+		return nil, err
+	}
+	if !role.Seleccionable {
+		return nil, ErrRoleNotSelectable
+	}
+
+	if err := s.addUserRole(ctx, idPersona, role.IDRol); err != nil {
+		return nil, err
+	}
+	return role, nil
+}
+
+// AssignRole agrega cualquier rol a un usuario (uso administrativo). idActor solo puede asignar
+// roles cuyos permisos tenga todos, para que users:write no alcance para llegar a admin.
+func (s *RoleService) AssignRole(ctx context.Context, idActor int, idPersona int, nombreRol string) (*Role, error) {
+	role, err := s.GetRoleByName(ctx, nombreRol)
+	if err != nil {
+		return nil, err
+	}
+
+	var covered bool
+	err = s.db.QueryRow(ctx,
+		`SELECT NOT EXISTS (
+		     SELECT 1 FROM tb_rol_permiso rp
+		     WHERE rp.id_rol = $1
+		       AND NOT EXISTS (SELECT 1 FROM tb_persona_rol pr
+		                       JOIN tb_rol_permiso ap ON ap.id_rol = pr.id_rol
+		                       WHERE pr.id_persona = $2 AND ap.id_permiso = rp.id_permiso))`,
+		role.IDRol, idActor,
+	).Scan(&covered)
+	if err != nil {
+		return nil, err
+	}
+	if !covered {
+		return nil, ErrRoleExceedsActor
+	}
+
+	if err := s.addUserRole(ctx, idPersona, role.IDRol); err != nil {
+		return nil, err
+	}
+	return role, nil
+}
+
+// RemoveRole quita un rol que el propio usuario había elegido
+func (s *RoleService) RemoveRole(ctx context.Context, idPersona int, nombreRol string) error {
+	role, err := s.GetRoleByName(ctx, nombreRol)
+	if err != nil {
+		return err
+	}
+	if !role.Seleccionable {
+		return ErrRoleNotSelectable
+	}
+
+	return s.removeUserRole(ctx, idPersona, role.IDRol)
+}
+
+// RevokeRole quita cualquier rol a un usuario (uso administrativo)
+func (s *RoleService) RevokeRole(ctx context.Context, idPersona int, nombreRol string) error {
+	role, err := s.GetRoleByName(ctx, nombreRol)
+	if err != nil {
+		return err
+	}
+
+	return s.removeUserRole(ctx, idPersona, role.IDRol)
+}
+
+// GetRoleProfile obtiene los datos de perfil del usuario para uno de sus roles
+func (s *RoleService) GetRoleProfile(ctx context.Context, idPersona int, nombreRol string) (map[string]any, error) {
+	var datos map[string]any
+	err := s.db.QueryRow(ctx,
+		`SELECT pr.datos
+		 FROM tb_persona_rol pr
+		 JOIN tb_rol r ON r.id_rol = pr.id_rol
+		 WHERE pr.id_persona = $1 AND r.nombre_rol = $2`,
+		idPersona, nombreRol,
+	).Scan(&datos)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, ErrRoleNotAssigned
+	}
+	if err != nil {
+		return nil, err
+	}
+	return datos, nil
+}
+
+// UpdateRoleProfile reemplaza los datos de perfil del usuario para uno de sus roles
+func (s *RoleService) UpdateRoleProfile(ctx context.Context, idPersona int, nombreRol string, datos map[string]any) error {
+	encoded, err := json.Marshal(datos)
+	if err != nil {
+		return err
+	}
+	if len(encoded) > maxRoleProfileSize {
+		return ErrRoleProfileTooLarge
+	}
+
+	result, err := s.db.Exec(ctx,
+		`UPDATE tb_persona_rol pr SET datos = $3
+		 FROM tb_rol r
+		 WHERE r.id_rol = pr.id_rol AND pr.id_persona = $1 AND r.nombre_rol = $2`,
+		idPersona, nombreRol, encoded,
+	)
+	if err != nil {
+		return err
+	}
+	if result.RowsAffected() == 0 {
+		return ErrRoleNotAssigned
+	}
+	return nil
+}
+
+func (s *RoleService) addUserRole(ctx context.Context, idPersona int, idRol int) error {
+	result, err := s.db.Exec(ctx,
+		`INSERT INTO tb_persona_rol (id_persona, id_rol)
+		 SELECT id_persona, $2 FROM tb_persona WHERE id_persona = $1
+		 ON CONFLICT DO NOTHING`,
+		idPersona, idRol,
+	)
+	if err != nil {
+		log.Printf("Error al agregar rol de usuario: %v", err)
+		return err
+	}
+	if result.RowsAffected() == 0 {
+		// O el usuario no existe o ya tenía el rol
+		var exists bool
+		if err := s.db.QueryRow(ctx, "SELECT EXISTS (SELECT 1 FROM tb_persona WHERE id_persona = $1)", idPersona).Scan(&exists); err != nil {
+			return err
+		}
+		if !exists {
+			return ErrUserNotFound
+		}
+		return ErrRoleAlreadyAssigned
+	}
+	return nil
+}
+
+func (s *RoleService) removeUserRole(ctx context.Context, idPersona int, idRol int) error {
+	result, err := s.db.Exec(ctx,
+		"DELETE FROM tb_persona_rol WHERE id_persona = $1 AND id_rol = $2",
+		idPersona, idRol,
+	)
+	if err != nil {
+		log.Printf("Error al quitar rol de usuario: %v", err)
+		return err
+	}
+	if result.RowsAffected() == 0 {
+		return ErrRoleNotAssigned
+	}
+	return nil
+}
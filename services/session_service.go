@@ -0,0 +1,187 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"mentorly-backend/models"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+// sessionActivityInterval evita escribir la última actividad en cada request
+const sessionActivityInterval = time.Minute
+
+// SessionService maneja las sesiones activas de los usuarios
+type SessionService struct {
+	db *pgxpool.Pool
+}
+
+// NewSessionService crea una nueva instancia del servicio de sesiones
+func NewSessionService(db *pgxpool.Pool) *SessionService {
+	return &SessionService{db: db}
+}
+
+// Validate comprueba que la sesión siga activa y la cuenta no esté suspendida, registra la actividad y devuelve el rol
+// activo. Si la sesión no eligió uno (o ya no lo tiene) se usa el primer rol del usuario;
+// sin roles devuelve "".
+func (s *SessionService) Validate(ctx context.Context, idSesion int, idPersona int) (string, error) {
+	var idle float64
+	var rolActivo string
+	var suspendido bool
+	err := s.db.QueryRow(ctx,
+		`SELECT EXTRACT(EPOCH FROM (NOW() - s.ultima_actividad))::float8, p.suspendido_en IS NOT NULL,
+		        COALESCE(
+		            (SELECT r.nombre_rol FROM tb_persona_rol pr JOIN tb_rol r ON r.id_rol = pr.id_rol
+		             WHERE pr.id_persona = s.id_persona AND pr.id_rol = s.id_rol_activo),
+		            (SELECT r.nombre_rol FROM tb_persona_rol pr JOIN tb_rol r ON r.id_rol = pr.id_rol
+		             WHERE pr.id_persona = s.id_persona
+		             ORDER BY pr.fecha_asignacion, r.id_rol LIMIT 1),
+		            '')
+		 FROM tb_sesion s
+		 JOIN tb_persona p ON p.id_persona = s.id_persona
+		 WHERE s.id_sesion = $1 AND s.id_persona = $2 AND s.revocada_en IS NULL`,
+		idSesion, idPersona,
+	).Scan(&idle, &suspendido, &rolActivo)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return "", ErrSessionRevoked
+	}
+	if err != nil {
+		return "", err
+	}
+	if suspendido {
+		return "", ErrAccountSuspended
+	}
+
+	if time.Duration(idle*float64(time.Second)) >= sessionActivityInterval {
+		_, err = s.db.Exec(ctx,
+			"UPDATE tb_sesion SET ultima_actividad = NOW() WHERE id_sesion = $1",
+			idSesion,
+		)
+	}
+	return rolActivo, err
+}
+
+// RequireRecentLogin comprueba que la sesión se haya iniciado hace menos de maxAge.
+// Se usa antes de operaciones sensibles que no piden la contraseña actual.
+func (s *SessionService) RequireRecentLogin(ctx context.Context, idSesion int, idPersona int, maxAge time.Duration) error {
+	var recent bool
+	err := s.db.QueryRow(ctx,
+		`SELECT fecha_creacion > NOW() - $3::float8 * INTERVAL '1 second'
+		 FROM tb_sesion
+		 WHERE id_sesion = $1 AND id_persona = $2 AND revocada_en IS NULL`,
+		idSesion, idPersona, maxAge.Seconds(),
+	).Scan(&recent)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return ErrSessionNotRecent
+	}
+	if err != nil {
+		return err
+	}
+	if !recent {
+		return ErrSessionNotRecent
+	}
+	return nil
+}
+
+// SetActiveRole cambia el rol con el que el usuario usa la aplicación en la sesión.
+// El usuario tiene que tener el rol.
+func (s *SessionService) SetActiveRole(ctx context.Context, idSesion int, idPersona int, nombreRol string) error {
+	result, err := s.db.Exec(ctx,
+		`UPDATE tb_sesion s SET id_rol_activo = pr.id_rol
+		 FROM tb_persona_rol pr JOIN tb_rol r ON r.id_rol = pr.id_rol
+		 WHERE s.id_sesion = $1 AND s.id_persona = $2 AND s.revocada_en IS NULL
+		   AND pr.id_persona = s.id_persona AND r.nombre_rol = $3`,
+		idSesion, idPersona, nombreRol,
+	)
+	if err != nil {
+		return err
+	}
+	if result.RowsAffected() == 0 {
+		return ErrRoleNotAssigned
+	}
+	return nil
+}
+
+// List devuelve las sesiones activas del usuario, marcando la actual
+func (s *SessionService) List(ctx context.Context, idPersona int, currentSesion int) ([]models.Session, error) {
+	rows, err := s.db.Query(ctx,
+		`SELECT id_sesion, user_agent, ip, fecha_creacion, ultima_actividad
+		 FROM tb_sesion
+		 WHERE id_persona = $1 AND revocada_en IS NULL
+		 ORDER BY ultima_actividad DESC`,
+		idPersona,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	sessions := []models.Session{}
+	for rows.Next() {
+		var session models.Session
+		if err := rows.Scan(&session.IDSesion, &session.UserAgent, &session.IP, &session.FechaCreacion, &session.UltimaActividad); err != nil {
+			return nil, err
+		}
+		session.Actual = session.IDSesion == currentSesion
+		sessions = append(sessions, session)
+	}
+	return sessions, rows.Err()
+}
+
+// Revoke cierra una sesión del usuario y revoca sus refresh tokens
+func (s *SessionService) Revoke(ctx context.Context, idPersona int, idSesion int) error {
+	var familia string
+	err := s.db.QueryRow(ctx,
+		"SELECT familia FROM tb_sesion WHERE id_sesion = $1 AND id_persona = $2 AND revocada_en IS NULL",
+		idSesion, idPersona,
+	).Scan(&familia)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return ErrSessionNotFound
+	}
+	if err != nil {
+		return err
+	}
+
+	return revokeFamily(ctx, s.db, familia)
+}
+
+// RevokeOthers cierra todas las sesiones del usuario menos la indicada.
+// Devuelve la cantidad de sesiones cerradas.
+func (s *SessionService) RevokeOthers(ctx context.Context, idPersona int, currentSesion int) (int, error) {
+	tx, err := s.db.Begin(ctx)
+	if err != nil {
+		return 0, err
+	}
+	defer tx.Rollback(ctx)
+
+	rows, err := tx.Query(ctx,
+		`UPDATE tb_sesion SET revocada_en = NOW()
+		 WHERE id_persona = $1 AND id_sesion <> $2 AND revocada_en IS NULL
+		 RETURNING familia`,
+		idPersona, currentSesion,
+	)
+	if err != nil {
+		return 0, err
+	}
+	familias, err := pgx.CollectRows(rows, pgx.RowTo[string])
+	if err != nil {
+		return 0, err
+	}
+
+	if len(familias) > 0 {
+		_, err = tx.Exec(ctx,
+			"UPDATE tb_refresh_token SET revocado_en = NOW() WHERE familia = ANY($1) AND revocado_en IS NULL",
+			familias,
+		)
+		if err != nil {
+			return 0, err
+		}
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return 0, err
+	}
+	return len(familias), nil
+}
@@ -0,0 +1,455 @@
+package services
+
+import (
+	"context"
+	"crypto"
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/base64"
+	"fmt"
+	"log"
+	"math/big"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+const (
+	// SigningAlgEdDSA y SigningAlgRS256 son los algoritmos soportados para firmar los JWT
+	SigningAlgEdDSA = "EdDSA"
+	SigningAlgRS256 = "RS256"
+
+	rsaKeyBits = 2048
+	// signingKeysReloadInterval es cada cuánto se releen las claves de la base,
+	// para ver las rotaciones hechas por otras instancias
+	signingKeysReloadInterval = time.Minute
+	// signingKeysRotationLock identifica el advisory lock que serializa la rotación entre instancias
+	signingKeysRotationLock = 7_310_011
+
+	// JWKSCacheMaxAge es cuánto pueden cachear /.well-known/jwks.json los que validan nuestros tokens
+	JWKSCacheMaxAge = 5 * time.Minute
+	// signingKeyPublishDelay es cuánto se publica una clave nueva antes de que empiece a firmar:
+	// lo que tardan las otras instancias en recargar las claves más lo que dura el JWKS en caché
+	signingKeyPublishDelay = signingKeysReloadInterval + JWKSCacheMaxAge
+)
+
+// SigningKeyConfig define cómo se firman y validan los JWT de acceso
+type SigningKeyConfig struct {
+	Algorithm string
+	Issuer    string
+	Audience  string
+	// Cada cuánto se genera una clave nueva para firmar
+	RotationInterval time.Duration
+	// Cuánto sigue publicada una clave después de dejar de firmar. Debe superar la vida de los tokens.
+	VerificationGrace time.Duration
+}
+
+// SigningKeyConfigFromEnv lee JWT_SIGNING_ALG (EdDSA o RS256), JWT_ISSUER, JWT_AUDIENCE,
+// JWT_KEY_ROTATION y JWT_KEY_GRACE (duraciones en formato de Go).
+func SigningKeyConfigFromEnv() SigningKeyConfig {
+	config := SigningKeyConfig{
+		Algorithm:         SigningAlgEdDSA,
+		Issuer:            "mentorly-backend",
+		Audience:          "mentorly",
+		RotationInterval:  30 * 24 * time.Hour,
+		VerificationGrace: 24 * time.Hour,
+	}
+
+	switch alg := os.Getenv("JWT_SIGNING_ALG"); alg {
+	case "":
+	case SigningAlgEdDSA, SigningAlgRS256:
+		config.Algorithm = alg
+	default:
+		log.Printf("Advertencia: JWT_SIGNING_ALG inválido (%q), se usa %s", alg, config.Algorithm)
+	}
+	if v := os.Getenv("JWT_ISSUER"); v != "" {
+		config.Issuer = v
+	}
+	if v := os.Getenv("JWT_AUDIENCE"); v != "" {
+		config.Audience = v
+	}
+	envDuration("JWT_KEY_ROTATION", &config.RotationInterval)
+	envDuration("JWT_KEY_GRACE", &config.VerificationGrace)
+
+	return config
+}
+
+// signingKey es una clave de firma cargada en memoria
+type signingKey struct {
+	kid       string
+	algorithm string
+	private   crypto.Signer
+	public    crypto.PublicKey
+}
+
+// JSONWebKeySet es el documento publicado en /.well-known/jwks.json
+type JSONWebKeySet struct {
+	Keys []jsonWebKey `json:"keys"`
+}
+
+// SigningKeyService firma y valida los JWT de acceso con claves asimétricas.
+// Las claves se guardan en tb_clave_firma (la privada cifrada) y se rotan
+// periódicamente: la nueva se publica signingKeyPublishDelay antes de firmar y
+// las anteriores se siguen aceptando durante VerificationGrace.
+type SigningKeyService struct {
+	db        *pgxpool.Pool
+	encryptor *Encryptor
+	config    SigningKeyConfig
+
+	mu       sync.RWMutex
+	current  *signingKey
+	keys     map[string]*signingKey
+	loadedAt time.Time
+}
+
+// NewSigningKeyService crea el servicio. Hay que llamar a Init antes de firmar tokens.
+func NewSigningKeyService(db *pgxpool.Pool, encryptor *Encryptor, config SigningKeyConfig) *SigningKeyService {
+	return &SigningKeyService{db: db, encryptor: encryptor, config: config}
+}
+
+// Init crea la primera clave si hace falta y carga las vigentes
+func (s *SigningKeyService) Init(ctx context.Context) error {
+	if err := s.Rotate(ctx, false); err != nil {
+		return err
+	}
+	return s.reload(ctx)
+}
+
+// StartRotation revisa periódicamente si corresponde rotar la clave, hasta que se cancele ctx
+func (s *SigningKeyService) StartRotation(ctx context.Context) {
+	ticker := time.NewTicker(signingKeysReloadInterval)
+	go func() {
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+				if err := s.Rotate(ctx, false); err != nil {
+					log.Printf("Error al rotar la clave de firma: %v", err)
+				}
+				if err := s.reload(ctx); err != nil {
+					log.Printf("Error al recargar las claves de firma: %v", err)
+				}
+			}
+		}
+	}()
+}
+
+// Rotate genera una clave nueva si la activa es más vieja que RotationInterval, o siempre si force es true.
+// La nueva empieza a firmar después de signingKeyPublishDelay (enseguida si no hay ninguna que firme);
+// desde ahí la anterior deja de firmar pero se sigue publicando durante VerificationGrace.
+func (s *SigningKeyService) Rotate(ctx context.Context, force bool) error {
+	tx, err := s.db.Begin(ctx)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback(ctx)
+
+	// Evita que dos instancias generen una clave a la vez
+	if _, err := tx.Exec(ctx, "SELECT pg_advisory_xact_lock($1)", signingKeysRotationLock); err != nil {
+		return err
+	}
+
+	// Las claves que ya no se publican no hace falta conservarlas
+	if _, err := tx.Exec(ctx, "DELETE FROM tb_clave_firma WHERE expira_en <= NOW()"); err != nil {
+		return err
+	}
+
+	// Una clave nueva que ya cumplió la espera reemplaza a las anteriores
+	_, err = tx.Exec(ctx,
+		`UPDATE tb_clave_firma c SET retirada_en = NOW(), expira_en = NOW() + $1::float8 * INTERVAL '1 second'
+		 WHERE c.retirada_en IS NULL AND EXISTS (
+		     SELECT 1 FROM tb_clave_firma n
+		     WHERE n.retirada_en IS NULL AND n.activa_desde <= NOW() AND n.fecha_creacion > c.fecha_creacion
+		 )`,
+		s.config.VerificationGrace.Seconds(),
+	)
+	if err != nil {
+		return err
+	}
+
+	// Una clave pendiente de empezar a firmar también cuenta como reciente
+	var needsRotation bool
+	err = tx.QueryRow(ctx,
+		`SELECT NOT EXISTS (
+		     SELECT 1 FROM tb_clave_firma
+		     WHERE retirada_en IS NULL AND algoritmo = $1
+		       AND fecha_creacion > NOW() - $2::float8 * INTERVAL '1 second'
+		 )`,
+		s.config.Algorithm, s.config.RotationInterval.Seconds(),
+	).Scan(&needsRotation)
+	if err != nil {
+		return err
+	}
+	if !needsRotation && !force {
+		return nil
+	}
+
+	key, err := generateSigningKey(s.config.Algorithm)
+	if err != nil {
+		return err
+	}
+
+	privateDER, err := x509.MarshalPKCS8PrivateKey(key.private)
+	if err != nil {
+		return err
+	}
+	publicDER, err := x509.MarshalPKIXPublicKey(key.public)
+	if err != nil {
+		return err
+	}
+	encrypted, err := s.encryptor.Encrypt(privateDER)
+	if err != nil {
+		return err
+	}
+
+	// Sin una clave que firme (primer arranque) la nueva se usa enseguida
+	var hasActive bool
+	err = tx.QueryRow(ctx,
+		"SELECT EXISTS (SELECT 1 FROM tb_clave_firma WHERE retirada_en IS NULL AND activa_desde <= NOW())",
+	).Scan(&hasActive)
+	if err != nil {
+		return err
+	}
+	delay := signingKeyPublishDelay
+	if !hasActive {
+		delay = 0
+	}
+
+	_, err = tx.Exec(ctx,
+		`INSERT INTO tb_clave_firma (kid, algoritmo, clave_privada_cifrada, clave_publica, fecha_creacion, activa_desde)
+		 VALUES ($1, $2, $3, $4, NOW(), NOW() + $5::float8 * INTERVAL '1 second')`,
+		key.kid, key.algorithm, encrypted, base64.StdEncoding.EncodeToString(publicDER), delay.Seconds(),
+	)
+	if err != nil {
+		return err
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return err
+	}
+
+	log.Printf("Nueva clave de firma JWT %s (%s), firma dentro de %s", key.kid, key.algorithm, delay)
+	return s.reload(ctx)
+}
+
+// reload lee de la base las claves que se publican. Firma la más nueva que ya esté activa;
+// las que todavía esperan para firmar solo se publican.
+func (s *SigningKeyService) reload(ctx context.Context) error {
+	rows, err := s.db.Query(ctx,
+		`SELECT kid, algoritmo, clave_privada_cifrada, clave_publica, retirada_en IS NULL AND activa_desde <= NOW()
+		 FROM tb_clave_firma
+		 WHERE expira_en IS NULL OR expira_en > NOW()
+		 ORDER BY fecha_creacion DESC`,
+	)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	keys := make(map[string]*signingKey)
+	var current *signingKey
+	for rows.Next() {
+		var kid, algorithm, encrypted, publicB64 string
+		var active bool
+		if err := rows.Scan(&kid, &algorithm, &encrypted, &publicB64, &active); err != nil {
+			return err
+		}
+
+		key, err := s.decodeKey(kid, algorithm, encrypted, publicB64, active)
+		if err != nil {
+			log.Printf("Advertencia: no se pudo cargar la clave de firma %s: %v", kid, err)
+			continue
+		}
+
+		keys[kid] = key
+		if active && current == nil {
+			current = key
+		}
+	}
+	if err := rows.Err(); err != nil {
+		return err
+	}
+	if current == nil {
+		return fmt.Errorf("no hay una clave de firma activa")
+	}
+
+	s.mu.Lock()
+	s.keys = keys
+	s.current = current
+	s.loadedAt = time.Now()
+	s.mu.Unlock()
+	return nil
+}
+
+func (s *SigningKeyService) decodeKey(kid, algorithm, encrypted, publicB64 string, active bool) (*signingKey, error) {
+	publicDER, err := base64.StdEncoding.DecodeString(publicB64)
+	if err != nil {
+		return nil, err
+	}
+	public, err := x509.ParsePKIXPublicKey(publicDER)
+	if err != nil {
+		return nil, err
+	}
+
+	key := &signingKey{kid: kid, algorithm: algorithm, public: public}
+
+	// Solo hace falta descifrar la privada de la clave que firma
+	if active {
+		privateDER, err := s.encryptor.Decrypt(encrypted)
+		if err != nil {
+			return nil, err
+		}
+		private, err := x509.ParsePKCS8PrivateKey(privateDER)
+		if err != nil {
+			return nil, err
+		}
+		signer, ok := private.(crypto.Signer)
+		if !ok {
+			return nil, fmt.Errorf("tipo de clave privada no soportado")
+		}
+		key.private = signer
+	}
+
+	return key, nil
+}
+
+// Sign firma los claims con la clave activa, agregando el kid en el header.
+// Completa iss y aud con la configuración.
+func (s *SigningKeyService) Sign(claims jwt.Claims) (string, error) {
+	s.mu.RLock()
+	key := s.current
+	s.mu.RUnlock()
+	if key == nil || key.private == nil {
+		return "", fmt.Errorf("no hay una clave de firma activa")
+	}
+
+	token := jwt.NewWithClaims(jwt.GetSigningMethod(key.algorithm), claims)
+	token.Header["kid"] = key.kid
+	return token.SignedString(key.private)
+}
+
+// Parse valida la firma, el kid, el emisor, la audiencia y el vencimiento de un token
+func (s *SigningKeyService) Parse(tokenString string, claims jwt.Claims) error {
+	token, err := jwt.ParseWithClaims(tokenString, claims, s.keyfunc,
+		jwt.WithValidMethods([]string{SigningAlgEdDSA, SigningAlgRS256}),
+		jwt.WithIssuer(s.config.Issuer),
+		jwt.WithAudience(s.config.Audience),
+		jwt.WithExpirationRequired(),
+	)
+	if err != nil {
+		return err
+	}
+	if !token.Valid {
+		return fmt.Errorf("token inválido")
+	}
+	return nil
+}
+
+func (s *SigningKeyService) keyfunc(token *jwt.Token) (interface{}, error) {
+	kid, _ := token.Header["kid"].(string)
+	if kid == "" {
+		return nil, fmt.Errorf("token sin kid")
+	}
+
+	key, ok := s.lookup(kid)
+	if !ok {
+		// Puede ser una clave recién rotada por otra instancia
+		s.mu.RLock()
+		stale := time.Since(s.loadedAt) >= jwksMinRefreshInterval
+		s.mu.RUnlock()
+		if stale {
+			if err := s.reload(context.Background()); err != nil {
+				return nil, err
+			}
+			key, ok = s.lookup(kid)
+		}
+	}
+	if !ok {
+		return nil, fmt.Errorf("clave de firma desconocida: %q", kid)
+	}
+
+	if token.Method.Alg() != key.algorithm {
+		return nil, fmt.Errorf("algoritmo %s no corresponde a la clave %s", token.Method.Alg(), kid)
+	}
+	return key.public, nil
+}
+
+func (s *SigningKeyService) lookup(kid string) (*signingKey, bool) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	key, ok := s.keys[kid]
+	return key, ok
+}
+
+// Issuer y Audience son los valores que llevan los tokens emitidos
+func (s *SigningKeyService) Issuer() string   { return s.config.Issuer }
+func (s *SigningKeyService) Audience() string { return s.config.Audience }
+
+// JWKS devuelve las claves públicas vigentes para que otros servicios validen los tokens
+func (s *SigningKeyService) JWKS() JSONWebKeySet {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	set := JSONWebKeySet{Keys: make([]jsonWebKey, 0, len(s.keys))}
+	for _, key := range s.keys {
+		jwk, err := newJSONWebKey(key)
+		if err != nil {
+			continue
+		}
+		set.Keys = append(set.Keys, jwk)
+	}
+	return set
+}
+
+func newJSONWebKey(key *signingKey) (jsonWebKey, error) {
+	jwk := jsonWebKey{Kid: key.kid, Use: "sig", Alg: key.algorithm}
+
+	switch public := key.public.(type) {
+	case ed25519.PublicKey:
+		jwk.Kty = "OKP"
+		jwk.Crv = "Ed25519"
+		jwk.X = base64.RawURLEncoding.EncodeToString(public)
+	case *rsa.PublicKey:
+		jwk.Kty = "RSA"
+		jwk.N = base64.RawURLEncoding.EncodeToString(public.N.Bytes())
+		jwk.E = base64.RawURLEncoding.EncodeToString(big.NewInt(int64(public.E)).Bytes())
+	default:
+		return jwk, fmt.Errorf("tipo de clave no soportado: %T", key.public)
+	}
+
+	return jwk, nil
+}
+
+func generateSigningKey(algorithm string) (*signingKey, error) {
+	kid, err := generateRandomToken(16)
+	if err != nil {
+		return nil, err
+	}
+
+	key := &signingKey{kid: kid, algorithm: algorithm}
+	switch algorithm {
+	case SigningAlgEdDSA:
+		public, private, err := ed25519.GenerateKey(rand.Reader)
+		if err != nil {
+			return nil, err
+		}
+		key.private, key.public = private, public
+	case SigningAlgRS256:
+		private, err := rsa.GenerateKey(rand.Reader, rsaKeyBits)
+		if err != nil {
+			return nil, err
+		}
+		key.private, key.public = private, &private.PublicKey
+	default:
+		return nil, fmt.Errorf("algoritmo de firma no soportado: %s", algorithm)
+	}
+
+	return key, nil
+}
@@ -0,0 +1,566 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"mentorly-backend/models"
+	"regexp"
+	"slices"
+	"strings"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+const (
+	// MaxUserSkills es la cantidad máxima de habilidades de cada tipo por usuario
+	MaxUserSkills = 30
+
+	maxSlugLength = 80
+)
+
+var validSlug = regexp.MustCompile(`^[a-z0-9]+(-[a-z0-9]+)*$`)
+
+// slugReplacer quita acentos y conserva lenguajes como C++ o C# (cpp, csharp)
+var slugReplacer = strings.NewReplacer(
+	"á", "a", "é", "e", "í", "i", "ó", "o", "ú", "u", "ü", "u", "ñ", "n",
+	"+", "p", "#", "sharp",
+)
+
+// SkillInput son los datos de una habilidad del catálogo. Slug vacío se genera del nombre.
+type SkillInput struct {
+	Nombre      string
+	Slug        string
+	IDCategoria int
+	Alias       []string
+}
+
+// UserSkillInput es una habilidad que el usuario agrega a su perfil
+type UserSkillInput struct {
+	IDHabilidad int
+	Tipo        string
+	Nivel       string
+}
+
+// SkillService maneja el catálogo de habilidades y las habilidades de cada usuario
+type SkillService struct {
+	db *pgxpool.Pool
+}
+
+// NewSkillService crea una nueva instancia del servicio de habilidades
+func NewSkillService(db *pgxpool.Pool) *SkillService {
+	return &SkillService{db: db}
+}
+
+// ListCategories devuelve las categorías de primer nivel con sus subcategorías, por nombre
+func (s *SkillService) ListCategories(ctx context.Context) ([]models.SkillCategory, error) {
+	rows, err := s.db.Query(ctx,
+		`SELECT id_categoria, id_padre, nombre, slug
+		 FROM tb_categoria_habilidad
+		 ORDER BY id_padre NULLS FIRST, nombre`,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	categories := []models.SkillCategory{}
+	index := map[int]int{}
+	for rows.Next() {
+		var c models.SkillCategory
+		if err := rows.Scan(&c.IDCategoria, &c.IDPadre, &c.Nombre, &c.Slug); err != nil {
+			return nil, err
+		}
+		// Las de primer nivel vienen antes que todas las subcategorías
+		if c.IDPadre == nil {
+			index[c.IDCategoria] = len(categories)
+			categories = append(categories, c)
+		} else if i, ok := index[*c.IDPadre]; ok {
+			categories[i].Subcategorias = append(categories[i].Subcategorias, c)
+		}
+	}
+	return categories, rows.Err()
+}
+
+// GetCategory obtiene una categoría por su ID
+func (s *SkillService) GetCategory(ctx context.Context, idCategoria int) (*models.SkillCategory, error) {
+	var c models.SkillCategory
+	err := s.db.QueryRow(ctx,
+		"SELECT id_categoria, id_padre, nombre, slug FROM tb_categoria_habilidad WHERE id_categoria = $1",
+		idCategoria,
+	).Scan(&c.IDCategoria, &c.IDPadre, &c.Nombre, &c.Slug)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, ErrSkillCategoryNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &c, nil
+}
+
+// CreateCategory crea una categoría, o una subcategoría si se indica idPadre.
+// Solo se admiten dos niveles.
+func (s *SkillService) CreateCategory(ctx context.Context, nombre string, slug string, idPadre *int) (*models.SkillCategory, error) {
+	nombre, slug, err := normalizeSlug(nombre, slug)
+	if err != nil {
+		return nil, err
+	}
+
+	if idPadre != nil {
+		parent, err := s.GetCategory(ctx, *idPadre)
+		if err != nil {
+			return nil, err
+		}
+		if parent.IDPadre != nil {
+			return nil, ErrSkillCategoryTooDeep
+		}
+	}
+
+	var id int
+	err = s.db.QueryRow(ctx,
+		"INSERT INTO tb_categoria_habilidad (id_padre, nombre, slug) VALUES ($1, $2, $3) RETURNING id_categoria",
+		idPadre, nombre, slug,
+	).Scan(&id)
+	if err := skillWriteError(err); err != nil {
+		return nil, err
+	}
+	return s.GetCategory(ctx, id)
+}
+
+// UpdateCategory cambia el nombre y el slug de una categoría
+func (s *SkillService) UpdateCategory(ctx context.Context, idCategoria int, nombre string, slug string) (*models.SkillCategory, error) {
+	nombre, slug, err := normalizeSlug(nombre, slug)
+	if err != nil {
+		return nil, err
+	}
+
+	tag, err := s.db.Exec(ctx,
+		"UPDATE tb_categoria_habilidad SET nombre = $2, slug = $3 WHERE id_categoria = $1",
+		idCategoria, nombre, slug,
+	)
+	if err := skillWriteError(err); err != nil {
+		return nil, err
+	}
+	if tag.RowsAffected() == 0 {
+		return nil, ErrSkillCategoryNotFound
+	}
+	return s.GetCategory(ctx, idCategoria)
+}
+
+// DeleteCategory borra una categoría vacía (sin subcategorías ni habilidades)
+func (s *SkillService) DeleteCategory(ctx context.Context, idCategoria int) error {
+	tag, err := s.db.Exec(ctx, "DELETE FROM tb_categoria_habilidad WHERE id_categoria = $1", idCategoria)
+	var pgErr *pgconn.PgError
+	if errors.As(err, &pgErr) && pgErr.Code == "23503" {
+		return ErrSkillCategoryInUse
+	}
+	if err != nil {
+		return err
+	}
+	if tag.RowsAffected() == 0 {
+		return ErrSkillCategoryNotFound
+	}
+	return nil
+}
+
+const skillColumns = `h.id_habilidad, h.nombre, h.slug, h.id_categoria, concat_ws(' / ', cp.nombre, c.nombre),
+	ARRAY(SELECT a.alias FROM tb_habilidad_alias a WHERE a.id_habilidad = h.id_habilidad ORDER BY a.alias),
+	h.fecha_creacion`
+
+const skillFrom = `tb_habilidad h
+	JOIN tb_categoria_habilidad c ON c.id_categoria = h.id_categoria
+	LEFT JOIN tb_categoria_habilidad cp ON cp.id_categoria = c.id_padre`
+
+func scanSkill(row pgx.Row) (*models.Skill, error) {
+	var h models.Skill
+	err := row.Scan(&h.IDHabilidad, &h.Nombre, &h.Slug, &h.IDCategoria, &h.Categoria, &h.Alias, &h.FechaCreacion)
+	if err != nil {
+		return nil, err
+	}
+	return &h, nil
+}
+
+// Search busca habilidades para autocompletar: por el comienzo del nombre, de una de sus
+// palabras o de un alias. Primero la coincidencia exacta, después las más usadas.
+func (s *SkillService) Search(ctx context.Context, query string, limit int) ([]models.Skill, error) {
+	key := slugify(query)
+	if key == "" {
+		return []models.Skill{}, nil
+	}
+
+	// key solo tiene letras, números y guiones: no hace falta escapar el LIKE
+	rows, err := s.db.Query(ctx,
+		`SELECT `+skillColumns+`
+		 FROM `+skillFrom+`
+		 WHERE h.slug LIKE $1 || '%' OR h.slug LIKE '%-' || $1 || '%'
+		    OR EXISTS (SELECT 1 FROM tb_habilidad_alias a
+		               WHERE a.id_habilidad = h.id_habilidad AND (a.slug LIKE $1 || '%' OR a.slug LIKE '%-' || $1 || '%'))
+		 ORDER BY h.slug = $1 DESC, h.slug LIKE $1 || '%' DESC,
+		          (SELECT COUNT(*) FROM tb_persona_habilidad ph WHERE ph.id_habilidad = h.id_habilidad) DESC,
+		          h.nombre
+		 LIMIT $2`,
+		key, limit,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	skills := []models.Skill{}
+	for rows.Next() {
+		skill, err := scanSkill(rows)
+		if err != nil {
+			return nil, err
+		}
+		skills = append(skills, *skill)
+	}
+	return skills, rows.Err()
+}
+
+// GetSkill obtiene una habilidad por su ID
+func (s *SkillService) GetSkill(ctx context.Context, idHabilidad int) (*models.Skill, error) {
+	skill, err := scanSkill(s.db.QueryRow(ctx,
+		`SELECT `+skillColumns+` FROM `+skillFrom+` WHERE h.id_habilidad = $1`,
+		idHabilidad,
+	))
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, ErrSkillNotFound
+	}
+	return skill, err
+}
+
+// CreateSkill agrega una habilidad al catálogo con sus alias
+func (s *SkillService) CreateSkill(ctx context.Context, input SkillInput) (*models.Skill, error) {
+	return s.saveSkill(ctx, 0, input)
+}
+
+// UpdateSkill modifica una habilidad y reemplaza sus alias
+func (s *SkillService) UpdateSkill(ctx context.Context, idHabilidad int, input SkillInput) (*models.Skill, error) {
+	return s.saveSkill(ctx, idHabilidad, input)
+}
+
+// saveSkill crea la habilidad (idHabilidad 0) o la actualiza. Un slug no puede repetirse
+// entre habilidades ni entre alias, para que cada uno identifique una sola habilidad.
+func (s *SkillService) saveSkill(ctx context.Context, idHabilidad int, input SkillInput) (*models.Skill, error) {
+	nombre, slug, err := normalizeSlug(input.Nombre, input.Slug)
+	if err != nil {
+		return nil, err
+	}
+
+	var aliasSlugs, aliases []string
+	for _, alias := range input.Alias {
+		alias = strings.TrimSpace(alias)
+		aliasSlug := truncate(slugify(alias), maxSlugLength)
+		if aliasSlug == "" || aliasSlug == slug || slices.Contains(aliasSlugs, aliasSlug) {
+			continue
+		}
+		aliasSlugs = append(aliasSlugs, aliasSlug)
+		aliases = append(aliases, alias)
+	}
+
+	tx, err := s.db.Begin(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback(ctx)
+
+	var taken bool
+	err = tx.QueryRow(ctx,
+		`SELECT EXISTS (SELECT 1 FROM tb_habilidad_alias WHERE slug = $1 AND id_habilidad <> $2)
+		     OR EXISTS (SELECT 1 FROM tb_habilidad WHERE slug = ANY($3) AND id_habilidad <> $2)`,
+		slug, idHabilidad, aliasSlugs,
+	).Scan(&taken)
+	if err != nil {
+		return nil, err
+	}
+	if taken {
+		return nil, ErrSlugTaken
+	}
+
+	if idHabilidad == 0 {
+		err = tx.QueryRow(ctx,
+			"INSERT INTO tb_habilidad (id_categoria, nombre, slug) VALUES ($1, $2, $3) RETURNING id_habilidad",
+			input.IDCategoria, nombre, slug,
+		).Scan(&idHabilidad)
+	} else {
+		var tag pgconn.CommandTag
+		tag, err = tx.Exec(ctx,
+			"UPDATE tb_habilidad SET id_categoria = $2, nombre = $3, slug = $4 WHERE id_habilidad = $1",
+			idHabilidad, input.IDCategoria, nombre, slug,
+		)
+		if err == nil && tag.RowsAffected() == 0 {
+			return nil, ErrSkillNotFound
+		}
+	}
+	if err := skillWriteError(err); err != nil {
+		return nil, err
+	}
+
+	if _, err := tx.Exec(ctx, "DELETE FROM tb_habilidad_alias WHERE id_habilidad = $1", idHabilidad); err != nil {
+		return nil, err
+	}
+	_, err = tx.Exec(ctx,
+		`INSERT INTO tb_habilidad_alias (slug, alias, id_habilidad)
+		 SELECT a.slug, a.alias, $1 FROM unnest($2::text[], $3::text[]) AS a(slug, alias)`,
+		idHabilidad, aliasSlugs, aliases,
+	)
+	if err := skillWriteError(err); err != nil {
+		return nil, err
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return nil, err
+	}
+	return s.GetSkill(ctx, idHabilidad)
+}
+
+// DeleteSkill borra una habilidad que ningún usuario tiene. Las que están en uso se fusionan.
+func (s *SkillService) DeleteSkill(ctx context.Context, idHabilidad int) error {
+	tag, err := s.db.Exec(ctx,
+		`DELETE FROM tb_habilidad h
+		 WHERE h.id_habilidad = $1
+		   AND NOT EXISTS (SELECT 1 FROM tb_persona_habilidad ph WHERE ph.id_habilidad = h.id_habilidad)`,
+		idHabilidad,
+	)
+	if err != nil {
+		return err
+	}
+	if tag.RowsAffected() == 0 {
+		if _, err := s.GetSkill(ctx, idHabilidad); err != nil {
+			return err
+		}
+		return ErrSkillInUse
+	}
+	return nil
+}
+
+// Merge fusiona la habilidad idOrigen en idDestino: los usuarios y alias pasan a la de destino
+// (si un usuario tenía las dos se queda con el nivel más alto), el nombre y el slug de la de
+// origen quedan como alias y la de origen se borra. Devuelve cuántos usuarios se actualizaron.
+func (s *SkillService) Merge(ctx context.Context, idOrigen int, idDestino int) (*models.Skill, int, error) {
+	if idOrigen == idDestino {
+		return nil, 0, ErrCannotMergeSameSkill
+	}
+
+	tx, err := s.db.Begin(ctx)
+	if err != nil {
+		return nil, 0, err
+	}
+	defer tx.Rollback(ctx)
+
+	// Se bloquean las dos en orden de ID para no trabarse con otra fusión
+	var origenNombre, origenSlug *string
+	var found int
+	err = tx.QueryRow(ctx,
+		`SELECT COUNT(*), MAX(nombre) FILTER (WHERE id_habilidad = $1), MAX(slug) FILTER (WHERE id_habilidad = $1)
+		 FROM (SELECT id_habilidad, nombre, slug FROM tb_habilidad
+		       WHERE id_habilidad IN ($1, $2) ORDER BY id_habilidad FOR UPDATE) h`,
+		idOrigen, idDestino,
+	).Scan(&found, &origenNombre, &origenSlug)
+	if err != nil {
+		return nil, 0, err
+	}
+	if found != 2 {
+		return nil, 0, ErrSkillNotFound
+	}
+
+	// Si el usuario ya tenía la de destino se conserva el nivel más alto de las dos
+	_, err = tx.Exec(ctx,
+		`UPDATE tb_persona_habilidad d SET nivel = o.nivel
+		 FROM tb_persona_habilidad o
+		 WHERE d.id_habilidad = $2 AND o.id_habilidad = $1
+		   AND d.id_persona = o.id_persona AND d.tipo = o.tipo
+		   AND array_position($3::text[], o.nivel) > array_position($3::text[], d.nivel)`,
+		idOrigen, idDestino, skillLevels,
+	)
+	if err != nil {
+		return nil, 0, err
+	}
+	_, err = tx.Exec(ctx,
+		`DELETE FROM tb_persona_habilidad o
+		 USING tb_persona_habilidad d
+		 WHERE o.id_habilidad = $1 AND d.id_habilidad = $2
+		   AND d.id_persona = o.id_persona AND d.tipo = o.tipo`,
+		idOrigen, idDestino,
+	)
+	if err != nil {
+		return nil, 0, err
+	}
+	tag, err := tx.Exec(ctx, "UPDATE tb_persona_habilidad SET id_habilidad = $2 WHERE id_habilidad = $1", idOrigen, idDestino)
+	if err != nil {
+		return nil, 0, err
+	}
+	referencias := int(tag.RowsAffected())
+
+	if _, err := tx.Exec(ctx, "UPDATE tb_habilidad_alias SET id_habilidad = $2 WHERE id_habilidad = $1", idOrigen, idDestino); err != nil {
+		return nil, 0, err
+	}
+	if _, err := tx.Exec(ctx, "DELETE FROM tb_habilidad WHERE id_habilidad = $1", idOrigen); err != nil {
+		return nil, 0, err
+	}
+	// Después de borrarla, para que el slug ya no esté ocupado por la habilidad
+	_, err = tx.Exec(ctx,
+		`INSERT INTO tb_habilidad_alias (slug, alias, id_habilidad)
+		 SELECT $1, $2, $3 WHERE NOT EXISTS (SELECT 1 FROM tb_habilidad WHERE slug = $1)
+		 ON CONFLICT (slug) DO NOTHING`,
+		*origenSlug, *origenNombre, idDestino,
+	)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return nil, 0, err
+	}
+
+	skill, err := s.GetSkill(ctx, idDestino)
+	if err != nil {
+		return nil, 0, err
+	}
+	return skill, referencias, nil
+}
+
+// GetUserSkills devuelve las habilidades del usuario, por tipo y de mayor a menor nivel
+func (s *SkillService) GetUserSkills(ctx context.Context, idPersona int) ([]models.UserSkill, error) {
+	skills, err := userSkills(ctx, s.db, []int{idPersona}, "")
+	if err != nil {
+		return nil, err
+	}
+	if skills[idPersona] == nil {
+		return []models.UserSkill{}, nil
+	}
+	return skills[idPersona], nil
+}
+
+// SetUserSkills reemplaza todas las habilidades del usuario
+func (s *SkillService) SetUserSkills(ctx context.Context, idPersona int, items []UserSkillInput) ([]models.UserSkill, error) {
+	ids := make([]int, 0, len(items))
+	tipos := make([]string, 0, len(items))
+	niveles := make([]string, 0, len(items))
+	perType := map[string]int{}
+	seen := map[UserSkillInput]bool{}
+	for _, item := range items {
+		if item.Tipo != models.SkillTypeExpertise && item.Tipo != models.SkillTypeInterest {
+			return nil, ErrInvalidSkillType
+		}
+		if !slices.Contains(skillLevels, item.Nivel) {
+			return nil, ErrInvalidSkillLevel
+		}
+		key := UserSkillInput{IDHabilidad: item.IDHabilidad, Tipo: item.Tipo}
+		if seen[key] {
+			return nil, ErrDuplicateUserSkill
+		}
+		seen[key] = true
+		if perType[item.Tipo]++; perType[item.Tipo] > MaxUserSkills {
+			return nil, ErrTooManyUserSkills
+		}
+		ids = append(ids, item.IDHabilidad)
+		tipos = append(tipos, item.Tipo)
+		niveles = append(niveles, item.Nivel)
+	}
+
+	tx, err := s.db.Begin(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback(ctx)
+
+	if _, err := tx.Exec(ctx, "DELETE FROM tb_persona_habilidad WHERE id_persona = $1", idPersona); err != nil {
+		return nil, err
+	}
+	_, err = tx.Exec(ctx,
+		`INSERT INTO tb_persona_habilidad (id_persona, id_habilidad, tipo, nivel)
+		 SELECT $1, u.id_habilidad, u.tipo, u.nivel
+		 FROM unnest($2::int[], $3::text[], $4::text[]) AS u(id_habilidad, tipo, nivel)`,
+		idPersona, ids, tipos, niveles,
+	)
+	var pgErr *pgconn.PgError
+	if errors.As(err, &pgErr) && pgErr.Code == "23503" {
+		return nil, ErrSkillNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return nil, err
+	}
+	return s.GetUserSkills(ctx, idPersona)
+}
+
+// skillLevels son los niveles en orden creciente
+var skillLevels = []string{
+	models.SkillLevelBasic, models.SkillLevelIntermediate, models.SkillLevelAdvanced, models.SkillLevelExpert,
+}
+
+// userSkills carga las habilidades de varios usuarios, opcionalmente de un solo tipo
+func userSkills(ctx context.Context, db *pgxpool.Pool, ids []int, tipo string) (map[int][]models.UserSkill, error) {
+	rows, err := db.Query(ctx,
+		`SELECT ph.id_persona, h.id_habilidad, h.nombre, h.slug, ph.tipo, ph.nivel
+		 FROM tb_persona_habilidad ph JOIN tb_habilidad h ON h.id_habilidad = ph.id_habilidad
+		 WHERE ph.id_persona = ANY($1) AND ($2 = '' OR ph.tipo = $2)
+		 ORDER BY ph.id_persona, ph.tipo, array_position($3::text[], ph.nivel) DESC, h.nombre`,
+		ids, tipo, skillLevels,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	skills := map[int][]models.UserSkill{}
+	for rows.Next() {
+		var idPersona int
+		var us models.UserSkill
+		if err := rows.Scan(&idPersona, &us.IDHabilidad, &us.Nombre, &us.Slug, &us.Tipo, &us.Nivel); err != nil {
+			return nil, err
+		}
+		skills[idPersona] = append(skills[idPersona], us)
+	}
+	return skills, rows.Err()
+}
+
+// normalizeSlug recorta el nombre y genera el slug si no se indicó
+func normalizeSlug(nombre string, slug string) (string, string, error) {
+	nombre = strings.TrimSpace(nombre)
+	slug = strings.TrimSpace(slug)
+	if slug == "" {
+		slug = strings.TrimRight(truncate(slugify(nombre), maxSlugLength), "-")
+	}
+	if len(slug) > maxSlugLength || !validSlug.MatchString(slug) {
+		return nombre, slug, ErrInvalidSlug
+	}
+	return nombre, slug, nil
+}
+
+// slugify pasa el texto a minúsculas sin acentos y reemplaza el resto de los símbolos por guiones
+func slugify(value string) string {
+	value = slugReplacer.Replace(strings.ToLower(strings.TrimSpace(value)))
+
+	var b strings.Builder
+	dash := false
+	for _, r := range value {
+		if (r >= 'a' && r <= 'z') || (r >= '0' && r <= '9') {
+			b.WriteRune(r)
+			dash = false
+		} else if b.Len() > 0 && !dash {
+			b.WriteByte('-')
+			dash = true
+		}
+	}
+	return strings.TrimSuffix(b.String(), "-")
+}
+
+// skillWriteError traduce las violaciones de restricciones al guardar una habilidad
+func skillWriteError(err error) error {
+	var pgErr *pgconn.PgError
+	if errors.As(err, &pgErr) {
+		switch pgErr.Code {
+		case "23505":
+			return ErrSlugTaken
+		case "23503":
+			return ErrSkillCategoryNotFound
+		}
+	}
+	return err
+}
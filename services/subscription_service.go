@@ -29,3 +29,27 @@ func (s *SubscriptionService) CreateSubscription(ctx context.Context, idPersona,
 	}
 	return &sub, nil
 }
+
+// GetUserSubscriptions obtiene las suscripciones de un usuario, las más recientes primero.
+func (s *SubscriptionService) GetUserSubscriptions(ctx context.Context, idPersona int) ([]models.Subscription, error) {
+	rows, err := s.db.Query(ctx,
+		`SELECT id_suscripcion, id_persona, id_plan, fecha_inicial, fecha_expiracion
+		 FROM tb_suscripcion WHERE id_persona = $1
+		 ORDER BY fecha_inicial DESC`,
+		idPersona,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	subs := []models.Subscription{}
+	for rows.Next() {
+		var sub models.Subscription
+		if err := rows.Scan(&sub.ID, &sub.IDPersona, &sub.IDPlan, &sub.FechaInicial, &sub.FechaExpiracion); err != nil {
+			return nil, err
+		}
+		subs = append(subs, sub)
+	}
+	return subs, rows.Err()
+}
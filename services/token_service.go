@@ -0,0 +1,289 @@
+package services
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"time"
+	"unicode/utf8"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+// RefreshTokenDuration es la vida útil de un refresh token
+const RefreshTokenDuration = 30 * 24 * time.Hour
+
+// TokenService maneja los refresh tokens persistidos en base de datos
+type TokenService struct {
+	db *pgxpool.Pool
+}
+
+// NewTokenService crea una nueva instancia del servicio de tokens
+func NewTokenService(db *pgxpool.Pool) *TokenService {
+	return &TokenService{db: db}
+}
+
+// SessionClient identifica el dispositivo desde el que se usa la sesión
+type SessionClient struct {
+	UserAgent string
+	IP        string
+}
+
+// RotatedSession es el resultado de rotar un refresh token
+type RotatedSession struct {
+	IDPersona    int
+	IDSesion     int
+	Email        string
+	RefreshToken string
+}
+
+// maxUserAgentLength recorta user agents anómalos antes de guardarlos
+const maxUserAgentLength = 512
+
+// StartSession crea una sesión con su primer refresh token (que inicia una nueva familia).
+// Devuelve el id de la sesión y el refresh token.
+func (s *TokenService) StartSession(ctx context.Context, idPersona int, client SessionClient) (int, string, error) {
+	familia, err := generateRandomToken(16)
+	if err != nil {
+		return 0, "", err
+	}
+
+	tx, err := s.db.Begin(ctx)
+	if err != nil {
+		return 0, "", err
+	}
+	defer tx.Rollback(ctx)
+
+	var idSesion int
+	err = tx.QueryRow(ctx,
+		`INSERT INTO tb_sesion (id_persona, familia, user_agent, ip, fecha_creacion, ultima_actividad)
+		 VALUES ($1, $2, $3, $4, NOW(), NOW())
+		 RETURNING id_sesion`,
+		idPersona, familia, truncate(client.UserAgent, maxUserAgentLength), client.IP,
+	).Scan(&idSesion)
+	if err != nil {
+		return 0, "", err
+	}
+
+	refreshToken, err := s.insertRefreshToken(ctx, tx, idPersona, familia)
+	if err != nil {
+		return 0, "", err
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return 0, "", err
+	}
+
+	return idSesion, refreshToken, nil
+}
+
+// RotateRefreshToken consume un refresh token y devuelve uno nuevo de la misma familia,
+// actualizando la actividad de la sesión. Si el token ya había sido usado se asume
+// que fue robado y se revoca toda la familia junto con su sesión.
+func (s *TokenService) RotateRefreshToken(ctx context.Context, rawToken string, client SessionClient) (*RotatedSession, error) {
+	tx, err := s.db.Begin(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback(ctx)
+
+	var idToken, idPersona int
+	var familia, email string
+	var fechaExpiracion time.Time
+	var usadoEn, revocadoEn *time.Time
+	var suspendido bool
+
+	err = tx.QueryRow(ctx,
+		`SELECT rt.id_refresh_token, rt.id_persona, rt.familia, rt.fecha_expiracion, rt.usado_en, rt.revocado_en, p.email,
+		        p.suspendido_en IS NOT NULL
+		 FROM tb_refresh_token rt
+		 JOIN tb_persona p ON p.id_persona = rt.id_persona
+		 WHERE rt.token_hash = $1
+		 FOR UPDATE OF rt`,
+		hashToken(rawToken),
+	).Scan(&idToken, &idPersona, &familia, &fechaExpiracion, &usadoEn, &revocadoEn, &email, &suspendido)
+
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, ErrInvalidRefreshToken
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	if revocadoEn != nil {
+		return nil, ErrInvalidRefreshToken
+	}
+	if suspendido {
+		return nil, ErrAccountSuspended
+	}
+
+	// Reutilización de un token ya rotado: se invalida la cadena completa
+	if usadoEn != nil {
+		if err := revokeFamily(ctx, tx, familia); err != nil {
+			return nil, err
+		}
+		if err := tx.Commit(ctx); err != nil {
+			return nil, err
+		}
+		return nil, ErrRefreshTokenReused
+	}
+
+	if time.Now().After(fechaExpiracion) {
+		return nil, ErrInvalidRefreshToken
+	}
+
+	if _, err := tx.Exec(ctx,
+		"UPDATE tb_refresh_token SET usado_en = NOW() WHERE id_refresh_token = $1",
+		idToken,
+	); err != nil {
+		return nil, err
+	}
+
+	var idSesion int
+	err = tx.QueryRow(ctx,
+		`UPDATE tb_sesion SET ultima_actividad = NOW(), user_agent = $2, ip = $3
+		 WHERE familia = $1 AND revocada_en IS NULL
+		 RETURNING id_sesion`,
+		familia, truncate(client.UserAgent, maxUserAgentLength), client.IP,
+	).Scan(&idSesion)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, ErrInvalidRefreshToken
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	newToken, err := s.insertRefreshToken(ctx, tx, idPersona, familia)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return nil, err
+	}
+
+	return &RotatedSession{
+		IDPersona:    idPersona,
+		IDSesion:     idSesion,
+		Email:        email,
+		RefreshToken: newToken,
+	}, nil
+}
+
+// RevokeFamily revoca la familia completa a la que pertenece el refresh token y cierra su sesión
+func (s *TokenService) RevokeFamily(ctx context.Context, rawToken string) error {
+	var familia string
+	err := s.db.QueryRow(ctx,
+		"SELECT familia FROM tb_refresh_token WHERE token_hash = $1",
+		hashToken(rawToken),
+	).Scan(&familia)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return ErrInvalidRefreshToken
+	}
+	if err != nil {
+		return err
+	}
+
+	return revokeFamily(ctx, s.db, familia)
+}
+
+// RevokeAllForUser revoca todos los refresh tokens y sesiones activas de un usuario
+func (s *TokenService) RevokeAllForUser(ctx context.Context, idPersona int) error {
+	_, err := revokeAllForUser(ctx, s.db, idPersona)
+	return err
+}
+
+// revokeAllForUser revoca los refresh tokens y sesiones del usuario.
+// Devuelve la cantidad de sesiones cerradas.
+func revokeAllForUser(ctx context.Context, db dbExecutor, idPersona int) (int, error) {
+	_, err := db.Exec(ctx,
+		"UPDATE tb_refresh_token SET revocado_en = NOW() WHERE id_persona = $1 AND revocado_en IS NULL",
+		idPersona,
+	)
+	if err != nil {
+		return 0, err
+	}
+
+	result, err := db.Exec(ctx,
+		"UPDATE tb_sesion SET revocada_en = NOW() WHERE id_persona = $1 AND revocada_en IS NULL",
+		idPersona,
+	)
+	if err != nil {
+		return 0, err
+	}
+	return int(result.RowsAffected()), nil
+}
+
+// revokeFamily revoca los refresh tokens de una familia y la sesión asociada
+func revokeFamily(ctx context.Context, db dbExecutor, familia string) error {
+	_, err := db.Exec(ctx,
+		"UPDATE tb_refresh_token SET revocado_en = NOW() WHERE familia = $1 AND revocado_en IS NULL",
+		familia,
+	)
+	if err != nil {
+		return err
+	}
+
+	_, err = db.Exec(ctx,
+		"UPDATE tb_sesion SET revocada_en = NOW() WHERE familia = $1 AND revocada_en IS NULL",
+		familia,
+	)
+	return err
+}
+
+// dbExecutor permite usar tanto el pool como una transacción
+type dbExecutor interface {
+	Exec(ctx context.Context, sql string, args ...interface{}) (pgconn.CommandTag, error)
+	QueryRow(ctx context.Context, sql string, args ...interface{}) pgx.Row
+}
+
+func (s *TokenService) insertRefreshToken(ctx context.Context, db dbExecutor, idPersona int, familia string) (string, error) {
+	rawToken, err := generateRandomToken(32)
+	if err != nil {
+		return "", err
+	}
+
+	var idToken int
+	err = db.QueryRow(ctx,
+		`INSERT INTO tb_refresh_token (id_persona, familia, token_hash, fecha_creacion, fecha_expiracion)
+		 VALUES ($1, $2, $3, $4, $5)
+		 RETURNING id_refresh_token`,
+		idPersona, familia, hashToken(rawToken), time.Now(), time.Now().Add(RefreshTokenDuration),
+	).Scan(&idToken)
+	if err != nil {
+		return "", err
+	}
+
+	return rawToken, nil
+}
+
+// generateRandomToken genera un token aleatorio codificado en base64 url-safe
+func generateRandomToken(size int) (string, error) {
+	buf := make([]byte, size)
+	if _, err := rand.Read(buf); err != nil {
+		return "", err
+	}
+	return base64.RawURLEncoding.EncodeToString(buf), nil
+}
+
+// truncate recorta un texto a max bytes sin cortar un carácter UTF-8 por la mitad
+func truncate(value string, max int) string {
+	if len(value) <= max {
+		return value
+	}
+	for max > 0 && !utf8.RuneStart(value[max]) {
+		max--
+	}
+	return value[:max]
+}
+
+// hashToken devuelve el hash SHA-256 de un token; en la base solo se guardan hashes
+func hashToken(rawToken string) string {
+	sum := sha256.Sum256([]byte(rawToken))
+	return hex.EncodeToString(sum[:])
+}
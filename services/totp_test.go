@@ -0,0 +1,101 @@
+package services
+
+import (
+	"testing"
+	"time"
+)
+
+// rfc6238Secret es la semilla SHA-1 del Apéndice B de RFC 6238
+var rfc6238Secret = []byte("12345678901234567890")
+
+func TestTOTPCodeRFC6238Vectors(t *testing.T) {
+	// Los vectores del RFC son de 8 dígitos; con 6 dígitos el código son los últimos 6
+	tests := []struct {
+		unix int64
+		rfc  string
+	}{
+		{unix: 59, rfc: "94287082"},
+		{unix: 1111111109, rfc: "07081804"},
+		{unix: 1111111111, rfc: "14050471"},
+		{unix: 1234567890, rfc: "89005924"},
+		{unix: 2000000000, rfc: "69279037"},
+		{unix: 20000000000, rfc: "65353130"},
+	}
+
+	for _, tt := range tests {
+		expected := tt.rfc[len(tt.rfc)-totpDigits:]
+		if got := totpCode(rfc6238Secret, tt.unix/totpPeriod); got != expected {
+			t.Errorf("T=%d: código = %s, se esperaba %s", tt.unix, got, expected)
+		}
+
+		counter, ok := validateTOTP(rfc6238Secret, expected, time.Unix(tt.unix, 0), 0)
+		if !ok || counter != tt.unix/totpPeriod {
+			t.Errorf("T=%d: validateTOTP = (%d, %v), se esperaba (%d, true)", tt.unix, counter, ok, tt.unix/totpPeriod)
+		}
+	}
+}
+
+func TestValidateTOTPWindow(t *testing.T) {
+	now := time.Unix(1111111111, 0)
+	current := now.Unix() / totpPeriod
+
+	tests := []struct {
+		name    string
+		counter int64
+		valid   bool
+	}{
+		{name: "paso actual", counter: current, valid: true},
+		{name: "paso anterior", counter: current - totpSkew, valid: true},
+		{name: "paso siguiente", counter: current + totpSkew, valid: true},
+		{name: "fuera de la ventana hacia atrás", counter: current - totpSkew - 1, valid: false},
+		{name: "fuera de la ventana hacia adelante", counter: current + totpSkew + 1, valid: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			counter, ok := validateTOTP(rfc6238Secret, totpCode(rfc6238Secret, tt.counter), now, 0)
+			if ok != tt.valid {
+				t.Fatalf("validateTOTP = %v, se esperaba %v", ok, tt.valid)
+			}
+			if ok && counter != tt.counter {
+				t.Errorf("paso = %d, se esperaba %d", counter, tt.counter)
+			}
+		})
+	}
+
+	if _, ok := validateTOTP(rfc6238Secret, "12345", now, 0); ok {
+		t.Error("un código con otra cantidad de dígitos no debería ser válido")
+	}
+}
+
+func TestValidateTOTPRejectsReplay(t *testing.T) {
+	now := time.Unix(1234567890, 0)
+	current := now.Unix() / totpPeriod
+	code := totpCode(rfc6238Secret, current)
+
+	lastCounter, ok := validateTOTP(rfc6238Secret, code, now, 0)
+	if !ok {
+		t.Fatal("el primer uso del código debería ser válido")
+	}
+
+	// El mismo código dentro de la ventana ya no se acepta, ni en el mismo paso ni en el siguiente
+	if _, ok := validateTOTP(rfc6238Secret, code, now, lastCounter); ok {
+		t.Error("un código ya usado no debería aceptarse de nuevo")
+	}
+	if _, ok := validateTOTP(rfc6238Secret, code, now.Add(totpPeriod*time.Second), lastCounter); ok {
+		t.Error("un código ya usado no debería aceptarse en el paso siguiente")
+	}
+
+	// Un código de un paso anterior al último usado tampoco
+	previous := totpCode(rfc6238Secret, current-1)
+	if _, ok := validateTOTP(rfc6238Secret, previous, now, lastCounter); ok {
+		t.Error("un código anterior al último usado no debería aceptarse")
+	}
+
+	// El código del paso siguiente sí
+	next := totpCode(rfc6238Secret, current+1)
+	counter, ok := validateTOTP(rfc6238Secret, next, now.Add(totpPeriod*time.Second), lastCounter)
+	if !ok || counter != current+1 {
+		t.Errorf("validateTOTP = (%d, %v), se esperaba (%d, true)", counter, ok, current+1)
+	}
+}
@@ -0,0 +1,241 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"mentorly-backend/models"
+	"strings"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+// UserFilter son los criterios de búsqueda del listado de usuarios. Los campos vacíos no filtran.
+type UserFilter struct {
+	// Query busca en nombre, apellido y email
+	Query           string
+	Rol             string
+	RegistradoDesde *time.Time
+	RegistradoHasta *time.Time
+	Suspendido      *bool
+	Limit           int
+	Offset          int
+}
+
+// UserAdminService maneja la administración de usuarios
+type UserAdminService struct {
+	db            *pgxpool.Pool
+	subscriptions *SubscriptionService
+}
+
+// NewUserAdminService crea una nueva instancia del servicio de administración de usuarios
+func NewUserAdminService(db *pgxpool.Pool) *UserAdminService {
+	return &UserAdminService{db: db, subscriptions: NewSubscriptionService(db)}
+}
+
+const userSummaryColumns = `p.id_persona, p.nombre, p.apellido, p.email, p.email_verificado,
+	ARRAY(SELECT r.nombre_rol
+	      FROM tb_persona_rol pr JOIN tb_rol r ON r.id_rol = pr.id_rol
+	      WHERE pr.id_persona = p.id_persona
+	      ORDER BY pr.fecha_asignacion, r.id_rol),
+	p.fecha_registro, p.suspendido_en`
+
+// Search lista los usuarios que cumplen el filtro, los más recientes primero, junto con el total
+func (s *UserAdminService) Search(ctx context.Context, filter UserFilter) (*models.UserList, error) {
+	var conditions []string
+	var args []interface{}
+	argID := 1
+
+	if q := strings.TrimSpace(filter.Query); q != "" {
+		conditions = append(conditions, fmt.Sprintf(
+			"(p.nombre ILIKE $%d OR p.apellido ILIKE $%d OR p.email ILIKE $%d OR (p.nombre || ' ' || p.apellido) ILIKE $%d)",
+			argID, argID, argID, argID))
+		args = append(args, "%"+escapeLike(q)+"%")
+		argID++
+	}
+	if filter.Rol != "" {
+		conditions = append(conditions, fmt.Sprintf(
+			`EXISTS (SELECT 1 FROM tb_persona_rol pr JOIN tb_rol r ON r.id_rol = pr.id_rol
+			         WHERE pr.id_persona = p.id_persona AND r.nombre_rol = $%d)`, argID))
+		args = append(args, filter.Rol)
+		argID++
+	}
+	if filter.RegistradoDesde != nil {
+		conditions = append(conditions, fmt.Sprintf("p.fecha_registro >= $%d", argID))
+		args = append(args, *filter.RegistradoDesde)
+		argID++
+	}
+	if filter.RegistradoHasta != nil {
+		conditions = append(conditions, fmt.Sprintf("p.fecha_registro < $%d", argID))
+		args = append(args, *filter.RegistradoHasta)
+		argID++
+	}
+	if filter.Suspendido != nil {
+		if *filter.Suspendido {
+			conditions = append(conditions, "p.suspendido_en IS NOT NULL")
+		} else {
+			conditions = append(conditions, "p.suspendido_en IS NULL")
+		}
+	}
+
+	where := ""
+	if len(conditions) > 0 {
+		where = "WHERE " + strings.Join(conditions, " AND ")
+	}
+
+	query := fmt.Sprintf(
+		`SELECT %s, COUNT(*) OVER ()
+		 FROM tb_persona p
+		 %s
+		 ORDER BY p.fecha_registro DESC, p.id_persona DESC
+		 LIMIT $%d OFFSET $%d`,
+		userSummaryColumns, where, argID, argID+1)
+	args = append(args, filter.Limit, filter.Offset)
+
+	rows, err := s.db.Query(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	list := &models.UserList{Usuarios: []models.UserSummary{}}
+	for rows.Next() {
+		var u models.UserSummary
+		err := rows.Scan(&u.IDPersona, &u.Nombre, &u.Apellido, &u.Email, &u.EmailVerificado,
+			&u.Roles, &u.FechaRegistro, &u.SuspendidoEn, &list.Total)
+		if err != nil {
+			return nil, err
+		}
+		list.Usuarios = append(list.Usuarios, u)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	// Con un offset fuera de rango no vuelven filas, pero el total sigue siendo útil
+	if len(list.Usuarios) == 0 && filter.Offset > 0 {
+		countQuery := fmt.Sprintf("SELECT COUNT(*) FROM tb_persona p %s", where)
+		if err := s.db.QueryRow(ctx, countQuery, args[:len(args)-2]...).Scan(&list.Total); err != nil {
+			return nil, err
+		}
+	}
+
+	return list, nil
+}
+
+// GetDetail obtiene la ficha de un usuario con sus roles, suscripciones y sesiones activas
+func (s *UserAdminService) GetDetail(ctx context.Context, idPersona int) (*models.UserDetail, error) {
+	var d models.UserDetail
+	err := s.db.QueryRow(ctx,
+		`SELECT `+userSummaryColumns+`, p.contrasena IS NOT NULL, p.motivo_suspension,
+		        (SELECT COUNT(*) FROM tb_sesion s WHERE s.id_persona = p.id_persona AND s.revocada_en IS NULL)
+		 FROM tb_persona p WHERE p.id_persona = $1`,
+		idPersona,
+	).Scan(&d.IDPersona, &d.Nombre, &d.Apellido, &d.Email, &d.EmailVerificado, &d.Roles,
+		&d.FechaRegistro, &d.SuspendidoEn, &d.TieneContrasena, &d.MotivoSuspension, &d.SesionesActivas)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, ErrUserNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	d.Suscripciones, err = s.subscriptions.GetUserSubscriptions(ctx, idPersona)
+	if err != nil {
+		return nil, err
+	}
+	return &d, nil
+}
+
+// Suspend suspende la cuenta y cierra todas sus sesiones
+func (s *UserAdminService) Suspend(ctx context.Context, idPersona int, idAdmin int, motivo string) error {
+	tx, err := s.db.Begin(ctx)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback(ctx)
+
+	result, err := tx.Exec(ctx,
+		`UPDATE tb_persona SET suspendido_en = NOW(), motivo_suspension = NULLIF($2, ''), suspendido_por = $3
+		 WHERE id_persona = $1 AND suspendido_en IS NULL`,
+		idPersona, strings.TrimSpace(motivo), idAdmin,
+	)
+	if err != nil {
+		return err
+	}
+	if result.RowsAffected() == 0 {
+		return s.suspensionStateError(ctx, idPersona, ErrUserAlreadySuspended)
+	}
+
+	if _, err := revokeAllForUser(ctx, tx, idPersona); err != nil {
+		return err
+	}
+
+	return tx.Commit(ctx)
+}
+
+// Reactivate levanta la suspensión de la cuenta
+func (s *UserAdminService) Reactivate(ctx context.Context, idPersona int) error {
+	result, err := s.db.Exec(ctx,
+		`UPDATE tb_persona SET suspendido_en = NULL, motivo_suspension = NULL, suspendido_por = NULL
+		 WHERE id_persona = $1 AND suspendido_en IS NOT NULL`,
+		idPersona,
+	)
+	if err != nil {
+		return err
+	}
+	if result.RowsAffected() == 0 {
+		return s.suspensionStateError(ctx, idPersona, ErrUserNotSuspended)
+	}
+	return nil
+}
+
+// ForceLogout cierra todas las sesiones del usuario. Devuelve la cantidad de sesiones cerradas.
+func (s *UserAdminService) ForceLogout(ctx context.Context, idPersona int) (int, error) {
+	if err := s.ensureExists(ctx, idPersona); err != nil {
+		return 0, err
+	}
+	return revokeAllForUser(ctx, s.db, idPersona)
+}
+
+// SetEmailVerified marca manualmente el email como verificado o no verificado
+func (s *UserAdminService) SetEmailVerified(ctx context.Context, idPersona int, verificado bool) error {
+	result, err := s.db.Exec(ctx,
+		"UPDATE tb_persona SET email_verificado = $2 WHERE id_persona = $1",
+		idPersona, verificado,
+	)
+	if err != nil {
+		return err
+	}
+	if result.RowsAffected() == 0 {
+		return ErrUserNotFound
+	}
+	return nil
+}
+
+// suspensionStateError distingue entre un usuario inexistente y uno que ya estaba en el estado pedido
+func (s *UserAdminService) suspensionStateError(ctx context.Context, idPersona int, stateErr error) error {
+	if err := s.ensureExists(ctx, idPersona); err != nil {
+		return err
+	}
+	return stateErr
+}
+
+func (s *UserAdminService) ensureExists(ctx context.Context, idPersona int) error {
+	var exists bool
+	err := s.db.QueryRow(ctx, "SELECT EXISTS (SELECT 1 FROM tb_persona WHERE id_persona = $1)", idPersona).Scan(&exists)
+	if err != nil {
+		return err
+	}
+	if !exists {
+		return ErrUserNotFound
+	}
+	return nil
+}
+
+// escapeLike escapa los comodines de LIKE para buscar el texto literal
+func escapeLike(value string) string {
+	return strings.NewReplacer(`\`, `\\`, `%`, `\%`, `_`, `\_`).Replace(value)
+}
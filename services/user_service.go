@@ -14,76 +14,52 @@ type UserService struct {
 }
 
 type UserProfile struct {
-	IDPersona int
-	Nombre    string
-	Apellido  string
-	Email     string
-	IDRol     int
-	Rol       string
+	IDPersona       int
+	Nombre          string
+	Apellido        string
+	Email           string
+	EmailVerificado bool
+	TieneContrasena bool
+	// Roles son los nombres de los roles del usuario en el orden en que se asignaron
+	Roles []string
 }
 
 func NewUserService(db *pgxpool.Pool) *UserService {
 	return &UserService{db: db}
 }
 
-// GetUserProfile obtiene el perfil completo del usuario
+// GetUserProfile obtiene el perfil completo del usuario, con todos sus roles
 func (s *UserService) GetUserProfile(ctx context.Context, idPersona int) (*UserProfile, error) {
 	var nombre, apellido, email string
-	var idRol int
+	var emailVerificado, tieneContrasena bool
+	var roles []string
 
 	err := s.db.QueryRow(ctx,
-		"SELECT id_persona, nombre, apellido, email, id_rol FROM tb_persona WHERE id_persona = $1", // Sin cambios, ya era correcto
+		`SELECT p.id_persona, p.nombre, p.apellido, p.email, p.email_verificado, p.contrasena IS NOT NULL,
+		        ARRAY(SELECT r.nombre_rol
+		              FROM tb_persona_rol pr JOIN tb_rol r ON r.id_rol = pr.id_rol
+		              WHERE pr.id_persona = p.id_persona
+		              ORDER BY pr.fecha_asignacion, r.id_rol)
+		 FROM tb_persona p WHERE p.id_persona = $1`,
 		idPersona,
-	).Scan(&idPersona, &nombre, &apellido, &email, &idRol)
+	).Scan(&idPersona, &nombre, &apellido, &email, &emailVerificado, &tieneContrasena, &roles)
 
 	if err != nil {
 		log.Printf("Error al obtener perfil de usuario: %v", err)
 		return nil, ErrUserNotFound // Corregido: Devolver el error correcto
 	}
 
-	// Mapear el rol
-	rol := s.GetRoleNameByID(ctx, idRol)
-
 	return &UserProfile{
-		IDPersona: idPersona,
-		Nombre:    nombre,
-		Apellido:  apellido,
-		Email:     email,
-		IDRol:     idRol,
-		Rol:       rol,
+		IDPersona:       idPersona,
+		Nombre:          nombre,
+		Apellido:        apellido,
+		Email:           email,
+		EmailVerificado: emailVerificado,
+		TieneContrasena: tieneContrasena,
+		Roles:           roles,
 	}, nil
 }
 
-// GetRoleNameByID obtiene el nombre del rol por su ID
-func (s *UserService) GetRoleNameByID(ctx context.Context, idRol int) string {
-	var nombreRol string
-	err := s.db.QueryRow(ctx,
-		"SELECT nombre_rol FROM tb_rol WHERE id_rol = $1", // Corregido: tb_rol
-		idRol,
-	).Scan(&nombreRol)
-
-	if err != nil {
-		return "Sin rol"
-	}
-
-	return nombreRol
-}
-
-// UpdateUserRole actualiza el rol del usuario
-func (s *UserService) UpdateUserRole(ctx context.Context, idPersona int, idRol int) error {
-	_, err := s.db.Exec(ctx,
-		"UPDATE tb_persona SET id_rol = $1 WHERE id_persona = $2",
-		idRol, idPersona,
-	)
-
-	if err != nil {
-		log.Printf("Error al actualizar rol de usuario: %v", err)
-		return err
-	}
-
-	return nil
-}
-
 // UpdateUserProfile actualiza los campos del perfil de un usuario.
 // Solo actualiza los campos que no son cadenas vacías.
 func (s *UserService) UpdateUserProfile(ctx context.Context, idPersona int, nombre, apellido string) error {
@@ -115,19 +91,3 @@ func (s *UserService) UpdateUserProfile(ctx context.Context, idPersona int, nomb
 	_, err := s.db.Exec(ctx, query, args...)
 	return err
 }
-
-// GetRoleIDByName obtiene el ID del rol por su nombre
-func (s *UserService) GetRoleIDByName(ctx context.Context, nombreRol string) (int, error) {
-	var idRol int
-	err := s.db.QueryRow(ctx,
-		"SELECT id_rol FROM tb_rol WHERE nombre_rol = $1", // Corregido: tb_rol
-		nombreRol,
-	).Scan(&idRol)
-
-	if err != nil {
-		log.Printf("Error al obtener ID de rol: %v", err)
-		return 0, err
-	}
-
-	return idRol, nil
-}
@@ -0,0 +1,107 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+// TokenPurpose identifica para qué sirve un token enviado por email
+type TokenPurpose string
+
+const (
+	PurposeEmailVerification TokenPurpose = "verificacion_email"
+	PurposePasswordReset     TokenPurpose = "recuperacion_contrasena"
+	PurposeAccountUnlock     TokenPurpose = "desbloqueo_cuenta"
+)
+
+// UserTokenService maneja tokens de un solo uso asociados a un usuario
+type UserTokenService struct {
+	db *pgxpool.Pool
+}
+
+// NewUserTokenService crea una nueva instancia del servicio de tokens de usuario
+func NewUserTokenService(db *pgxpool.Pool) *UserTokenService {
+	return &UserTokenService{db: db}
+}
+
+// Issue genera un token nuevo e invalida los anteriores del mismo propósito que no se usaron
+func (s *UserTokenService) Issue(ctx context.Context, idPersona int, purpose TokenPurpose, ttl time.Duration) (string, error) {
+	rawToken, err := generateRandomToken(32)
+	if err != nil {
+		return "", err
+	}
+
+	tx, err := s.db.Begin(ctx)
+	if err != nil {
+		return "", err
+	}
+	defer tx.Rollback(ctx)
+
+	_, err = tx.Exec(ctx,
+		`UPDATE tb_token_usuario SET fecha_expiracion = NOW()
+		 WHERE id_persona = $1 AND proposito = $2 AND usado_en IS NULL AND fecha_expiracion > NOW()`,
+		idPersona, string(purpose),
+	)
+	if err != nil {
+		return "", err
+	}
+
+	_, err = tx.Exec(ctx,
+		`INSERT INTO tb_token_usuario (id_persona, proposito, token_hash, fecha_creacion, fecha_expiracion)
+		 VALUES ($1, $2, $3, $4, $5)`,
+		idPersona, string(purpose), hashToken(rawToken), time.Now(), time.Now().Add(ttl),
+	)
+	if err != nil {
+		return "", err
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return "", err
+	}
+
+	return rawToken, nil
+}
+
+// Consume valida el token y lo marca como usado. Devuelve el ID de la persona dueña del token.
+func (s *UserTokenService) Consume(ctx context.Context, purpose TokenPurpose, rawToken string) (int, error) {
+	if rawToken == "" {
+		return 0, ErrInvalidUserToken
+	}
+
+	var idPersona int
+	err := s.db.QueryRow(ctx,
+		`UPDATE tb_token_usuario SET usado_en = NOW()
+		 WHERE token_hash = $1 AND proposito = $2 AND usado_en IS NULL AND fecha_expiracion > NOW()
+		 RETURNING id_persona`,
+		hashToken(rawToken), string(purpose),
+	).Scan(&idPersona)
+
+	if errors.Is(err, pgx.ErrNoRows) {
+		return 0, ErrInvalidUserToken
+	}
+	if err != nil {
+		return 0, err
+	}
+
+	return idPersona, nil
+}
+
+// RecentIssues devuelve cuántos tokens de ese propósito se emitieron desde "since"
+// y la fecha del último, para poder limitar los reenvíos.
+func (s *UserTokenService) RecentIssues(ctx context.Context, idPersona int, purpose TokenPurpose, since time.Time) (int, *time.Time, error) {
+	var count int
+	var last *time.Time
+	err := s.db.QueryRow(ctx,
+		`SELECT COUNT(*), MAX(fecha_creacion) FROM tb_token_usuario
+		 WHERE id_persona = $1 AND proposito = $2 AND fecha_creacion >= $3`,
+		idPersona, string(purpose), since,
+	).Scan(&count, &last)
+	if err != nil {
+		return 0, nil, err
+	}
+	return count, last, nil
+}
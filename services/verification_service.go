@@ -0,0 +1,120 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/url"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+const (
+	// EmailVerificationTokenDuration es la vigencia del enlace de verificación
+	EmailVerificationTokenDuration = 48 * time.Hour
+	// verificationResendInterval es el tiempo mínimo entre dos envíos
+	verificationResendInterval = time.Minute
+	// verificationMaxPerHour es la cantidad máxima de envíos por hora
+	verificationMaxPerHour = 5
+)
+
+// EmailVerificationService maneja la verificación del email de los usuarios
+type EmailVerificationService struct {
+	db          *pgxpool.Pool
+	tokens      *UserTokenService
+	mailer      Mailer
+	linkBaseURL string
+}
+
+// NewEmailVerificationService crea el servicio. linkBaseURL es la URL del frontend
+// donde está la pantalla que completa la verificación.
+func NewEmailVerificationService(db *pgxpool.Pool, mailer Mailer, linkBaseURL string) *EmailVerificationService {
+	return &EmailVerificationService{
+		db:          db,
+		tokens:      NewUserTokenService(db),
+		mailer:      mailer,
+		linkBaseURL: linkBaseURL,
+	}
+}
+
+// SendVerification genera un token nuevo y envía el enlace de verificación
+func (s *EmailVerificationService) SendVerification(ctx context.Context, idPersona int) error {
+	var nombre, email string
+	var verificado bool
+	err := s.db.QueryRow(ctx,
+		"SELECT nombre, email, email_verificado FROM tb_persona WHERE id_persona = $1",
+		idPersona,
+	).Scan(&nombre, &email, &verificado)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return ErrUserNotFound
+	}
+	if err != nil {
+		return err
+	}
+
+	if verificado {
+		return ErrEmailAlreadyVerified
+	}
+
+	rawToken, err := s.tokens.Issue(ctx, idPersona, PurposeEmailVerification, EmailVerificationTokenDuration)
+	if err != nil {
+		return err
+	}
+
+	link := fmt.Sprintf("%s/verify-email?token=%s", s.linkBaseURL, url.QueryEscape(rawToken))
+	return s.mailer.Send(ctx, EmailMessage{
+		To:      email,
+		Subject: "Confirmá tu email en Mentorly",
+		Body: fmt.Sprintf("Hola %s,\n\nPara confirmar tu email ingresá al siguiente enlace:\n\n%s\n\n"+
+			"El enlace vence en %d horas. Si no creaste una cuenta en Mentorly, ignorá este mensaje.\n",
+			nombre, link, int(EmailVerificationTokenDuration.Hours())),
+	})
+}
+
+// ResendVerification reenvía el enlace respetando los límites de reenvío
+func (s *EmailVerificationService) ResendVerification(ctx context.Context, idPersona int) error {
+	count, last, err := s.tokens.RecentIssues(ctx, idPersona, PurposeEmailVerification, time.Now().Add(-time.Hour))
+	if err != nil {
+		return err
+	}
+
+	if last != nil {
+		if wait := verificationResendInterval - time.Since(*last); wait > 0 {
+			return &RetryAfterError{RetryAfter: wait}
+		}
+	}
+	if count >= verificationMaxPerHour {
+		return &RetryAfterError{RetryAfter: time.Hour}
+	}
+
+	return s.SendVerification(ctx, idPersona)
+}
+
+// VerifyEmail consume el token y marca el email como verificado
+func (s *EmailVerificationService) VerifyEmail(ctx context.Context, rawToken string) error {
+	idPersona, err := s.tokens.Consume(ctx, PurposeEmailVerification, rawToken)
+	if err != nil {
+		return err
+	}
+
+	_, err = s.db.Exec(ctx,
+		"UPDATE tb_persona SET email_verificado = TRUE WHERE id_persona = $1",
+		idPersona,
+	)
+	return err
+}
+
+// IsVerified indica si el usuario ya verificó su email
+func (s *EmailVerificationService) IsVerified(ctx context.Context, idPersona int) (bool, error) {
+	var verificado bool
+	err := s.db.QueryRow(ctx,
+		"SELECT email_verificado FROM tb_persona WHERE id_persona = $1",
+		idPersona,
+	).Scan(&verificado)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return false, ErrUserNotFound
+	}
+	return verificado, err
+}